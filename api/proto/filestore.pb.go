@@ -27,6 +27,13 @@ type UploadRequest struct {
 	Chunk         []byte                 `protobuf:"bytes,2,opt,name=chunk,proto3" json:"chunk,omitempty"`
 	TotalSize     int64                  `protobuf:"varint,3,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
 	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	FileId        string                 `protobuf:"bytes,5,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`                        // required for UploadVersion
+	WriteQuorum   int32                  `protobuf:"varint,6,opt,name=write_quorum,json=writeQuorum,proto3" json:"write_quorum,omitempty"`        // optional, replicas that must ack; server default if 0
+	StorageMode   string                 `protobuf:"bytes,7,opt,name=storage_mode,json=storageMode,proto3" json:"storage_mode,omitempty"`         // optional, "replicated" or "erasure"; server default if empty
+	DataShards    int32                  `protobuf:"varint,8,opt,name=data_shards,json=dataShards,proto3" json:"data_shards,omitempty"`           // optional, erasure data shards; server default if 0
+	ParityShards  int32                  `protobuf:"varint,9,opt,name=parity_shards,json=parityShards,proto3" json:"parity_shards,omitempty"`     // optional, erasure parity shards; server default if 0
+	ReplicaFactor int32                  `protobuf:"varint,10,opt,name=replica_factor,json=replicaFactor,proto3" json:"replica_factor,omitempty"` // optional, replicas to keep of a new file; server default if 0
+	Compression   string                 `protobuf:"bytes,11,opt,name=compression,proto3" json:"compression,omitempty"`                           // optional, "none", "zstd", "gzip" or "snappy"; chosen by content type if empty
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -89,6 +96,55 @@ func (x *UploadRequest) GetContentType() string {
 	return ""
 }
 
+func (x *UploadRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *UploadRequest) GetWriteQuorum() int32 {
+	if x != nil {
+		return x.WriteQuorum
+	}
+	return 0
+}
+
+func (x *UploadRequest) GetStorageMode() string {
+	if x != nil {
+		return x.StorageMode
+	}
+	return ""
+}
+
+func (x *UploadRequest) GetDataShards() int32 {
+	if x != nil {
+		return x.DataShards
+	}
+	return 0
+}
+
+func (x *UploadRequest) GetParityShards() int32 {
+	if x != nil {
+		return x.ParityShards
+	}
+	return 0
+}
+
+func (x *UploadRequest) GetReplicaFactor() int32 {
+	if x != nil {
+		return x.ReplicaFactor
+	}
+	return 0
+}
+
+func (x *UploadRequest) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
 type UploadResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
@@ -97,6 +153,7 @@ type UploadResponse struct {
 	NodeLocations []string               `protobuf:"bytes,4,rep,name=node_locations,json=nodeLocations,proto3" json:"node_locations,omitempty"`
 	Success       bool                   `protobuf:"varint,5,opt,name=success,proto3" json:"success,omitempty"`
 	Message       string                 `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
+	Replicas      []*ReplicaStatus       `protobuf:"bytes,7,rep,name=replicas,proto3" json:"replicas,omitempty"` // outcome of the write to each replica
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -173,17 +230,101 @@ func (x *UploadResponse) GetMessage() string {
 	return ""
 }
 
+func (x *UploadResponse) GetReplicas() []*ReplicaStatus {
+	if x != nil {
+		return x.Replicas
+	}
+	return nil
+}
+
+type ReplicaStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,4,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	HintedFor     string                 `protobuf:"bytes,5,opt,name=hinted_for,json=hintedFor,proto3" json:"hinted_for,omitempty"` // ring node this replica stands in for, if that node failed the write
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicaStatus) Reset() {
+	*x = ReplicaStatus{}
+	mi := &file_api_proto_filestore_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicaStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicaStatus) ProtoMessage() {}
+
+func (x *ReplicaStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicaStatus.ProtoReflect.Descriptor instead.
+func (*ReplicaStatus) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ReplicaStatus) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *ReplicaStatus) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReplicaStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ReplicaStatus) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *ReplicaStatus) GetHintedFor() string {
+	if x != nil {
+		return x.HintedFor
+	}
+	return ""
+}
+
 type DownloadRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
-	VersionId     string                 `protobuf:"bytes,2,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"` // optional, downloads latest if empty
+	VersionId     string                 `protobuf:"bytes,2,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`     // optional, downloads latest if empty
+	ReadQuorum    int32                  `protobuf:"varint,3,opt,name=read_quorum,json=readQuorum,proto3" json:"read_quorum,omitempty"` // optional, replicas whose checksums must agree; server default if 0
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DownloadRequest) Reset() {
 	*x = DownloadRequest{}
-	mi := &file_api_proto_filestore_proto_msgTypes[2]
+	mi := &file_api_proto_filestore_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -195,7 +336,7 @@ func (x *DownloadRequest) String() string {
 func (*DownloadRequest) ProtoMessage() {}
 
 func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_filestore_proto_msgTypes[2]
+	mi := &file_api_proto_filestore_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -208,7 +349,7 @@ func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DownloadRequest.ProtoReflect.Descriptor instead.
 func (*DownloadRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_filestore_proto_rawDescGZIP(), []int{2}
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *DownloadRequest) GetFileId() string {
@@ -225,6 +366,13 @@ func (x *DownloadRequest) GetVersionId() string {
 	return ""
 }
 
+func (x *DownloadRequest) GetReadQuorum() int32 {
+	if x != nil {
+		return x.ReadQuorum
+	}
+	return 0
+}
+
 type DownloadResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Chunk         []byte                 `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
@@ -236,7 +384,7 @@ type DownloadResponse struct {
 
 func (x *DownloadResponse) Reset() {
 	*x = DownloadResponse{}
-	mi := &file_api_proto_filestore_proto_msgTypes[3]
+	mi := &file_api_proto_filestore_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -248,7 +396,7 @@ func (x *DownloadResponse) String() string {
 func (*DownloadResponse) ProtoMessage() {}
 
 func (x *DownloadResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_filestore_proto_msgTypes[3]
+	mi := &file_api_proto_filestore_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -261,7 +409,7 @@ func (x *DownloadResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DownloadResponse.ProtoReflect.Descriptor instead.
 func (*DownloadResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_filestore_proto_rawDescGZIP(), []int{3}
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *DownloadResponse) GetChunk() []byte {
@@ -294,7 +442,7 @@ type DeleteRequest struct {
 
 func (x *DeleteRequest) Reset() {
 	*x = DeleteRequest{}
-	mi := &file_api_proto_filestore_proto_msgTypes[4]
+	mi := &file_api_proto_filestore_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -306,7 +454,7 @@ func (x *DeleteRequest) String() string {
 func (*DeleteRequest) ProtoMessage() {}
 
 func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_filestore_proto_msgTypes[4]
+	mi := &file_api_proto_filestore_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -319,7 +467,7 @@ func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
 func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_filestore_proto_rawDescGZIP(), []int{4}
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *DeleteRequest) GetFileId() string {
@@ -339,7 +487,7 @@ type DeleteResponse struct {
 
 func (x *DeleteResponse) Reset() {
 	*x = DeleteResponse{}
-	mi := &file_api_proto_filestore_proto_msgTypes[5]
+	mi := &file_api_proto_filestore_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -351,7 +499,7 @@ func (x *DeleteResponse) String() string {
 func (*DeleteResponse) ProtoMessage() {}
 
 func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_filestore_proto_msgTypes[5]
+	mi := &file_api_proto_filestore_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -364,7 +512,7 @@ func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
 func (*DeleteResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_filestore_proto_rawDescGZIP(), []int{5}
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *DeleteResponse) GetSuccess() bool {
@@ -390,7 +538,7 @@ type FileInfoRequest struct {
 
 func (x *FileInfoRequest) Reset() {
 	*x = FileInfoRequest{}
-	mi := &file_api_proto_filestore_proto_msgTypes[6]
+	mi := &file_api_proto_filestore_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -402,7 +550,7 @@ func (x *FileInfoRequest) String() string {
 func (*FileInfoRequest) ProtoMessage() {}
 
 func (x *FileInfoRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_filestore_proto_msgTypes[6]
+	mi := &file_api_proto_filestore_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -415,7 +563,7 @@ func (x *FileInfoRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileInfoRequest.ProtoReflect.Descriptor instead.
 func (*FileInfoRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_filestore_proto_rawDescGZIP(), []int{6}
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *FileInfoRequest) GetFileId() string {
@@ -426,22 +574,29 @@ func (x *FileInfoRequest) GetFileId() string {
 }
 
 type FileInfoResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
-	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
-	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
-	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
-	CreatedAt     string                 `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
-	UpdatedAt     string                 `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
-	Versions      []string               `protobuf:"bytes,7,rep,name=versions,proto3" json:"versions,omitempty"`
-	Replicas      []string               `protobuf:"bytes,8,rep,name=replicas,proto3" json:"replicas,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	FileId              string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Filename            string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Size                int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	ContentType         string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	CreatedAt           string                 `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt           string                 `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Versions            []string               `protobuf:"bytes,7,rep,name=versions,proto3" json:"versions,omitempty"`
+	Replicas            []string               `protobuf:"bytes,8,rep,name=replicas,proto3" json:"replicas,omitempty"`
+	RetentionKeepLast   int32                  `protobuf:"varint,9,opt,name=retention_keep_last,json=retentionKeepLast,proto3" json:"retention_keep_last,omitempty"`
+	RetentionMaxAgeDays int32                  `protobuf:"varint,10,opt,name=retention_max_age_days,json=retentionMaxAgeDays,proto3" json:"retention_max_age_days,omitempty"`
+	StorageMode         string                 `protobuf:"bytes,11,opt,name=storage_mode,json=storageMode,proto3" json:"storage_mode,omitempty"`               // of the latest version
+	ReplicaFactor       int32                  `protobuf:"varint,12,opt,name=replica_factor,json=replicaFactor,proto3" json:"replica_factor,omitempty"`        // replicas kept of replicated versions; 0 if the server default applies
+	Compression         string                 `protobuf:"bytes,13,opt,name=compression,proto3" json:"compression,omitempty"`                                  // codec of the latest version
+	StoredSize          int64                  `protobuf:"varint,14,opt,name=stored_size,json=storedSize,proto3" json:"stored_size,omitempty"`                 // bytes one copy of the latest version takes on disk, or all its shards if erasure-coded
+	EncryptionKeyId     string                 `protobuf:"bytes,15,opt,name=encryption_key_id,json=encryptionKeyId,proto3" json:"encryption_key_id,omitempty"` // master key wrapping the latest version's data key; empty if unencrypted
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *FileInfoResponse) Reset() {
 	*x = FileInfoResponse{}
-	mi := &file_api_proto_filestore_proto_msgTypes[7]
+	mi := &file_api_proto_filestore_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -453,7 +608,7 @@ func (x *FileInfoResponse) String() string {
 func (*FileInfoResponse) ProtoMessage() {}
 
 func (x *FileInfoResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_filestore_proto_msgTypes[7]
+	mi := &file_api_proto_filestore_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -466,7 +621,7 @@ func (x *FileInfoResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileInfoResponse.ProtoReflect.Descriptor instead.
 func (*FileInfoResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_filestore_proto_rawDescGZIP(), []int{7}
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *FileInfoResponse) GetFileId() string {
@@ -525,6 +680,55 @@ func (x *FileInfoResponse) GetReplicas() []string {
 	return nil
 }
 
+func (x *FileInfoResponse) GetRetentionKeepLast() int32 {
+	if x != nil {
+		return x.RetentionKeepLast
+	}
+	return 0
+}
+
+func (x *FileInfoResponse) GetRetentionMaxAgeDays() int32 {
+	if x != nil {
+		return x.RetentionMaxAgeDays
+	}
+	return 0
+}
+
+func (x *FileInfoResponse) GetStorageMode() string {
+	if x != nil {
+		return x.StorageMode
+	}
+	return ""
+}
+
+func (x *FileInfoResponse) GetReplicaFactor() int32 {
+	if x != nil {
+		return x.ReplicaFactor
+	}
+	return 0
+}
+
+func (x *FileInfoResponse) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
+func (x *FileInfoResponse) GetStoredSize() int64 {
+	if x != nil {
+		return x.StoredSize
+	}
+	return 0
+}
+
+func (x *FileInfoResponse) GetEncryptionKeyId() string {
+	if x != nil {
+		return x.EncryptionKeyId
+	}
+	return ""
+}
+
 type ListFilesRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Page          int32                  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
@@ -535,7 +739,7 @@ type ListFilesRequest struct {
 
 func (x *ListFilesRequest) Reset() {
 	*x = ListFilesRequest{}
-	mi := &file_api_proto_filestore_proto_msgTypes[8]
+	mi := &file_api_proto_filestore_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -547,7 +751,7 @@ func (x *ListFilesRequest) String() string {
 func (*ListFilesRequest) ProtoMessage() {}
 
 func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_filestore_proto_msgTypes[8]
+	mi := &file_api_proto_filestore_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -560,7 +764,7 @@ func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListFilesRequest.ProtoReflect.Descriptor instead.
 func (*ListFilesRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_filestore_proto_rawDescGZIP(), []int{8}
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ListFilesRequest) GetPage() int32 {
@@ -587,7 +791,7 @@ type ListFilesResponse struct {
 
 func (x *ListFilesResponse) Reset() {
 	*x = ListFilesResponse{}
-	mi := &file_api_proto_filestore_proto_msgTypes[9]
+	mi := &file_api_proto_filestore_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -599,7 +803,7 @@ func (x *ListFilesResponse) String() string {
 func (*ListFilesResponse) ProtoMessage() {}
 
 func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_filestore_proto_msgTypes[9]
+	mi := &file_api_proto_filestore_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -612,7 +816,7 @@ func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListFilesResponse.ProtoReflect.Descriptor instead.
 func (*ListFilesResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_filestore_proto_rawDescGZIP(), []int{9}
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *ListFilesResponse) GetFiles() []*FileInfoResponse {
@@ -633,13 +837,14 @@ type VersionRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
 	VersionId     string                 `protobuf:"bytes,2,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`
+	ReadQuorum    int32                  `protobuf:"varint,3,opt,name=read_quorum,json=readQuorum,proto3" json:"read_quorum,omitempty"` // optional, GetVersion only; server default if 0
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *VersionRequest) Reset() {
 	*x = VersionRequest{}
-	mi := &file_api_proto_filestore_proto_msgTypes[10]
+	mi := &file_api_proto_filestore_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -651,7 +856,7 @@ func (x *VersionRequest) String() string {
 func (*VersionRequest) ProtoMessage() {}
 
 func (x *VersionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_filestore_proto_msgTypes[10]
+	mi := &file_api_proto_filestore_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -664,7 +869,7 @@ func (x *VersionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use VersionRequest.ProtoReflect.Descriptor instead.
 func (*VersionRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_filestore_proto_rawDescGZIP(), []int{10}
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *VersionRequest) GetFileId() string {
@@ -681,71 +886,2347 @@ func (x *VersionRequest) GetVersionId() string {
 	return ""
 }
 
-var File_api_proto_filestore_proto protoreflect.FileDescriptor
+func (x *VersionRequest) GetReadQuorum() int32 {
+	if x != nil {
+		return x.ReadQuorum
+	}
+	return 0
+}
 
-const file_api_proto_filestore_proto_rawDesc = "" +
-	"\n" +
-	"\x19api/proto/filestore.proto\x12\tfilestore\"\x83\x01\n" +
-	"\rUploadRequest\x12\x1a\n" +
-	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x14\n" +
-	"\x05chunk\x18\x02 \x01(\fR\x05chunk\x12\x1d\n" +
-	"\n" +
-	"total_size\x18\x03 \x01(\x03R\ttotalSize\x12!\n" +
-	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\"\xb7\x01\n" +
-	"\x0eUploadResponse\x12\x17\n" +
-	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1d\n" +
-	"\n" +
-	"version_id\x18\x02 \x01(\tR\tversionId\x12\x12\n" +
-	"\x04size\x18\x03 \x01(\x03R\x04size\x12%\n" +
-	"\x0enode_locations\x18\x04 \x03(\tR\rnodeLocations\x12\x18\n" +
-	"\asuccess\x18\x05 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x06 \x01(\tR\amessage\"I\n" +
-	"\x0fDownloadRequest\x12\x17\n" +
-	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1d\n" +
-	"\n" +
-	"version_id\x18\x02 \x01(\tR\tversionId\"j\n" +
-	"\x10DownloadResponse\x12\x14\n" +
-	"\x05chunk\x18\x01 \x01(\fR\x05chunk\x12\x1d\n" +
-	"\n" +
-	"total_size\x18\x02 \x01(\x03R\ttotalSize\x12!\n" +
-	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\"(\n" +
-	"\rDeleteRequest\x12\x17\n" +
-	"\afile_id\x18\x01 \x01(\tR\x06fileId\"D\n" +
-	"\x0eDeleteResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"*\n" +
-	"\x0fFileInfoRequest\x12\x17\n" +
-	"\afile_id\x18\x01 \x01(\tR\x06fileId\"\xf4\x01\n" +
-	"\x10FileInfoResponse\x12\x17\n" +
-	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1a\n" +
-	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x12\n" +
-	"\x04size\x18\x03 \x01(\x03R\x04size\x12!\n" +
-	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x1d\n" +
-	"\n" +
-	"created_at\x18\x05 \x01(\tR\tcreatedAt\x12\x1d\n" +
-	"\n" +
-	"updated_at\x18\x06 \x01(\tR\tupdatedAt\x12\x1a\n" +
-	"\bversions\x18\a \x03(\tR\bversions\x12\x1a\n" +
-	"\breplicas\x18\b \x03(\tR\breplicas\"C\n" +
-	"\x10ListFilesRequest\x12\x12\n" +
-	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x1b\n" +
-	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\"g\n" +
-	"\x11ListFilesResponse\x121\n" +
-	"\x05files\x18\x01 \x03(\v2\x1b.filestore.FileInfoResponseR\x05files\x12\x1f\n" +
-	"\vtotal_count\x18\x02 \x01(\x05R\n" +
-	"totalCount\"H\n" +
-	"\x0eVersionRequest\x12\x17\n" +
-	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1d\n" +
-	"\n" +
-	"version_id\x18\x02 \x01(\tR\tversionId2\xaa\x03\n" +
-	"\tFileStore\x12?\n" +
-	"\x06Upload\x12\x18.filestore.UploadRequest\x1a\x19.filestore.UploadResponse(\x01\x12E\n" +
-	"\bDownload\x12\x1a.filestore.DownloadRequest\x1a\x1b.filestore.DownloadResponse0\x01\x12=\n" +
-	"\x06Delete\x12\x18.filestore.DeleteRequest\x1a\x19.filestore.DeleteResponse\x12F\n" +
-	"\vGetFileInfo\x12\x1a.filestore.FileInfoRequest\x1a\x1b.filestore.FileInfoResponse\x12F\n" +
-	"\tListFiles\x12\x1b.filestore.ListFilesRequest\x1a\x1c.filestore.ListFilesResponse\x12F\n" +
+// Zero values clear the file's policy so the global policy applies
+type SetRetentionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	KeepLast      int32                  `protobuf:"varint,2,opt,name=keep_last,json=keepLast,proto3" json:"keep_last,omitempty"`         // keep at most this many versions
+	MaxAgeDays    int32                  `protobuf:"varint,3,opt,name=max_age_days,json=maxAgeDays,proto3" json:"max_age_days,omitempty"` // drop versions older than this many days
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetRetentionRequest) Reset() {
+	*x = SetRetentionRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetRetentionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetRetentionRequest) ProtoMessage() {}
+
+func (x *SetRetentionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetRetentionRequest.ProtoReflect.Descriptor instead.
+func (*SetRetentionRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SetRetentionRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *SetRetentionRequest) GetKeepLast() int32 {
+	if x != nil {
+		return x.KeepLast
+	}
+	return 0
+}
+
+func (x *SetRetentionRequest) GetMaxAgeDays() int32 {
+	if x != nil {
+		return x.MaxAgeDays
+	}
+	return 0
+}
+
+type SetRetentionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetRetentionResponse) Reset() {
+	*x = SetRetentionResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetRetentionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetRetentionResponse) ProtoMessage() {}
+
+func (x *SetRetentionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetRetentionResponse.ProtoReflect.Descriptor instead.
+func (*SetRetentionResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SetRetentionResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SetRetentionResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type SetReplicaFactorRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	ReplicaFactor int32                  `protobuf:"varint,2,opt,name=replica_factor,json=replicaFactor,proto3" json:"replica_factor,omitempty"` // 0 reverts to the server default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetReplicaFactorRequest) Reset() {
+	*x = SetReplicaFactorRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetReplicaFactorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetReplicaFactorRequest) ProtoMessage() {}
+
+func (x *SetReplicaFactorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetReplicaFactorRequest.ProtoReflect.Descriptor instead.
+func (*SetReplicaFactorRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SetReplicaFactorRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *SetReplicaFactorRequest) GetReplicaFactor() int32 {
+	if x != nil {
+		return x.ReplicaFactor
+	}
+	return 0
+}
+
+type SetReplicaFactorResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Replicas      []string               `protobuf:"bytes,3,rep,name=replicas,proto3" json:"replicas,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetReplicaFactorResponse) Reset() {
+	*x = SetReplicaFactorResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetReplicaFactorResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetReplicaFactorResponse) ProtoMessage() {}
+
+func (x *SetReplicaFactorResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetReplicaFactorResponse.ProtoReflect.Descriptor instead.
+func (*SetReplicaFactorResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SetReplicaFactorResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SetReplicaFactorResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SetReplicaFactorResponse) GetReplicas() []string {
+	if x != nil {
+		return x.Replicas
+	}
+	return nil
+}
+
+type ReplicationStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunNow        bool                   `protobuf:"varint,1,opt,name=run_now,json=runNow,proto3" json:"run_now,omitempty"` // start a scan without waiting for the next interval
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicationStatusRequest) Reset() {
+	*x = ReplicationStatusRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicationStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationStatusRequest) ProtoMessage() {}
+
+func (x *ReplicationStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationStatusRequest.ProtoReflect.Descriptor instead.
+func (*ReplicationStatusRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ReplicationStatusRequest) GetRunNow() bool {
+	if x != nil {
+		return x.RunNow
+	}
+	return false
+}
+
+// Progress of the running re-replication scan, or of the last one
+type ReplicationStatusResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Running         bool                   `protobuf:"varint,1,opt,name=running,proto3" json:"running,omitempty"`
+	LastStarted     string                 `protobuf:"bytes,2,opt,name=last_started,json=lastStarted,proto3" json:"last_started,omitempty"`
+	LastFinished    string                 `protobuf:"bytes,3,opt,name=last_finished,json=lastFinished,proto3" json:"last_finished,omitempty"`
+	FilesScanned    int32                  `protobuf:"varint,4,opt,name=files_scanned,json=filesScanned,proto3" json:"files_scanned,omitempty"`
+	VersionsScanned int32                  `protobuf:"varint,5,opt,name=versions_scanned,json=versionsScanned,proto3" json:"versions_scanned,omitempty"`
+	UnderReplicated int32                  `protobuf:"varint,6,opt,name=under_replicated,json=underReplicated,proto3" json:"under_replicated,omitempty"`
+	Repaired        int32                  `protobuf:"varint,7,opt,name=repaired,proto3" json:"repaired,omitempty"`
+	Failed          int32                  `protobuf:"varint,8,opt,name=failed,proto3" json:"failed,omitempty"`
+	BytesCopied     int64                  `protobuf:"varint,9,opt,name=bytes_copied,json=bytesCopied,proto3" json:"bytes_copied,omitempty"`
+	LastError       string                 `protobuf:"bytes,10,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ReplicationStatusResponse) Reset() {
+	*x = ReplicationStatusResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicationStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationStatusResponse) ProtoMessage() {}
+
+func (x *ReplicationStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationStatusResponse.ProtoReflect.Descriptor instead.
+func (*ReplicationStatusResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ReplicationStatusResponse) GetRunning() bool {
+	if x != nil {
+		return x.Running
+	}
+	return false
+}
+
+func (x *ReplicationStatusResponse) GetLastStarted() string {
+	if x != nil {
+		return x.LastStarted
+	}
+	return ""
+}
+
+func (x *ReplicationStatusResponse) GetLastFinished() string {
+	if x != nil {
+		return x.LastFinished
+	}
+	return ""
+}
+
+func (x *ReplicationStatusResponse) GetFilesScanned() int32 {
+	if x != nil {
+		return x.FilesScanned
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetVersionsScanned() int32 {
+	if x != nil {
+		return x.VersionsScanned
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetUnderReplicated() int32 {
+	if x != nil {
+		return x.UnderReplicated
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetRepaired() int32 {
+	if x != nil {
+		return x.Repaired
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetBytesCopied() int64 {
+	if x != nil {
+		return x.BytesCopied
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+type RebalanceRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	DryRun         bool                   `protobuf:"varint,1,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`                           // only report the planned moves
+	BytesPerSecond int64                  `protobuf:"varint,2,opt,name=bytes_per_second,json=bytesPerSecond,proto3" json:"bytes_per_second,omitempty"` // optional, copy rate limit; server default if 0
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RebalanceRequest) Reset() {
+	*x = RebalanceRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RebalanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RebalanceRequest) ProtoMessage() {}
+
+func (x *RebalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RebalanceRequest.ProtoReflect.Descriptor instead.
+func (*RebalanceRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RebalanceRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *RebalanceRequest) GetBytesPerSecond() int64 {
+	if x != nil {
+		return x.BytesPerSecond
+	}
+	return 0
+}
+
+type RebalanceMove struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	VersionId     string                 `protobuf:"bytes,2,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Add           []string               `protobuf:"bytes,4,rep,name=add,proto3" json:"add,omitempty"`
+	Remove        []string               `protobuf:"bytes,5,rep,name=remove,proto3" json:"remove,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RebalanceMove) Reset() {
+	*x = RebalanceMove{}
+	mi := &file_api_proto_filestore_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RebalanceMove) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RebalanceMove) ProtoMessage() {}
+
+func (x *RebalanceMove) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RebalanceMove.ProtoReflect.Descriptor instead.
+func (*RebalanceMove) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RebalanceMove) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *RebalanceMove) GetVersionId() string {
+	if x != nil {
+		return x.VersionId
+	}
+	return ""
+}
+
+func (x *RebalanceMove) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *RebalanceMove) GetAdd() []string {
+	if x != nil {
+		return x.Add
+	}
+	return nil
+}
+
+func (x *RebalanceMove) GetRemove() []string {
+	if x != nil {
+		return x.Remove
+	}
+	return nil
+}
+
+type RebalanceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DryRun        bool                   `protobuf:"varint,1,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	Moves         []*RebalanceMove       `protobuf:"bytes,2,rep,name=moves,proto3" json:"moves,omitempty"`
+	Moved         int32                  `protobuf:"varint,3,opt,name=moved,proto3" json:"moved,omitempty"`
+	Failed        int32                  `protobuf:"varint,4,opt,name=failed,proto3" json:"failed,omitempty"`
+	BytesCopied   int64                  `protobuf:"varint,5,opt,name=bytes_copied,json=bytesCopied,proto3" json:"bytes_copied,omitempty"`
+	Started       string                 `protobuf:"bytes,6,opt,name=started,proto3" json:"started,omitempty"`
+	Finished      string                 `protobuf:"bytes,7,opt,name=finished,proto3" json:"finished,omitempty"`
+	Error         string                 `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RebalanceResponse) Reset() {
+	*x = RebalanceResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RebalanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RebalanceResponse) ProtoMessage() {}
+
+func (x *RebalanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RebalanceResponse.ProtoReflect.Descriptor instead.
+func (*RebalanceResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *RebalanceResponse) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *RebalanceResponse) GetMoves() []*RebalanceMove {
+	if x != nil {
+		return x.Moves
+	}
+	return nil
+}
+
+func (x *RebalanceResponse) GetMoved() int32 {
+	if x != nil {
+		return x.Moved
+	}
+	return 0
+}
+
+func (x *RebalanceResponse) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *RebalanceResponse) GetBytesCopied() int64 {
+	if x != nil {
+		return x.BytesCopied
+	}
+	return 0
+}
+
+func (x *RebalanceResponse) GetStarted() string {
+	if x != nil {
+		return x.Started
+	}
+	return ""
+}
+
+func (x *RebalanceResponse) GetFinished() string {
+	if x != nil {
+		return x.Finished
+	}
+	return ""
+}
+
+func (x *RebalanceResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type RepairLogRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limit         int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"` // optional, most recent events to return; all kept if 0
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RepairLogRequest) Reset() {
+	*x = RepairLogRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepairLogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepairLogRequest) ProtoMessage() {}
+
+func (x *RepairLogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepairLogRequest.ProtoReflect.Descriptor instead.
+func (*RepairLogRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *RepairLogRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type RepairEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          string                 `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	FileId        string                 `protobuf:"bytes,2,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	VersionId     string                 `protobuf:"bytes,3,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`
+	NodeId        string                 `protobuf:"bytes,4,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"` // missing, corrupt or diverged
+	Source        string                 `protobuf:"bytes,6,opt,name=source,proto3" json:"source,omitempty"`
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RepairEvent) Reset() {
+	*x = RepairEvent{}
+	mi := &file_api_proto_filestore_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepairEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepairEvent) ProtoMessage() {}
+
+func (x *RepairEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepairEvent.ProtoReflect.Descriptor instead.
+func (*RepairEvent) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *RepairEvent) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *RepairEvent) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *RepairEvent) GetVersionId() string {
+	if x != nil {
+		return x.VersionId
+	}
+	return ""
+}
+
+func (x *RepairEvent) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *RepairEvent) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *RepairEvent) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *RepairEvent) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Bad replicas found on a node and the outcome of repairing them
+type NodeRepairStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Missing       int32                  `protobuf:"varint,2,opt,name=missing,proto3" json:"missing,omitempty"`
+	Corrupt       int32                  `protobuf:"varint,3,opt,name=corrupt,proto3" json:"corrupt,omitempty"`
+	Diverged      int32                  `protobuf:"varint,4,opt,name=diverged,proto3" json:"diverged,omitempty"`
+	Repaired      int32                  `protobuf:"varint,5,opt,name=repaired,proto3" json:"repaired,omitempty"`
+	Failed        int32                  `protobuf:"varint,6,opt,name=failed,proto3" json:"failed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeRepairStats) Reset() {
+	*x = NodeRepairStats{}
+	mi := &file_api_proto_filestore_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeRepairStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeRepairStats) ProtoMessage() {}
+
+func (x *NodeRepairStats) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeRepairStats.ProtoReflect.Descriptor instead.
+func (*NodeRepairStats) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *NodeRepairStats) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *NodeRepairStats) GetMissing() int32 {
+	if x != nil {
+		return x.Missing
+	}
+	return 0
+}
+
+func (x *NodeRepairStats) GetCorrupt() int32 {
+	if x != nil {
+		return x.Corrupt
+	}
+	return 0
+}
+
+func (x *NodeRepairStats) GetDiverged() int32 {
+	if x != nil {
+		return x.Diverged
+	}
+	return 0
+}
+
+func (x *NodeRepairStats) GetRepaired() int32 {
+	if x != nil {
+		return x.Repaired
+	}
+	return 0
+}
+
+func (x *NodeRepairStats) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+type RepairLogResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*RepairEvent         `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Nodes         []*NodeRepairStats     `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RepairLogResponse) Reset() {
+	*x = RepairLogResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepairLogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepairLogResponse) ProtoMessage() {}
+
+func (x *RepairLogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepairLogResponse.ProtoReflect.Descriptor instead.
+func (*RepairLogResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *RepairLogResponse) GetEvents() []*RepairEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *RepairLogResponse) GetNodes() []*NodeRepairStats {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type ScrubStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunNow        bool                   `protobuf:"varint,1,opt,name=run_now,json=runNow,proto3" json:"run_now,omitempty"` // start a scrub without waiting for the next interval
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScrubStatusRequest) Reset() {
+	*x = ScrubStatusRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScrubStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScrubStatusRequest) ProtoMessage() {}
+
+func (x *ScrubStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScrubStatusRequest.ProtoReflect.Descriptor instead.
+func (*ScrubStatusRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ScrubStatusRequest) GetRunNow() bool {
+	if x != nil {
+		return x.RunNow
+	}
+	return false
+}
+
+type CorruptObject struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	FileId        string                 `protobuf:"bytes,2,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	VersionId     string                 `protobuf:"bytes,3,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CorruptObject) Reset() {
+	*x = CorruptObject{}
+	mi := &file_api_proto_filestore_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CorruptObject) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CorruptObject) ProtoMessage() {}
+
+func (x *CorruptObject) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CorruptObject.ProtoReflect.Descriptor instead.
+func (*CorruptObject) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *CorruptObject) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *CorruptObject) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *CorruptObject) GetVersionId() string {
+	if x != nil {
+		return x.VersionId
+	}
+	return ""
+}
+
+func (x *CorruptObject) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Stats of the running scrub, or of the last one
+type ScrubStatusResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Running        bool                   `protobuf:"varint,1,opt,name=running,proto3" json:"running,omitempty"`
+	Started        string                 `protobuf:"bytes,2,opt,name=started,proto3" json:"started,omitempty"`
+	Finished       string                 `protobuf:"bytes,3,opt,name=finished,proto3" json:"finished,omitempty"`
+	NodesScanned   int32                  `protobuf:"varint,4,opt,name=nodes_scanned,json=nodesScanned,proto3" json:"nodes_scanned,omitempty"`
+	ObjectsScanned int32                  `protobuf:"varint,5,opt,name=objects_scanned,json=objectsScanned,proto3" json:"objects_scanned,omitempty"`
+	BytesScanned   int64                  `protobuf:"varint,6,opt,name=bytes_scanned,json=bytesScanned,proto3" json:"bytes_scanned,omitempty"`
+	Corrupt        int32                  `protobuf:"varint,7,opt,name=corrupt,proto3" json:"corrupt,omitempty"`
+	Repaired       int32                  `protobuf:"varint,8,opt,name=repaired,proto3" json:"repaired,omitempty"`
+	RepairFailed   int32                  `protobuf:"varint,9,opt,name=repair_failed,json=repairFailed,proto3" json:"repair_failed,omitempty"`
+	CorruptObjects []*CorruptObject       `protobuf:"bytes,10,rep,name=corrupt_objects,json=corruptObjects,proto3" json:"corrupt_objects,omitempty"`
+	LastError      string                 `protobuf:"bytes,11,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ScrubStatusResponse) Reset() {
+	*x = ScrubStatusResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScrubStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScrubStatusResponse) ProtoMessage() {}
+
+func (x *ScrubStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScrubStatusResponse.ProtoReflect.Descriptor instead.
+func (*ScrubStatusResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ScrubStatusResponse) GetRunning() bool {
+	if x != nil {
+		return x.Running
+	}
+	return false
+}
+
+func (x *ScrubStatusResponse) GetStarted() string {
+	if x != nil {
+		return x.Started
+	}
+	return ""
+}
+
+func (x *ScrubStatusResponse) GetFinished() string {
+	if x != nil {
+		return x.Finished
+	}
+	return ""
+}
+
+func (x *ScrubStatusResponse) GetNodesScanned() int32 {
+	if x != nil {
+		return x.NodesScanned
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetObjectsScanned() int32 {
+	if x != nil {
+		return x.ObjectsScanned
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetBytesScanned() int64 {
+	if x != nil {
+		return x.BytesScanned
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetCorrupt() int32 {
+	if x != nil {
+		return x.Corrupt
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetRepaired() int32 {
+	if x != nil {
+		return x.Repaired
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetRepairFailed() int32 {
+	if x != nil {
+		return x.RepairFailed
+	}
+	return 0
+}
+
+func (x *ScrubStatusResponse) GetCorruptObjects() []*CorruptObject {
+	if x != nil {
+		return x.CorruptObjects
+	}
+	return nil
+}
+
+func (x *ScrubStatusResponse) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+type AntiEntropyStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunNow        bool                   `protobuf:"varint,1,opt,name=run_now,json=runNow,proto3" json:"run_now,omitempty"` // start a run without waiting for the next interval
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AntiEntropyStatusRequest) Reset() {
+	*x = AntiEntropyStatusRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AntiEntropyStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AntiEntropyStatusRequest) ProtoMessage() {}
+
+func (x *AntiEntropyStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AntiEntropyStatusRequest.ProtoReflect.Descriptor instead.
+func (*AntiEntropyStatusRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *AntiEntropyStatusRequest) GetRunNow() bool {
+	if x != nil {
+		return x.RunNow
+	}
+	return false
+}
+
+// Stats of the running anti-entropy pass, or of the last one
+type AntiEntropyStatusResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Running         bool                   `protobuf:"varint,1,opt,name=running,proto3" json:"running,omitempty"`
+	Started         string                 `protobuf:"bytes,2,opt,name=started,proto3" json:"started,omitempty"`
+	Finished        string                 `protobuf:"bytes,3,opt,name=finished,proto3" json:"finished,omitempty"`
+	PairsCompared   int32                  `protobuf:"varint,4,opt,name=pairs_compared,json=pairsCompared,proto3" json:"pairs_compared,omitempty"`
+	BucketsDiffered int32                  `protobuf:"varint,5,opt,name=buckets_differed,json=bucketsDiffered,proto3" json:"buckets_differed,omitempty"`
+	ObjectsDiffered int32                  `protobuf:"varint,6,opt,name=objects_differed,json=objectsDiffered,proto3" json:"objects_differed,omitempty"`
+	Repaired        int32                  `protobuf:"varint,7,opt,name=repaired,proto3" json:"repaired,omitempty"`
+	Removed         int32                  `protobuf:"varint,8,opt,name=removed,proto3" json:"removed,omitempty"`
+	Failed          int32                  `protobuf:"varint,9,opt,name=failed,proto3" json:"failed,omitempty"`
+	LastError       string                 `protobuf:"bytes,10,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *AntiEntropyStatusResponse) Reset() {
+	*x = AntiEntropyStatusResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AntiEntropyStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AntiEntropyStatusResponse) ProtoMessage() {}
+
+func (x *AntiEntropyStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AntiEntropyStatusResponse.ProtoReflect.Descriptor instead.
+func (*AntiEntropyStatusResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *AntiEntropyStatusResponse) GetRunning() bool {
+	if x != nil {
+		return x.Running
+	}
+	return false
+}
+
+func (x *AntiEntropyStatusResponse) GetStarted() string {
+	if x != nil {
+		return x.Started
+	}
+	return ""
+}
+
+func (x *AntiEntropyStatusResponse) GetFinished() string {
+	if x != nil {
+		return x.Finished
+	}
+	return ""
+}
+
+func (x *AntiEntropyStatusResponse) GetPairsCompared() int32 {
+	if x != nil {
+		return x.PairsCompared
+	}
+	return 0
+}
+
+func (x *AntiEntropyStatusResponse) GetBucketsDiffered() int32 {
+	if x != nil {
+		return x.BucketsDiffered
+	}
+	return 0
+}
+
+func (x *AntiEntropyStatusResponse) GetObjectsDiffered() int32 {
+	if x != nil {
+		return x.ObjectsDiffered
+	}
+	return 0
+}
+
+func (x *AntiEntropyStatusResponse) GetRepaired() int32 {
+	if x != nil {
+		return x.Repaired
+	}
+	return 0
+}
+
+func (x *AntiEntropyStatusResponse) GetRemoved() int32 {
+	if x != nil {
+		return x.Removed
+	}
+	return 0
+}
+
+func (x *AntiEntropyStatusResponse) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *AntiEntropyStatusResponse) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+type HandoffStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunNow        bool                   `protobuf:"varint,1,opt,name=run_now,json=runNow,proto3" json:"run_now,omitempty"` // start a pass without waiting for the next interval
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HandoffStatusRequest) Reset() {
+	*x = HandoffStatusRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HandoffStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandoffStatusRequest) ProtoMessage() {}
+
+func (x *HandoffStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandoffStatusRequest.ProtoReflect.Descriptor instead.
+func (*HandoffStatusRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *HandoffStatusRequest) GetRunNow() bool {
+	if x != nil {
+		return x.RunNow
+	}
+	return false
+}
+
+// Stats of the running hinted handoff pass, or of the last one
+type HandoffStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Running       bool                   `protobuf:"varint,1,opt,name=running,proto3" json:"running,omitempty"`
+	LastStarted   string                 `protobuf:"bytes,2,opt,name=last_started,json=lastStarted,proto3" json:"last_started,omitempty"`
+	LastFinished  string                 `protobuf:"bytes,3,opt,name=last_finished,json=lastFinished,proto3" json:"last_finished,omitempty"`
+	Hints         int32                  `protobuf:"varint,4,opt,name=hints,proto3" json:"hints,omitempty"`
+	HandedOff     int32                  `protobuf:"varint,5,opt,name=handed_off,json=handedOff,proto3" json:"handed_off,omitempty"`
+	Dropped       int32                  `protobuf:"varint,6,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	Waiting       int32                  `protobuf:"varint,7,opt,name=waiting,proto3" json:"waiting,omitempty"`
+	Failed        int32                  `protobuf:"varint,8,opt,name=failed,proto3" json:"failed,omitempty"`
+	BytesCopied   int64                  `protobuf:"varint,9,opt,name=bytes_copied,json=bytesCopied,proto3" json:"bytes_copied,omitempty"`
+	LastError     string                 `protobuf:"bytes,10,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HandoffStatusResponse) Reset() {
+	*x = HandoffStatusResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HandoffStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandoffStatusResponse) ProtoMessage() {}
+
+func (x *HandoffStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandoffStatusResponse.ProtoReflect.Descriptor instead.
+func (*HandoffStatusResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *HandoffStatusResponse) GetRunning() bool {
+	if x != nil {
+		return x.Running
+	}
+	return false
+}
+
+func (x *HandoffStatusResponse) GetLastStarted() string {
+	if x != nil {
+		return x.LastStarted
+	}
+	return ""
+}
+
+func (x *HandoffStatusResponse) GetLastFinished() string {
+	if x != nil {
+		return x.LastFinished
+	}
+	return ""
+}
+
+func (x *HandoffStatusResponse) GetHints() int32 {
+	if x != nil {
+		return x.Hints
+	}
+	return 0
+}
+
+func (x *HandoffStatusResponse) GetHandedOff() int32 {
+	if x != nil {
+		return x.HandedOff
+	}
+	return 0
+}
+
+func (x *HandoffStatusResponse) GetDropped() int32 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+func (x *HandoffStatusResponse) GetWaiting() int32 {
+	if x != nil {
+		return x.Waiting
+	}
+	return 0
+}
+
+func (x *HandoffStatusResponse) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *HandoffStatusResponse) GetBytesCopied() int64 {
+	if x != nil {
+		return x.BytesCopied
+	}
+	return 0
+}
+
+func (x *HandoffStatusResponse) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+type FsckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Repair        bool                   `protobuf:"varint,1,opt,name=repair,proto3" json:"repair,omitempty"` // fix what can be fixed instead of only reporting
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FsckRequest) Reset() {
+	*x = FsckRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FsckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FsckRequest) ProtoMessage() {}
+
+func (x *FsckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FsckRequest.ProtoReflect.Descriptor instead.
+func (*FsckRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *FsckRequest) GetRepair() bool {
+	if x != nil {
+		return x.Repair
+	}
+	return false
+}
+
+// An inconsistency between the metadata and the storage nodes
+type FsckIssue struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"` // dangling, diverged, under-replicated, over-replicated or orphan
+	FileId        string                 `protobuf:"bytes,2,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	VersionId     string                 `protobuf:"bytes,3,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`
+	NodeId        string                 `protobuf:"bytes,4,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"` // empty for issues with the whole version
+	Detail        string                 `protobuf:"bytes,5,opt,name=detail,proto3" json:"detail,omitempty"`
+	Repaired      bool                   `protobuf:"varint,6,opt,name=repaired,proto3" json:"repaired,omitempty"`
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FsckIssue) Reset() {
+	*x = FsckIssue{}
+	mi := &file_api_proto_filestore_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FsckIssue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FsckIssue) ProtoMessage() {}
+
+func (x *FsckIssue) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FsckIssue.ProtoReflect.Descriptor instead.
+func (*FsckIssue) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *FsckIssue) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *FsckIssue) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *FsckIssue) GetVersionId() string {
+	if x != nil {
+		return x.VersionId
+	}
+	return ""
+}
+
+func (x *FsckIssue) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *FsckIssue) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+func (x *FsckIssue) GetRepaired() bool {
+	if x != nil {
+		return x.Repaired
+	}
+	return false
+}
+
+func (x *FsckIssue) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type FsckResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Repair          bool                   `protobuf:"varint,1,opt,name=repair,proto3" json:"repair,omitempty"`
+	Started         string                 `protobuf:"bytes,2,opt,name=started,proto3" json:"started,omitempty"`
+	Finished        string                 `protobuf:"bytes,3,opt,name=finished,proto3" json:"finished,omitempty"`
+	NodesChecked    int32                  `protobuf:"varint,4,opt,name=nodes_checked,json=nodesChecked,proto3" json:"nodes_checked,omitempty"`
+	FilesChecked    int32                  `protobuf:"varint,5,opt,name=files_checked,json=filesChecked,proto3" json:"files_checked,omitempty"`
+	VersionsChecked int32                  `protobuf:"varint,6,opt,name=versions_checked,json=versionsChecked,proto3" json:"versions_checked,omitempty"`
+	ObjectsChecked  int32                  `protobuf:"varint,7,opt,name=objects_checked,json=objectsChecked,proto3" json:"objects_checked,omitempty"`
+	Issues          []*FsckIssue           `protobuf:"bytes,8,rep,name=issues,proto3" json:"issues,omitempty"`
+	Repaired        int32                  `protobuf:"varint,9,opt,name=repaired,proto3" json:"repaired,omitempty"`
+	Failed          int32                  `protobuf:"varint,10,opt,name=failed,proto3" json:"failed,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *FsckResponse) Reset() {
+	*x = FsckResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FsckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FsckResponse) ProtoMessage() {}
+
+func (x *FsckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FsckResponse.ProtoReflect.Descriptor instead.
+func (*FsckResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *FsckResponse) GetRepair() bool {
+	if x != nil {
+		return x.Repair
+	}
+	return false
+}
+
+func (x *FsckResponse) GetStarted() string {
+	if x != nil {
+		return x.Started
+	}
+	return ""
+}
+
+func (x *FsckResponse) GetFinished() string {
+	if x != nil {
+		return x.Finished
+	}
+	return ""
+}
+
+func (x *FsckResponse) GetNodesChecked() int32 {
+	if x != nil {
+		return x.NodesChecked
+	}
+	return 0
+}
+
+func (x *FsckResponse) GetFilesChecked() int32 {
+	if x != nil {
+		return x.FilesChecked
+	}
+	return 0
+}
+
+func (x *FsckResponse) GetVersionsChecked() int32 {
+	if x != nil {
+		return x.VersionsChecked
+	}
+	return 0
+}
+
+func (x *FsckResponse) GetObjectsChecked() int32 {
+	if x != nil {
+		return x.ObjectsChecked
+	}
+	return 0
+}
+
+func (x *FsckResponse) GetIssues() []*FsckIssue {
+	if x != nil {
+		return x.Issues
+	}
+	return nil
+}
+
+func (x *FsckResponse) GetRepaired() int32 {
+	if x != nil {
+		return x.Repaired
+	}
+	return 0
+}
+
+func (x *FsckResponse) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+type RotateKeysRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateKeysRequest) Reset() {
+	*x = RotateKeysRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateKeysRequest) ProtoMessage() {}
+
+func (x *RotateKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateKeysRequest.ProtoReflect.Descriptor instead.
+func (*RotateKeysRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{35}
+}
+
+type RotateKeysResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyId         string                 `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"` // master key the data keys are now wrapped with
+	Rewrapped     int32                  `protobuf:"varint,2,opt,name=rewrapped,proto3" json:"rewrapped,omitempty"`
+	Failed        int32                  `protobuf:"varint,3,opt,name=failed,proto3" json:"failed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateKeysResponse) Reset() {
+	*x = RotateKeysResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateKeysResponse) ProtoMessage() {}
+
+func (x *RotateKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateKeysResponse.ProtoReflect.Descriptor instead.
+func (*RotateKeysResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *RotateKeysResponse) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *RotateKeysResponse) GetRewrapped() int32 {
+	if x != nil {
+		return x.Rewrapped
+	}
+	return 0
+}
+
+func (x *RotateKeysResponse) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+type MembershipRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProbeNow      bool                   `protobuf:"varint,1,opt,name=probe_now,json=probeNow,proto3" json:"probe_now,omitempty"` // probe the nodes before reporting
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MembershipRequest) Reset() {
+	*x = MembershipRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MembershipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MembershipRequest) ProtoMessage() {}
+
+func (x *MembershipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MembershipRequest.ProtoReflect.Descriptor instead.
+func (*MembershipRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *MembershipRequest) GetProbeNow() bool {
+	if x != nil {
+		return x.ProbeNow
+	}
+	return false
+}
+
+type MembershipResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Members       []*Member              `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MembershipResponse) Reset() {
+	*x = MembershipResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MembershipResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MembershipResponse) ProtoMessage() {}
+
+func (x *MembershipResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MembershipResponse.ProtoReflect.Descriptor instead.
+func (*MembershipResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *MembershipResponse) GetMembers() []*Member {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type Member struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"` // empty for nodes that are probed
+	State         string                 `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`     // alive, suspect or dead
+	LastHeartbeat string                 `protobuf:"bytes,4,opt,name=last_heartbeat,json=lastHeartbeat,proto3" json:"last_heartbeat,omitempty"`
+	StateChanged  string                 `protobuf:"bytes,5,opt,name=state_changed,json=stateChanged,proto3" json:"state_changed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Member) Reset() {
+	*x = Member{}
+	mi := &file_api_proto_filestore_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Member) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Member) ProtoMessage() {}
+
+func (x *Member) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Member.ProtoReflect.Descriptor instead.
+func (*Member) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *Member) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *Member) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Member) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Member) GetLastHeartbeat() string {
+	if x != nil {
+		return x.LastHeartbeat
+	}
+	return ""
+}
+
+func (x *Member) GetStateChanged() string {
+	if x != nil {
+		return x.StateChanged
+	}
+	return ""
+}
+
+type HeartbeatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"` // address the node's StorageNode service listens on
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_api_proto_filestore_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *HeartbeatRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type HeartbeatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IntervalMs    int64                  `protobuf:"varint,1,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"` // how often the node should send heartbeats
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_api_proto_filestore_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_filestore_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_filestore_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *HeartbeatResponse) GetIntervalMs() int64 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+var File_api_proto_filestore_proto protoreflect.FileDescriptor
+
+const file_api_proto_filestore_proto_rawDesc = "" +
+	"\n" +
+	"\x19api/proto/filestore.proto\x12\tfilestore\"\xf1\x02\n" +
+	"\rUploadRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x14\n" +
+	"\x05chunk\x18\x02 \x01(\fR\x05chunk\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x03 \x01(\x03R\ttotalSize\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x17\n" +
+	"\afile_id\x18\x05 \x01(\tR\x06fileId\x12!\n" +
+	"\fwrite_quorum\x18\x06 \x01(\x05R\vwriteQuorum\x12!\n" +
+	"\fstorage_mode\x18\a \x01(\tR\vstorageMode\x12\x1f\n" +
+	"\vdata_shards\x18\b \x01(\x05R\n" +
+	"dataShards\x12#\n" +
+	"\rparity_shards\x18\t \x01(\x05R\fparityShards\x12%\n" +
+	"\x0ereplica_factor\x18\n" +
+	" \x01(\x05R\rreplicaFactor\x12 \n" +
+	"\vcompression\x18\v \x01(\tR\vcompression\"\xed\x01\n" +
+	"\x0eUploadResponse\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1d\n" +
+	"\n" +
+	"version_id\x18\x02 \x01(\tR\tversionId\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12%\n" +
+	"\x0enode_locations\x18\x04 \x03(\tR\rnodeLocations\x12\x18\n" +
+	"\asuccess\x18\x05 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x06 \x01(\tR\amessage\x124\n" +
+	"\breplicas\x18\a \x03(\v2\x18.filestore.ReplicaStatusR\breplicas\"\x98\x01\n" +
+	"\rReplicaStatus\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1f\n" +
+	"\vduration_ms\x18\x04 \x01(\x03R\n" +
+	"durationMs\x12\x1d\n" +
+	"\n" +
+	"hinted_for\x18\x05 \x01(\tR\thintedFor\"j\n" +
+	"\x0fDownloadRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1d\n" +
+	"\n" +
+	"version_id\x18\x02 \x01(\tR\tversionId\x12\x1f\n" +
+	"\vread_quorum\x18\x03 \x01(\x05R\n" +
+	"readQuorum\"j\n" +
+	"\x10DownloadResponse\x12\x14\n" +
+	"\x05chunk\x18\x01 \x01(\fR\x05chunk\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x02 \x01(\x03R\ttotalSize\x12!\n" +
+	"\fcontent_type\x18\x03 \x01(\tR\vcontentType\"(\n" +
+	"\rDeleteRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\"D\n" +
+	"\x0eDeleteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"*\n" +
+	"\x0fFileInfoRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\"\x92\x04\n" +
+	"\x10FileInfoResponse\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\tR\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\tR\tupdatedAt\x12\x1a\n" +
+	"\bversions\x18\a \x03(\tR\bversions\x12\x1a\n" +
+	"\breplicas\x18\b \x03(\tR\breplicas\x12.\n" +
+	"\x13retention_keep_last\x18\t \x01(\x05R\x11retentionKeepLast\x123\n" +
+	"\x16retention_max_age_days\x18\n" +
+	" \x01(\x05R\x13retentionMaxAgeDays\x12!\n" +
+	"\fstorage_mode\x18\v \x01(\tR\vstorageMode\x12%\n" +
+	"\x0ereplica_factor\x18\f \x01(\x05R\rreplicaFactor\x12 \n" +
+	"\vcompression\x18\r \x01(\tR\vcompression\x12\x1f\n" +
+	"\vstored_size\x18\x0e \x01(\x03R\n" +
+	"storedSize\x12*\n" +
+	"\x11encryption_key_id\x18\x0f \x01(\tR\x0fencryptionKeyId\"C\n" +
+	"\x10ListFilesRequest\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\"g\n" +
+	"\x11ListFilesResponse\x121\n" +
+	"\x05files\x18\x01 \x03(\v2\x1b.filestore.FileInfoResponseR\x05files\x12\x1f\n" +
+	"\vtotal_count\x18\x02 \x01(\x05R\n" +
+	"totalCount\"i\n" +
+	"\x0eVersionRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1d\n" +
+	"\n" +
+	"version_id\x18\x02 \x01(\tR\tversionId\x12\x1f\n" +
+	"\vread_quorum\x18\x03 \x01(\x05R\n" +
+	"readQuorum\"m\n" +
+	"\x13SetRetentionRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1b\n" +
+	"\tkeep_last\x18\x02 \x01(\x05R\bkeepLast\x12 \n" +
+	"\fmax_age_days\x18\x03 \x01(\x05R\n" +
+	"maxAgeDays\"J\n" +
+	"\x14SetRetentionResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"Y\n" +
+	"\x17SetReplicaFactorRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12%\n" +
+	"\x0ereplica_factor\x18\x02 \x01(\x05R\rreplicaFactor\"j\n" +
+	"\x18SetReplicaFactorResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1a\n" +
+	"\breplicas\x18\x03 \x03(\tR\breplicas\"3\n" +
+	"\x18ReplicationStatusRequest\x12\x17\n" +
+	"\arun_now\x18\x01 \x01(\bR\x06runNow\"\xee\x02\n" +
+	"\x19ReplicationStatusResponse\x12\x18\n" +
+	"\arunning\x18\x01 \x01(\bR\arunning\x12!\n" +
+	"\flast_started\x18\x02 \x01(\tR\vlastStarted\x12#\n" +
+	"\rlast_finished\x18\x03 \x01(\tR\flastFinished\x12#\n" +
+	"\rfiles_scanned\x18\x04 \x01(\x05R\ffilesScanned\x12)\n" +
+	"\x10versions_scanned\x18\x05 \x01(\x05R\x0fversionsScanned\x12)\n" +
+	"\x10under_replicated\x18\x06 \x01(\x05R\x0funderReplicated\x12\x1a\n" +
+	"\brepaired\x18\a \x01(\x05R\brepaired\x12\x16\n" +
+	"\x06failed\x18\b \x01(\x05R\x06failed\x12!\n" +
+	"\fbytes_copied\x18\t \x01(\x03R\vbytesCopied\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\n" +
+	" \x01(\tR\tlastError\"U\n" +
+	"\x10RebalanceRequest\x12\x17\n" +
+	"\adry_run\x18\x01 \x01(\bR\x06dryRun\x12(\n" +
+	"\x10bytes_per_second\x18\x02 \x01(\x03R\x0ebytesPerSecond\"\x85\x01\n" +
+	"\rRebalanceMove\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1d\n" +
+	"\n" +
+	"version_id\x18\x02 \x01(\tR\tversionId\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x10\n" +
+	"\x03add\x18\x04 \x03(\tR\x03add\x12\x16\n" +
+	"\x06remove\x18\x05 \x03(\tR\x06remove\"\xf9\x01\n" +
+	"\x11RebalanceResponse\x12\x17\n" +
+	"\adry_run\x18\x01 \x01(\bR\x06dryRun\x12.\n" +
+	"\x05moves\x18\x02 \x03(\v2\x18.filestore.RebalanceMoveR\x05moves\x12\x14\n" +
+	"\x05moved\x18\x03 \x01(\x05R\x05moved\x12\x16\n" +
+	"\x06failed\x18\x04 \x01(\x05R\x06failed\x12!\n" +
+	"\fbytes_copied\x18\x05 \x01(\x03R\vbytesCopied\x12\x18\n" +
+	"\astarted\x18\x06 \x01(\tR\astarted\x12\x1a\n" +
+	"\bfinished\x18\a \x01(\tR\bfinished\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\"(\n" +
+	"\x10RepairLogRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\"\xb8\x01\n" +
+	"\vRepairEvent\x12\x12\n" +
+	"\x04time\x18\x01 \x01(\tR\x04time\x12\x17\n" +
+	"\afile_id\x18\x02 \x01(\tR\x06fileId\x12\x1d\n" +
+	"\n" +
+	"version_id\x18\x03 \x01(\tR\tversionId\x12\x17\n" +
+	"\anode_id\x18\x04 \x01(\tR\x06nodeId\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12\x16\n" +
+	"\x06source\x18\x06 \x01(\tR\x06source\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\"\xae\x01\n" +
+	"\x0fNodeRepairStats\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x18\n" +
+	"\amissing\x18\x02 \x01(\x05R\amissing\x12\x18\n" +
+	"\acorrupt\x18\x03 \x01(\x05R\acorrupt\x12\x1a\n" +
+	"\bdiverged\x18\x04 \x01(\x05R\bdiverged\x12\x1a\n" +
+	"\brepaired\x18\x05 \x01(\x05R\brepaired\x12\x16\n" +
+	"\x06failed\x18\x06 \x01(\x05R\x06failed\"u\n" +
+	"\x11RepairLogResponse\x12.\n" +
+	"\x06events\x18\x01 \x03(\v2\x16.filestore.RepairEventR\x06events\x120\n" +
+	"\x05nodes\x18\x02 \x03(\v2\x1a.filestore.NodeRepairStatsR\x05nodes\"-\n" +
+	"\x12ScrubStatusRequest\x12\x17\n" +
+	"\arun_now\x18\x01 \x01(\bR\x06runNow\"v\n" +
+	"\rCorruptObject\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x17\n" +
+	"\afile_id\x18\x02 \x01(\tR\x06fileId\x12\x1d\n" +
+	"\n" +
+	"version_id\x18\x03 \x01(\tR\tversionId\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\x95\x03\n" +
+	"\x13ScrubStatusResponse\x12\x18\n" +
+	"\arunning\x18\x01 \x01(\bR\arunning\x12\x18\n" +
+	"\astarted\x18\x02 \x01(\tR\astarted\x12\x1a\n" +
+	"\bfinished\x18\x03 \x01(\tR\bfinished\x12#\n" +
+	"\rnodes_scanned\x18\x04 \x01(\x05R\fnodesScanned\x12'\n" +
+	"\x0fobjects_scanned\x18\x05 \x01(\x05R\x0eobjectsScanned\x12#\n" +
+	"\rbytes_scanned\x18\x06 \x01(\x03R\fbytesScanned\x12\x18\n" +
+	"\acorrupt\x18\a \x01(\x05R\acorrupt\x12\x1a\n" +
+	"\brepaired\x18\b \x01(\x05R\brepaired\x12#\n" +
+	"\rrepair_failed\x18\t \x01(\x05R\frepairFailed\x12A\n" +
+	"\x0fcorrupt_objects\x18\n" +
+	" \x03(\v2\x18.filestore.CorruptObjectR\x0ecorruptObjects\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\v \x01(\tR\tlastError\"3\n" +
+	"\x18AntiEntropyStatusRequest\x12\x17\n" +
+	"\arun_now\x18\x01 \x01(\bR\x06runNow\"\xd5\x02\n" +
+	"\x19AntiEntropyStatusResponse\x12\x18\n" +
+	"\arunning\x18\x01 \x01(\bR\arunning\x12\x18\n" +
+	"\astarted\x18\x02 \x01(\tR\astarted\x12\x1a\n" +
+	"\bfinished\x18\x03 \x01(\tR\bfinished\x12%\n" +
+	"\x0epairs_compared\x18\x04 \x01(\x05R\rpairsCompared\x12)\n" +
+	"\x10buckets_differed\x18\x05 \x01(\x05R\x0fbucketsDiffered\x12)\n" +
+	"\x10objects_differed\x18\x06 \x01(\x05R\x0fobjectsDiffered\x12\x1a\n" +
+	"\brepaired\x18\a \x01(\x05R\brepaired\x12\x18\n" +
+	"\aremoved\x18\b \x01(\x05R\aremoved\x12\x16\n" +
+	"\x06failed\x18\t \x01(\x05R\x06failed\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\n" +
+	" \x01(\tR\tlastError\"/\n" +
+	"\x14HandoffStatusRequest\x12\x17\n" +
+	"\arun_now\x18\x01 \x01(\bR\x06runNow\"\xbc\x02\n" +
+	"\x15HandoffStatusResponse\x12\x18\n" +
+	"\arunning\x18\x01 \x01(\bR\arunning\x12!\n" +
+	"\flast_started\x18\x02 \x01(\tR\vlastStarted\x12#\n" +
+	"\rlast_finished\x18\x03 \x01(\tR\flastFinished\x12\x14\n" +
+	"\x05hints\x18\x04 \x01(\x05R\x05hints\x12\x1d\n" +
+	"\n" +
+	"handed_off\x18\x05 \x01(\x05R\thandedOff\x12\x18\n" +
+	"\adropped\x18\x06 \x01(\x05R\adropped\x12\x18\n" +
+	"\awaiting\x18\a \x01(\x05R\awaiting\x12\x16\n" +
+	"\x06failed\x18\b \x01(\x05R\x06failed\x12!\n" +
+	"\fbytes_copied\x18\t \x01(\x03R\vbytesCopied\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\n" +
+	" \x01(\tR\tlastError\"%\n" +
+	"\vFsckRequest\x12\x16\n" +
+	"\x06repair\x18\x01 \x01(\bR\x06repair\"\xba\x01\n" +
+	"\tFsckIssue\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x17\n" +
+	"\afile_id\x18\x02 \x01(\tR\x06fileId\x12\x1d\n" +
+	"\n" +
+	"version_id\x18\x03 \x01(\tR\tversionId\x12\x17\n" +
+	"\anode_id\x18\x04 \x01(\tR\x06nodeId\x12\x16\n" +
+	"\x06detail\x18\x05 \x01(\tR\x06detail\x12\x1a\n" +
+	"\brepaired\x18\x06 \x01(\bR\brepaired\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\"\xdc\x02\n" +
+	"\fFsckResponse\x12\x16\n" +
+	"\x06repair\x18\x01 \x01(\bR\x06repair\x12\x18\n" +
+	"\astarted\x18\x02 \x01(\tR\astarted\x12\x1a\n" +
+	"\bfinished\x18\x03 \x01(\tR\bfinished\x12#\n" +
+	"\rnodes_checked\x18\x04 \x01(\x05R\fnodesChecked\x12#\n" +
+	"\rfiles_checked\x18\x05 \x01(\x05R\ffilesChecked\x12)\n" +
+	"\x10versions_checked\x18\x06 \x01(\x05R\x0fversionsChecked\x12'\n" +
+	"\x0fobjects_checked\x18\a \x01(\x05R\x0eobjectsChecked\x12,\n" +
+	"\x06issues\x18\b \x03(\v2\x14.filestore.FsckIssueR\x06issues\x12\x1a\n" +
+	"\brepaired\x18\t \x01(\x05R\brepaired\x12\x16\n" +
+	"\x06failed\x18\n" +
+	" \x01(\x05R\x06failed\"\x13\n" +
+	"\x11RotateKeysRequest\"a\n" +
+	"\x12RotateKeysResponse\x12\x15\n" +
+	"\x06key_id\x18\x01 \x01(\tR\x05keyId\x12\x1c\n" +
+	"\trewrapped\x18\x02 \x01(\x05R\trewrapped\x12\x16\n" +
+	"\x06failed\x18\x03 \x01(\x05R\x06failed\"0\n" +
+	"\x11MembershipRequest\x12\x1b\n" +
+	"\tprobe_now\x18\x01 \x01(\bR\bprobeNow\"A\n" +
+	"\x12MembershipResponse\x12+\n" +
+	"\amembers\x18\x01 \x03(\v2\x11.filestore.MemberR\amembers\"\x9d\x01\n" +
+	"\x06Member\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05state\x18\x03 \x01(\tR\x05state\x12%\n" +
+	"\x0elast_heartbeat\x18\x04 \x01(\tR\rlastHeartbeat\x12#\n" +
+	"\rstate_changed\x18\x05 \x01(\tR\fstateChanged\"E\n" +
+	"\x10HeartbeatRequest\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\"4\n" +
+	"\x11HeartbeatResponse\x12\x1f\n" +
+	"\vinterval_ms\x18\x01 \x01(\x03R\n" +
+	"intervalMs2\xca\f\n" +
+	"\tFileStore\x12?\n" +
+	"\x06Upload\x12\x18.filestore.UploadRequest\x1a\x19.filestore.UploadResponse(\x01\x12E\n" +
+	"\bDownload\x12\x1a.filestore.DownloadRequest\x1a\x1b.filestore.DownloadResponse0\x01\x12=\n" +
+	"\x06Delete\x12\x18.filestore.DeleteRequest\x1a\x19.filestore.DeleteResponse\x12F\n" +
+	"\vGetFileInfo\x12\x1a.filestore.FileInfoRequest\x1a\x1b.filestore.FileInfoResponse\x12F\n" +
+	"\tListFiles\x12\x1b.filestore.ListFilesRequest\x1a\x1c.filestore.ListFilesResponse\x12F\n" +
+	"\n" +
+	"GetVersion\x12\x19.filestore.VersionRequest\x1a\x1b.filestore.DownloadResponse0\x01\x12F\n" +
+	"\rUploadVersion\x12\x18.filestore.UploadRequest\x1a\x19.filestore.UploadResponse(\x01\x12E\n" +
+	"\rDeleteVersion\x12\x19.filestore.VersionRequest\x1a\x19.filestore.DeleteResponse\x12F\n" +
+	"\x0eRestoreVersion\x12\x19.filestore.VersionRequest\x1a\x19.filestore.UploadResponse\x12O\n" +
+	"\fSetRetention\x12\x1e.filestore.SetRetentionRequest\x1a\x1f.filestore.SetRetentionResponse\x12[\n" +
+	"\x10SetReplicaFactor\x12\".filestore.SetReplicaFactorRequest\x1a#.filestore.SetReplicaFactorResponse\x12a\n" +
+	"\x14GetReplicationStatus\x12#.filestore.ReplicationStatusRequest\x1a$.filestore.ReplicationStatusResponse\x12F\n" +
+	"\tRebalance\x12\x1b.filestore.RebalanceRequest\x1a\x1c.filestore.RebalanceResponse\x12I\n" +
+	"\fGetRepairLog\x12\x1b.filestore.RepairLogRequest\x1a\x1c.filestore.RepairLogResponse\x12O\n" +
+	"\x0eGetScrubStatus\x12\x1d.filestore.ScrubStatusRequest\x1a\x1e.filestore.ScrubStatusResponse\x12a\n" +
+	"\x14GetAntiEntropyStatus\x12#.filestore.AntiEntropyStatusRequest\x1a$.filestore.AntiEntropyStatusResponse\x12U\n" +
+	"\x10GetHandoffStatus\x12\x1f.filestore.HandoffStatusRequest\x1a .filestore.HandoffStatusResponse\x127\n" +
+	"\x04Fsck\x12\x16.filestore.FsckRequest\x1a\x17.filestore.FsckResponse\x12I\n" +
 	"\n" +
-	"GetVersion\x12\x19.filestore.VersionRequest\x1a\x1b.filestore.DownloadResponse0\x01B5Z3github.com/yashlad/distributed-file-store/api/protob\x06proto3"
+	"RotateKeys\x12\x1c.filestore.RotateKeysRequest\x1a\x1d.filestore.RotateKeysResponse\x12L\n" +
+	"\rGetMembership\x12\x1c.filestore.MembershipRequest\x1a\x1d.filestore.MembershipResponse\x12F\n" +
+	"\tHeartbeat\x12\x1b.filestore.HeartbeatRequest\x1a\x1c.filestore.HeartbeatResponseB5Z3github.com/yashlad/distributed-file-store/api/protob\x06proto3"
 
 var (
 	file_api_proto_filestore_proto_rawDescOnce sync.Once
@@ -759,39 +3240,107 @@ func file_api_proto_filestore_proto_rawDescGZIP() []byte {
 	return file_api_proto_filestore_proto_rawDescData
 }
 
-var file_api_proto_filestore_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_api_proto_filestore_proto_msgTypes = make([]protoimpl.MessageInfo, 42)
 var file_api_proto_filestore_proto_goTypes = []any{
-	(*UploadRequest)(nil),     // 0: filestore.UploadRequest
-	(*UploadResponse)(nil),    // 1: filestore.UploadResponse
-	(*DownloadRequest)(nil),   // 2: filestore.DownloadRequest
-	(*DownloadResponse)(nil),  // 3: filestore.DownloadResponse
-	(*DeleteRequest)(nil),     // 4: filestore.DeleteRequest
-	(*DeleteResponse)(nil),    // 5: filestore.DeleteResponse
-	(*FileInfoRequest)(nil),   // 6: filestore.FileInfoRequest
-	(*FileInfoResponse)(nil),  // 7: filestore.FileInfoResponse
-	(*ListFilesRequest)(nil),  // 8: filestore.ListFilesRequest
-	(*ListFilesResponse)(nil), // 9: filestore.ListFilesResponse
-	(*VersionRequest)(nil),    // 10: filestore.VersionRequest
+	(*UploadRequest)(nil),             // 0: filestore.UploadRequest
+	(*UploadResponse)(nil),            // 1: filestore.UploadResponse
+	(*ReplicaStatus)(nil),             // 2: filestore.ReplicaStatus
+	(*DownloadRequest)(nil),           // 3: filestore.DownloadRequest
+	(*DownloadResponse)(nil),          // 4: filestore.DownloadResponse
+	(*DeleteRequest)(nil),             // 5: filestore.DeleteRequest
+	(*DeleteResponse)(nil),            // 6: filestore.DeleteResponse
+	(*FileInfoRequest)(nil),           // 7: filestore.FileInfoRequest
+	(*FileInfoResponse)(nil),          // 8: filestore.FileInfoResponse
+	(*ListFilesRequest)(nil),          // 9: filestore.ListFilesRequest
+	(*ListFilesResponse)(nil),         // 10: filestore.ListFilesResponse
+	(*VersionRequest)(nil),            // 11: filestore.VersionRequest
+	(*SetRetentionRequest)(nil),       // 12: filestore.SetRetentionRequest
+	(*SetRetentionResponse)(nil),      // 13: filestore.SetRetentionResponse
+	(*SetReplicaFactorRequest)(nil),   // 14: filestore.SetReplicaFactorRequest
+	(*SetReplicaFactorResponse)(nil),  // 15: filestore.SetReplicaFactorResponse
+	(*ReplicationStatusRequest)(nil),  // 16: filestore.ReplicationStatusRequest
+	(*ReplicationStatusResponse)(nil), // 17: filestore.ReplicationStatusResponse
+	(*RebalanceRequest)(nil),          // 18: filestore.RebalanceRequest
+	(*RebalanceMove)(nil),             // 19: filestore.RebalanceMove
+	(*RebalanceResponse)(nil),         // 20: filestore.RebalanceResponse
+	(*RepairLogRequest)(nil),          // 21: filestore.RepairLogRequest
+	(*RepairEvent)(nil),               // 22: filestore.RepairEvent
+	(*NodeRepairStats)(nil),           // 23: filestore.NodeRepairStats
+	(*RepairLogResponse)(nil),         // 24: filestore.RepairLogResponse
+	(*ScrubStatusRequest)(nil),        // 25: filestore.ScrubStatusRequest
+	(*CorruptObject)(nil),             // 26: filestore.CorruptObject
+	(*ScrubStatusResponse)(nil),       // 27: filestore.ScrubStatusResponse
+	(*AntiEntropyStatusRequest)(nil),  // 28: filestore.AntiEntropyStatusRequest
+	(*AntiEntropyStatusResponse)(nil), // 29: filestore.AntiEntropyStatusResponse
+	(*HandoffStatusRequest)(nil),      // 30: filestore.HandoffStatusRequest
+	(*HandoffStatusResponse)(nil),     // 31: filestore.HandoffStatusResponse
+	(*FsckRequest)(nil),               // 32: filestore.FsckRequest
+	(*FsckIssue)(nil),                 // 33: filestore.FsckIssue
+	(*FsckResponse)(nil),              // 34: filestore.FsckResponse
+	(*RotateKeysRequest)(nil),         // 35: filestore.RotateKeysRequest
+	(*RotateKeysResponse)(nil),        // 36: filestore.RotateKeysResponse
+	(*MembershipRequest)(nil),         // 37: filestore.MembershipRequest
+	(*MembershipResponse)(nil),        // 38: filestore.MembershipResponse
+	(*Member)(nil),                    // 39: filestore.Member
+	(*HeartbeatRequest)(nil),          // 40: filestore.HeartbeatRequest
+	(*HeartbeatResponse)(nil),         // 41: filestore.HeartbeatResponse
 }
 var file_api_proto_filestore_proto_depIdxs = []int32{
-	7,  // 0: filestore.ListFilesResponse.files:type_name -> filestore.FileInfoResponse
-	0,  // 1: filestore.FileStore.Upload:input_type -> filestore.UploadRequest
-	2,  // 2: filestore.FileStore.Download:input_type -> filestore.DownloadRequest
-	4,  // 3: filestore.FileStore.Delete:input_type -> filestore.DeleteRequest
-	6,  // 4: filestore.FileStore.GetFileInfo:input_type -> filestore.FileInfoRequest
-	8,  // 5: filestore.FileStore.ListFiles:input_type -> filestore.ListFilesRequest
-	10, // 6: filestore.FileStore.GetVersion:input_type -> filestore.VersionRequest
-	1,  // 7: filestore.FileStore.Upload:output_type -> filestore.UploadResponse
-	3,  // 8: filestore.FileStore.Download:output_type -> filestore.DownloadResponse
-	5,  // 9: filestore.FileStore.Delete:output_type -> filestore.DeleteResponse
-	7,  // 10: filestore.FileStore.GetFileInfo:output_type -> filestore.FileInfoResponse
-	9,  // 11: filestore.FileStore.ListFiles:output_type -> filestore.ListFilesResponse
-	3,  // 12: filestore.FileStore.GetVersion:output_type -> filestore.DownloadResponse
-	7,  // [7:13] is the sub-list for method output_type
-	1,  // [1:7] is the sub-list for method input_type
-	1,  // [1:1] is the sub-list for extension type_name
-	1,  // [1:1] is the sub-list for extension extendee
-	0,  // [0:1] is the sub-list for field type_name
+	2,  // 0: filestore.UploadResponse.replicas:type_name -> filestore.ReplicaStatus
+	8,  // 1: filestore.ListFilesResponse.files:type_name -> filestore.FileInfoResponse
+	19, // 2: filestore.RebalanceResponse.moves:type_name -> filestore.RebalanceMove
+	22, // 3: filestore.RepairLogResponse.events:type_name -> filestore.RepairEvent
+	23, // 4: filestore.RepairLogResponse.nodes:type_name -> filestore.NodeRepairStats
+	26, // 5: filestore.ScrubStatusResponse.corrupt_objects:type_name -> filestore.CorruptObject
+	33, // 6: filestore.FsckResponse.issues:type_name -> filestore.FsckIssue
+	39, // 7: filestore.MembershipResponse.members:type_name -> filestore.Member
+	0,  // 8: filestore.FileStore.Upload:input_type -> filestore.UploadRequest
+	3,  // 9: filestore.FileStore.Download:input_type -> filestore.DownloadRequest
+	5,  // 10: filestore.FileStore.Delete:input_type -> filestore.DeleteRequest
+	7,  // 11: filestore.FileStore.GetFileInfo:input_type -> filestore.FileInfoRequest
+	9,  // 12: filestore.FileStore.ListFiles:input_type -> filestore.ListFilesRequest
+	11, // 13: filestore.FileStore.GetVersion:input_type -> filestore.VersionRequest
+	0,  // 14: filestore.FileStore.UploadVersion:input_type -> filestore.UploadRequest
+	11, // 15: filestore.FileStore.DeleteVersion:input_type -> filestore.VersionRequest
+	11, // 16: filestore.FileStore.RestoreVersion:input_type -> filestore.VersionRequest
+	12, // 17: filestore.FileStore.SetRetention:input_type -> filestore.SetRetentionRequest
+	14, // 18: filestore.FileStore.SetReplicaFactor:input_type -> filestore.SetReplicaFactorRequest
+	16, // 19: filestore.FileStore.GetReplicationStatus:input_type -> filestore.ReplicationStatusRequest
+	18, // 20: filestore.FileStore.Rebalance:input_type -> filestore.RebalanceRequest
+	21, // 21: filestore.FileStore.GetRepairLog:input_type -> filestore.RepairLogRequest
+	25, // 22: filestore.FileStore.GetScrubStatus:input_type -> filestore.ScrubStatusRequest
+	28, // 23: filestore.FileStore.GetAntiEntropyStatus:input_type -> filestore.AntiEntropyStatusRequest
+	30, // 24: filestore.FileStore.GetHandoffStatus:input_type -> filestore.HandoffStatusRequest
+	32, // 25: filestore.FileStore.Fsck:input_type -> filestore.FsckRequest
+	35, // 26: filestore.FileStore.RotateKeys:input_type -> filestore.RotateKeysRequest
+	37, // 27: filestore.FileStore.GetMembership:input_type -> filestore.MembershipRequest
+	40, // 28: filestore.FileStore.Heartbeat:input_type -> filestore.HeartbeatRequest
+	1,  // 29: filestore.FileStore.Upload:output_type -> filestore.UploadResponse
+	4,  // 30: filestore.FileStore.Download:output_type -> filestore.DownloadResponse
+	6,  // 31: filestore.FileStore.Delete:output_type -> filestore.DeleteResponse
+	8,  // 32: filestore.FileStore.GetFileInfo:output_type -> filestore.FileInfoResponse
+	10, // 33: filestore.FileStore.ListFiles:output_type -> filestore.ListFilesResponse
+	4,  // 34: filestore.FileStore.GetVersion:output_type -> filestore.DownloadResponse
+	1,  // 35: filestore.FileStore.UploadVersion:output_type -> filestore.UploadResponse
+	6,  // 36: filestore.FileStore.DeleteVersion:output_type -> filestore.DeleteResponse
+	1,  // 37: filestore.FileStore.RestoreVersion:output_type -> filestore.UploadResponse
+	13, // 38: filestore.FileStore.SetRetention:output_type -> filestore.SetRetentionResponse
+	15, // 39: filestore.FileStore.SetReplicaFactor:output_type -> filestore.SetReplicaFactorResponse
+	17, // 40: filestore.FileStore.GetReplicationStatus:output_type -> filestore.ReplicationStatusResponse
+	20, // 41: filestore.FileStore.Rebalance:output_type -> filestore.RebalanceResponse
+	24, // 42: filestore.FileStore.GetRepairLog:output_type -> filestore.RepairLogResponse
+	27, // 43: filestore.FileStore.GetScrubStatus:output_type -> filestore.ScrubStatusResponse
+	29, // 44: filestore.FileStore.GetAntiEntropyStatus:output_type -> filestore.AntiEntropyStatusResponse
+	31, // 45: filestore.FileStore.GetHandoffStatus:output_type -> filestore.HandoffStatusResponse
+	34, // 46: filestore.FileStore.Fsck:output_type -> filestore.FsckResponse
+	36, // 47: filestore.FileStore.RotateKeys:output_type -> filestore.RotateKeysResponse
+	38, // 48: filestore.FileStore.GetMembership:output_type -> filestore.MembershipResponse
+	41, // 49: filestore.FileStore.Heartbeat:output_type -> filestore.HeartbeatResponse
+	29, // [29:50] is the sub-list for method output_type
+	8,  // [8:29] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_filestore_proto_init() }
@@ -805,7 +3354,7 @@ func file_api_proto_filestore_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_filestore_proto_rawDesc), len(file_api_proto_filestore_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   11,
+			NumMessages:   42,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,12 +19,27 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	FileStore_Upload_FullMethodName      = "/filestore.FileStore/Upload"
-	FileStore_Download_FullMethodName    = "/filestore.FileStore/Download"
-	FileStore_Delete_FullMethodName      = "/filestore.FileStore/Delete"
-	FileStore_GetFileInfo_FullMethodName = "/filestore.FileStore/GetFileInfo"
-	FileStore_ListFiles_FullMethodName   = "/filestore.FileStore/ListFiles"
-	FileStore_GetVersion_FullMethodName  = "/filestore.FileStore/GetVersion"
+	FileStore_Upload_FullMethodName               = "/filestore.FileStore/Upload"
+	FileStore_Download_FullMethodName             = "/filestore.FileStore/Download"
+	FileStore_Delete_FullMethodName               = "/filestore.FileStore/Delete"
+	FileStore_GetFileInfo_FullMethodName          = "/filestore.FileStore/GetFileInfo"
+	FileStore_ListFiles_FullMethodName            = "/filestore.FileStore/ListFiles"
+	FileStore_GetVersion_FullMethodName           = "/filestore.FileStore/GetVersion"
+	FileStore_UploadVersion_FullMethodName        = "/filestore.FileStore/UploadVersion"
+	FileStore_DeleteVersion_FullMethodName        = "/filestore.FileStore/DeleteVersion"
+	FileStore_RestoreVersion_FullMethodName       = "/filestore.FileStore/RestoreVersion"
+	FileStore_SetRetention_FullMethodName         = "/filestore.FileStore/SetRetention"
+	FileStore_SetReplicaFactor_FullMethodName     = "/filestore.FileStore/SetReplicaFactor"
+	FileStore_GetReplicationStatus_FullMethodName = "/filestore.FileStore/GetReplicationStatus"
+	FileStore_Rebalance_FullMethodName            = "/filestore.FileStore/Rebalance"
+	FileStore_GetRepairLog_FullMethodName         = "/filestore.FileStore/GetRepairLog"
+	FileStore_GetScrubStatus_FullMethodName       = "/filestore.FileStore/GetScrubStatus"
+	FileStore_GetAntiEntropyStatus_FullMethodName = "/filestore.FileStore/GetAntiEntropyStatus"
+	FileStore_GetHandoffStatus_FullMethodName     = "/filestore.FileStore/GetHandoffStatus"
+	FileStore_Fsck_FullMethodName                 = "/filestore.FileStore/Fsck"
+	FileStore_RotateKeys_FullMethodName           = "/filestore.FileStore/RotateKeys"
+	FileStore_GetMembership_FullMethodName        = "/filestore.FileStore/GetMembership"
+	FileStore_Heartbeat_FullMethodName            = "/filestore.FileStore/Heartbeat"
 )
 
 // FileStoreClient is the client API for FileStore service.
@@ -39,6 +54,23 @@ type FileStoreClient interface {
 	GetFileInfo(ctx context.Context, in *FileInfoRequest, opts ...grpc.CallOption) (*FileInfoResponse, error)
 	ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error)
 	GetVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DownloadResponse], error)
+	UploadVersion(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadRequest, UploadResponse], error)
+	DeleteVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	RestoreVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*UploadResponse, error)
+	SetRetention(ctx context.Context, in *SetRetentionRequest, opts ...grpc.CallOption) (*SetRetentionResponse, error)
+	SetReplicaFactor(ctx context.Context, in *SetReplicaFactorRequest, opts ...grpc.CallOption) (*SetReplicaFactorResponse, error)
+	// Admin operations
+	GetReplicationStatus(ctx context.Context, in *ReplicationStatusRequest, opts ...grpc.CallOption) (*ReplicationStatusResponse, error)
+	Rebalance(ctx context.Context, in *RebalanceRequest, opts ...grpc.CallOption) (*RebalanceResponse, error)
+	GetRepairLog(ctx context.Context, in *RepairLogRequest, opts ...grpc.CallOption) (*RepairLogResponse, error)
+	GetScrubStatus(ctx context.Context, in *ScrubStatusRequest, opts ...grpc.CallOption) (*ScrubStatusResponse, error)
+	GetAntiEntropyStatus(ctx context.Context, in *AntiEntropyStatusRequest, opts ...grpc.CallOption) (*AntiEntropyStatusResponse, error)
+	GetHandoffStatus(ctx context.Context, in *HandoffStatusRequest, opts ...grpc.CallOption) (*HandoffStatusResponse, error)
+	Fsck(ctx context.Context, in *FsckRequest, opts ...grpc.CallOption) (*FsckResponse, error)
+	RotateKeys(ctx context.Context, in *RotateKeysRequest, opts ...grpc.CallOption) (*RotateKeysResponse, error)
+	GetMembership(ctx context.Context, in *MembershipRequest, opts ...grpc.CallOption) (*MembershipResponse, error)
+	// Sent by storage node daemons to register and stay registered
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
 }
 
 type fileStoreClient struct {
@@ -130,6 +162,159 @@ func (c *fileStoreClient) GetVersion(ctx context.Context, in *VersionRequest, op
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type FileStore_GetVersionClient = grpc.ServerStreamingClient[DownloadResponse]
 
+func (c *fileStoreClient) UploadVersion(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadRequest, UploadResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FileStore_ServiceDesc.Streams[3], FileStore_UploadVersion_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadRequest, UploadResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileStore_UploadVersionClient = grpc.ClientStreamingClient[UploadRequest, UploadResponse]
+
+func (c *fileStoreClient) DeleteVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, FileStore_DeleteVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) RestoreVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*UploadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UploadResponse)
+	err := c.cc.Invoke(ctx, FileStore_RestoreVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) SetRetention(ctx context.Context, in *SetRetentionRequest, opts ...grpc.CallOption) (*SetRetentionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetRetentionResponse)
+	err := c.cc.Invoke(ctx, FileStore_SetRetention_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) SetReplicaFactor(ctx context.Context, in *SetReplicaFactorRequest, opts ...grpc.CallOption) (*SetReplicaFactorResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetReplicaFactorResponse)
+	err := c.cc.Invoke(ctx, FileStore_SetReplicaFactor_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) GetReplicationStatus(ctx context.Context, in *ReplicationStatusRequest, opts ...grpc.CallOption) (*ReplicationStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReplicationStatusResponse)
+	err := c.cc.Invoke(ctx, FileStore_GetReplicationStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) Rebalance(ctx context.Context, in *RebalanceRequest, opts ...grpc.CallOption) (*RebalanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RebalanceResponse)
+	err := c.cc.Invoke(ctx, FileStore_Rebalance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) GetRepairLog(ctx context.Context, in *RepairLogRequest, opts ...grpc.CallOption) (*RepairLogResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RepairLogResponse)
+	err := c.cc.Invoke(ctx, FileStore_GetRepairLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) GetScrubStatus(ctx context.Context, in *ScrubStatusRequest, opts ...grpc.CallOption) (*ScrubStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScrubStatusResponse)
+	err := c.cc.Invoke(ctx, FileStore_GetScrubStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) GetAntiEntropyStatus(ctx context.Context, in *AntiEntropyStatusRequest, opts ...grpc.CallOption) (*AntiEntropyStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AntiEntropyStatusResponse)
+	err := c.cc.Invoke(ctx, FileStore_GetAntiEntropyStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) GetHandoffStatus(ctx context.Context, in *HandoffStatusRequest, opts ...grpc.CallOption) (*HandoffStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HandoffStatusResponse)
+	err := c.cc.Invoke(ctx, FileStore_GetHandoffStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) Fsck(ctx context.Context, in *FsckRequest, opts ...grpc.CallOption) (*FsckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FsckResponse)
+	err := c.cc.Invoke(ctx, FileStore_Fsck_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) RotateKeys(ctx context.Context, in *RotateKeysRequest, opts ...grpc.CallOption) (*RotateKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RotateKeysResponse)
+	err := c.cc.Invoke(ctx, FileStore_RotateKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) GetMembership(ctx context.Context, in *MembershipRequest, opts ...grpc.CallOption) (*MembershipResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MembershipResponse)
+	err := c.cc.Invoke(ctx, FileStore_GetMembership_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, FileStore_Heartbeat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FileStoreServer is the server API for FileStore service.
 // All implementations must embed UnimplementedFileStoreServer
 // for forward compatibility.
@@ -142,6 +327,23 @@ type FileStoreServer interface {
 	GetFileInfo(context.Context, *FileInfoRequest) (*FileInfoResponse, error)
 	ListFiles(context.Context, *ListFilesRequest) (*ListFilesResponse, error)
 	GetVersion(*VersionRequest, grpc.ServerStreamingServer[DownloadResponse]) error
+	UploadVersion(grpc.ClientStreamingServer[UploadRequest, UploadResponse]) error
+	DeleteVersion(context.Context, *VersionRequest) (*DeleteResponse, error)
+	RestoreVersion(context.Context, *VersionRequest) (*UploadResponse, error)
+	SetRetention(context.Context, *SetRetentionRequest) (*SetRetentionResponse, error)
+	SetReplicaFactor(context.Context, *SetReplicaFactorRequest) (*SetReplicaFactorResponse, error)
+	// Admin operations
+	GetReplicationStatus(context.Context, *ReplicationStatusRequest) (*ReplicationStatusResponse, error)
+	Rebalance(context.Context, *RebalanceRequest) (*RebalanceResponse, error)
+	GetRepairLog(context.Context, *RepairLogRequest) (*RepairLogResponse, error)
+	GetScrubStatus(context.Context, *ScrubStatusRequest) (*ScrubStatusResponse, error)
+	GetAntiEntropyStatus(context.Context, *AntiEntropyStatusRequest) (*AntiEntropyStatusResponse, error)
+	GetHandoffStatus(context.Context, *HandoffStatusRequest) (*HandoffStatusResponse, error)
+	Fsck(context.Context, *FsckRequest) (*FsckResponse, error)
+	RotateKeys(context.Context, *RotateKeysRequest) (*RotateKeysResponse, error)
+	GetMembership(context.Context, *MembershipRequest) (*MembershipResponse, error)
+	// Sent by storage node daemons to register and stay registered
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
 	mustEmbedUnimplementedFileStoreServer()
 }
 
@@ -170,6 +372,51 @@ func (UnimplementedFileStoreServer) ListFiles(context.Context, *ListFilesRequest
 func (UnimplementedFileStoreServer) GetVersion(*VersionRequest, grpc.ServerStreamingServer[DownloadResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
 }
+func (UnimplementedFileStoreServer) UploadVersion(grpc.ClientStreamingServer[UploadRequest, UploadResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadVersion not implemented")
+}
+func (UnimplementedFileStoreServer) DeleteVersion(context.Context, *VersionRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteVersion not implemented")
+}
+func (UnimplementedFileStoreServer) RestoreVersion(context.Context, *VersionRequest) (*UploadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreVersion not implemented")
+}
+func (UnimplementedFileStoreServer) SetRetention(context.Context, *SetRetentionRequest) (*SetRetentionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetRetention not implemented")
+}
+func (UnimplementedFileStoreServer) SetReplicaFactor(context.Context, *SetReplicaFactorRequest) (*SetReplicaFactorResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetReplicaFactor not implemented")
+}
+func (UnimplementedFileStoreServer) GetReplicationStatus(context.Context, *ReplicationStatusRequest) (*ReplicationStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReplicationStatus not implemented")
+}
+func (UnimplementedFileStoreServer) Rebalance(context.Context, *RebalanceRequest) (*RebalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rebalance not implemented")
+}
+func (UnimplementedFileStoreServer) GetRepairLog(context.Context, *RepairLogRequest) (*RepairLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRepairLog not implemented")
+}
+func (UnimplementedFileStoreServer) GetScrubStatus(context.Context, *ScrubStatusRequest) (*ScrubStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetScrubStatus not implemented")
+}
+func (UnimplementedFileStoreServer) GetAntiEntropyStatus(context.Context, *AntiEntropyStatusRequest) (*AntiEntropyStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAntiEntropyStatus not implemented")
+}
+func (UnimplementedFileStoreServer) GetHandoffStatus(context.Context, *HandoffStatusRequest) (*HandoffStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHandoffStatus not implemented")
+}
+func (UnimplementedFileStoreServer) Fsck(context.Context, *FsckRequest) (*FsckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Fsck not implemented")
+}
+func (UnimplementedFileStoreServer) RotateKeys(context.Context, *RotateKeysRequest) (*RotateKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateKeys not implemented")
+}
+func (UnimplementedFileStoreServer) GetMembership(context.Context, *MembershipRequest) (*MembershipResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMembership not implemented")
+}
+func (UnimplementedFileStoreServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
 func (UnimplementedFileStoreServer) mustEmbedUnimplementedFileStoreServer() {}
 func (UnimplementedFileStoreServer) testEmbeddedByValue()                   {}
 
@@ -274,6 +521,265 @@ func _FileStore_GetVersion_Handler(srv interface{}, stream grpc.ServerStream) er
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type FileStore_GetVersionServer = grpc.ServerStreamingServer[DownloadResponse]
 
+func _FileStore_UploadVersion_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FileStoreServer).UploadVersion(&grpc.GenericServerStream[UploadRequest, UploadResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileStore_UploadVersionServer = grpc.ClientStreamingServer[UploadRequest, UploadResponse]
+
+func _FileStore_DeleteVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).DeleteVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_DeleteVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).DeleteVersion(ctx, req.(*VersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_RestoreVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).RestoreVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_RestoreVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).RestoreVersion(ctx, req.(*VersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_SetRetention_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRetentionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).SetRetention(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_SetRetention_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).SetRetention(ctx, req.(*SetRetentionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_SetReplicaFactor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetReplicaFactorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).SetReplicaFactor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_SetReplicaFactor_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).SetReplicaFactor(ctx, req.(*SetReplicaFactorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_GetReplicationStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplicationStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).GetReplicationStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_GetReplicationStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).GetReplicationStatus(ctx, req.(*ReplicationStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_Rebalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).Rebalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_Rebalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).Rebalance(ctx, req.(*RebalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_GetRepairLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RepairLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).GetRepairLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_GetRepairLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).GetRepairLog(ctx, req.(*RepairLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_GetScrubStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScrubStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).GetScrubStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_GetScrubStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).GetScrubStatus(ctx, req.(*ScrubStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_GetAntiEntropyStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AntiEntropyStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).GetAntiEntropyStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_GetAntiEntropyStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).GetAntiEntropyStatus(ctx, req.(*AntiEntropyStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_GetHandoffStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandoffStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).GetHandoffStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_GetHandoffStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).GetHandoffStatus(ctx, req.(*HandoffStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_Fsck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FsckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).Fsck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_Fsck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).Fsck(ctx, req.(*FsckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_RotateKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).RotateKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_RotateKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).RotateKeys(ctx, req.(*RotateKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_GetMembership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MembershipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).GetMembership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_GetMembership_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).GetMembership(ctx, req.(*MembershipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // FileStore_ServiceDesc is the grpc.ServiceDesc for FileStore service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -293,6 +799,62 @@ var FileStore_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListFiles",
 			Handler:    _FileStore_ListFiles_Handler,
 		},
+		{
+			MethodName: "DeleteVersion",
+			Handler:    _FileStore_DeleteVersion_Handler,
+		},
+		{
+			MethodName: "RestoreVersion",
+			Handler:    _FileStore_RestoreVersion_Handler,
+		},
+		{
+			MethodName: "SetRetention",
+			Handler:    _FileStore_SetRetention_Handler,
+		},
+		{
+			MethodName: "SetReplicaFactor",
+			Handler:    _FileStore_SetReplicaFactor_Handler,
+		},
+		{
+			MethodName: "GetReplicationStatus",
+			Handler:    _FileStore_GetReplicationStatus_Handler,
+		},
+		{
+			MethodName: "Rebalance",
+			Handler:    _FileStore_Rebalance_Handler,
+		},
+		{
+			MethodName: "GetRepairLog",
+			Handler:    _FileStore_GetRepairLog_Handler,
+		},
+		{
+			MethodName: "GetScrubStatus",
+			Handler:    _FileStore_GetScrubStatus_Handler,
+		},
+		{
+			MethodName: "GetAntiEntropyStatus",
+			Handler:    _FileStore_GetAntiEntropyStatus_Handler,
+		},
+		{
+			MethodName: "GetHandoffStatus",
+			Handler:    _FileStore_GetHandoffStatus_Handler,
+		},
+		{
+			MethodName: "Fsck",
+			Handler:    _FileStore_Fsck_Handler,
+		},
+		{
+			MethodName: "RotateKeys",
+			Handler:    _FileStore_RotateKeys_Handler,
+		},
+		{
+			MethodName: "GetMembership",
+			Handler:    _FileStore_GetMembership_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _FileStore_Heartbeat_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -310,6 +872,11 @@ var FileStore_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _FileStore_GetVersion_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "UploadVersion",
+			Handler:       _FileStore_UploadVersion_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "api/proto/filestore.proto",
 }
@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"os"
+	"path"
+	"sort"
+	"strconv"
 	"time"
 
 	"google.golang.org/grpc"
@@ -40,15 +44,25 @@ func main() {
 	switch command {
 	case "upload":
 		if len(os.Args) < 3 {
-			log.Fatal("Usage: client upload <filepath>")
+			log.Fatal("Usage: client upload <filepath> [--file-id <file_id>] [--write-quorum <w>] [--replica-factor <n>] [--storage-mode <replicated|erasure>] [--data-shards <k>] [--parity-shards <m>] [--compression <none|zstd|gzip|snappy>]")
 		}
-		uploadFile(client, os.Args[2])
+		flags := parseFlags(os.Args[3:], "--file-id", "--write-quorum", "--replica-factor", "--storage-mode", "--data-shards", "--parity-shards", "--compression")
+		uploadFile(client, os.Args[2], &pb.UploadRequest{
+			FileId:        flags["--file-id"],
+			WriteQuorum:   flagInt(flags, "--write-quorum"),
+			ReplicaFactor: flagInt(flags, "--replica-factor"),
+			StorageMode:   flags["--storage-mode"],
+			DataShards:    flagInt(flags, "--data-shards"),
+			ParityShards:  flagInt(flags, "--parity-shards"),
+			Compression:   flags["--compression"],
+		})
 
 	case "download":
 		if len(os.Args) < 4 {
-			log.Fatal("Usage: client download <file_id> <output_path>")
+			log.Fatal("Usage: client download <file_id> <output_path> [--read-quorum <r>]")
 		}
-		downloadFile(client, os.Args[2], os.Args[3])
+		flags := parseFlags(os.Args[4:], "--read-quorum")
+		downloadFile(client, os.Args[2], os.Args[3], flagInt(flags, "--read-quorum"))
 
 	case "delete":
 		if len(os.Args) < 3 {
@@ -65,14 +79,54 @@ func main() {
 	case "list":
 		listFiles(client)
 
+	case "delete-version":
+		if len(os.Args) < 4 {
+			log.Fatal("Usage: client delete-version <file_id> <version_id>")
+		}
+		deleteVersion(client, os.Args[2], os.Args[3])
+
+	case "restore":
+		if len(os.Args) < 4 {
+			log.Fatal("Usage: client restore <file_id> <version_id>")
+		}
+		restoreVersion(client, os.Args[2], os.Args[3])
+
+	case "retention":
+		if len(os.Args) < 3 {
+			log.Fatal("Usage: client retention <file_id> [--keep-last <n>] [--max-age-days <d>]")
+		}
+		setRetention(client, os.Args[2], os.Args[3:])
+
+	case "replicas":
+		if len(os.Args) < 4 {
+			log.Fatal("Usage: client replicas <file_id> <replica_factor>")
+		}
+		replicaFactor, err := strconv.Atoi(os.Args[3])
+		if err != nil {
+			log.Fatalf("Invalid replica factor %q: %v", os.Args[3], err)
+		}
+		setReplicaFactor(client, os.Args[2], int32(replicaFactor))
+
+	case "admin":
+		if len(os.Args) < 3 {
+			log.Fatal("Usage: client admin <command> [options]")
+		}
+		runAdmin(client, os.Args[2], os.Args[3:])
+
 	default:
 		printUsage()
 		os.Exit(1)
 	}
 }
 
-func uploadFile(client pb.FileStoreClient, filepath string) {
-	log.Printf("Uploading file: %s", filepath)
+// uploadFile uploads a file, sending the options set in settings with every chunk
+func uploadFile(client pb.FileStoreClient, filepath string, settings *pb.UploadRequest) {
+	fileID := settings.FileId
+	if fileID != "" {
+		log.Printf("Uploading new version of %s: %s", fileID, filepath)
+	} else {
+		log.Printf("Uploading file: %s", filepath)
+	}
 
 	file, err := os.Open(filepath)
 	if err != nil {
@@ -85,10 +139,22 @@ func uploadFile(client pb.FileStoreClient, filepath string) {
 		log.Fatalf("Failed to stat file: %v", err)
 	}
 
+	// The server picks a codec by content type unless one is given
+	contentType := mime.TypeByExtension(path.Ext(filepath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	stream, err := client.Upload(ctx)
+	// Uploading with a file ID adds a new version to that file
+	var stream pb.FileStore_UploadClient
+	if fileID != "" {
+		stream, err = client.UploadVersion(ctx)
+	} else {
+		stream, err = client.Upload(ctx)
+	}
 	if err != nil {
 		log.Fatalf("Failed to create upload stream: %v", err)
 	}
@@ -107,10 +173,17 @@ func uploadFile(client pb.FileStoreClient, filepath string) {
 		}
 
 		req := &pb.UploadRequest{
-			Filename:    stat.Name(),
-			Chunk:       buffer[:n],
-			TotalSize:   stat.Size(),
-			ContentType: "application/octet-stream",
+			Filename:     stat.Name(),
+			Chunk:        buffer[:n],
+			TotalSize:    stat.Size(),
+			ContentType:  contentType,
+			FileId:        fileID,
+			WriteQuorum:   settings.WriteQuorum,
+			ReplicaFactor: settings.ReplicaFactor,
+			StorageMode:   settings.StorageMode,
+			DataShards:    settings.DataShards,
+			ParityShards:  settings.ParityShards,
+			Compression:   settings.Compression,
 		}
 
 		if err := stream.Send(req); err != nil {
@@ -136,16 +209,31 @@ func uploadFile(client pb.FileStoreClient, filepath string) {
 	} else {
 		fmt.Printf("\n✗ Upload failed: %s\n", res.Message)
 	}
+	printReplicaStatuses(res.Replicas)
+}
+
+// printReplicaStatuses prints the outcome of the write to each replica
+func printReplicaStatuses(replicas []*pb.ReplicaStatus) {
+	for _, replica := range replicas {
+		if replica.Success && replica.HintedFor != "" {
+			fmt.Printf("    ✓ %s (%dms, holding for %s)\n", replica.NodeId, replica.DurationMs, replica.HintedFor)
+		} else if replica.Success {
+			fmt.Printf("    ✓ %s (%dms)\n", replica.NodeId, replica.DurationMs)
+		} else {
+			fmt.Printf("    ✗ %s: %s\n", replica.NodeId, replica.Error)
+		}
+	}
 }
 
-func downloadFile(client pb.FileStoreClient, fileID, outputPath string) {
+func downloadFile(client pb.FileStoreClient, fileID, outputPath string, readQuorum int32) {
 	log.Printf("Downloading file: %s", fileID)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
 	stream, err := client.Download(ctx, &pb.DownloadRequest{
-		FileId: fileID,
+		FileId:     fileID,
+		ReadQuorum: readQuorum,
 	})
 	if err != nil {
 		log.Fatalf("Failed to download: %v", err)
@@ -166,7 +254,10 @@ func downloadFile(client pb.FileStoreClient, fileID, outputPath string) {
 			break
 		}
 		if err != nil {
-			log.Fatalf("Failed to receive chunk: %v", err)
+			// Don't leave a truncated or corrupted file behind
+			file.Close()
+			os.Remove(outputPath)
+			log.Fatalf("\nFailed to receive chunk: %v", err)
 		}
 
 		if totalSize == 0 {
@@ -227,6 +318,21 @@ func getFileInfo(client pb.FileStoreClient, fileID string) {
 	fmt.Printf("  Updated: %s\n", res.UpdatedAt)
 	fmt.Printf("  Versions: %v\n", res.Versions)
 	fmt.Printf("  Replicas: %v\n", res.Replicas)
+	fmt.Printf("  Storage Mode: %s\n", res.StorageMode)
+	fmt.Printf("  Compression: %s (%d bytes stored)\n", res.Compression, res.StoredSize)
+	if res.EncryptionKeyId != "" {
+		fmt.Printf("  Encryption: AES-256-GCM (master key %s)\n", res.EncryptionKeyId)
+	} else {
+		fmt.Printf("  Encryption: none\n")
+	}
+	if res.ReplicaFactor > 0 {
+		fmt.Printf("  Replica Factor: %d\n", res.ReplicaFactor)
+	} else {
+		fmt.Printf("  Replica Factor: server default\n")
+	}
+	if res.RetentionKeepLast > 0 || res.RetentionMaxAgeDays > 0 {
+		fmt.Printf("  Retention: keep last %d, max age %d days\n", res.RetentionKeepLast, res.RetentionMaxAgeDays)
+	}
 }
 
 func listFiles(client pb.FileStoreClient) {
@@ -259,18 +365,519 @@ func listFiles(client pb.FileStoreClient) {
 	}
 }
 
+func deleteVersion(client pb.FileStoreClient, fileID, versionID string) {
+	log.Printf("Deleting version %s of file: %s", versionID, fileID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := client.DeleteVersion(ctx, &pb.VersionRequest{
+		FileId:    fileID,
+		VersionId: versionID,
+	})
+	if err != nil {
+		log.Fatalf("Failed to delete version: %v", err)
+	}
+
+	if res.Success {
+		fmt.Printf("✓ Version deleted successfully\n")
+	} else {
+		fmt.Printf("✗ Delete failed: %s\n", res.Message)
+	}
+}
+
+func restoreVersion(client pb.FileStoreClient, fileID, versionID string) {
+	log.Printf("Restoring version %s of file: %s", versionID, fileID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	res, err := client.RestoreVersion(ctx, &pb.VersionRequest{
+		FileId:    fileID,
+		VersionId: versionID,
+	})
+	if err != nil {
+		log.Fatalf("Failed to restore version: %v", err)
+	}
+
+	if res.Success {
+		fmt.Printf("✓ %s\n", res.Message)
+		fmt.Printf("  New Version ID: %s\n", res.VersionId)
+		fmt.Printf("  Size: %d bytes\n", res.Size)
+	} else {
+		fmt.Printf("✗ Restore failed: %s\n", res.Message)
+	}
+	printReplicaStatuses(res.Replicas)
+}
+
+func setRetention(client pb.FileStoreClient, fileID string, args []string) {
+	flags := parseFlags(args, "--keep-last", "--max-age-days")
+	req := &pb.SetRetentionRequest{
+		FileId:     fileID,
+		KeepLast:   flagInt(flags, "--keep-last"),
+		MaxAgeDays: flagInt(flags, "--max-age-days"),
+	}
+
+	log.Printf("Setting retention for file: %s", fileID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := client.SetRetention(ctx, req)
+	if err != nil {
+		log.Fatalf("Failed to set retention: %v", err)
+	}
+
+	if res.Success {
+		fmt.Printf("✓ %s\n", res.Message)
+	} else {
+		fmt.Printf("✗ %s\n", res.Message)
+	}
+}
+
+func setReplicaFactor(client pb.FileStoreClient, fileID string, replicaFactor int32) {
+	log.Printf("Setting replica factor for file: %s", fileID)
+
+	// Adding replicas copies every version, so allow more time than for
+	// metadata-only changes
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	res, err := client.SetReplicaFactor(ctx, &pb.SetReplicaFactorRequest{
+		FileId:        fileID,
+		ReplicaFactor: replicaFactor,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set replica factor: %v", err)
+	}
+
+	if res.Success {
+		fmt.Printf("✓ %s\n", res.Message)
+		fmt.Printf("  Replicas: %v\n", res.Replicas)
+	} else {
+		fmt.Printf("✗ %s\n", res.Message)
+	}
+}
+
+// runAdmin runs an administrative command
+func runAdmin(client pb.FileStoreClient, command string, args []string) {
+	switch command {
+	case "replication":
+		replicationStatus(client, hasFlag(args, "--run"))
+
+	case "rebalance":
+		dryRun := hasFlag(args, "--dry-run")
+		var rest []string
+		for _, arg := range args {
+			if arg != "--dry-run" {
+				rest = append(rest, arg)
+			}
+		}
+		flags := parseFlags(rest, "--bytes-per-sec")
+		rebalance(client, dryRun, int64(flagInt(flags, "--bytes-per-sec")))
+
+	case "repairs":
+		flags := parseFlags(args, "--limit")
+		repairLog(client, flagInt(flags, "--limit"))
+
+	case "scrub":
+		scrubStatus(client, hasFlag(args, "--run"))
+
+	case "anti-entropy":
+		antiEntropyStatus(client, hasFlag(args, "--run"))
+
+	case "handoff":
+		handoffStatus(client, hasFlag(args, "--run"))
+
+	case "fsck":
+		fsck(client, hasFlag(args, "--repair"))
+
+	case "rotate-keys":
+		rotateKeys(client)
+
+	case "members":
+		members(client, hasFlag(args, "--probe"))
+
+	default:
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func replicationStatus(client pb.FileStoreClient, runNow bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := client.GetReplicationStatus(ctx, &pb.ReplicationStatusRequest{RunNow: runNow})
+	if err != nil {
+		log.Fatalf("Failed to get replication status: %v", err)
+	}
+
+	if runNow {
+		fmt.Println("✓ Re-replication scan requested")
+	}
+	state := "idle"
+	if res.Running {
+		state = "running"
+	}
+	fmt.Printf("Re-replication: %s\n", state)
+	if res.LastStarted == "" {
+		fmt.Println("  No scan has run yet")
+		return
+	}
+	fmt.Printf("  Started: %s\n", res.LastStarted)
+	if res.LastFinished != "" {
+		fmt.Printf("  Finished: %s\n", res.LastFinished)
+	}
+	fmt.Printf("  Files scanned: %d (%d versions)\n", res.FilesScanned, res.VersionsScanned)
+	fmt.Printf("  Under-replicated: %d\n", res.UnderReplicated)
+	fmt.Printf("  Repaired: %d\n", res.Repaired)
+	fmt.Printf("  Failed: %d\n", res.Failed)
+	fmt.Printf("  Bytes copied: %d\n", res.BytesCopied)
+	if res.LastError != "" {
+		fmt.Printf("  Last error: %s\n", res.LastError)
+	}
+}
+
+func rebalance(client pb.FileStoreClient, dryRun bool, bytesPerSecond int64) {
+	if dryRun {
+		log.Printf("Planning rebalance")
+	} else {
+		log.Printf("Rebalancing")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 24*time.Hour)
+	defer cancel()
+
+	res, err := client.Rebalance(ctx, &pb.RebalanceRequest{
+		DryRun:         dryRun,
+		BytesPerSecond: bytesPerSecond,
+	})
+	if err != nil {
+		log.Fatalf("Failed to rebalance: %v", err)
+	}
+
+	var total int64
+	for _, move := range res.Moves {
+		total += move.Size * int64(len(move.Add))
+		fmt.Printf("  %s/%s (%d bytes): +%v -%v\n", move.FileId, move.VersionId, move.Size, move.Add, move.Remove)
+	}
+
+	if res.DryRun {
+		fmt.Printf("✓ %d versions would move (%d bytes to copy)\n", len(res.Moves), total)
+	} else {
+		fmt.Printf("✓ Moved %d of %d versions (%d failed, %d bytes copied)\n", res.Moved, len(res.Moves), res.Failed, res.BytesCopied)
+	}
+	if res.Error != "" {
+		fmt.Printf("✗ Rebalance stopped: %s\n", res.Error)
+	}
+}
+
+func repairLog(client pb.FileStoreClient, limit int32) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := client.GetRepairLog(ctx, &pb.RepairLogRequest{Limit: limit})
+	if err != nil {
+		log.Fatalf("Failed to get repair log: %v", err)
+	}
+
+	if len(res.Nodes) == 0 {
+		fmt.Println("No replicas have needed repair")
+		return
+	}
+
+	fmt.Println("Repairs by node:")
+	for _, node := range res.Nodes {
+		fmt.Printf("  %s: %d missing, %d corrupt, %d diverged (%d repaired, %d failed)\n",
+			node.NodeId, node.Missing, node.Corrupt, node.Diverged, node.Repaired, node.Failed)
+	}
+
+	fmt.Printf("\nRecent repairs (%d):\n", len(res.Events))
+	for _, event := range res.Events {
+		if event.Error != "" {
+			fmt.Printf("  ✗ %s %s/%s on %s (%s): %s\n", event.Time, event.FileId, event.VersionId, event.NodeId, event.Reason, event.Error)
+		} else {
+			fmt.Printf("  ✓ %s %s/%s on %s (%s) from %s\n", event.Time, event.FileId, event.VersionId, event.NodeId, event.Reason, event.Source)
+		}
+	}
+}
+
+func scrubStatus(client pb.FileStoreClient, runNow bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := client.GetScrubStatus(ctx, &pb.ScrubStatusRequest{RunNow: runNow})
+	if err != nil {
+		log.Fatalf("Failed to get scrub status: %v", err)
+	}
+
+	if runNow {
+		fmt.Println("✓ Scrub requested")
+	}
+	state := "idle"
+	if res.Running {
+		state = "running"
+	}
+	fmt.Printf("Scrubber: %s\n", state)
+	if res.Started == "" {
+		fmt.Println("  No scrub has run yet")
+		return
+	}
+	fmt.Printf("  Started: %s\n", res.Started)
+	if res.Finished != "" {
+		fmt.Printf("  Finished: %s\n", res.Finished)
+	}
+	fmt.Printf("  Nodes scanned: %d\n", res.NodesScanned)
+	fmt.Printf("  Objects scanned: %d (%d bytes)\n", res.ObjectsScanned, res.BytesScanned)
+	fmt.Printf("  Corrupt: %d (%d repaired, %d repair failures)\n", res.Corrupt, res.Repaired, res.RepairFailed)
+	for _, object := range res.CorruptObjects {
+		fmt.Printf("    ✗ %s/%s on %s: %s\n", object.FileId, object.VersionId, object.NodeId, object.Error)
+	}
+	if res.LastError != "" {
+		fmt.Printf("  Last error: %s\n", res.LastError)
+	}
+}
+
+func antiEntropyStatus(client pb.FileStoreClient, runNow bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := client.GetAntiEntropyStatus(ctx, &pb.AntiEntropyStatusRequest{RunNow: runNow})
+	if err != nil {
+		log.Fatalf("Failed to get anti-entropy status: %v", err)
+	}
+
+	if runNow {
+		fmt.Println("✓ Anti-entropy run requested")
+	}
+	state := "idle"
+	if res.Running {
+		state = "running"
+	}
+	fmt.Printf("Anti-entropy: %s\n", state)
+	if res.Started == "" {
+		fmt.Println("  No run has happened yet")
+		return
+	}
+	fmt.Printf("  Started: %s\n", res.Started)
+	if res.Finished != "" {
+		fmt.Printf("  Finished: %s\n", res.Finished)
+	}
+	fmt.Printf("  Node pairs compared: %d (%d differing buckets)\n", res.PairsCompared, res.BucketsDiffered)
+	fmt.Printf("  Objects differing: %d\n", res.ObjectsDiffered)
+	fmt.Printf("  Repaired: %d, removed: %d, failed: %d\n", res.Repaired, res.Removed, res.Failed)
+	if res.LastError != "" {
+		fmt.Printf("  Last error: %s\n", res.LastError)
+	}
+}
+
+func handoffStatus(client pb.FileStoreClient, runNow bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := client.GetHandoffStatus(ctx, &pb.HandoffStatusRequest{RunNow: runNow})
+	if err != nil {
+		log.Fatalf("Failed to get hinted handoff status: %v", err)
+	}
+
+	if runNow {
+		fmt.Println("✓ Hinted handoff pass requested")
+	}
+	state := "idle"
+	if res.Running {
+		state = "running"
+	}
+	fmt.Printf("Hinted handoff: %s\n", state)
+	if res.LastStarted == "" {
+		fmt.Println("  No pass has run yet")
+		return
+	}
+	fmt.Printf("  Started: %s\n", res.LastStarted)
+	if res.LastFinished != "" {
+		fmt.Printf("  Finished: %s\n", res.LastFinished)
+	}
+	fmt.Printf("  Hints: %d\n", res.Hints)
+	fmt.Printf("  Handed off: %d, dropped: %d, waiting: %d, failed: %d\n", res.HandedOff, res.Dropped, res.Waiting, res.Failed)
+	fmt.Printf("  Bytes copied: %d\n", res.BytesCopied)
+	if res.LastError != "" {
+		fmt.Printf("  Last error: %s\n", res.LastError)
+	}
+}
+
+func fsck(client pb.FileStoreClient, repair bool) {
+	if repair {
+		log.Printf("Checking and repairing consistency")
+	} else {
+		log.Printf("Checking consistency")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 24*time.Hour)
+	defer cancel()
+
+	res, err := client.Fsck(ctx, &pb.FsckRequest{Repair: repair})
+	if err != nil {
+		log.Fatalf("Failed to run fsck: %v", err)
+	}
+
+	fmt.Printf("Checked %d files (%d versions) against %d objects on %d nodes\n",
+		res.FilesChecked, res.VersionsChecked, res.ObjectsChecked, res.NodesChecked)
+	if len(res.Issues) == 0 {
+		fmt.Println("✓ No issues found")
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, issue := range res.Issues {
+		counts[issue.Kind]++
+
+		location := issue.FileId + "/" + issue.VersionId
+		if issue.NodeId != "" {
+			location += " on " + issue.NodeId
+		}
+		status := ""
+		if issue.Repaired {
+			status = " (repaired)"
+		} else if issue.Error != "" {
+			status = fmt.Sprintf(" (repair failed: %s)", issue.Error)
+		}
+		fmt.Printf("  %s %s: %s%s\n", issue.Kind, location, issue.Detail, status)
+	}
+
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	fmt.Printf("✗ %d issues found:", len(res.Issues))
+	for _, kind := range kinds {
+		fmt.Printf(" %d %s", counts[kind], kind)
+	}
+	fmt.Println()
+	if res.Repair {
+		fmt.Printf("  %d repaired, %d failed\n", res.Repaired, res.Failed)
+	}
+}
+
+func rotateKeys(client pb.FileStoreClient) {
+	log.Printf("Rewrapping data keys with the current master key")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	res, err := client.RotateKeys(ctx, &pb.RotateKeysRequest{})
+	if err != nil {
+		log.Fatalf("Failed to rotate keys: %v", err)
+	}
+
+	if res.Failed > 0 {
+		fmt.Printf("✗ Rewrapped %d data keys with master key %s, %d failed\n", res.Rewrapped, res.KeyId, res.Failed)
+		return
+	}
+	fmt.Printf("✓ Rewrapped %d data keys with master key %s\n", res.Rewrapped, res.KeyId)
+}
+
+func members(client pb.FileStoreClient, probeNow bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := client.GetMembership(ctx, &pb.MembershipRequest{ProbeNow: probeNow})
+	if err != nil {
+		log.Fatalf("Failed to get membership: %v", err)
+	}
+
+	if len(res.Members) == 0 {
+		fmt.Println("No storage nodes known")
+		return
+	}
+
+	fmt.Printf("Storage nodes (%d):\n", len(res.Members))
+	for _, member := range res.Members {
+		mark := "✓"
+		if member.State != "alive" {
+			mark = "✗"
+		}
+		address := member.Address
+		if address == "" {
+			address = "probed"
+		}
+		fmt.Printf("  %s %s [%s] %s\n", mark, member.NodeId, member.State, address)
+		fmt.Printf("      Last heartbeat: %s, state since: %s\n", member.LastHeartbeat, member.StateChanged)
+	}
+}
+
 func printUsage() {
 	fmt.Println("Distributed File Store CLI Client")
 	fmt.Println("\nUsage:")
-	fmt.Println("  client upload <filepath>")
-	fmt.Println("  client download <file_id> <output_path>")
+	fmt.Println("  client upload <filepath> [--file-id <file_id>] [--write-quorum <w>] [--replica-factor <n>]")
+	fmt.Println("                [--storage-mode <replicated|erasure>] [--data-shards <k>] [--parity-shards <m>]")
+	fmt.Println("                [--compression <none|zstd|gzip|snappy>]")
+	fmt.Println("  client download <file_id> <output_path> [--read-quorum <r>]")
 	fmt.Println("  client delete <file_id>")
 	fmt.Println("  client info <file_id>")
 	fmt.Println("  client list")
+	fmt.Println("  client delete-version <file_id> <version_id>")
+	fmt.Println("  client restore <file_id> <version_id>")
+	fmt.Println("  client retention <file_id> [--keep-last <n>] [--max-age-days <d>]")
+	fmt.Println("  client replicas <file_id> <replica_factor>")
+	fmt.Println("  client admin replication [--run]")
+	fmt.Println("  client admin rebalance [--dry-run] [--bytes-per-sec <n>]")
+	fmt.Println("  client admin repairs [--limit <n>]")
+	fmt.Println("  client admin scrub [--run]")
+	fmt.Println("  client admin anti-entropy [--run]")
+	fmt.Println("  client admin handoff [--run]")
+	fmt.Println("  client admin fsck [--repair]")
+	fmt.Println("  client admin rotate-keys")
+	fmt.Println("  client admin members [--probe]")
 	fmt.Println("\nEnvironment Variables:")
 	fmt.Println("  SERVER_ADDR - Server address (default: localhost:50051)")
 }
 
+// hasFlag reports whether a boolean option is present in args
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFlags parses "--name value" pairs, rejecting options not in allowed
+func parseFlags(args []string, allowed ...string) map[string]string {
+	flags := make(map[string]string)
+	for i := 0; i < len(args); i += 2 {
+		known := false
+		for _, name := range allowed {
+			if args[i] == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			log.Fatalf("Unknown option: %s", args[i])
+		}
+		if i+1 >= len(args) {
+			log.Fatalf("Missing value for %s", args[i])
+		}
+		flags[args[i]] = args[i+1]
+	}
+	return flags
+}
+
+// flagInt returns the integer value of a parsed flag, or zero if it was not given
+func flagInt(flags map[string]string, name string) int32 {
+	value, ok := flags[name]
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid value for %s: %v", name, err)
+	}
+	return int32(n)
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -6,15 +6,24 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
 	pb "github.com/yashlad/distributed-file-store/api/proto"
+	"github.com/yashlad/distributed-file-store/internal/chunker"
+	"github.com/yashlad/distributed-file-store/internal/compression"
+	"github.com/yashlad/distributed-file-store/internal/encryption"
+	"github.com/yashlad/distributed-file-store/internal/gossip"
 	"github.com/yashlad/distributed-file-store/internal/manager"
 	"github.com/yashlad/distributed-file-store/internal/metadata"
 	"github.com/yashlad/distributed-file-store/internal/server"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+	"github.com/yashlad/distributed-file-store/internal/storagenode"
 )
 
 const (
@@ -22,6 +31,21 @@ const (
 	defaultMongoURI    = "mongodb://localhost:27017"
 	defaultDatabase    = "filestore"
 	defaultReplicaFactor = 2
+	defaultPruneMinutes  = 60
+	defaultWriteQuorum   = 1
+	defaultReadQuorum    = 1
+	defaultReplicaTimeoutSeconds = 300
+	defaultReplicationMinutes    = 10
+	defaultRebalanceBytesPerSec  = 32 * 1024 * 1024
+	defaultScrubHours            = 24
+	defaultScrubBytesPerSec      = 16 * 1024 * 1024
+	defaultAntiEntropyMinutes    = 60
+	defaultHandoffMinutes        = 1
+	defaultDataShards            = 4
+	defaultParityShards          = 2
+	defaultHeartbeatSeconds      = 5
+	defaultSuspectSeconds        = 15
+	defaultDeadSeconds           = 60
 )
 
 func main() {
@@ -42,24 +66,189 @@ func main() {
 	log.Printf("✓ Connected to MongoDB")
 
 	// Initialize file manager
-	fileManager := manager.NewFileManager(metadataStore, defaultReplicaFactor)
+	replicaFactor := getEnvInt("REPLICA_FACTOR", defaultReplicaFactor)
+	if replicaFactor < 1 {
+		log.Fatalf("Invalid replica factor: %d must be positive", replicaFactor)
+	}
+	fileManager := manager.NewFileManager(metadataStore, replicaFactor)
+
+	writeQuorum := getEnvInt("WRITE_QUORUM", defaultWriteQuorum)
+	readQuorum := getEnvInt("READ_QUORUM", defaultReadQuorum)
+	if err := fileManager.SetQuorum(writeQuorum, readQuorum); err != nil {
+		log.Fatalf("Invalid quorum configuration: %v", err)
+	}
+	log.Printf("Replica factor: %d (write quorum: %d, read quorum: %d)", replicaFactor, writeQuorum, readQuorum)
+
+	replicaTimeout := time.Duration(getEnvInt("REPLICA_TIMEOUT_SECONDS", defaultReplicaTimeoutSeconds)) * time.Second
+	if err := fileManager.SetReplicaTimeout(replicaTimeout); err != nil {
+		log.Fatalf("Invalid replica timeout: %v", err)
+	}
+
+	chunking := chunker.Config{
+		MinSize: getEnvInt("CHUNK_MIN_KB", chunker.DefaultMinSize/1024) * 1024,
+		AvgSize: getEnvInt("CHUNK_AVG_KB", chunker.DefaultAvgSize/1024) * 1024,
+		MaxSize: getEnvInt("CHUNK_MAX_KB", chunker.DefaultMaxSize/1024) * 1024,
+	}
+	if err := fileManager.SetChunking(chunking); err != nil {
+		log.Fatalf("Invalid chunking configuration: %v", err)
+	}
+	log.Printf("Chunk sizes: min %d, avg %d, max %d bytes", chunking.MinSize, chunking.AvgSize, chunking.MaxSize)
+
+	storageMode := getEnv("STORAGE_MODE", metadata.StorageReplicated)
+	dataShards := getEnvInt("ERASURE_DATA_SHARDS", defaultDataShards)
+	parityShards := getEnvInt("ERASURE_PARITY_SHARDS", defaultParityShards)
+	if err := fileManager.SetStorageMode(storageMode, dataShards, parityShards); err != nil {
+		log.Fatalf("Invalid storage mode configuration: %v", err)
+	}
+	log.Printf("Storage mode: %s (erasure coding: %d data + %d parity shards)", storageMode, dataShards, parityShards)
+
+	defaultCodec := getEnv("COMPRESSION", compression.None)
+	compressionRules, err := compression.ParseRules(os.Getenv("COMPRESSION_RULES"))
+	if err != nil {
+		log.Fatalf("Invalid compression rules: %v", err)
+	}
+	if err := fileManager.SetCompression(defaultCodec, compressionRules); err != nil {
+		log.Fatalf("Invalid compression configuration: %v", err)
+	}
+	log.Printf("Compression: %s (%d content-type rules)", defaultCodec, len(compressionRules))
+
+	if keyfile := os.Getenv("ENCRYPTION_KEYFILE"); keyfile != "" {
+		keyring, err := encryption.LoadKeyfile(keyfile)
+		if err != nil {
+			log.Fatalf("Failed to load encryption keyfile: %v", err)
+		}
+		fileManager.SetKeyManager(keyring)
+		log.Printf("✓ Encryption at rest enabled (master key: %s)", keyring.CurrentKeyID())
+	} else {
+		log.Printf("Encryption at rest disabled")
+	}
+
+	// Node daemons are reached with mutual TLS, so data keys are never sent
+	// in cleartext
+	var clusterTLS *storagenode.ClusterTLS
+	if certFile := os.Getenv("NODE_TLS_CERT"); certFile != "" {
+		clusterTLS, err = storagenode.LoadClusterTLS(certFile, os.Getenv("NODE_TLS_KEY"), os.Getenv("NODE_TLS_CA"))
+		if err != nil {
+			log.Fatalf("Failed to load cluster TLS: %v", err)
+		}
+		log.Printf("✓ Cluster TLS enabled for node daemons")
+	}
+
+	// Register storage nodes. STORAGE_NODES lists node daemons to reach
+	// over gRPC; without it the nodes run in process.
+	if spec := os.Getenv("STORAGE_NODES"); spec != "" {
+		remoteNodes, err := parseStorageNodes(spec)
+		if err != nil {
+			log.Fatalf("Invalid STORAGE_NODES: %v", err)
+		}
+		for _, node := range remoteNodes {
+			client, err := storagenode.Dial(node.address, clusterTLS)
+			if err != nil {
+				log.Fatalf("Failed to dial node %s at %s: %v", node.id, node.address, err)
+			}
+			if err := fileManager.AddNode(node.id, client); err != nil {
+				log.Fatalf("Failed to register node %s: %v", node.id, err)
+			}
+			log.Printf("✓ Registered storage node: %s (daemon at %s)", node.id, node.address)
+		}
+	} else if getEnv("LOCAL_NODES", "true") == "true" {
+		// In production, these would be separate servers
+		nodes := []struct {
+			id   string
+			path string
+		}{
+			{"node-1", "/tmp/filestore/node-1"},
+			{"node-2", "/tmp/filestore/node-2"},
+			{"node-3", "/tmp/filestore/node-3"},
+		}
+
+		for _, node := range nodes {
+			backend := nodeBackend(node.id, node.path)
+			if err := fileManager.RegisterNode(node.id, backend); err != nil {
+				log.Fatalf("Failed to register node %s: %v", node.id, err)
+			}
+			log.Printf("✓ Registered storage node: %s (%s backend)", node.id, backend.Type)
+		}
+	}
 
-	// Register storage nodes
-	// In production, these would be separate servers
-	nodes := []struct {
-		id   string
-		path string
-	}{
-		{"node-1", "/tmp/filestore/node-1"},
-		{"node-2", "/tmp/filestore/node-2"},
-		{"node-3", "/tmp/filestore/node-3"},
+	// Start the version retention pruner
+	retention := metadata.RetentionPolicy{
+		KeepLast: getEnvInt("RETENTION_KEEP_LAST", 0),
+		MaxAge:   time.Duration(getEnvInt("RETENTION_MAX_AGE_DAYS", 0)) * 24 * time.Hour,
 	}
+	pruneInterval := time.Duration(getEnvInt("PRUNE_INTERVAL_MINUTES", defaultPruneMinutes)) * time.Minute
+	pruner := manager.NewPruner(fileManager, retention, pruneInterval)
+	pruner.Start()
+	log.Printf("✓ Retention pruner running every %s (keep last: %d, max age: %s)", pruneInterval, retention.KeepLast, retention.MaxAge)
 
-	for _, node := range nodes {
-		if err := fileManager.RegisterNode(node.id, node.path); err != nil {
-			log.Fatalf("Failed to register node %s: %v", node.id, err)
+	// Start re-replication of versions that lost replicas
+	replicationInterval := time.Duration(getEnvInt("REPLICATION_INTERVAL_MINUTES", defaultReplicationMinutes)) * time.Minute
+	replicator := manager.NewReplicator(fileManager, replicationInterval)
+	replicator.Start()
+	log.Printf("✓ Re-replication running every %s and on node changes", replicationInterval)
+
+	// Move data to new owners when nodes join
+	rebalanceRate := int64(getEnvInt("REBALANCE_BYTES_PER_SEC", defaultRebalanceBytesPerSec))
+	rebalancer := manager.NewRebalancer(fileManager, rebalanceRate)
+	rebalancer.Start()
+	log.Printf("✓ Rebalancer running on node changes (limit: %d bytes/sec)", rebalanceRate)
+
+	// Verify stored checksums in the background
+	scrubInterval := time.Duration(getEnvInt("SCRUB_INTERVAL_HOURS", defaultScrubHours)) * time.Hour
+	scrubRate := int64(getEnvInt("SCRUB_BYTES_PER_SEC", defaultScrubBytesPerSec))
+	scrubRepair := getEnv("SCRUB_REPAIR", "true") == "true"
+	scrubber := manager.NewScrubber(fileManager, scrubInterval, scrubRate, scrubRepair)
+	scrubber.Start()
+	log.Printf("✓ Scrubber running every %s (limit: %d bytes/sec, repair: %t)", scrubInterval, scrubRate, scrubRepair)
+
+	// Reconcile replicas that drifted apart
+	antiEntropyInterval := time.Duration(getEnvInt("ANTI_ENTROPY_INTERVAL_MINUTES", defaultAntiEntropyMinutes)) * time.Minute
+	antiEntropy := manager.NewAntiEntropy(fileManager, antiEntropyInterval)
+	antiEntropy.Start()
+	log.Printf("✓ Anti-entropy running every %s", antiEntropyInterval)
+
+	// Hand versions written to stand-in nodes back to their owners
+	handoffInterval := time.Duration(getEnvInt("HANDOFF_INTERVAL_MINUTES", defaultHandoffMinutes)) * time.Minute
+	handoff := manager.NewHintedHandoff(fileManager, handoffInterval)
+	handoff.Start()
+	log.Printf("✓ Hinted handoff running every %s and on node changes", handoffInterval)
+
+	// Start failure detection once the workers that react to node changes
+	// run. With GOSSIP_BIND set, nodes are found and checked through gossip
+	// with the storage nodes and other servers; otherwise node daemons
+	// started with COORDINATOR_ADDR register themselves with heartbeats and
+	// the other nodes are probed.
+	dial := func(address string) (manager.NodeClient, error) {
+		return storagenode.Dial(address, clusterTLS)
+	}
+	var membership *manager.Membership
+	var gossipNode *gossip.Node
+	var follower *manager.GossipFollower
+	if bind := os.Getenv("GOSSIP_BIND"); bind != "" {
+		// Gossip is not authenticated, so only nodes that present a
+		// certificate from the cluster CA may be registered through it
+		if clusterTLS == nil {
+			log.Fatalf("GOSSIP_BIND requires cluster TLS (NODE_TLS_CERT, NODE_TLS_KEY and NODE_TLS_CA)")
+		}
+		gossipNode = startGossip(bind, port)
+		follower = manager.FollowGossip(fileManager, gossipNode, dial)
+		if seeds := splitList(os.Getenv("GOSSIP_SEEDS")); len(seeds) > 0 {
+			if err := gossipNode.Join(seeds...); err != nil {
+				log.Printf("Warning: failed to join gossip cluster: %v", err)
+			}
 		}
-		log.Printf("✓ Registered storage node: %s", node.id)
+		log.Printf("✓ Gossip membership running as %s on %s", gossipNode.LocalMember().ID, gossipNode.LocalMember().Addr)
+	} else {
+		membershipConfig := manager.MembershipConfig{
+			HeartbeatInterval: time.Duration(getEnvInt("HEARTBEAT_INTERVAL_SECONDS", defaultHeartbeatSeconds)) * time.Second,
+			SuspectAfter:      time.Duration(getEnvInt("SUSPECT_AFTER_SECONDS", defaultSuspectSeconds)) * time.Second,
+			DeadAfter:         time.Duration(getEnvInt("DEAD_AFTER_SECONDS", defaultDeadSeconds)) * time.Second,
+		}
+		membership = manager.NewMembership(fileManager, membershipConfig)
+		membership.SetDialer(dial)
+		membership.Start()
+		log.Printf("✓ Membership running (heartbeat: %s, suspect after: %s, dead after: %s)",
+			membershipConfig.HeartbeatInterval, membershipConfig.SuspectAfter, membershipConfig.DeadAfter)
 	}
 
 	// Create gRPC server
@@ -75,6 +264,14 @@ func main() {
 
 	// Register FileStore service
 	fileStoreServer := server.NewFileStoreServer(fileManager)
+	fileStoreServer.SetReplicator(replicator)
+	fileStoreServer.SetRebalancer(rebalancer)
+	fileStoreServer.SetScrubber(scrubber)
+	fileStoreServer.SetAntiEntropy(antiEntropy)
+	fileStoreServer.SetHintedHandoff(handoff)
+	if membership != nil {
+		fileStoreServer.SetMembership(membership)
+	}
 	pb.RegisterFileStoreServer(grpcServer, fileStoreServer)
 
 	// Enable reflection for debugging with grpcurl
@@ -82,6 +279,27 @@ func main() {
 
 	log.Printf("✓ gRPC server listening on port %s", port)
 
+	// Node daemons with a cluster certificate send heartbeats to
+	// CLUSTER_PORT, where they are trusted to move to a new address
+	var clusterServer *grpc.Server
+	if clusterPort := os.Getenv("CLUSTER_PORT"); clusterPort != "" {
+		if clusterTLS == nil {
+			log.Fatalf("CLUSTER_PORT requires cluster TLS (NODE_TLS_CERT, NODE_TLS_KEY and NODE_TLS_CA)")
+		}
+		clusterLis, err := net.Listen("tcp", fmt.Sprintf(":%s", clusterPort))
+		if err != nil {
+			log.Fatalf("Failed to listen on cluster port: %v", err)
+		}
+		clusterServer = grpc.NewServer(grpc.Creds(clusterTLS.ServerCredentials()))
+		pb.RegisterFileStoreServer(clusterServer, fileStoreServer)
+		go func() {
+			if err := clusterServer.Serve(clusterLis); err != nil {
+				log.Fatalf("Failed to serve cluster port: %v", err)
+			}
+		}()
+		log.Printf("✓ Cluster TLS listening on port %s", clusterPort)
+	}
+
 	// Handle graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
@@ -89,6 +307,21 @@ func main() {
 		<-sigChan
 
 		log.Println("\nShutting down gracefully...")
+		if gossipNode != nil {
+			follower.Stop()
+			gossipNode.Leave()
+		} else {
+			membership.Stop()
+		}
+		pruner.Stop()
+		replicator.Stop()
+		rebalancer.Stop()
+		scrubber.Stop()
+		antiEntropy.Stop()
+		handoff.Stop()
+		if clusterServer != nil {
+			clusterServer.GracefulStop()
+		}
 		grpcServer.GracefulStop()
 		log.Println("✓ Server stopped")
 	}()
@@ -100,9 +333,107 @@ func main() {
 	}
 }
 
+// nodeBackend returns the backend a node stores its data in. STORAGE_BACKEND
+// sets it for every node and STORAGE_BACKEND_<NODE>, e.g.
+// STORAGE_BACKEND_NODE_3, for a single one. Nodes on S3 share the configured
+// bucket, each under a prefix of its own.
+func nodeBackend(nodeID, path string) storage.BackendConfig {
+	override := "STORAGE_BACKEND_" + strings.ToUpper(strings.ReplaceAll(nodeID, "-", "_"))
+	config := storage.BackendConfig{
+		Type: getEnv(override, getEnv("STORAGE_BACKEND", storage.BackendFS)),
+		Path: path,
+	}
+	if config.Type == storage.BackendS3 {
+		config.S3 = storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Prefix:    nodeID + "/",
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+	}
+	return config
+}
+
+// remoteNode is a storage node daemon listed in STORAGE_NODES
+type remoteNode struct {
+	id      string
+	address string
+}
+
+// parseStorageNodes parses a comma-separated list of node daemons of the form
+// "node-1=host:port,node-2=host:port"
+func parseStorageNodes(spec string) ([]remoteNode, error) {
+	var nodes []remoteNode
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, address, ok := strings.Cut(entry, "=")
+		id, address = strings.TrimSpace(id), strings.TrimSpace(address)
+		if !ok || id == "" || address == "" {
+			return nil, fmt.Errorf("%q is not of the form node=host:port", entry)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("node %s listed twice", id)
+		}
+		seen[id] = true
+		nodes = append(nodes, remoteNode{id: id, address: address})
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes listed")
+	}
+	return nodes, nil
+}
+
+// startGossip starts this server's member of the gossip cluster, listening
+// on bind. GOSSIP_ID names the member and GOSSIP_ADVERTISE is the address
+// other members reach it at.
+func startGossip(bind, port string) *gossip.Node {
+	transport, err := gossip.ListenUDP(bind, os.Getenv("GOSSIP_ADVERTISE"))
+	if err != nil {
+		log.Fatalf("Failed to start gossip: %v", err)
+	}
+	hostname, _ := os.Hostname()
+	config := gossip.DefaultConfig()
+	config.ID = getEnv("GOSSIP_ID", fmt.Sprintf("server-%s-%s", hostname, port))
+	config.Tags = map[string]string{gossip.RoleTag: gossip.RoleCoordinator}
+	node, err := gossip.New(config, transport)
+	if err != nil {
+		log.Fatalf("Failed to start gossip: %v", err)
+	}
+	return node
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(spec string) []string {
+	var items []string
+	for _, item := range strings.Split(spec, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid value for %s: %v", key, err)
+	}
+	return n
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/yashlad/distributed-file-store/internal/gossip"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+	"github.com/yashlad/distributed-file-store/internal/storagenode"
+)
+
+const (
+	defaultNodeID = "node-1"
+	defaultPort   = "50061"
+)
+
+func main() {
+	// Get configuration from environment variables
+	nodeID := getEnv("NODE_ID", defaultNodeID)
+	port := getEnv("PORT", defaultPort)
+	config := storage.BackendConfig{
+		Type: getEnv("STORAGE_BACKEND", storage.BackendFS),
+		Path: getEnv("STORAGE_PATH", "/tmp/filestore/"+nodeID),
+	}
+	if config.Type == storage.BackendS3 {
+		config.S3 = storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Prefix:    getEnv("S3_PREFIX", nodeID+"/"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+	}
+
+	log.Printf("Starting storage node %s...", nodeID)
+	log.Printf("Port: %s", port)
+
+	backend, err := storage.OpenBackend(config)
+	if err != nil {
+		log.Fatalf("Failed to open %s backend: %v", config.Type, err)
+	}
+	node, err := storage.NewBackendNode(nodeID, backend)
+	if err != nil {
+		log.Fatalf("Failed to open storage node: %v", err)
+	}
+	log.Printf("✓ Storage ready (%s backend)", config.Type)
+
+	// Serve mutual TLS to the servers and the other daemons of the cluster
+	var clusterTLS *storagenode.ClusterTLS
+	if certFile := os.Getenv("NODE_TLS_CERT"); certFile != "" {
+		clusterTLS, err = storagenode.LoadClusterTLS(certFile, os.Getenv("NODE_TLS_KEY"), os.Getenv("NODE_TLS_CA"))
+		if err != nil {
+			log.Fatalf("Failed to load cluster TLS: %v", err)
+		}
+		log.Printf("✓ Cluster TLS enabled")
+	} else {
+		log.Printf("Warning: cluster TLS disabled; encrypted uploads and copies between nodes are refused")
+	}
+
+	daemon, err := storagenode.Listen(":"+port, node, clusterTLS)
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	log.Printf("✓ gRPC server listening on %s", daemon.Address())
+
+	// Register with the coordinator, which dials the node back at the
+	// advertised address
+	advertise := getEnv("ADVERTISE_ADDR", "localhost:"+port)
+	var heartbeater *storagenode.Heartbeater
+	if coordinator := os.Getenv("COORDINATOR_ADDR"); coordinator != "" {
+		heartbeater, err = storagenode.NewHeartbeater(coordinator, nodeID, advertise, clusterTLS)
+		if err != nil {
+			log.Fatalf("Failed to connect to coordinator %s: %v", coordinator, err)
+		}
+		heartbeater.Start()
+		log.Printf("✓ Sending heartbeats to %s as %s", coordinator, advertise)
+	}
+
+	// Or announce the node to the gossip cluster, where every server
+	// following the cluster picks it up
+	var gossipNode *gossip.Node
+	if bind := os.Getenv("GOSSIP_BIND"); bind != "" {
+		// Servers only register nodes found through gossip over cluster TLS
+		if clusterTLS == nil {
+			log.Fatalf("GOSSIP_BIND requires cluster TLS (NODE_TLS_CERT, NODE_TLS_KEY and NODE_TLS_CA)")
+		}
+		transport, err := gossip.ListenUDP(bind, os.Getenv("GOSSIP_ADVERTISE"))
+		if err != nil {
+			log.Fatalf("Failed to start gossip: %v", err)
+		}
+		config := gossip.DefaultConfig()
+		config.ID = nodeID
+		config.Tags = map[string]string{
+			gossip.RoleTag:    gossip.RoleStorage,
+			gossip.AddressTag: advertise,
+		}
+		gossipNode, err = gossip.New(config, transport)
+		if err != nil {
+			log.Fatalf("Failed to start gossip: %v", err)
+		}
+		var seeds []string
+		for _, seed := range strings.Split(os.Getenv("GOSSIP_SEEDS"), ",") {
+			if seed = strings.TrimSpace(seed); seed != "" {
+				seeds = append(seeds, seed)
+			}
+		}
+		if len(seeds) > 0 {
+			if err := gossipNode.Join(seeds...); err != nil {
+				log.Printf("Warning: failed to join gossip cluster: %v", err)
+			}
+		}
+		log.Printf("✓ Gossiping on %s as %s", transport.Addr(), advertise)
+	}
+
+	// Handle graceful shutdown
+	go func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		<-sigChan
+
+		log.Println("\nShutting down gracefully...")
+		if heartbeater != nil {
+			heartbeater.Stop()
+		}
+		if gossipNode != nil {
+			gossipNode.Leave()
+		}
+		daemon.GracefulStop()
+		log.Println("✓ Storage node stopped")
+	}()
+
+	log.Println("🚀 Storage node ready to accept connections")
+	if err := daemon.Serve(); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -4,6 +4,7 @@ go 1.25.3
 
 require (
 	github.com/google/uuid v1.6.0
+	github.com/klauspost/compress v1.16.7
 	go.mongodb.org/mongo-driver v1.17.6
 	google.golang.org/grpc v1.76.0
 	google.golang.org/protobuf v1.36.10
@@ -11,7 +12,6 @@ require (
 
 require (
 	github.com/golang/snappy v0.0.4 // indirect
-	github.com/klauspost/compress v1.16.7 // indirect
 	github.com/montanaflynn/stats v0.7.1 // indirect
 	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
 	github.com/xdg-go/scram v1.1.2 // indirect
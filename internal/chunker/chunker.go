@@ -0,0 +1,172 @@
+package chunker
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+const (
+	// DefaultMinSize is the default smallest chunk, other than the last one
+	DefaultMinSize = 256 * 1024 // 256KB
+
+	// DefaultAvgSize is the default average chunk size
+	DefaultAvgSize = 1024 * 1024 // 1MB
+
+	// DefaultMaxSize is the default largest chunk
+	DefaultMaxSize = 4 * 1024 * 1024 // 4MB
+)
+
+// Config sets the chunk sizes a Chunker aims for
+type Config struct {
+	MinSize int
+	AvgSize int
+	MaxSize int
+}
+
+// DefaultConfig returns the default chunk sizes
+func DefaultConfig() Config {
+	return Config{
+		MinSize: DefaultMinSize,
+		AvgSize: DefaultAvgSize,
+		MaxSize: DefaultMaxSize,
+	}
+}
+
+// Validate checks that the sizes are positive and ordered
+func (c Config) Validate() error {
+	if c.MinSize <= 0 {
+		return fmt.Errorf("minimum chunk size %d must be positive", c.MinSize)
+	}
+	if c.AvgSize < c.MinSize || c.MaxSize < c.AvgSize {
+		return fmt.Errorf("chunk sizes must satisfy min %d <= avg %d <= max %d", c.MinSize, c.AvgSize, c.MaxSize)
+	}
+	return nil
+}
+
+// Chunker splits a stream into content-defined chunks using FastCDC. A cut
+// point depends only on the bytes just before it, so inserting or removing
+// data only changes the chunks around the edit and the rest of the stream is
+// split exactly as before.
+type Chunker struct {
+	r      io.Reader
+	config Config
+
+	// maskSmall is used before the average size and maskLarge after it, which
+	// pulls chunk sizes towards the average (normalized chunking)
+	maskSmall uint64
+	maskLarge uint64
+
+	buf   []byte
+	start int
+	end   int
+	eof   bool
+}
+
+// New returns a Chunker that reads from r
+func New(r io.Reader, config Config) (*Chunker, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	avgBits := bits.Len(uint(config.AvgSize)) - 1
+	return &Chunker{
+		r:         r,
+		config:    config,
+		maskSmall: cutMask(avgBits + 1),
+		maskLarge: cutMask(avgBits - 1),
+		buf:       make([]byte, config.MaxSize),
+	}, nil
+}
+
+// cutMask returns a mask of the top n bits of the rolling hash. The top bits
+// depend on the last 64 bytes, where the bottom ones would only see a few.
+func cutMask(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	if n > 64 {
+		n = 64
+	}
+	return ^uint64(0) << (64 - n)
+}
+
+// Next returns the next chunk, or io.EOF once the stream is exhausted. The
+// chunk is only valid until the following call to Next.
+func (c *Chunker) Next() ([]byte, error) {
+	if c.end-c.start < c.config.MaxSize && !c.eof {
+		if err := c.fill(); err != nil {
+			return nil, err
+		}
+	}
+	if c.start == c.end {
+		return nil, io.EOF
+	}
+
+	cut := c.cutPoint(c.buf[c.start:c.end])
+	chunk := c.buf[c.start : c.start+cut]
+	c.start += cut
+	return chunk, nil
+}
+
+// fill moves the unread data to the front of the buffer and tops it up
+func (c *Chunker) fill() error {
+	c.end = copy(c.buf, c.buf[c.start:c.end])
+	c.start = 0
+
+	n, err := io.ReadFull(c.r, c.buf[c.end:])
+	c.end += n
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		c.eof = true
+		return nil
+	}
+	return err
+}
+
+// cutPoint returns the length of the chunk at the start of data
+func (c *Chunker) cutPoint(data []byte) int {
+	if len(data) <= c.config.MinSize {
+		return len(data)
+	}
+	if len(data) > c.config.MaxSize {
+		data = data[:c.config.MaxSize]
+	}
+
+	normal := c.config.AvgSize
+	if normal > len(data) {
+		normal = len(data)
+	}
+
+	// Skipping the minimum size is safe: no cut can fall there anyway
+	var hash uint64
+	i := c.config.MinSize
+	for ; i < normal; i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&c.maskSmall == 0 {
+			return i + 1
+		}
+	}
+	for ; i < len(data); i++ {
+		hash = (hash << 1) + gear[data[i]]
+		if hash&c.maskLarge == 0 {
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// gear maps each byte to a random value for the rolling hash. It is generated
+// from a fixed seed so every node cuts the same data in the same places.
+var gear = func() [256]uint64 {
+	var table [256]uint64
+	state := uint64(0x6a09e667f3bcc908)
+	for i := range table {
+		// splitmix64
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
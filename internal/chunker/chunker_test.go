@@ -0,0 +1,142 @@
+package chunker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// testConfig uses small chunks so tests stay fast
+var testConfig = Config{MinSize: 1024, AvgSize: 4096, MaxSize: 16384}
+
+// randomData returns deterministic pseudo-random bytes
+func randomData(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// split returns the chunks of data
+func split(t *testing.T, data []byte, config Config) [][]byte {
+	t.Helper()
+	c, err := New(bytes.NewReader(data), config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	var chunks [][]byte
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		chunks = append(chunks, append([]byte(nil), chunk...))
+	}
+}
+
+// digests returns the set of SHA-256 digests of chunks
+func digests(chunks [][]byte) map[[32]byte]bool {
+	set := make(map[[32]byte]bool)
+	for _, chunk := range chunks {
+		set[sha256.Sum256(chunk)] = true
+	}
+	return set
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"default", DefaultConfig(), false},
+		{"fixed size", Config{MinSize: 4096, AvgSize: 4096, MaxSize: 4096}, false},
+		{"zero min", Config{MinSize: 0, AvgSize: 4096, MaxSize: 8192}, true},
+		{"avg below min", Config{MinSize: 4096, AvgSize: 1024, MaxSize: 8192}, true},
+		{"max below avg", Config{MinSize: 1024, AvgSize: 4096, MaxSize: 2048}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChunker(t *testing.T) {
+	data := randomData(1, 1024*1024)
+
+	t.Run("chunks reassemble to the input", func(t *testing.T) {
+		chunks := split(t, data, testConfig)
+		if !bytes.Equal(bytes.Join(chunks, nil), data) {
+			t.Error("chunks do not reassemble to the input")
+		}
+	})
+
+	t.Run("chunks respect the size bounds", func(t *testing.T) {
+		chunks := split(t, data, testConfig)
+		for i, chunk := range chunks {
+			if len(chunk) > testConfig.MaxSize {
+				t.Errorf("chunk %d is %d bytes, above the maximum", i, len(chunk))
+			}
+			if len(chunk) < testConfig.MinSize && i != len(chunks)-1 {
+				t.Errorf("chunk %d is %d bytes, below the minimum", i, len(chunk))
+			}
+		}
+
+		avg := len(data) / len(chunks)
+		if avg < testConfig.AvgSize/2 || avg > testConfig.AvgSize*2 {
+			t.Errorf("average chunk size %d is far from %d", avg, testConfig.AvgSize)
+		}
+	})
+
+	t.Run("cut points are deterministic", func(t *testing.T) {
+		first := split(t, data, testConfig)
+		second := split(t, data, testConfig)
+		if len(first) != len(second) {
+			t.Fatalf("got %d and %d chunks", len(first), len(second))
+		}
+		for i := range first {
+			if !bytes.Equal(first[i], second[i]) {
+				t.Fatalf("chunk %d differs", i)
+			}
+		}
+	})
+
+	t.Run("an insertion only changes nearby chunks", func(t *testing.T) {
+		edited := append(append(append([]byte(nil), data[:len(data)/2]...), []byte("inserted")...), data[len(data)/2:]...)
+
+		original := split(t, data, testConfig)
+		shared := digests(split(t, edited, testConfig))
+		reused := 0
+		for _, chunk := range original {
+			if shared[sha256.Sum256(chunk)] {
+				reused++
+			}
+		}
+		if reused < len(original)-3 {
+			t.Errorf("only %d of %d chunks reused after an insertion", reused, len(original))
+		}
+	})
+
+	t.Run("repetitive data is cut at the maximum", func(t *testing.T) {
+		chunks := split(t, bytes.Repeat([]byte("a"), 3*testConfig.MaxSize), testConfig)
+		if len(chunks) != 3 {
+			t.Errorf("got %d chunks, want 3", len(chunks))
+		}
+	})
+
+	t.Run("empty input has no chunks", func(t *testing.T) {
+		if chunks := split(t, nil, testConfig); len(chunks) != 0 {
+			t.Errorf("got %d chunks, want 0", len(chunks))
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codecs data can be stored with
+const (
+	None   = "none"
+	Zstd   = "zstd"
+	Gzip   = "gzip"
+	Snappy = "snappy"
+)
+
+// Validate checks that codec is a known codec. The empty string is accepted
+// and means None.
+func Validate(codec string) error {
+	switch codec {
+	case "", None, Zstd, Gzip, Snappy:
+		return nil
+	default:
+		return fmt.Errorf("unknown compression codec %q", codec)
+	}
+}
+
+// IsNone reports whether codec stores data uncompressed
+func IsNone(codec string) bool {
+	return codec == "" || codec == None
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// zstdCoders returns the shared zstd encoder and decoder, which are safe for
+// concurrent use through EncodeAll and DecodeAll
+func zstdCoders() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+// Compress returns data compressed with codec. With None, data is returned
+// as is.
+func Compress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case "", None:
+		return data, nil
+	case Zstd:
+		encoder, _, err := zstdCoders()
+		if err != nil {
+			return nil, err
+		}
+		return encoder.EncodeAll(data, nil), nil
+	case Gzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case Snappy:
+		return snappy.Encode(nil, data), nil
+	default:
+		return nil, Validate(codec)
+	}
+}
+
+// Decompress returns the original data of a block compressed with codec
+func Decompress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case "", None:
+		return data, nil
+	case Zstd:
+		_, decoder, err := zstdCoders()
+		if err != nil {
+			return nil, err
+		}
+		return decoder.DecodeAll(data, nil)
+	case Gzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	case Snappy:
+		return snappy.Decode(nil, data)
+	default:
+		return nil, Validate(codec)
+	}
+}
+
+// Rule selects the codec for content types matching Pattern, a MIME type
+// such as "application/json" or a wildcard such as "text/*"
+type Rule struct {
+	Pattern string
+	Codec   string
+}
+
+// ParseRules parses a comma-separated list of pattern=codec rules, such as
+// "text/*=zstd,application/json=zstd,image/*=none"
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, codec, ok := strings.Cut(entry, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("malformed compression rule %q, want pattern=codec", entry)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("malformed pattern in compression rule %q: %w", entry, err)
+		}
+		if err := Validate(codec); err != nil {
+			return nil, err
+		}
+		rules = append(rules, Rule{Pattern: pattern, Codec: codec})
+	}
+	return rules, nil
+}
+
+// Match returns the codec of the first rule matching contentType. Parameters
+// such as "; charset=utf-8" are ignored.
+func Match(rules []Rule, contentType string) (string, bool) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, rule := range rules {
+		if matched, _ := path.Match(strings.ToLower(rule.Pattern), mediaType); matched {
+			return rule.Codec, true
+		}
+	}
+	return "", false
+}
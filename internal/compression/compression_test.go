@@ -0,0 +1,93 @@
+package compression
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("2024-01-01T00:00:00Z INFO request served status=200\n"), 1000)
+
+	for _, codec := range []string{None, Zstd, Gzip, Snappy} {
+		t.Run(codec, func(t *testing.T) {
+			compressed, err := Compress(codec, data)
+			if err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+			if codec != None && len(compressed) >= len(data) {
+				t.Errorf("compressed to %d of %d bytes", len(compressed), len(data))
+			}
+
+			decompressed, err := Decompress(codec, compressed)
+			if err != nil {
+				t.Fatalf("Decompress failed: %v", err)
+			}
+			if !bytes.Equal(decompressed, data) {
+				t.Error("round trip changed the data")
+			}
+		})
+	}
+
+	t.Run("corrupt input", func(t *testing.T) {
+		for _, codec := range []string{Zstd, Gzip, Snappy} {
+			if _, err := Decompress(codec, []byte("not compressed")); err == nil {
+				t.Errorf("Decompress(%s) accepted corrupt input", codec)
+			}
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		codec   string
+		wantErr bool
+	}{
+		{"", false},
+		{None, false},
+		{Zstd, false},
+		{Gzip, false},
+		{Snappy, false},
+		{"lz4", true},
+	}
+
+	for _, tt := range tests {
+		if err := Validate(tt.codec); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", tt.codec, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	rules, err := ParseRules("text/*=zstd, application/json=gzip,image/*=none")
+	if err != nil {
+		t.Fatalf("ParseRules failed: %v", err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("parsed %d rules, want 3", len(rules))
+	}
+
+	tests := []struct {
+		contentType string
+		want        string
+		wantOK      bool
+	}{
+		{"text/plain", Zstd, true},
+		{"text/csv; charset=utf-8", Zstd, true},
+		{"Application/JSON", Gzip, true},
+		{"image/png", None, true},
+		{"application/octet-stream", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Match(rules, tt.contentType)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Match(%q) = %q, %v, want %q, %v", tt.contentType, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	for _, spec := range []string{"text/*", "=zstd", "text/*=lz4", "[=zstd"} {
+		if _, err := ParseRules(spec); err == nil {
+			t.Errorf("ParseRules(%q) accepted a malformed rule", spec)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	plaintext := []byte("quarterly results")
+
+	sealed, err := Seal(key, plaintext, []byte("chunk-1"))
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Error("sealed data contains the plaintext")
+	}
+	again, _ := Seal(key, plaintext, []byte("chunk-1"))
+	if bytes.Equal(sealed, again) {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+
+	opened, err := Open(key, sealed, []byte("chunk-1"))
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open = %q, %v", opened, err)
+	}
+
+	otherKey, _ := GenerateKey()
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+	tests := []struct {
+		name           string
+		key, sealed    []byte
+		additionalData string
+	}{
+		{"wrong key", otherKey, sealed, "chunk-1"},
+		{"wrong additional data", key, sealed, "chunk-2"},
+		{"tampered", key, tampered, "chunk-1"},
+		{"truncated", key, sealed[:8], "chunk-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(tt.key, tt.sealed, []byte(tt.additionalData)); !errors.Is(err, ErrDecrypt) {
+				t.Errorf("Open error = %v, want ErrDecrypt", err)
+			}
+		})
+	}
+
+	if _, err := Seal([]byte("short"), plaintext, nil); err == nil {
+		t.Error("expected error for a short key")
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	ctx := context.Background()
+	oldKey, _ := GenerateKey()
+	newKey, _ := GenerateKey()
+	dataKey, _ := GenerateKey()
+
+	path := filepath.Join(t.TempDir(), "keyfile")
+	keyfile := "# master keys, newest last\n" +
+		"2024-01 " + base64.StdEncoding.EncodeToString(oldKey) + "\n\n" +
+		"2024-07 " + base64.StdEncoding.EncodeToString(newKey) + "\n"
+	os.WriteFile(path, []byte(keyfile), 0600)
+
+	ring, err := LoadKeyfile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyfile failed: %v", err)
+	}
+	if ring.CurrentKeyID() != "2024-07" {
+		t.Errorf("current key = %q, want the last one", ring.CurrentKeyID())
+	}
+
+	keyID, wrapped, err := ring.WrapKey(ctx, dataKey)
+	if err != nil || keyID != "2024-07" {
+		t.Fatalf("WrapKey = %q, %v", keyID, err)
+	}
+	unwrapped, err := ring.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil || !bytes.Equal(unwrapped, dataKey) {
+		t.Errorf("UnwrapKey = %x, %v", unwrapped, err)
+	}
+
+	// Keys wrapped before a rotation still unwrap
+	oldRing, _ := NewKeyring(map[string][]byte{"2024-01": oldKey}, "2024-01")
+	_, oldWrapped, _ := oldRing.WrapKey(ctx, dataKey)
+	if unwrapped, err := ring.UnwrapKey(ctx, "2024-01", oldWrapped); err != nil || !bytes.Equal(unwrapped, dataKey) {
+		t.Errorf("UnwrapKey of an old key = %x, %v", unwrapped, err)
+	}
+
+	if _, err := ring.UnwrapKey(ctx, "2024-01", wrapped); err == nil {
+		t.Error("unwrapped a key under the wrong master key ID")
+	}
+	if _, err := ring.UnwrapKey(ctx, "2023-01", wrapped); err == nil {
+		t.Error("expected error for an unknown master key")
+	}
+}
+
+func TestLoadKeyfileErrors(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, KeySize))
+	tests := []struct {
+		name    string
+		keyfile string
+	}{
+		{"empty", "# no keys\n"},
+		{"missing key", "k1\n"},
+		{"bad base64", "k1 not-base64!\n"},
+		{"short key", "k1 " + base64.StdEncoding.EncodeToString([]byte("short")) + "\n"},
+		{"duplicate", "k1 " + key + "\nk1 " + key + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keyfile")
+			os.WriteFile(path, []byte(tt.keyfile), 0600)
+			if _, err := LoadKeyfile(path); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if _, err := LoadKeyfile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for a missing keyfile")
+	}
+}
@@ -0,0 +1,119 @@
+package encryption
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyManager protects data keys with master keys it never hands out, such as
+// a local keyring or a cloud KMS. Data is encrypted with a data key, and only
+// the data key, wrapped by the master key, is stored alongside it. Rotating
+// the master key only rewraps data keys; the data is not rewritten.
+type KeyManager interface {
+	// CurrentKeyID returns the ID of the master key new data keys are
+	// wrapped with
+	CurrentKeyID() string
+
+	// WrapKey encrypts a data key with the current master key, returning the
+	// master key's ID and the wrapped key
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+
+	// UnwrapKey decrypts a data key wrapped with the master key keyID
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Keyring is a KeyManager holding its master keys in memory, typically
+// loaded from a keyfile
+type Keyring struct {
+	keys    map[string][]byte
+	current string
+}
+
+// NewKeyring returns a keyring wrapping new data keys with the key current.
+// The other keys are kept to unwrap data keys wrapped before a rotation.
+func NewKeyring(keys map[string][]byte, current string) (*Keyring, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current master key %q not in keyring", current)
+	}
+	ring := &Keyring{keys: make(map[string][]byte, len(keys)), current: current}
+	for id, key := range keys {
+		if id == "" || strings.ContainsAny(id, " \t") {
+			return nil, fmt.Errorf("invalid master key ID %q", id)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("master key %q is %d bytes, want %d", id, len(key), KeySize)
+		}
+		ring.keys[id] = append([]byte(nil), key...)
+	}
+	return ring, nil
+}
+
+// LoadKeyfile reads a keyring from a file with one "<key-id> <base64 key>"
+// line per master key. The last key is the current one; earlier keys are
+// only used to unwrap data keys until they have been rotated. Blank lines and
+// lines starting with # are ignored.
+func LoadKeyfile(path string) (*Keyring, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keys := make(map[string][]byte)
+	var current string
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want \"<key-id> <base64 key>\"", path, line)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if _, exists := keys[fields[0]]; exists {
+			return nil, fmt.Errorf("%s:%d: duplicate master key %q", path, line, fields[0])
+		}
+		keys[fields[0]] = key
+		current = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current == "" {
+		return nil, fmt.Errorf("%s: no master keys", path)
+	}
+	return NewKeyring(keys, current)
+}
+
+// CurrentKeyID implements KeyManager
+func (k *Keyring) CurrentKeyID() string {
+	return k.current
+}
+
+// WrapKey implements KeyManager. The master key's ID is authenticated with
+// the wrapped key, so it can't be unwrapped under another ID.
+func (k *Keyring) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	wrapped, err := Seal(k.keys[k.current], dataKey, []byte(k.current))
+	if err != nil {
+		return "", nil, err
+	}
+	return k.current, wrapped, nil
+}
+
+// UnwrapKey implements KeyManager
+func (k *Keyring) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown master key %q", keyID)
+	}
+	return Open(master, wrapped, []byte(keyID))
+}
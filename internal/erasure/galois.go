@@ -0,0 +1,43 @@
+package erasure
+
+// Arithmetic in GF(2^8) with the polynomial x^8 + x^4 + x^3 + x^2 + 1
+
+var (
+	expTable [510]byte
+	logTable [256]byte
+
+	// mulTable[a][b] is a*b, so encoding only needs table lookups
+	mulTable [256][256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		expTable[i] = byte(x)
+		expTable[i+255] = byte(x)
+		logTable[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+
+	for a := 0; a < 256; a++ {
+		for b := 0; b < 256; b++ {
+			mulTable[a][b] = gfMul(byte(a), byte(b))
+		}
+	}
+}
+
+// gfMul multiplies two field elements
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+// gfInverse returns the multiplicative inverse of a non-zero element
+func gfInverse(a byte) byte {
+	return expTable[255-int(logTable[a])]
+}
@@ -0,0 +1,216 @@
+package erasure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTooFewShards is returned when fewer shards than the data shard count
+// survive, so the data can't be reconstructed
+var ErrTooFewShards = errors.New("too few shards to reconstruct")
+
+// Coder is a systematic Reed-Solomon erasure coder over GF(2^8). Data is
+// split into DataShards shards and ParityShards parity shards are computed
+// from them; any DataShards of the total are enough to rebuild the rest.
+type Coder struct {
+	DataShards   int
+	ParityShards int
+
+	// matrix has one row per shard: the identity for the data shards followed
+	// by a Cauchy matrix for the parity shards. Every square submatrix made of
+	// DataShards of its rows is invertible.
+	matrix [][]byte
+}
+
+// New creates a coder for the given number of data and parity shards
+func New(dataShards, parityShards int) (*Coder, error) {
+	if dataShards <= 0 {
+		return nil, fmt.Errorf("data shards %d must be positive", dataShards)
+	}
+	if parityShards < 0 {
+		return nil, fmt.Errorf("parity shards %d must not be negative", parityShards)
+	}
+	if dataShards+parityShards > 256 {
+		return nil, fmt.Errorf("at most 256 shards are supported, got %d", dataShards+parityShards)
+	}
+
+	total := dataShards + parityShards
+	matrix := make([][]byte, total)
+	for i := range matrix {
+		matrix[i] = make([]byte, dataShards)
+		if i < dataShards {
+			matrix[i][i] = 1
+			continue
+		}
+		for j := range matrix[i] {
+			// x_i = i and y_j = j are all distinct, so x_i ^ y_j is never zero
+			matrix[i][j] = gfInverse(byte(i) ^ byte(j))
+		}
+	}
+
+	return &Coder{DataShards: dataShards, ParityShards: parityShards, matrix: matrix}, nil
+}
+
+// Shards returns the total number of shards
+func (c *Coder) Shards() int {
+	return c.DataShards + c.ParityShards
+}
+
+// Encode computes the parity shards from the data shards. shards must hold
+// Shards() slices of equal length; the parity slices are overwritten.
+func (c *Coder) Encode(shards [][]byte) error {
+	if err := c.checkShards(shards, false); err != nil {
+		return err
+	}
+	for i := c.DataShards; i < c.Shards(); i++ {
+		c.computeShard(c.matrix[i], shards[:c.DataShards], shards[i])
+	}
+	return nil
+}
+
+// Reconstruct rebuilds the missing shards, which are given as nil, from the
+// ones present. At least DataShards shards must be present.
+func (c *Coder) Reconstruct(shards [][]byte) error {
+	return c.reconstruct(shards, false)
+}
+
+// ReconstructData rebuilds only the missing data shards, leaving missing
+// parity shards nil. It is cheaper than Reconstruct when only the original
+// data is needed.
+func (c *Coder) ReconstructData(shards [][]byte) error {
+	return c.reconstruct(shards, true)
+}
+
+// reconstruct rebuilds the missing data shards and, unless dataOnly is set,
+// the missing parity shards
+func (c *Coder) reconstruct(shards [][]byte, dataOnly bool) error {
+	if err := c.checkShards(shards, true); err != nil {
+		return err
+	}
+
+	size := 0
+	var present []int
+	for i, shard := range shards {
+		if shard != nil {
+			size = len(shard)
+			present = append(present, i)
+		}
+	}
+	if len(present) < c.DataShards {
+		return ErrTooFewShards
+	}
+	if len(present) == c.Shards() || (dataOnly && present[c.DataShards-1] == c.DataShards-1) {
+		return nil
+	}
+
+	// Invert the rows of the shards used so the data shards can be solved for
+	present = present[:c.DataShards]
+	sub := make([][]byte, c.DataShards)
+	inputs := make([][]byte, c.DataShards)
+	for i, index := range present {
+		sub[i] = c.matrix[index]
+		inputs[i] = shards[index]
+	}
+	decode, err := invert(sub)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < c.DataShards; i++ {
+		if shards[i] == nil {
+			shards[i] = make([]byte, size)
+			c.computeShard(decode[i], inputs, shards[i])
+		}
+	}
+	if dataOnly {
+		return nil
+	}
+	for i := c.DataShards; i < c.Shards(); i++ {
+		if shards[i] == nil {
+			shards[i] = make([]byte, size)
+			c.computeShard(c.matrix[i], shards[:c.DataShards], shards[i])
+		}
+	}
+	return nil
+}
+
+// checkShards validates the shard count and that present shards have equal
+// lengths
+func (c *Coder) checkShards(shards [][]byte, allowMissing bool) error {
+	if len(shards) != c.Shards() {
+		return fmt.Errorf("got %d shards, want %d", len(shards), c.Shards())
+	}
+	size := -1
+	for i, shard := range shards {
+		if shard == nil {
+			if allowMissing {
+				continue
+			}
+			return fmt.Errorf("shard %d is missing", i)
+		}
+		if size >= 0 && len(shard) != size {
+			return fmt.Errorf("shard %d is %d bytes, want %d", i, len(shard), size)
+		}
+		size = len(shard)
+	}
+	return nil
+}
+
+// computeShard sets out to the combination of inputs given by coefficients
+func (c *Coder) computeShard(coefficients []byte, inputs [][]byte, out []byte) {
+	for i := range out {
+		out[i] = 0
+	}
+	for j, input := range inputs {
+		coefficient := coefficients[j]
+		if coefficient == 0 {
+			continue
+		}
+		row := mulTable[coefficient]
+		for i, b := range input {
+			out[i] ^= row[b]
+		}
+	}
+}
+
+// invert returns the inverse of a square matrix by Gauss-Jordan elimination
+func invert(matrix [][]byte) ([][]byte, error) {
+	n := len(matrix)
+	work := make([][]byte, n)
+	for i := range work {
+		work[i] = make([]byte, 2*n)
+		copy(work[i], matrix[i])
+		work[i][n+i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && work[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, errors.New("matrix is singular")
+		}
+		work[col], work[pivot] = work[pivot], work[col]
+
+		scale := gfInverse(work[col][col])
+		for j := range work[col] {
+			work[col][j] = gfMul(work[col][j], scale)
+		}
+		for row := 0; row < n; row++ {
+			if row == col || work[row][col] == 0 {
+				continue
+			}
+			factor := work[row][col]
+			for j := range work[row] {
+				work[row][j] ^= gfMul(factor, work[col][j])
+			}
+		}
+	}
+
+	inverse := make([][]byte, n)
+	for i := range inverse {
+		inverse[i] = work[i][n:]
+	}
+	return inverse, nil
+}
@@ -0,0 +1,140 @@
+package erasure
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func newShards(t *testing.T, coder *Coder, size int) [][]byte {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	shards := make([][]byte, coder.Shards())
+	for i := range shards {
+		shards[i] = make([]byte, size)
+		if i < coder.DataShards {
+			rng.Read(shards[i])
+		}
+	}
+	if err := coder.Encode(shards); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	return shards
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    int
+		parity  int
+		wantErr bool
+	}{
+		{"valid", 4, 2, false},
+		{"no parity", 3, 0, false},
+		{"zero data", 0, 2, true},
+		{"negative parity", 4, -1, true},
+		{"too many shards", 200, 57, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.data, tt.parity)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New(%d, %d) error = %v, wantErr %v", tt.data, tt.parity, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReconstruct(t *testing.T) {
+	coder, err := New(4, 2)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	original := newShards(t, coder, 1000)
+
+	// Every combination of up to two lost shards must be recoverable
+	for a := 0; a < coder.Shards(); a++ {
+		for b := a; b < coder.Shards(); b++ {
+			shards := make([][]byte, len(original))
+			for i := range original {
+				shards[i] = append([]byte(nil), original[i]...)
+			}
+			shards[a] = nil
+			shards[b] = nil
+
+			if err := coder.Reconstruct(shards); err != nil {
+				t.Fatalf("Reconstruct without %d and %d failed: %v", a, b, err)
+			}
+			for i := range shards {
+				if !bytes.Equal(shards[i], original[i]) {
+					t.Fatalf("shard %d differs after losing %d and %d", i, a, b)
+				}
+			}
+		}
+	}
+}
+
+func TestReconstructData(t *testing.T) {
+	coder, err := New(3, 2)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	original := newShards(t, coder, 500)
+
+	shards := make([][]byte, len(original))
+	copy(shards, original)
+	shards[1] = nil
+	shards[4] = nil
+
+	if err := coder.ReconstructData(shards); err != nil {
+		t.Fatalf("ReconstructData failed: %v", err)
+	}
+	if !bytes.Equal(shards[1], original[1]) {
+		t.Error("data shard 1 differs after reconstruction")
+	}
+	if shards[4] != nil {
+		t.Error("parity shard 4 should be left missing")
+	}
+}
+
+func TestReconstructTooFewShards(t *testing.T) {
+	coder, err := New(4, 2)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	shards := newShards(t, coder, 100)
+	shards[0], shards[2], shards[5] = nil, nil, nil
+
+	if err := coder.Reconstruct(shards); !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("Reconstruct error = %v, want ErrTooFewShards", err)
+	}
+}
+
+func TestEncodeValidation(t *testing.T) {
+	coder, err := New(2, 1)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	t.Run("wrong count", func(t *testing.T) {
+		if err := coder.Encode(make([][]byte, 2)); err == nil {
+			t.Error("expected error for wrong shard count")
+		}
+	})
+
+	t.Run("uneven sizes", func(t *testing.T) {
+		shards := [][]byte{make([]byte, 4), make([]byte, 5), make([]byte, 4)}
+		if err := coder.Encode(shards); err == nil {
+			t.Error("expected error for uneven shard sizes")
+		}
+	})
+
+	t.Run("missing shard", func(t *testing.T) {
+		shards := [][]byte{make([]byte, 4), nil, make([]byte, 4)}
+		if err := coder.Encode(shards); err == nil {
+			t.Error("expected error for missing shard")
+		}
+	})
+}
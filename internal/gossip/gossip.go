@@ -0,0 +1,742 @@
+// Package gossip implements SWIM-style cluster membership. Members probe
+// each other directly and through peers, suspect members that stop
+// answering, and spread every membership change by piggybacking it on the
+// protocol's own messages, so each member converges on the same view of the
+// cluster without a central registry.
+package gossip
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Member states
+const (
+	StateAlive   = "alive"
+	StateSuspect = "suspect"
+	StateDead    = "dead"
+	StateLeft    = "left"
+)
+
+// Tags the file store's members announce themselves with
+const (
+	// RoleTag says what a member is
+	RoleTag = "role"
+
+	// AddressTag is the address of a storage node's gRPC service
+	AddressTag = "address"
+
+	// RoleStorage members are storage node daemons
+	RoleStorage = "storage"
+
+	// RoleCoordinator members are file store servers
+	RoleCoordinator = "coordinator"
+)
+
+// Message kinds
+const (
+	kindPing      = "ping"
+	kindPingReq   = "ping-req"
+	kindAck       = "ack"
+	kindGossip    = "gossip"
+	kindSync      = "sync"
+	kindSyncReply = "sync-reply"
+)
+
+// maxPiggyback is the most updates piggybacked on a single message
+const maxPiggyback = 8
+
+// leaveFanout is the number of members told directly that a member is
+// leaving
+const leaveFanout = 3
+
+// Member is a participant in the cluster as seen by one member. Tags carry
+// whatever the member wants the rest of the cluster to know about it, such
+// as its role or the address of its services.
+type Member struct {
+	ID          string            `json:"id"`
+	Addr        string            `json:"addr"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	State       string            `json:"state"`
+	Incarnation uint64            `json:"incarnation"`
+}
+
+// Config configures a gossip member
+type Config struct {
+	// ID identifies the member. It must be unique in the cluster and stay
+	// the same across restarts.
+	ID string
+
+	// Tags are announced to the rest of the cluster
+	Tags map[string]string
+
+	// ProbeInterval is how often a member is probed
+	ProbeInterval time.Duration
+
+	// ProbeTimeout is how long to wait for a direct probe to be answered
+	// before asking other members to probe indirectly
+	ProbeTimeout time.Duration
+
+	// IndirectChecks is the number of members asked to probe indirectly
+	IndirectChecks int
+
+	// SuspicionTimeout is how long a member stays suspect before it is
+	// declared dead, giving it a chance to refute the suspicion
+	SuspicionTimeout time.Duration
+
+	// SyncInterval is how often the full membership is exchanged with a
+	// random member, which heals views that drifted apart during a partition
+	SyncInterval time.Duration
+
+	// RetransmitMult scales how many times each update is piggybacked,
+	// which is RetransmitMult * log(cluster size)
+	RetransmitMult int
+}
+
+// DefaultConfig returns a configuration suited to a cluster on one network
+func DefaultConfig() Config {
+	return Config{
+		ProbeInterval:    time.Second,
+		ProbeTimeout:     500 * time.Millisecond,
+		IndirectChecks:   3,
+		SuspicionTimeout: 5 * time.Second,
+		SyncInterval:     30 * time.Second,
+		RetransmitMult:   4,
+	}
+}
+
+// message is the wire format of every gossip message
+type message struct {
+	Kind    string   `json:"kind"`
+	From    string   `json:"from"`
+	Seq     uint64   `json:"seq,omitempty"`
+	Target  string   `json:"target,omitempty"`
+	Updates []Member `json:"updates,omitempty"`
+}
+
+// memberState is a member along with when it became suspect
+type memberState struct {
+	Member
+	suspected time.Time
+}
+
+// broadcast is an update waiting to be piggybacked
+type broadcast struct {
+	member    Member
+	transmits int
+}
+
+// Node is this process's member of a gossip cluster
+type Node struct {
+	config    Config
+	transport Transport
+
+	mu         sync.Mutex
+	members    map[string]*memberState
+	broadcasts []*broadcast
+	acks       map[uint64]chan struct{}
+	seq        uint64
+	probeOrder []string
+	listeners  []func(Member)
+	events     []Member
+
+	wake      chan struct{}
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// New starts a member of a gossip cluster on transport. Until it joins
+// other members with Join, or they join it, it is a cluster of one.
+func New(config Config, transport Transport) (*Node, error) {
+	if config.ID == "" {
+		return nil, errors.New("gossip member ID is required")
+	}
+
+	defaults := DefaultConfig()
+	if config.ProbeInterval <= 0 {
+		config.ProbeInterval = defaults.ProbeInterval
+	}
+	if config.ProbeTimeout <= 0 || config.ProbeTimeout >= config.ProbeInterval {
+		config.ProbeTimeout = config.ProbeInterval / 2
+	}
+	if config.IndirectChecks <= 0 {
+		config.IndirectChecks = defaults.IndirectChecks
+	}
+	if config.SuspicionTimeout <= 0 {
+		config.SuspicionTimeout = 5 * config.ProbeInterval
+	}
+	if config.SyncInterval <= 0 {
+		config.SyncInterval = defaults.SyncInterval
+	}
+	if config.RetransmitMult <= 0 {
+		config.RetransmitMult = defaults.RetransmitMult
+	}
+
+	n := &Node{
+		config:    config,
+		transport: transport,
+		members:   make(map[string]*memberState),
+		acks:      make(map[uint64]chan struct{}),
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+	n.members[config.ID] = &memberState{Member: Member{
+		ID:    config.ID,
+		Addr:  transport.Addr(),
+		Tags:  copyTags(config.Tags),
+		State: StateAlive,
+	}}
+
+	n.wg.Add(4)
+	go n.receiveLoop()
+	go n.probeLoop()
+	go n.syncLoop()
+	go n.dispatchLoop()
+	return n, nil
+}
+
+// Join exchanges the full membership with the members at the seed addresses
+// and waits for at least one of them to answer
+func (n *Node) Join(seeds ...string) error {
+	if len(seeds) == 0 {
+		return errors.New("no seeds to join")
+	}
+
+	seq, answered := n.expectAck()
+	defer n.dropAck(seq)
+
+	for _, seed := range seeds {
+		if seed == n.transport.Addr() {
+			continue
+		}
+		n.send(seed, message{Kind: kindSync, Seq: seq, Updates: n.snapshot()})
+	}
+
+	select {
+	case <-answered:
+		return nil
+	case <-time.After(4 * n.config.ProbeInterval):
+		return fmt.Errorf("no answer from seeds %v", seeds)
+	case <-n.stop:
+		return errors.New("gossip member closed")
+	}
+}
+
+// Leave tells the cluster this member is leaving, so it is removed at once
+// rather than after failing probes, and then closes it
+func (n *Node) Leave() error {
+	n.mu.Lock()
+	self := n.members[n.config.ID]
+	self.State = StateLeft
+	self.Incarnation++
+	update := copyMember(self.Member)
+	targets := n.randomMembers(leaveFanout, "")
+	n.mu.Unlock()
+
+	for _, target := range targets {
+		n.send(target.Addr, message{Kind: kindGossip, Updates: []Member{update}})
+	}
+	return n.Close()
+}
+
+// Close stops taking part in the cluster without telling anyone. The rest of
+// the cluster finds out through failed probes.
+func (n *Node) Close() error {
+	var err error
+	n.closeOnce.Do(func() {
+		close(n.stop)
+		err = n.transport.Close()
+		n.wg.Wait()
+	})
+	return err
+}
+
+// LocalMember returns this member as the cluster sees it
+func (n *Node) LocalMember() Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return copyMember(n.members[n.config.ID].Member)
+}
+
+// Members returns every known member, including dead and departed ones,
+// sorted by ID
+func (n *Node) Members() []Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	members := make([]Member, 0, len(n.members))
+	for _, m := range n.members {
+		members = append(members, copyMember(m.Member))
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID < members[j].ID
+	})
+	return members
+}
+
+// SetTags replaces the tags announced to the rest of the cluster
+func (n *Node) SetTags(tags map[string]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	self := n.members[n.config.ID]
+	self.Tags = copyTags(tags)
+	self.Incarnation++
+	n.queueBroadcast(self.Member)
+	n.notify(self.Member)
+}
+
+// OnChange registers fn to be called with a member whenever its state,
+// address or tags change. Calls are made one at a time, in the order the
+// changes happened.
+func (n *Node) OnChange(fn func(Member)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.listeners = append(n.listeners, fn)
+}
+
+// receiveLoop handles incoming messages until the node is closed
+func (n *Node) receiveLoop() {
+	defer n.wg.Done()
+
+	packets := n.transport.Receive()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case packet, ok := <-packets:
+			if !ok {
+				return
+			}
+			var msg message
+			if err := json.Unmarshal(packet.Data, &msg); err != nil {
+				continue
+			}
+			n.handle(msg)
+		}
+	}
+}
+
+// handle applies the updates carried by a message and answers it
+func (n *Node) handle(msg message) {
+	n.mu.Lock()
+	for _, update := range msg.Updates {
+		n.merge(update)
+	}
+	n.mu.Unlock()
+
+	switch msg.Kind {
+	case kindPing:
+		n.send(msg.From, message{Kind: kindAck, Seq: msg.Seq})
+	case kindPingReq:
+		go n.probeFor(msg.From, msg.Target, msg.Seq)
+	case kindAck, kindSyncReply:
+		n.mu.Lock()
+		if ack, exists := n.acks[msg.Seq]; exists {
+			close(ack)
+			delete(n.acks, msg.Seq)
+		}
+		n.mu.Unlock()
+	case kindSync:
+		n.send(msg.From, message{Kind: kindSyncReply, Seq: msg.Seq, Updates: n.snapshot()})
+	}
+}
+
+// probeFor probes target on behalf of the member at requester and passes
+// the answer on
+func (n *Node) probeFor(requester, target string, seq uint64) {
+	ackSeq, ack := n.expectAck()
+	defer n.dropAck(ackSeq)
+
+	n.send(target, message{Kind: kindPing, Seq: ackSeq})
+	select {
+	case <-ack:
+		n.send(requester, message{Kind: kindAck, Seq: seq})
+	case <-time.After(n.config.ProbeTimeout):
+	case <-n.stop:
+	}
+}
+
+// probeLoop probes one member every probe interval until the node is closed
+func (n *Node) probeLoop() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(n.config.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			n.probe()
+			n.expireSuspects()
+		}
+	}
+}
+
+// probe checks the next member, first directly and then through other
+// members, and suspects it if neither gets an answer
+func (n *Node) probe() {
+	n.mu.Lock()
+	target, ok := n.nextProbeTarget()
+	n.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	seq, ack := n.expectAck()
+	defer n.dropAck(seq)
+
+	n.send(target.Addr, message{Kind: kindPing, Seq: seq})
+	select {
+	case <-ack:
+		return
+	case <-time.After(n.config.ProbeTimeout):
+	case <-n.stop:
+		return
+	}
+
+	n.mu.Lock()
+	helpers := n.randomMembers(n.config.IndirectChecks, target.ID)
+	n.mu.Unlock()
+	for _, helper := range helpers {
+		n.send(helper.Addr, message{Kind: kindPingReq, Seq: seq, Target: target.Addr})
+	}
+
+	select {
+	case <-ack:
+	case <-time.After(n.config.ProbeInterval - n.config.ProbeTimeout):
+		n.mu.Lock()
+		if current := n.members[target.ID]; current != nil && current.State == StateAlive {
+			suspect := current.Member
+			suspect.State = StateSuspect
+			n.merge(suspect)
+		}
+		n.mu.Unlock()
+	case <-n.stop:
+	}
+}
+
+// nextProbeTarget returns the next member to probe, going round the live
+// members in a random order that is reshuffled after every round. It must be
+// called with mu held.
+func (n *Node) nextProbeTarget() (Member, bool) {
+	for {
+		if len(n.probeOrder) == 0 {
+			for id, m := range n.members {
+				if id != n.config.ID && (m.State == StateAlive || m.State == StateSuspect) {
+					n.probeOrder = append(n.probeOrder, id)
+				}
+			}
+			if len(n.probeOrder) == 0 {
+				return Member{}, false
+			}
+			rand.Shuffle(len(n.probeOrder), func(i, j int) {
+				n.probeOrder[i], n.probeOrder[j] = n.probeOrder[j], n.probeOrder[i]
+			})
+		}
+
+		id := n.probeOrder[0]
+		n.probeOrder = n.probeOrder[1:]
+		if m := n.members[id]; m != nil && (m.State == StateAlive || m.State == StateSuspect) {
+			return m.Member, true
+		}
+	}
+}
+
+// expireSuspects declares members dead once they have been suspect for the
+// suspicion timeout without refuting it
+func (n *Node) expireSuspects() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, m := range n.members {
+		if m.State == StateSuspect && time.Since(m.suspected) >= n.config.SuspicionTimeout {
+			dead := m.Member
+			dead.State = StateDead
+			n.merge(dead)
+		}
+	}
+}
+
+// syncLoop exchanges the full membership with a random member every sync
+// interval. Dead members are candidates too, so two sides of a healed
+// partition find each other again.
+func (n *Node) syncLoop() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(n.config.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			n.mu.Lock()
+			var candidates []string
+			for id, m := range n.members {
+				if id != n.config.ID && m.State != StateLeft {
+					candidates = append(candidates, m.Addr)
+				}
+			}
+			n.mu.Unlock()
+
+			if len(candidates) > 0 {
+				target := candidates[rand.Intn(len(candidates))]
+				n.send(target, message{Kind: kindSync, Updates: n.snapshot()})
+			}
+		}
+	}
+}
+
+// dispatchLoop calls the change listeners with queued changes until the
+// node is closed
+func (n *Node) dispatchLoop() {
+	defer n.wg.Done()
+
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-n.wake:
+		}
+
+		n.mu.Lock()
+		events := n.events
+		n.events = nil
+		listeners := n.listeners
+		n.mu.Unlock()
+
+		for _, event := range events {
+			for _, fn := range listeners {
+				fn(event)
+			}
+		}
+	}
+}
+
+// merge applies an update to the membership if it is newer than what is
+// known, queueing it to be passed on. A member hearing that it is suspected
+// or dead refutes it by announcing itself alive with a higher incarnation,
+// which is also how a restarted member takes over from its old entry. It
+// must be called with mu held.
+func (n *Node) merge(update Member) {
+	if update.ID == "" {
+		return
+	}
+
+	if update.ID == n.config.ID {
+		self := n.members[n.config.ID]
+		if self.State != StateAlive || update.Incarnation < self.Incarnation {
+			return
+		}
+		if update.State == StateAlive && update.Addr == self.Addr && sameTags(update.Tags, self.Tags) {
+			self.Incarnation = update.Incarnation
+			return
+		}
+		// Refute a suspicion, or details left over from before a restart
+		self.Incarnation = update.Incarnation + 1
+		n.queueBroadcast(self.Member)
+		return
+	}
+
+	current, exists := n.members[update.ID]
+	if exists && !supersedes(update, current.Member) {
+		return
+	}
+	if !exists {
+		current = &memberState{}
+		n.members[update.ID] = current
+	}
+
+	changed := !exists || current.State != update.State || current.Addr != update.Addr || !sameTags(current.Tags, update.Tags)
+	if update.State == StateSuspect && current.State != StateSuspect {
+		current.suspected = time.Now()
+	}
+	current.Member = copyMember(update)
+	n.queueBroadcast(current.Member)
+	if changed {
+		n.notify(current.Member)
+	}
+}
+
+// supersedes reports whether update is newer than current. Higher
+// incarnations win, and within an incarnation left beats dead, dead beats
+// suspect and suspect beats alive; only the member itself raises its
+// incarnation, which is how it refutes a suspicion.
+func supersedes(update, current Member) bool {
+	if update.Incarnation != current.Incarnation {
+		return update.Incarnation > current.Incarnation
+	}
+	return stateRank(update.State) > stateRank(current.State)
+}
+
+// stateRank orders states within an incarnation
+func stateRank(state string) int {
+	switch state {
+	case StateSuspect:
+		return 1
+	case StateDead:
+		return 2
+	case StateLeft:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// queueBroadcast queues an update to be piggybacked, replacing any older
+// update about the same member. It must be called with mu held.
+func (n *Node) queueBroadcast(m Member) {
+	for i, b := range n.broadcasts {
+		if b.member.ID == m.ID {
+			n.broadcasts = append(n.broadcasts[:i], n.broadcasts[i+1:]...)
+			break
+		}
+	}
+	n.broadcasts = append(n.broadcasts, &broadcast{member: copyMember(m)})
+}
+
+// piggyback takes the updates to attach to the next message, preferring the
+// ones sent least, and retires updates that have been sent often enough to
+// reach the whole cluster. It must be called with mu held.
+func (n *Node) piggyback() []Member {
+	if len(n.broadcasts) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(n.broadcasts, func(i, j int) bool {
+		return n.broadcasts[i].transmits < n.broadcasts[j].transmits
+	})
+	limit := n.config.RetransmitMult * int(math.Ceil(math.Log10(float64(len(n.members)+1))))
+
+	var updates []Member
+	kept := n.broadcasts[:0]
+	for i, b := range n.broadcasts {
+		if i < maxPiggyback {
+			updates = append(updates, b.member)
+			b.transmits++
+		}
+		if b.transmits < limit {
+			kept = append(kept, b)
+		}
+	}
+	n.broadcasts = kept
+	return updates
+}
+
+// notify queues a change for the listeners. It must be called with mu held.
+func (n *Node) notify(m Member) {
+	if len(n.listeners) == 0 {
+		return
+	}
+	n.events = append(n.events, copyMember(m))
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// snapshot returns the full membership to send in a sync
+func (n *Node) snapshot() []Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	members := make([]Member, 0, len(n.members))
+	for _, m := range n.members {
+		members = append(members, copyMember(m.Member))
+	}
+	return members
+}
+
+// randomMembers returns up to count random live members other than this
+// one and exclude. It must be called with mu held.
+func (n *Node) randomMembers(count int, exclude string) []Member {
+	var candidates []Member
+	for id, m := range n.members {
+		if id != n.config.ID && id != exclude && m.State == StateAlive {
+			candidates = append(candidates, m.Member)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+	return candidates
+}
+
+// expectAck allocates a sequence number and returns the channel closed when
+// it is acknowledged
+func (n *Node) expectAck() (uint64, chan struct{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.seq++
+	ack := make(chan struct{})
+	n.acks[n.seq] = ack
+	return n.seq, ack
+}
+
+// dropAck stops waiting for an acknowledgement
+func (n *Node) dropAck(seq uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.acks, seq)
+}
+
+// send sends a message, piggybacking pending updates unless it already
+// carries some
+func (n *Node) send(addr string, msg message) {
+	n.mu.Lock()
+	msg.From = n.transport.Addr()
+	if msg.Updates == nil {
+		msg.Updates = n.piggyback()
+	}
+	n.mu.Unlock()
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	n.transport.Send(addr, data)
+}
+
+// copyMember returns a copy of m that shares no tags with it
+func copyMember(m Member) Member {
+	m.Tags = copyTags(m.Tags)
+	return m
+}
+
+// copyTags returns a copy of tags
+func copyTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(tags))
+	for k, v := range tags {
+		copied[k] = v
+	}
+	return copied
+}
+
+// sameTags reports whether two sets of tags are equal
+func sameTags(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, exists := b[k]; !exists || bv != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,264 @@
+package gossip
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// testConfig returns a configuration with short intervals so tests run fast
+func testConfig(id string) Config {
+	return Config{
+		ID:               id,
+		ProbeInterval:    20 * time.Millisecond,
+		ProbeTimeout:     8 * time.Millisecond,
+		SuspicionTimeout: 100 * time.Millisecond,
+		SyncInterval:     100 * time.Millisecond,
+	}
+}
+
+// startMember starts a member addressed by its ID on network
+func startMember(t *testing.T, network *Network, config Config) *Node {
+	t.Helper()
+	transport, err := network.Transport(config.ID)
+	if err != nil {
+		t.Fatalf("Transport failed: %v", err)
+	}
+	node, err := New(config, transport)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { node.Close() })
+	return node
+}
+
+// startCluster starts a member for each ID, all joining through the first
+func startCluster(t *testing.T, network *Network, ids ...string) []*Node {
+	t.Helper()
+	nodes := make([]*Node, len(ids))
+	for i, id := range ids {
+		nodes[i] = startMember(t, network, testConfig(id))
+		if i > 0 {
+			if err := nodes[i].Join(ids[0]); err != nil {
+				t.Fatalf("Join failed: %v", err)
+			}
+		}
+	}
+	return nodes
+}
+
+// states returns the state of every member known to node by ID
+func states(node *Node) map[string]string {
+	states := make(map[string]string)
+	for _, m := range node.Members() {
+		states[m.ID] = m.State
+	}
+	return states
+}
+
+// waitFor fails the test if cond doesn't hold within a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// seesState reports whether every node in nodes sees each of ids in state
+func seesState(nodes []*Node, state string, ids ...string) bool {
+	for _, node := range nodes {
+		view := states(node)
+		for _, id := range ids {
+			if view[id] != state {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestGossip(t *testing.T) {
+	ids := []string{"node-a", "node-b", "node-c", "node-d", "node-e"}
+
+	t.Run("members join through a seed", func(t *testing.T) {
+		nodes := startCluster(t, NewNetwork(), ids...)
+		waitFor(t, "every member sees the whole cluster", func() bool {
+			return seesState(nodes, StateAlive, ids...)
+		})
+		if err := nodes[1].Join("nowhere"); err == nil {
+			t.Error("expected error joining through an unreachable seed")
+		}
+	})
+
+	t.Run("failed members are suspected and declared dead", func(t *testing.T) {
+		nodes := startCluster(t, NewNetwork(), ids...)
+		waitFor(t, "the cluster converges", func() bool {
+			return seesState(nodes, StateAlive, ids...)
+		})
+
+		var mu sync.Mutex
+		var seen []string
+		nodes[0].OnChange(func(m Member) {
+			if m.ID == "node-e" {
+				mu.Lock()
+				seen = append(seen, m.State)
+				mu.Unlock()
+			}
+		})
+
+		nodes[4].Close()
+		waitFor(t, "the failed member is declared dead", func() bool {
+			return seesState(nodes[:4], StateDead, "node-e")
+		})
+		if !seesState(nodes[:4], StateAlive, ids[:4]...) {
+			t.Errorf("live members suspected: %v", states(nodes[0]))
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(seen) != 2 || seen[0] != StateSuspect || seen[1] != StateDead {
+			t.Errorf("changes = %v, want suspect then dead", seen)
+		}
+	})
+
+	t.Run("members leave gracefully", func(t *testing.T) {
+		nodes := startCluster(t, NewNetwork(), ids...)
+		waitFor(t, "the cluster converges", func() bool {
+			return seesState(nodes, StateAlive, ids...)
+		})
+
+		if err := nodes[2].Leave(); err != nil {
+			t.Fatalf("Leave failed: %v", err)
+		}
+		remaining := []*Node{nodes[0], nodes[1], nodes[3], nodes[4]}
+		waitFor(t, "the member is known to have left", func() bool {
+			return seesState(remaining, StateLeft, "node-c")
+		})
+	})
+
+	t.Run("tag changes spread", func(t *testing.T) {
+		nodes := startCluster(t, NewNetwork(), ids...)
+		nodes[3].SetTags(map[string]string{"role": "storage"})
+		waitFor(t, "every member sees the new tags", func() bool {
+			for _, node := range nodes {
+				for _, m := range node.Members() {
+					if m.ID == "node-d" && m.Tags["role"] != "storage" {
+						return false
+					}
+				}
+			}
+			return true
+		})
+		if tags := nodes[3].LocalMember().Tags; tags["role"] != "storage" {
+			t.Errorf("local tags = %v", tags)
+		}
+	})
+
+	t.Run("partitions heal", func(t *testing.T) {
+		network := NewNetwork()
+		nodes := startCluster(t, network, ids...)
+		waitFor(t, "the cluster converges", func() bool {
+			return seesState(nodes, StateAlive, ids...)
+		})
+
+		network.Partition([]string{"node-a", "node-b"}, []string{"node-c", "node-d", "node-e"})
+		waitFor(t, "each side declares the other dead", func() bool {
+			return seesState(nodes[:2], StateDead, ids[2:]...) && seesState(nodes[2:], StateDead, ids[:2]...)
+		})
+		if !seesState(nodes[:2], StateAlive, ids[:2]...) || !seesState(nodes[2:], StateAlive, ids[2:]...) {
+			t.Errorf("members on the same side suspected: %v, %v", states(nodes[0]), states(nodes[2]))
+		}
+
+		network.Heal()
+		waitFor(t, "the cluster converges again", func() bool {
+			return seesState(nodes, StateAlive, ids...)
+		})
+	})
+
+	t.Run("restarted members take over their old entry", func(t *testing.T) {
+		network := NewNetwork()
+		nodes := startCluster(t, network, ids[:3]...)
+		waitFor(t, "the cluster converges", func() bool {
+			return seesState(nodes, StateAlive, ids[:3]...)
+		})
+
+		nodes[2].Close()
+		waitFor(t, "the stopped member is declared dead", func() bool {
+			return seesState(nodes[:2], StateDead, "node-c")
+		})
+
+		config := testConfig("node-c")
+		config.Tags = map[string]string{"version": "2"}
+		restarted := startMember(t, network, config)
+		if err := restarted.Join("node-a"); err != nil {
+			t.Fatalf("Join failed: %v", err)
+		}
+		waitFor(t, "the restarted member is alive with its new tags", func() bool {
+			for _, node := range nodes[:2] {
+				for _, m := range node.Members() {
+					if m.ID == "node-c" && (m.State != StateAlive || m.Tags["version"] != "2") {
+						return false
+					}
+				}
+			}
+			return true
+		})
+	})
+
+	t.Run("member ID is required", func(t *testing.T) {
+		transport, _ := NewNetwork().Transport("node-a")
+		if _, err := New(Config{}, transport); err == nil {
+			t.Error("expected error for a member without an ID")
+		}
+	})
+}
+
+func TestNetwork(t *testing.T) {
+	network := NewNetwork()
+	a, _ := network.Transport("a")
+	b, _ := network.Transport("b")
+	if _, err := network.Transport("a"); err == nil {
+		t.Error("expected error reusing an address in use")
+	}
+
+	received := func(t *testing.T, transport *MemoryTransport) bool {
+		select {
+		case packet := <-transport.Receive():
+			if packet.From != "a" || string(packet.Data) != "hello" {
+				t.Errorf("packet = %+v", packet)
+			}
+			return true
+		case <-time.After(20 * time.Millisecond):
+			return false
+		}
+	}
+
+	a.Send("b", []byte("hello"))
+	if !received(t, b) {
+		t.Error("packet not delivered")
+	}
+
+	network.Partition([]string{"a"})
+	a.Send("b", []byte("hello"))
+	if received(t, b) {
+		t.Error("packet delivered across a partition")
+	}
+
+	network.Heal()
+	a.Send("b", []byte("hello"))
+	if !received(t, b) {
+		t.Error("packet not delivered after healing")
+	}
+
+	b.Close()
+	if err := a.Send("b", []byte("hello")); err != nil {
+		t.Errorf("Send to a closed address failed: %v", err)
+	}
+	if _, err := network.Transport("b"); err != nil {
+		t.Errorf("closed address not reusable: %v", err)
+	}
+}
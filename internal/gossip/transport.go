@@ -0,0 +1,222 @@
+package gossip
+
+import (
+	"fmt"
+	"net"
+	"sync"
+)
+
+// maxPacketSize is the largest message a UDP transport sends
+const maxPacketSize = 65507
+
+// receiveQueueDepth is the number of packets a transport buffers before it
+// drops new ones, as a full socket buffer would
+const receiveQueueDepth = 1024
+
+// Packet is a message received by a transport
+type Packet struct {
+	From string
+	Data []byte
+}
+
+// Transport carries gossip messages between members. Delivery is best
+// effort: like UDP, a transport may drop, delay or reorder packets, and
+// sending to an unreachable address is not an error.
+type Transport interface {
+	// Addr returns the address other members reach this one at
+	Addr() string
+
+	// Send sends data to the member at addr
+	Send(addr string, data []byte) error
+
+	// Receive returns the channel received packets are delivered on. It is
+	// closed when the transport is closed.
+	Receive() <-chan Packet
+
+	// Close stops sending and receiving
+	Close() error
+}
+
+// UDPTransport sends gossip messages as UDP datagrams
+type UDPTransport struct {
+	conn    *net.UDPConn
+	addr    string
+	packets chan Packet
+}
+
+// ListenUDP creates a transport listening on bindAddr. Other members reach
+// it at advertiseAddr, or at the address it listens on if advertiseAddr is
+// empty.
+func ListenUDP(bindAddr, advertiseAddr string) (*UDPTransport, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", bindAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", bindAddr, err)
+	}
+	if advertiseAddr == "" {
+		advertiseAddr = conn.LocalAddr().String()
+	}
+
+	t := &UDPTransport{
+		conn:    conn,
+		addr:    advertiseAddr,
+		packets: make(chan Packet, receiveQueueDepth),
+	}
+	go t.readLoop()
+	return t, nil
+}
+
+// Addr implements Transport
+func (t *UDPTransport) Addr() string {
+	return t.addr
+}
+
+// Send implements Transport
+func (t *UDPTransport) Send(addr string, data []byte) error {
+	if len(data) > maxPacketSize {
+		return fmt.Errorf("gossip message of %d bytes exceeds the %d byte limit", len(data), maxPacketSize)
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	_, err = t.conn.WriteToUDP(data, udpAddr)
+	return err
+}
+
+// Receive implements Transport
+func (t *UDPTransport) Receive() <-chan Packet {
+	return t.packets
+}
+
+// Close implements Transport
+func (t *UDPTransport) Close() error {
+	return t.conn.Close()
+}
+
+// readLoop delivers received datagrams until the connection is closed
+func (t *UDPTransport) readLoop() {
+	defer close(t.packets)
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := t.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		packet := Packet{From: from.String(), Data: append([]byte(nil), buf[:n]...)}
+		select {
+		case t.packets <- packet:
+		default:
+		}
+	}
+}
+
+// Network is an in-memory network for tests. It delivers packets between
+// the transports created on it, and can be partitioned so that packets
+// between groups of addresses are dropped.
+type Network struct {
+	mu        sync.Mutex
+	endpoints map[string]*MemoryTransport
+	groups    map[string]int
+}
+
+// NewNetwork creates an in-memory network
+func NewNetwork() *Network {
+	return &Network{endpoints: make(map[string]*MemoryTransport)}
+}
+
+// Transport creates a transport reachable at addr
+func (n *Network) Transport(addr string) (*MemoryTransport, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, exists := n.endpoints[addr]; exists {
+		return nil, fmt.Errorf("address %s already in use", addr)
+	}
+	t := &MemoryTransport{
+		network: n,
+		addr:    addr,
+		packets: make(chan Packet, receiveQueueDepth),
+	}
+	n.endpoints[addr] = t
+	return t, nil
+}
+
+// Partition splits the network: packets between addresses in different
+// groups are dropped. Addresses not listed in any group form one more group
+// of their own.
+func (n *Network) Partition(groups ...[]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.groups = make(map[string]int)
+	for i, group := range groups {
+		for _, addr := range group {
+			n.groups[addr] = i + 1
+		}
+	}
+}
+
+// Heal removes any partition
+func (n *Network) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.groups = nil
+}
+
+// deliver queues a packet for the transport at to, unless it is closed,
+// partitioned from the sender or its queue is full
+func (n *Network) deliver(from, to string, data []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	target, exists := n.endpoints[to]
+	if !exists || n.groups[from] != n.groups[to] {
+		return
+	}
+	select {
+	case target.packets <- Packet{From: from, Data: append([]byte(nil), data...)}:
+	default:
+	}
+}
+
+// MemoryTransport is a transport on an in-memory Network
+type MemoryTransport struct {
+	network *Network
+	addr    string
+	packets chan Packet
+}
+
+// Addr implements Transport
+func (t *MemoryTransport) Addr() string {
+	return t.addr
+}
+
+// Send implements Transport
+func (t *MemoryTransport) Send(addr string, data []byte) error {
+	t.network.deliver(t.addr, addr, data)
+	return nil
+}
+
+// Receive implements Transport
+func (t *MemoryTransport) Receive() <-chan Packet {
+	return t.packets
+}
+
+// Close implements Transport. The address becomes unreachable and can be
+// used by a new transport.
+func (t *MemoryTransport) Close() error {
+	t.network.mu.Lock()
+	defer t.network.mu.Unlock()
+
+	if t.network.endpoints[t.addr] != t {
+		return nil
+	}
+	delete(t.network.endpoints, t.addr)
+	close(t.packets)
+	return nil
+}
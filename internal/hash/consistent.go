@@ -19,6 +19,9 @@ type ConsistentHash struct {
 	nodes         map[uint32]string
 	virtualNodes  int
 	replicaFactor int
+
+	// unhealthy holds the nodes that preference lists of healthy nodes skip
+	unhealthy map[string]bool
 }
 
 // NewConsistentHash creates a new consistent hash ring
@@ -34,6 +37,7 @@ func NewConsistentHash(virtualNodes, replicaFactor int) *ConsistentHash {
 		nodes:         make(map[uint32]string),
 		virtualNodes:  virtualNodes,
 		replicaFactor: replicaFactor,
+		unhealthy:     make(map[string]bool),
 	}
 }
 
@@ -74,10 +78,64 @@ func (ch *ConsistentHash) RemoveNode(nodeID string) {
 		}
 		delete(ch.nodes, hash)
 	}
+	delete(ch.unhealthy, nodeID)
+}
+
+// SetHealthy marks a node healthy or unhealthy. Unhealthy nodes keep their
+// place on the ring, so GetNodes still names them as owners, but
+// GetHealthyNodesN skips them. A node removed from the ring is healthy again
+// when it is added back.
+func (ch *ConsistentHash) SetHealthy(nodeID string, healthy bool) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if healthy {
+		delete(ch.unhealthy, nodeID)
+	} else {
+		ch.unhealthy[nodeID] = true
+	}
+}
+
+// IsHealthy reports whether a node is not marked unhealthy
+func (ch *ConsistentHash) IsHealthy(nodeID string) bool {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return !ch.unhealthy[nodeID]
 }
 
 // GetNodes returns the primary and replica nodes for a given key
 func (ch *ConsistentHash) GetNodes(key string) []string {
+	return ch.GetNodesN(key, ch.replicaFactor)
+}
+
+// GetNodesN returns up to n distinct nodes for a given key, walking the ring
+// clockwise from the key. The first nodes are the same as those returned by
+// GetNodes.
+func (ch *ConsistentHash) GetNodesN(key string, n int) []string {
+	return ch.walk(key, n, false)
+}
+
+// PreferenceList returns every physical node in the order a walk clockwise
+// from the key reaches them. The first nodes are the owners returned by
+// GetNodes; the rest are the fallbacks to try, in order, when owners are
+// unavailable.
+func (ch *ConsistentHash) PreferenceList(key string) []string {
+	return ch.walk(key, -1, false)
+}
+
+// GetHealthyNodesN returns up to n distinct nodes for a given key, walking
+// the ring clockwise from the key and skipping nodes marked unhealthy. With
+// every node healthy it returns the same nodes as GetNodesN; otherwise each
+// unhealthy owner is replaced by the next healthy node along the ring, so a
+// write can still reach n nodes.
+func (ch *ConsistentHash) GetHealthyNodesN(key string, n int) []string {
+	return ch.walk(key, n, true)
+}
+
+// walk returns up to n distinct nodes, or all of them if n is negative,
+// walking the ring clockwise from the key. It skips unhealthy nodes if
+// healthyOnly is set.
+func (ch *ConsistentHash) walk(key string, n int, healthyOnly bool) []string {
 	ch.mu.RLock()
 	defer ch.mu.RUnlock()
 
@@ -91,15 +149,19 @@ func (ch *ConsistentHash) GetNodes(key string) []string {
 	// Get unique nodes for replication
 	nodeSet := make(map[string]bool)
 	nodes := []string{}
-	
-	for i := 0; len(nodes) < ch.replicaFactor && i < len(ch.hashRing); i++ {
+
+	for i := 0; (n < 0 || len(nodes) < n) && i < len(ch.hashRing); i++ {
 		ringIdx := (idx + i) % len(ch.hashRing)
 		nodeID := ch.nodes[ch.hashRing[ringIdx]]
-		
-		if !nodeSet[nodeID] {
-			nodeSet[nodeID] = true
-			nodes = append(nodes, nodeID)
+
+		if nodeSet[nodeID] {
+			continue
 		}
+		nodeSet[nodeID] = true
+		if healthyOnly && ch.unhealthy[nodeID] {
+			continue
+		}
+		nodes = append(nodes, nodeID)
 	}
 
 	return nodes
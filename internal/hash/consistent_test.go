@@ -146,6 +146,126 @@ func TestGetNodes(t *testing.T) {
 	})
 }
 
+func TestGetNodesN(t *testing.T) {
+	ch := NewConsistentHash(10, 2)
+	ch.AddNode("node-1")
+	ch.AddNode("node-2")
+	ch.AddNode("node-3")
+	ch.AddNode("node-4")
+
+	t.Run("extends GetNodes", func(t *testing.T) {
+		nodes := ch.GetNodesN("test-key", 4)
+		if len(nodes) != 4 {
+			t.Fatalf("got %d nodes, want 4", len(nodes))
+		}
+		primary := ch.GetNodes("test-key")
+		for i := range primary {
+			if nodes[i] != primary[i] {
+				t.Errorf("node %d = %s, want %s", i, nodes[i], primary[i])
+			}
+		}
+
+		seen := make(map[string]bool)
+		for _, node := range nodes {
+			if seen[node] {
+				t.Errorf("returned duplicate node %s", node)
+			}
+			seen[node] = true
+		}
+	})
+
+	t.Run("capped at node count", func(t *testing.T) {
+		if nodes := ch.GetNodesN("test-key", 10); len(nodes) != 4 {
+			t.Errorf("got %d nodes, want 4", len(nodes))
+		}
+	})
+}
+
+func TestPreferenceList(t *testing.T) {
+	ch := NewConsistentHash(10, 2)
+
+	t.Run("empty ring", func(t *testing.T) {
+		if nodes := ch.PreferenceList("test-key"); len(nodes) != 0 {
+			t.Errorf("got %v, want no nodes", nodes)
+		}
+	})
+
+	for i := 1; i <= 5; i++ {
+		ch.AddNode(fmt.Sprintf("node-%d", i))
+	}
+
+	t.Run("every node in ring order", func(t *testing.T) {
+		nodes := ch.PreferenceList("test-key")
+		want := ch.GetNodesN("test-key", 5)
+		if fmt.Sprint(nodes) != fmt.Sprint(want) {
+			t.Errorf("PreferenceList = %v, want %v", nodes, want)
+		}
+	})
+
+	t.Run("starts with the owners", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			nodes := ch.PreferenceList(key)
+			owners := ch.GetNodes(key)
+			if fmt.Sprint(nodes[:len(owners)]) != fmt.Sprint(owners) {
+				t.Errorf("PreferenceList(%s) = %v, want it to start with %v", key, nodes, owners)
+			}
+		}
+	})
+}
+
+func TestGetHealthyNodesN(t *testing.T) {
+	ch := NewConsistentHash(10, 2)
+	for i := 1; i <= 4; i++ {
+		ch.AddNode(fmt.Sprintf("node-%d", i))
+	}
+	list := ch.PreferenceList("test-key")
+
+	t.Run("all healthy matches GetNodesN", func(t *testing.T) {
+		if nodes := ch.GetHealthyNodesN("test-key", 2); fmt.Sprint(nodes) != fmt.Sprint(ch.GetNodesN("test-key", 2)) {
+			t.Errorf("GetHealthyNodesN = %v, want %v", nodes, ch.GetNodesN("test-key", 2))
+		}
+	})
+
+	t.Run("unhealthy owner replaced by next node", func(t *testing.T) {
+		ch.SetHealthy(list[0], false)
+		defer ch.SetHealthy(list[0], true)
+
+		if ch.IsHealthy(list[0]) {
+			t.Errorf("%s still healthy", list[0])
+		}
+		nodes := ch.GetHealthyNodesN("test-key", 2)
+		if fmt.Sprint(nodes) != fmt.Sprint(list[1:3]) {
+			t.Errorf("GetHealthyNodesN = %v, want %v", nodes, list[1:3])
+		}
+		if owners := ch.GetNodes("test-key"); owners[0] != list[0] {
+			t.Errorf("GetNodes = %v, want the unhealthy owner to stay", owners)
+		}
+	})
+
+	t.Run("capped at healthy node count", func(t *testing.T) {
+		ch.SetHealthy(list[1], false)
+		ch.SetHealthy(list[3], false)
+		defer ch.SetHealthy(list[1], true)
+		defer ch.SetHealthy(list[3], true)
+
+		nodes := ch.GetHealthyNodesN("test-key", 3)
+		want := []string{list[0], list[2]}
+		if fmt.Sprint(nodes) != fmt.Sprint(want) {
+			t.Errorf("GetHealthyNodesN = %v, want %v", nodes, want)
+		}
+	})
+
+	t.Run("removed node is healthy when added back", func(t *testing.T) {
+		ch.SetHealthy("node-1", false)
+		ch.RemoveNode("node-1")
+		ch.AddNode("node-1")
+		if !ch.IsHealthy("node-1") {
+			t.Error("node-1 still unhealthy after being added back")
+		}
+	})
+}
+
 func TestGetPrimaryNode(t *testing.T) {
 	ch := NewConsistentHash(10, 2)
 	ch.AddNode("node-1")
@@ -0,0 +1,406 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// orphanGracePeriod is how old a copy that no version references must be
+// before anti-entropy deletes it. Uploads and moves write data before the
+// metadata that references it, so younger copies may still be in flight.
+const orphanGracePeriod = time.Hour
+
+// AntiEntropyStats reports the anti-entropy run that is running or, if none
+// is, the last one that ran
+type AntiEntropyStats struct {
+	Running         bool
+	Started         time.Time
+	Finished        time.Time
+	PairsCompared   int
+	BucketsDiffered int
+	ObjectsDiffered int
+	Repaired        int
+	Removed         int
+	Failed          int
+	LastError       string
+}
+
+// AntiEntropy periodically compares the Merkle trees of every pair of nodes
+// that share files on the ring and reconciles the versions they disagree on
+// with the metadata: missing or diverged replicas are copied from a healthy
+// one and copies that no version references are deleted.
+type AntiEntropy struct {
+	fileManager *FileManager
+	interval    time.Duration
+	gracePeriod time.Duration
+	pending     chan struct{}
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	runMu sync.Mutex
+
+	statsMu sync.Mutex
+	stats   AntiEntropyStats
+}
+
+// NewAntiEntropy creates an anti-entropy process that runs every interval
+func NewAntiEntropy(fileManager *FileManager, interval time.Duration) *AntiEntropy {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &AntiEntropy{
+		fileManager: fileManager,
+		interval:    interval,
+		gracePeriod: orphanGracePeriod,
+		pending:     make(chan struct{}, 1),
+	}
+}
+
+// Start runs anti-entropy in the background until Stop is called
+func (a *AntiEntropy) Start() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.done = make(chan struct{})
+
+	go func() {
+		defer close(a.done)
+
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-a.pending:
+			}
+
+			stats, err := a.RunOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Anti-entropy failed: %v", err)
+			} else if stats.ObjectsDiffered > 0 {
+				log.Printf("Anti-entropy reconciled %d objects (%d repaired, %d removed, %d failed)",
+					stats.ObjectsDiffered, stats.Repaired, stats.Removed, stats.Failed)
+			}
+		}
+	}()
+}
+
+// Stop stops the background anti-entropy process and waits for it to exit
+func (a *AntiEntropy) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cancel == nil {
+		return
+	}
+	a.cancel()
+	<-a.done
+	a.cancel = nil
+}
+
+// Trigger requests a run as soon as possible without waiting for the next
+// interval. It does nothing if a run is already pending.
+func (a *AntiEntropy) Trigger() {
+	select {
+	case a.pending <- struct{}{}:
+	default:
+	}
+}
+
+// Stats returns the stats of the current or last run
+func (a *AntiEntropy) Stats() AntiEntropyStats {
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	return a.stats
+}
+
+// updateStats applies fn to the stats under their lock
+func (a *AntiEntropy) updateStats(fn func(stats *AntiEntropyStats)) {
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	fn(&a.stats)
+}
+
+// RunOnce compares every pair of nodes once, reconciles the differences and
+// returns the final stats
+func (a *AntiEntropy) RunOnce(ctx context.Context) (AntiEntropyStats, error) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+
+	a.updateStats(func(stats *AntiEntropyStats) {
+		*stats = AntiEntropyStats{Running: true, Started: time.Now()}
+	})
+
+	err := a.run(ctx)
+
+	a.updateStats(func(stats *AntiEntropyStats) {
+		stats.Running = false
+		stats.Finished = time.Now()
+		if err != nil {
+			stats.LastError = err.Error()
+		}
+	})
+	return a.Stats(), err
+}
+
+// run takes the inventory of every node and compares the trees of each pair
+// over the files the ring assigns to both
+func (a *AntiEntropy) run(ctx context.Context) error {
+	nodes := a.fileManager.snapshotNodes()
+	nodeIDs := make([]string, 0, len(nodes))
+	inventories := make(map[string][]storage.InventoryEntry, len(nodes))
+	for nodeID, node := range nodes {
+		inventory, err := node.Inventory()
+		if err != nil {
+			log.Printf("Failed to take inventory of node %s: %v", nodeID, err)
+			continue
+		}
+		nodeIDs = append(nodeIDs, nodeID)
+		inventories[nodeID] = inventory
+	}
+	sort.Strings(nodeIDs)
+
+	r := &reconciler{
+		antiEntropy: a,
+		files:       make(map[string]*metadata.FileMetadata),
+		checked:     make(map[string]bool),
+	}
+	for i, first := range nodeIDs {
+		for _, second := range nodeIDs[i+1:] {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			owners := make(map[string][]string)
+			shared := func(fileID string) bool {
+				nodes, ok := owners[fileID]
+				if !ok {
+					nodes = r.owners(ctx, fileID)
+					owners[fileID] = nodes
+				}
+				return containsNode(nodes, first) && containsNode(nodes, second)
+			}
+
+			firstTree := storage.BuildMerkleTree(inventories[first], shared)
+			secondTree := storage.BuildMerkleTree(inventories[second], shared)
+			buckets := firstTree.Diff(secondTree)
+			a.updateStats(func(stats *AntiEntropyStats) {
+				stats.PairsCompared++
+				stats.BucketsDiffered += len(buckets)
+			})
+
+			for _, bucket := range buckets {
+				r.reconcileBucket(ctx, first, firstTree.Bucket(bucket), second, secondTree.Bucket(bucket))
+			}
+		}
+	}
+	return nil
+}
+
+// reconciler holds the state of one anti-entropy run
+type reconciler struct {
+	antiEntropy *AntiEntropy
+
+	// files caches metadata lookups; a nil entry means the file does not exist
+	files map[string]*metadata.FileMetadata
+
+	// checked holds the fileID/versionID/nodeID of copies already reconciled
+	checked map[string]bool
+}
+
+// reconcileBucket reconciles every version that differs between the same
+// bucket of two nodes' trees
+func (r *reconciler) reconcileBucket(ctx context.Context, first string, firstEntries []storage.InventoryEntry, second string, secondEntries []storage.InventoryEntry) {
+	firstCopies := make(map[string]storage.InventoryEntry, len(firstEntries))
+	for _, entry := range firstEntries {
+		firstCopies[entry.FileID+"/"+entry.VersionID] = entry
+	}
+	secondCopies := make(map[string]storage.InventoryEntry, len(secondEntries))
+	for _, entry := range secondEntries {
+		secondCopies[entry.FileID+"/"+entry.VersionID] = entry
+	}
+
+	differing := make(map[string]storage.InventoryEntry)
+	for key, entry := range firstCopies {
+		if other, ok := secondCopies[key]; !ok || other.Checksum != entry.Checksum {
+			differing[key] = entry
+		}
+	}
+	for key, entry := range secondCopies {
+		if _, ok := firstCopies[key]; !ok {
+			differing[key] = entry
+		}
+	}
+
+	keys := make([]string, 0, len(differing))
+	for key := range differing {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return
+		}
+		entry := differing[key]
+		r.antiEntropy.updateStats(func(stats *AntiEntropyStats) {
+			stats.ObjectsDiffered++
+		})
+
+		firstCopy, onFirst := firstCopies[key]
+		r.reconcileCopy(ctx, entry.FileID, entry.VersionID, first, firstCopy, onFirst)
+		secondCopy, onSecond := secondCopies[key]
+		r.reconcileCopy(ctx, entry.FileID, entry.VersionID, second, secondCopy, onSecond)
+	}
+}
+
+// reconcileCopy brings the copy of a version on one node in line with the
+// metadata
+func (r *reconciler) reconcileCopy(ctx context.Context, fileID, versionID, nodeID string, entry storage.InventoryEntry, stored bool) {
+	key := fileID + "/" + versionID + "/" + nodeID
+	if r.checked[key] {
+		return
+	}
+	r.checked[key] = true
+
+	version, found, err := r.version(ctx, fileID, versionID)
+	if err != nil {
+		log.Printf("Failed to get metadata for %s: %v", fileID, err)
+		r.antiEntropy.updateStats(func(stats *AntiEntropyStats) {
+			stats.Failed++
+		})
+		return
+	}
+
+	placed := found && containsNode(version.Nodes, nodeID)
+	switch {
+	case !placed && stored:
+		if time.Since(entry.ModTime) < r.antiEntropy.gracePeriod {
+			return
+		}
+		removed, err := r.antiEntropy.fileManager.removeUnreferencedCopy(ctx, fileID, versionID, nodeID)
+		if err == nil && !removed {
+			return
+		}
+		r.antiEntropy.updateStats(func(stats *AntiEntropyStats) {
+			if err != nil {
+				stats.Failed++
+			} else {
+				stats.Removed++
+			}
+		})
+
+	case placed && (!stored || entry.Checksum != version.ChecksumOn(nodeID)):
+		reason := RepairDiverged
+		if !stored {
+			reason = RepairMissing
+		}
+		err = r.antiEntropy.fileManager.repairReplica(ctx, fileID, versionID, nodeID, reason)
+		if errors.Is(err, errVersionGone) {
+			return
+		}
+		r.antiEntropy.updateStats(func(stats *AntiEntropyStats) {
+			if err != nil {
+				stats.Failed++
+			} else {
+				stats.Repaired++
+			}
+		})
+	}
+}
+
+// file looks up a file in the metadata, caching it for the rest of the run.
+// It returns nil if the file has no metadata.
+func (r *reconciler) file(ctx context.Context, fileID string) (*metadata.FileMetadata, error) {
+	if file, cached := r.files[fileID]; cached {
+		return file, nil
+	}
+
+	file, err := r.antiEntropy.fileManager.metadataStore.GetMetadata(ctx, fileID)
+	if errors.Is(err, metadata.ErrNotFound) {
+		file = nil
+	} else if err != nil {
+		return nil, err
+	}
+	r.files[fileID] = file
+	return file, nil
+}
+
+// owners returns the ring nodes a file's replicas belong on, at the server
+// default replica factor if the file's own can't be looked up
+func (r *reconciler) owners(ctx context.Context, fileID string) []string {
+	fm := r.antiEntropy.fileManager
+	file, err := r.file(ctx, fileID)
+	if err != nil || file == nil {
+		return fm.hashRing.GetNodes(fileID)
+	}
+	return fm.replicaOwners(file)
+}
+
+// version looks up a version in the metadata
+func (r *reconciler) version(ctx context.Context, fileID, versionID string) (metadata.Version, bool, error) {
+	file, err := r.file(ctx, fileID)
+	if err != nil {
+		return metadata.Version{}, false, err
+	}
+
+	if file != nil {
+		for _, version := range file.Versions {
+			if version.VersionID == versionID {
+				return version, true, nil
+			}
+		}
+	}
+	return metadata.Version{}, false, nil
+}
+
+// removeUnreferencedCopy deletes a copy of a version from a node after
+// checking again, with placement changes held off, that no version
+// references it. It reports whether the copy was deleted.
+func (fm *FileManager) removeUnreferencedCopy(ctx context.Context, fileID, versionID, nodeID string) (bool, error) {
+	fm.placementMu.Lock()
+	defer fm.placementMu.Unlock()
+
+	fileMeta, err := fm.metadataStore.GetMetadata(ctx, fileID)
+	if err != nil && !errors.Is(err, metadata.ErrNotFound) {
+		return false, err
+	}
+	if err == nil {
+		for _, version := range fileMeta.Versions {
+			if version.VersionID == versionID && containsNode(version.Nodes, nodeID) {
+				return false, nil
+			}
+		}
+	}
+
+	node, exists := fm.getNode(nodeID)
+	if !exists {
+		return false, nil
+	}
+	if err := node.DeleteFile(fileID, versionID); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Failed to remove orphaned copy of %s/%s from node %s: %v", fileID, versionID, nodeID, err)
+		return false, fmt.Errorf("failed to remove orphaned copy: %w", err)
+	}
+	log.Printf("Removed orphaned copy of %s/%s from node %s", fileID, versionID, nodeID)
+	return true, nil
+}
@@ -0,0 +1,142 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAntiEntropyRunOnce(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("keep replicas in sync")
+
+	t.Run("replicas in sync", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		for i := 0; i < 5; i++ {
+			fm.UploadFile(ctx, fmt.Sprintf("sync-%d.txt", i), data, "text/plain")
+		}
+
+		stats, err := NewAntiEntropy(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stats.PairsCompared != 3 {
+			t.Errorf("PairsCompared = %d, want 3", stats.PairsCompared)
+		}
+		if stats.BucketsDiffered != 0 || stats.ObjectsDiffered != 0 || stats.Running || stats.Finished.IsZero() {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("restores missing replica", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "missing.txt", data, "text/plain")
+		version := meta.Versions[0]
+		fm.nodes[version.Nodes[0]].DeleteFile(meta.FileID, version.VersionID)
+
+		stats, err := NewAntiEntropy(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stats.BucketsDiffered != 1 || stats.Repaired != 1 || stats.Failed != 0 {
+			t.Errorf("stats = %+v, want one repaired replica", stats)
+		}
+		checkRepaired(t, fm, version.Nodes[0], meta.FileID, version.VersionID, data, RepairMissing)
+	})
+
+	t.Run("repairs diverged replica", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "diverged.txt", data, "text/plain")
+		version := meta.Versions[0]
+		checksumPath := filepath.Join(localNode(fm, version.Nodes[1]).StoragePath, meta.FileID, version.VersionID, "checksum")
+		os.WriteFile(checksumPath, []byte("0000"), 0644)
+
+		stats, err := NewAntiEntropy(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stats.Repaired != 1 || stats.Failed != 0 {
+			t.Errorf("stats = %+v, want one repaired replica", stats)
+		}
+		checkRepaired(t, fm, version.Nodes[1], meta.FileID, version.VersionID, data, RepairDiverged)
+	})
+
+	t.Run("removes orphaned copies after grace period", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		owners := fm.hashRing.GetNodes("orphan-file")
+		node := localNode(fm, owners[0])
+		node.StoreFile("orphan-file", "v1", data)
+
+		antiEntropy := NewAntiEntropy(fm, time.Hour)
+		stats, err := antiEntropy.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stats.ObjectsDiffered != 1 || stats.Removed != 0 {
+			t.Errorf("stats = %+v, want recent orphan kept", stats)
+		}
+		if !node.FileExists("orphan-file", "v1") {
+			t.Fatal("orphan removed during grace period")
+		}
+
+		antiEntropy.gracePeriod = 0
+		stats, err = antiEntropy.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stats.Removed != 1 {
+			t.Errorf("stats = %+v, want one removed orphan", stats)
+		}
+		if node.FileExists("orphan-file", "v1") {
+			t.Error("orphan not removed")
+		}
+	})
+
+	t.Run("removes copy of deleted version", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "deleted.txt", data, "text/plain")
+		version := meta.Versions[0]
+		leftover := fm.nodes[version.Nodes[0]]
+		fm.metadataStore.DeleteMetadata(ctx, meta.FileID)
+		fm.nodes[version.Nodes[1]].DeleteAllVersions(meta.FileID)
+
+		antiEntropy := NewAntiEntropy(fm, time.Hour)
+		antiEntropy.gracePeriod = 0
+		stats, err := antiEntropy.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stats.Removed != 1 || stats.Repaired != 0 {
+			t.Errorf("stats = %+v, want one removed copy", stats)
+		}
+		if leftover.FileExists(meta.FileID, version.VersionID) {
+			t.Error("copy of deleted version not removed")
+		}
+	})
+}
+
+func TestAntiEntropyTrigger(t *testing.T) {
+	fm := setupTestFileManager(t)
+	antiEntropy := NewAntiEntropy(fm, time.Hour)
+	antiEntropy.Start()
+	defer antiEntropy.Stop()
+	antiEntropy.Trigger()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := antiEntropy.Stats()
+		if !stats.Running && !stats.Finished.IsZero() {
+			if stats.PairsCompared != 3 {
+				t.Errorf("PairsCompared = %d, want 3", stats.PairsCompared)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("anti-entropy did not run after trigger, stats = %+v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
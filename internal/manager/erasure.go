@@ -0,0 +1,589 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/yashlad/distributed-file-store/internal/erasure"
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+const (
+	// erasureBlockSize is the size of the block each shard receives per stripe
+	erasureBlockSize = 64 * 1024
+
+	// Default Reed-Solomon layout of erasure-coded uploads
+	defaultDataShards   = 4
+	defaultParityShards = 2
+)
+
+// storageLayout is how an upload is stored: replicated, or erasure-coded
+// into dataShards data and parityShards parity shards
+type storageLayout struct {
+	mode         string
+	dataShards   int
+	parityShards int
+}
+
+// defaultStorageLayout replicates uploads and splits erasure-coded uploads
+// into four data and two parity shards
+func defaultStorageLayout() storageLayout {
+	return storageLayout{
+		mode:         metadata.StorageReplicated,
+		dataShards:   defaultDataShards,
+		parityShards: defaultParityShards,
+	}
+}
+
+// newStorageLayout validates a storage mode and shard counts
+func newStorageLayout(mode string, dataShards, parityShards int) (storageLayout, error) {
+	switch mode {
+	case metadata.StorageReplicated, metadata.StorageErasure:
+	default:
+		return storageLayout{}, fmt.Errorf("unknown storage mode %q", mode)
+	}
+	if dataShards < 1 {
+		return storageLayout{}, fmt.Errorf("data shards %d must be at least 1", dataShards)
+	}
+	if parityShards < 1 {
+		return storageLayout{}, fmt.Errorf("parity shards %d must be at least 1", parityShards)
+	}
+	if _, err := erasure.New(dataShards, parityShards); err != nil {
+		return storageLayout{}, err
+	}
+	return storageLayout{mode: mode, dataShards: dataShards, parityShards: parityShards}, nil
+}
+
+// storeErasure splits a file version into erasure-coded shards and streams
+// each shard to its own ring node. Unlike replicated uploads there is no
+// write quorum: every shard must be stored, otherwise the shards that were
+// stored are removed and the upload fails with a QuorumError. Nodes compress
+// their shard with codec and encrypt it with dataKey.
+func (fm *FileManager) storeErasure(ctx context.Context, fileID, versionID string, r io.Reader, layout storageLayout, codec string, dataKey []byte) (*streamResult, error) {
+	coder, err := erasure.New(layout.dataShards, layout.parityShards)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeIDs := fm.hashRing.GetNodesN(fileID, coder.Shards())
+	if len(nodeIDs) < coder.Shards() {
+		return nil, fmt.Errorf("erasure coding into %d shards needs %d storage nodes, have %d",
+			coder.Shards(), coder.Shards(), len(nodeIDs))
+	}
+
+	result, err := fm.streamShards(ctx, fileID, versionID, nodeIDs, coder, r, codec, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.StoredNodes) < coder.Shards() {
+		fm.cleanupFailedUpload(fileID, versionID, result.StoredNodes)
+		return nil, &QuorumError{Required: coder.Shards(), Replicas: result.Replicas}
+	}
+	return result, nil
+}
+
+// streamShards encodes the data read from r one stripe at a time and writes
+// shard i to nodeIDs[i]. Each shard is fed through the same bounded queues
+// and per-replica deadlines as a replicated upload, so memory stays bounded.
+// Since every shard is needed, the upload stops reading as soon as one fails.
+func (fm *FileManager) streamShards(ctx context.Context, fileID, versionID string, nodeIDs []string, coder *erasure.Coder, r io.Reader, codec string, dataKey []byte) (*streamResult, error) {
+	result := &streamResult{StorageMode: metadata.StorageErasure, Compression: codec}
+
+	nodes := make([]NodeClient, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		node, exists := fm.getNode(nodeID)
+		if !exists {
+			return nil, fmt.Errorf("node %s not registered", nodeID)
+		}
+		nodes[i] = node
+	}
+
+	streams := make([]*replicaStream, len(nodes))
+	shardHashers := make([]hash.Hash, len(nodes))
+	for i, node := range nodes {
+		streams[i] = fm.startReplica(ctx, nodeIDs[i], node, fileID, versionID, codec, dataKey)
+		shardHashers[i] = sha256.New()
+	}
+
+	hasher := sha256.New()
+	stripe := make([]byte, coder.DataShards*erasureBlockSize)
+	var size int64
+
+	for {
+		n, readErr := io.ReadFull(r, stripe)
+		if n > 0 {
+			hasher.Write(stripe[:n])
+			size += int64(n)
+
+			// Replicas read the blocks concurrently, so each stripe gets its
+			// own buffer; the last stripe is zero-padded
+			data := make([]byte, coder.Shards()*erasureBlockSize)
+			copy(data, stripe[:n])
+			blocks := make([][]byte, coder.Shards())
+			for i := range blocks {
+				blocks[i] = data[i*erasureBlockSize : (i+1)*erasureBlockSize]
+			}
+			if err := coder.Encode(blocks); err != nil {
+				fm.abortStreams(fileID, versionID, streams, err)
+				return nil, err
+			}
+
+			failed := false
+			for i, rs := range streams {
+				shardHashers[i].Write(blocks[i])
+				if rs.failed {
+					failed = true
+					continue
+				}
+				select {
+				case rs.chunks <- blocks[i]:
+				case <-rs.ctx.Done():
+					rs.failed = true
+					failed = true
+				}
+			}
+			if failed {
+				// The version can't be stored without this shard; stop reading
+				break
+			}
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			fm.abortStreams(fileID, versionID, streams, readErr)
+			return nil, readErr
+		}
+	}
+
+	result.Size = size
+	result.Checksum = hex.EncodeToString(hasher.Sum(nil))
+	result.Erasure = &metadata.ErasureCoding{
+		DataShards:     coder.DataShards,
+		ParityShards:   coder.ParityShards,
+		BlockSize:      erasureBlockSize,
+		ShardChecksums: make([]string, len(shardHashers)),
+	}
+	for i, shardHasher := range shardHashers {
+		result.Erasure.ShardChecksums[i] = hex.EncodeToString(shardHasher.Sum(nil))
+	}
+
+	fm.finishStreams(fileID, versionID, streams, result)
+	for _, replica := range result.Replicas {
+		result.StoredSize += replica.storedSize
+	}
+	return result, nil
+}
+
+// dataOutput makes an erasureReader return the data of the version rather
+// than one of its shards
+const dataOutput = -1
+
+// erasureReader reads an erasure-coded version one stripe at a time. Blocks
+// are read from the data shards where possible; when a shard can't be opened
+// or fails partway, the next shard is opened, skipped ahead to the current
+// stripe, and the missing blocks are rebuilt. Shards found missing or corrupt
+// are scheduled for repair.
+//
+// With output set to dataOutput the reader returns the data of the version,
+// checked against the version checksum at EOF. Otherwise it returns the
+// contents of shard output, rebuilt from the other shards, for writing a
+// replacement shard.
+type erasureReader struct {
+	fileManager *FileManager
+	fileID      string
+	version     metadata.Version
+	dataKey     []byte
+	coder       *erasure.Coder
+	output      int
+
+	readers []io.ReadCloser
+	failed  []bool
+
+	stripe    int64
+	stripes   int64
+	remaining int64
+	pending   []byte
+	hasher    hash.Hash
+	expected  string
+	err       error
+}
+
+// openErasure opens an erasure-coded version for reading, decrypting its
+// shards with dataKey, and fails if fewer shards than needed to rebuild it
+// can be opened
+func (fm *FileManager) openErasure(fileID string, version metadata.Version, dataKey []byte, output int) (*erasureReader, error) {
+	layout := version.Erasure
+	coder, err := erasure.New(layout.DataShards, layout.ParityShards)
+	if err != nil {
+		return nil, err
+	}
+	if len(version.Nodes) != coder.Shards() || len(layout.ShardChecksums) != coder.Shards() {
+		return nil, fmt.Errorf("version has %d shard nodes, want %d", len(version.Nodes), coder.Shards())
+	}
+
+	stripeSize := int64(layout.DataShards) * int64(layout.BlockSize)
+	r := &erasureReader{
+		fileManager: fm,
+		fileID:      fileID,
+		version:     version,
+		dataKey:     dataKey,
+		coder:       coder,
+		output:      output,
+		readers:     make([]io.ReadCloser, coder.Shards()),
+		failed:      make([]bool, coder.Shards()),
+		stripes:     (version.Size + stripeSize - 1) / stripeSize,
+		remaining:   version.Size,
+		hasher:      sha256.New(),
+		expected:    version.Checksum,
+	}
+	if output != dataOutput {
+		// The shard being rebuilt is not a source
+		r.failed[output] = true
+		r.remaining = r.stripes * int64(layout.BlockSize)
+		r.expected = layout.ShardChecksums[output]
+	}
+
+	opened := 0
+	for i := range r.readers {
+		if opened == coder.DataShards {
+			break
+		}
+		if r.open(i) == nil {
+			opened++
+		}
+	}
+	if opened < coder.DataShards {
+		r.Close()
+		return nil, fmt.Errorf("only %d of %d shards available, need %d", opened, coder.Shards(), coder.DataShards)
+	}
+	return r, nil
+}
+
+// open opens shard i and skips the stripes already read
+func (r *erasureReader) open(i int) error {
+	if r.failed[i] {
+		return fmt.Errorf("shard %d unavailable", i)
+	}
+	if r.readers[i] != nil {
+		return nil
+	}
+
+	nodeID := r.version.Nodes[i]
+	node, exists := r.fileManager.getNode(nodeID)
+	if !exists {
+		r.failed[i] = true
+		return fmt.Errorf("node %s not registered", nodeID)
+	}
+
+	reader, err := node.OpenFile(r.fileID, r.version.VersionID, r.dataKey)
+	if err != nil {
+		r.fail(i, err)
+		return err
+	}
+	r.readers[i] = reader
+
+	skip := r.stripe * int64(r.version.Erasure.BlockSize)
+	if _, err := io.CopyN(io.Discard, reader, skip); err != nil {
+		r.fail(i, err)
+		return err
+	}
+	return nil
+}
+
+// fail stops reading shard i, scheduling a repair if rewriting it would help
+func (r *erasureReader) fail(i int, err error) {
+	if r.readers[i] != nil {
+		r.readers[i].Close()
+		r.readers[i] = nil
+	}
+	r.failed[i] = true
+	if reason, ok := repairReason(err); ok {
+		r.fileManager.scheduleRepair(r.fileID, r.version.VersionID, r.version.Nodes[i], reason)
+	}
+}
+
+// Read implements io.Reader
+func (r *erasureReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.stripe == r.stripes {
+			r.err = r.finish()
+			continue
+		}
+		if err := r.readStripe(); err != nil {
+			r.err = err
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// readStripe reads the blocks of the next stripe from as few shards as
+// possible and rebuilds the output from them
+func (r *erasureReader) readStripe() error {
+	blockSize := r.version.Erasure.BlockSize
+	blocks := make([][]byte, r.coder.Shards())
+	available := 0
+	for i := range blocks {
+		if available == r.coder.DataShards {
+			break
+		}
+		if r.open(i) != nil {
+			continue
+		}
+
+		block := make([]byte, blockSize)
+		if _, err := io.ReadFull(r.readers[i], block); err != nil {
+			r.fail(i, err)
+			continue
+		}
+		blocks[i] = block
+		available++
+	}
+	if available < r.coder.DataShards {
+		return fmt.Errorf("failed to read stripe %d: only %d of %d shards available, need %d",
+			r.stripe, available, r.coder.Shards(), r.coder.DataShards)
+	}
+
+	var out []byte
+	if r.output == dataOutput {
+		if err := r.coder.ReconstructData(blocks); err != nil {
+			return err
+		}
+		out = make([]byte, 0, r.coder.DataShards*blockSize)
+		for _, block := range blocks[:r.coder.DataShards] {
+			out = append(out, block...)
+		}
+	} else {
+		if err := r.coder.Reconstruct(blocks); err != nil {
+			return err
+		}
+		out = blocks[r.output]
+	}
+
+	// Drop the padding of the last stripe
+	if int64(len(out)) > r.remaining {
+		out = out[:r.remaining]
+	}
+	r.remaining -= int64(len(out))
+	r.stripe++
+	r.hasher.Write(out)
+	r.pending = out
+	return nil
+}
+
+// finish reads the shards to the end so each node verifies its copy, and
+// checks the output against its checksum
+func (r *erasureReader) finish() error {
+	for i, reader := range r.readers {
+		if reader == nil {
+			continue
+		}
+		if _, err := io.Copy(io.Discard, reader); err != nil {
+			r.fail(i, err)
+		}
+	}
+
+	if hex.EncodeToString(r.hasher.Sum(nil)) != r.expected {
+		return storage.ErrChecksumMismatch
+	}
+	return io.EOF
+}
+
+// sources returns the nodes of the shards that were read without error
+func (r *erasureReader) sources() []string {
+	var nodeIDs []string
+	for i, reader := range r.readers {
+		if reader != nil {
+			nodeIDs = append(nodeIDs, r.version.Nodes[i])
+		}
+	}
+	return nodeIDs
+}
+
+// Close implements io.Closer
+func (r *erasureReader) Close() error {
+	for i, reader := range r.readers {
+		if reader != nil {
+			reader.Close()
+			r.readers[i] = nil
+		}
+	}
+	return nil
+}
+
+// rebuildShard rebuilds shard i of an erasure-coded version from the other
+// shards and stores it on the target node, verifying it against the shard
+// checksum. If the target is the node that holds the shard, its old copy is
+// replaced only by a complete rebuild and kept if the rebuild fails. Reads
+// are paced by limiter if one is given. It returns the nodes the shard was
+// rebuilt from and the number of bytes written.
+func (fm *FileManager) rebuildShard(ctx context.Context, fileID string, version metadata.Version, i int, targetID string, limiter *rateLimiter) (string, int64, error) {
+	target, exists := fm.getNode(targetID)
+	if !exists {
+		return "", 0, fmt.Errorf("node %s not registered", targetID)
+	}
+
+	dataKey, err := fm.dataKey(ctx, version)
+	if err != nil {
+		return "", 0, err
+	}
+	reader, err := fm.openErasure(fileID, version, dataKey, i)
+	if err != nil {
+		return "", 0, err
+	}
+	defer reader.Close()
+
+	counter := &countingReader{Reader: &throttledReader{ctx: ctx, reader: reader, limiter: limiter}}
+	if err := replicateTo(ctx, target, fileID, version.VersionID, counter, version.Compression, dataKey); err != nil {
+		if version.Nodes[i] != targetID {
+			target.DeleteFile(fileID, version.VersionID)
+		}
+		return "", counter.n, err
+	}
+	sources := strings.Join(reader.sources(), ",")
+
+	checksum, err := target.Checksum(fileID, version.VersionID)
+	if err != nil {
+		return sources, counter.n, err
+	}
+	if checksum != version.Erasure.ShardChecksums[i] {
+		target.DeleteFile(fileID, version.VersionID)
+		return sources, counter.n, storage.ErrChecksumMismatch
+	}
+	return sources, counter.n, nil
+}
+
+// shardIndex returns the shard of an erasure-coded version stored on a node,
+// or -1 if the node holds none
+func shardIndex(version metadata.Version, nodeID string) int {
+	for i, id := range version.Nodes {
+		if id == nodeID {
+			return i
+		}
+	}
+	return -1
+}
+
+// replicateShards rebuilds the shards of an erasure-coded version that are
+// missing, corrupt or on removed nodes. A missing or corrupt shard is
+// rebuilt in place, and one on a removed node on the next ring node that
+// holds no shard of the version. Shards that could not be checked are left
+// alone. It returns the placement change, with no new placement if no shard
+// was rebuilt, and how many bytes were written.
+func (fm *FileManager) replicateShards(ctx context.Context, fileID string, version metadata.Version) (placementChange, int64, error) {
+	shards := fm.healthyReplicas(fileID, version)
+	placed := shards.placed()
+	if len(placed) == len(version.Nodes) {
+		return placementChange{}, 0, nil
+	}
+	if len(shards.healthy) < version.Erasure.DataShards {
+		return placementChange{}, 0, fmt.Errorf("only %d of %d shards healthy, need %d to rebuild",
+			len(shards.healthy), len(version.Nodes), version.Erasure.DataShards)
+	}
+
+	nodes := append([]string(nil), version.Nodes...)
+	candidates := fm.hashRing.PreferenceList(fileID)
+	var written []string
+	var copied int64
+	for i, nodeID := range version.Nodes {
+		if containsNode(placed, nodeID) {
+			continue
+		}
+
+		target := nodeID
+		if _, exists := fm.getNode(nodeID); !exists {
+			target = ""
+			for _, candidate := range candidates {
+				if !containsNode(nodes, candidate) {
+					target = candidate
+					break
+				}
+			}
+			if target == "" {
+				fmt.Printf("No node available for shard %d of %s\n", i, fileID)
+				continue
+			}
+		}
+
+		_, n, err := fm.rebuildShard(ctx, fileID, version, i, target, nil)
+		copied += n
+		if err != nil {
+			fmt.Printf("Failed to rebuild shard %d on node %s: %v\n", i, target, err)
+			continue
+		}
+		nodes[i] = target
+		written = append(written, target)
+	}
+
+	if len(written) == 0 {
+		return placementChange{}, copied, fmt.Errorf("failed to rebuild any of %d shards", len(version.Nodes)-len(placed))
+	}
+	change := placementChange{Nodes: nodes, Written: written}
+	if missing := len(version.Nodes) - len(placed) - len(written); missing > 0 {
+		return change, copied, fmt.Errorf("%d of %d shards still unavailable", missing, len(version.Nodes))
+	}
+	return change, copied, nil
+}
+
+// moveShards moves the shards of an erasure-coded version on nodes the ring
+// no longer assigns it to to the ring nodes that hold none of its shards.
+// Each shard is copied from its current node, or rebuilt from the others if
+// that copy is unhealthy; the old copy is deleted once the new placement is
+// recorded. It returns the placement change and the number of bytes written.
+func (fm *FileManager) moveShards(ctx context.Context, file *metadata.FileMetadata, version metadata.Version, limiter *rateLimiter) (placementChange, int64, error) {
+	fileID := file.FileID
+	owners := fm.ringOwners(file, version)
+	healthy := fm.healthyReplicas(fileID, version).healthy
+
+	var free []string
+	for _, nodeID := range owners {
+		if !containsNode(version.Nodes, nodeID) {
+			free = append(free, nodeID)
+		}
+	}
+
+	nodes := append([]string(nil), version.Nodes...)
+	var written []string
+	var copied int64
+	var copyErr error
+	for i, nodeID := range version.Nodes {
+		if containsNode(owners, nodeID) || len(free) == 0 {
+			continue
+		}
+		target := free[0]
+		free = free[1:]
+
+		var n int64
+		var err error
+		if containsNode(healthy, nodeID) {
+			_, n, err = fm.copyReplica(ctx, fileID, version, []string{nodeID}, target, limiter)
+		} else {
+			_, n, err = fm.rebuildShard(ctx, fileID, version, i, target, limiter)
+		}
+		copied += n
+		if err != nil {
+			copyErr = fmt.Errorf("move shard %d to %s: %w", i, target, err)
+			continue
+		}
+		nodes[i] = target
+		written = append(written, target)
+	}
+	if len(written) == 0 {
+		return placementChange{}, copied, copyErr
+	}
+	return placementChange{Nodes: nodes, Written: written}, copied, copyErr
+}
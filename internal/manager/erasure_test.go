@@ -0,0 +1,295 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// erasureOptions stores an upload as three data and two parity shards
+var erasureOptions = UploadOptions{StorageMode: metadata.StorageErasure, DataShards: 3, ParityShards: 2}
+
+// setupErasure returns a file manager with six nodes and an erasure-coded
+// file spanning several stripes
+func setupErasure(t *testing.T) (*FileManager, *metadata.FileMetadata, []byte) {
+	t.Helper()
+	fm := NewFileManager(NewMockMetadataStore(), 2)
+	tempDir := t.TempDir()
+	for i := 1; i <= 6; i++ {
+		nodeID := "test-node-" + string(rune('0'+i))
+		if err := fm.RegisterNode(nodeID, storage.BackendConfig{Path: tempDir + "/" + nodeID}); err != nil {
+			t.Fatalf("Failed to register node: %v", err)
+		}
+	}
+	t.Cleanup(fm.repairWG.Wait)
+
+	// Not a multiple of the stripe size, so the last stripe is padded
+	data := make([]byte, 1000*1000)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	result, err := fm.UploadStream(context.Background(), "cold.bin", bytes.NewReader(data), "application/octet-stream", erasureOptions)
+	if err != nil {
+		t.Fatalf("UploadStream failed: %v", err)
+	}
+	return fm, result.FileMetadata, data
+}
+
+// checkDownload verifies the latest version of a file downloads intact
+func checkDownload(t *testing.T, fm *FileManager, fileID string, data []byte) {
+	t.Helper()
+	downloaded, _, err := fm.DownloadFile(context.Background(), fileID, "", DownloadOptions{})
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Error("downloaded data does not match original")
+	}
+}
+
+func TestErasureUpload(t *testing.T) {
+	fm, meta, data := setupErasure(t)
+	version := meta.Versions[0]
+
+	if !version.IsErasureCoded() {
+		t.Fatalf("storage mode = %q, want erasure", version.StorageMode)
+	}
+	if version.Erasure.DataShards != 3 || version.Erasure.ParityShards != 2 {
+		t.Errorf("layout = %+v, want 3 data and 2 parity shards", version.Erasure)
+	}
+	if len(version.Nodes) != 5 {
+		t.Fatalf("version nodes = %v, want one per shard", version.Nodes)
+	}
+	sum := sha256.Sum256(data)
+	if version.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("checksum = %s, want checksum of the whole file", version.Checksum)
+	}
+	if version.Chunks != nil {
+		t.Errorf("chunks = %v, want none for erasure-coded versions", version.Chunks)
+	}
+
+	seen := make(map[string]bool)
+	for i, nodeID := range version.Nodes {
+		if seen[nodeID] {
+			t.Errorf("node %s holds more than one shard", nodeID)
+		}
+		seen[nodeID] = true
+
+		checksum, err := fm.nodes[nodeID].Checksum(meta.FileID, version.VersionID)
+		if err != nil {
+			t.Fatalf("shard %d missing on %s: %v", i, nodeID, err)
+		}
+		if checksum != version.Erasure.ShardChecksums[i] {
+			t.Errorf("shard %d on %s has checksum %s, want %s", i, nodeID, checksum, version.Erasure.ShardChecksums[i])
+		}
+	}
+
+	checkDownload(t, fm, meta.FileID, data)
+
+	t.Run("streamed download", func(t *testing.T) {
+		reader, err := fm.OpenFile(context.Background(), meta.FileID, "", DownloadOptions{})
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		defer reader.Close()
+
+		streamed, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if !bytes.Equal(streamed, data) {
+			t.Error("streamed data does not match original")
+		}
+	})
+
+	t.Run("fsck finds no issues", func(t *testing.T) {
+		report, err := fm.Fsck(context.Background(), FsckOptions{})
+		if err != nil {
+			t.Fatalf("Fsck failed: %v", err)
+		}
+		if len(report.Issues) != 0 {
+			t.Errorf("issues = %+v, want none", report.Issues)
+		}
+	})
+
+	t.Run("restore keeps storage mode", func(t *testing.T) {
+		result, err := fm.RestoreVersion(context.Background(), meta.FileID, version.VersionID)
+		if err != nil {
+			t.Fatalf("RestoreVersion failed: %v", err)
+		}
+		restored := result.Versions[len(result.Versions)-1]
+		if !restored.IsErasureCoded() || restored.Erasure.DataShards != 3 {
+			t.Errorf("restored version = %+v, want the same erasure layout", restored)
+		}
+	})
+}
+
+func TestErasureUploadValidation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("too few nodes", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		_, err := fm.UploadStream(ctx, "cold.bin", bytes.NewReader([]byte("data")), "", erasureOptions)
+		if err == nil {
+			t.Error("expected error with fewer nodes than shards")
+		}
+	})
+
+	t.Run("write quorum rejected", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		opts := erasureOptions
+		opts.WriteQuorum = 1
+		if _, err := fm.UploadStream(ctx, "cold.bin", bytes.NewReader([]byte("data")), "", opts); err == nil {
+			t.Error("expected error for a write quorum on an erasure-coded upload")
+		}
+	})
+
+	t.Run("unknown storage mode", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		opts := UploadOptions{StorageMode: "mirrored"}
+		if _, err := fm.UploadStream(ctx, "cold.bin", bytes.NewReader([]byte("data")), "", opts); err == nil {
+			t.Error("expected error for an unknown storage mode")
+		}
+	})
+}
+
+func TestErasureDegradedRead(t *testing.T) {
+	t.Run("lost shards are rebuilt", func(t *testing.T) {
+		fm, meta, data := setupErasure(t)
+		version := meta.Versions[0]
+
+		// Lose two data shards, so the read has to use both parity shards
+		for _, i := range []int{0, 2} {
+			if err := fm.nodes[version.Nodes[i]].DeleteFile(meta.FileID, version.VersionID); err != nil {
+				t.Fatalf("DeleteFile failed: %v", err)
+			}
+		}
+
+		checkDownload(t, fm, meta.FileID, data)
+
+		fm.repairWG.Wait()
+		for _, i := range []int{0, 2} {
+			checksum, err := fm.nodes[version.Nodes[i]].Checksum(meta.FileID, version.VersionID)
+			if err != nil || checksum != version.Erasure.ShardChecksums[i] {
+				t.Errorf("shard %d not repaired: checksum %q, err %v", i, checksum, err)
+			}
+		}
+	})
+
+	t.Run("corrupt shard", func(t *testing.T) {
+		fm, meta, data := setupErasure(t)
+		version := meta.Versions[0]
+		corruptReplica(t, fm, version.Nodes[1], meta.FileID, version.VersionID)
+
+		checkDownload(t, fm, meta.FileID, data)
+
+		fm.repairWG.Wait()
+		if _, err := fm.nodes[version.Nodes[1]].RetrieveFile(meta.FileID, version.VersionID, nil); err != nil {
+			t.Errorf("shard 1 not repaired: %v", err)
+		}
+		events := fm.RepairLog()
+		if len(events) != 1 || events[0].Reason != RepairCorrupt || events[0].Error != "" {
+			t.Errorf("repair log = %+v, want one successful corrupt repair", events)
+		}
+	})
+
+	t.Run("too many shards lost", func(t *testing.T) {
+		fm, meta, _ := setupErasure(t)
+		version := meta.Versions[0]
+		for _, nodeID := range version.Nodes[:3] {
+			fm.UnregisterNode(nodeID)
+		}
+
+		if _, _, err := fm.DownloadFile(context.Background(), meta.FileID, "", DownloadOptions{}); err == nil {
+			t.Error("expected error with fewer shards than data shards")
+		}
+	})
+
+	t.Run("shard kept when it can't be rebuilt", func(t *testing.T) {
+		fm, meta, _ := setupErasure(t)
+		version := meta.Versions[0]
+		for _, nodeID := range version.Nodes[1:4] {
+			fm.UnregisterNode(nodeID)
+		}
+
+		err := fm.repairReplica(context.Background(), meta.FileID, version.VersionID, version.Nodes[0], RepairCorrupt)
+		if err == nil {
+			t.Fatal("expected repair to fail with too few shards")
+		}
+		checksum, err := fm.nodes[version.Nodes[0]].Checksum(meta.FileID, version.VersionID)
+		if err != nil || checksum != version.Erasure.ShardChecksums[0] {
+			t.Errorf("shard 0 was not kept: checksum %q, err %v", checksum, err)
+		}
+	})
+}
+
+func TestErasureReplicator(t *testing.T) {
+	fm, meta, data := setupErasure(t)
+	version := meta.Versions[0]
+	removed := version.Nodes[2]
+	fm.UnregisterNode(removed)
+
+	status, err := NewReplicator(fm, 0).RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if status.Repaired != 1 || status.Failed != 0 {
+		t.Errorf("status = %+v, want one repaired version", status)
+	}
+
+	info, err := fm.GetFileInfo(context.Background(), meta.FileID)
+	if err != nil {
+		t.Fatalf("GetFileInfo failed: %v", err)
+	}
+	current := info.Versions[0]
+	if len(current.Nodes) != 5 || containsNode(current.Nodes, removed) {
+		t.Fatalf("version nodes = %v, want five nodes without %s", current.Nodes, removed)
+	}
+	for i, nodeID := range version.Nodes {
+		if i != 2 && current.Nodes[i] != nodeID {
+			t.Errorf("shard %d moved from %s to %s", i, nodeID, current.Nodes[i])
+		}
+	}
+	checksum, err := fm.nodes[current.Nodes[2]].Checksum(meta.FileID, version.VersionID)
+	if err != nil || checksum != version.Erasure.ShardChecksums[2] {
+		t.Errorf("rebuilt shard has checksum %q, err %v", checksum, err)
+	}
+
+	checkDownload(t, fm, meta.FileID, data)
+}
+
+func TestSetStorageMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         string
+		dataShards   int
+		parityShards int
+		wantErr      bool
+	}{
+		{"replicated", metadata.StorageReplicated, 4, 2, false},
+		{"erasure", metadata.StorageErasure, 6, 3, false},
+		{"unknown mode", "mirrored", 4, 2, true},
+		{"no data shards", metadata.StorageErasure, 0, 2, true},
+		{"no parity shards", metadata.StorageErasure, 4, 0, true},
+		{"too many shards", metadata.StorageErasure, 200, 100, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm := setupTestFileManager(t)
+			err := fm.SetStorageMode(tt.mode, tt.dataShards, tt.parityShards)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetStorageMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && fm.storage.mode != tt.mode {
+				t.Errorf("storage mode = %q, want %q", fm.storage.mode, tt.mode)
+			}
+		})
+	}
+}
@@ -1,11 +1,21 @@
 package manager
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yashlad/distributed-file-store/internal/chunker"
+	"github.com/yashlad/distributed-file-store/internal/compression"
+	"github.com/yashlad/distributed-file-store/internal/encryption"
 	"github.com/yashlad/distributed-file-store/internal/hash"
 	"github.com/yashlad/distributed-file-store/internal/metadata"
 	"github.com/yashlad/distributed-file-store/internal/storage"
@@ -13,90 +23,183 @@ import (
 
 // FileManager coordinates file operations across storage nodes
 type FileManager struct {
-	nodes         map[string]*storage.Node
-	hashRing      *hash.ConsistentHash
-	metadataStore metadata.Store
-	replicaFactor int
+	mu             sync.RWMutex
+	nodes          map[string]NodeClient
+	hashRing       *hash.ConsistentHash
+	metadataStore  metadata.Store
+	replicaFactor  int
+	writeQuorum    int
+	readQuorum     int
+	replicaTimeout time.Duration
+	chunking       chunker.Config
+	storage        storageLayout
+	compression    compressionPolicy
+	keys           encryption.KeyManager
+
+	topologyListeners []func()
+
+	// placementMu serializes changes to where versions are stored
+	placementMu sync.Mutex
+
+	repairs   repairLog
+	repairMu  sync.Mutex
+	repairing map[string]bool
+	repairWG  sync.WaitGroup
 }
 
 // NewFileManager creates a new file manager
 func NewFileManager(metadataStore metadata.Store, replicaFactor int) *FileManager {
 	return &FileManager{
-		nodes:         make(map[string]*storage.Node),
+		nodes:         make(map[string]NodeClient),
 		hashRing:      hash.NewConsistentHash(150, replicaFactor),
 		metadataStore: metadataStore,
 		replicaFactor: replicaFactor,
+		writeQuorum:   1,
+		readQuorum:    1,
+		chunking:      chunker.DefaultConfig(),
+		storage:       defaultStorageLayout(),
+		compression:   compressionPolicy{defaultCodec: compression.None},
 	}
 }
 
-// RegisterNode registers a storage node
-func (fm *FileManager) RegisterNode(nodeID, storagePath string) error {
-	node, err := storage.NewNode(nodeID, storagePath)
+// RegisterNode registers a storage node keeping its data in the backend the
+// config describes. Nodes on different backends can be mixed freely.
+func (fm *FileManager) RegisterNode(nodeID string, config storage.BackendConfig) error {
+	backend, err := storage.OpenBackend(config)
+	if err != nil {
+		return err
+	}
+	node, err := storage.NewBackendNode(nodeID, backend)
 	if err != nil {
 		return err
 	}
+	return fm.AddNode(nodeID, node)
+}
 
-	fm.nodes[nodeID] = node
+// AddNode registers a storage node reached through client, such as a node
+// daemon on another machine. Adding a node that is already registered only
+// replaces its client.
+func (fm *FileManager) AddNode(nodeID string, client NodeClient) error {
+	fm.mu.Lock()
+	// Every replica must split versions the same way
+	if err := client.SetChunking(fm.chunking); err != nil {
+		fm.mu.Unlock()
+		return fmt.Errorf("configure node %s: %w", nodeID, err)
+	}
+	_, existed := fm.nodes[nodeID]
+	fm.nodes[nodeID] = client
+	fm.mu.Unlock()
+	if existed {
+		return nil
+	}
 	fm.hashRing.AddNode(nodeID)
-	
+	fm.notifyTopologyChange()
+
 	return nil
 }
 
 // UnregisterNode removes a storage node
 func (fm *FileManager) UnregisterNode(nodeID string) {
+	fm.mu.Lock()
+	_, existed := fm.nodes[nodeID]
 	delete(fm.nodes, nodeID)
+	fm.mu.Unlock()
+	if !existed {
+		return
+	}
 	fm.hashRing.RemoveNode(nodeID)
+	fm.notifyTopologyChange()
+}
+
+// SetNodeHealthy marks a storage node healthy or unhealthy. New versions
+// skip an unhealthy node in favour of the next healthy node along the ring,
+// which holds them until they are handed back; the node stays on the ring,
+// so reads and placement are unaffected.
+func (fm *FileManager) SetNodeHealthy(nodeID string, healthy bool) {
+	fm.hashRing.SetHealthy(nodeID, healthy)
+}
+
+// onTopologyChange registers fn to be called whenever a node is registered
+// or removed. Listeners must not block.
+func (fm *FileManager) onTopologyChange(fn func()) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	fm.topologyListeners = append(fm.topologyListeners, fn)
+}
+
+// notifyTopologyChange calls every topology listener
+func (fm *FileManager) notifyTopologyChange() {
+	fm.mu.RLock()
+	listeners := append([]func(){}, fm.topologyListeners...)
+	fm.mu.RUnlock()
+
+	for _, fn := range listeners {
+		fn()
+	}
 }
 
 // UploadFile handles file upload with sharding and replication
 func (fm *FileManager) UploadFile(ctx context.Context, filename string, data []byte, contentType string) (*metadata.FileMetadata, error) {
-	fileID := uuid.New().String()
-	versionID := uuid.New().String()
-
-	// Get nodes for this file using consistent hashing
-	nodeIDs := fm.hashRing.GetNodes(fileID)
-	if len(nodeIDs) == 0 {
-		return nil, fmt.Errorf("no storage nodes available")
+	result, err := fm.UploadStream(ctx, filename, bytes.NewReader(data), contentType, UploadOptions{})
+	if err != nil {
+		return nil, err
 	}
+	return result.FileMetadata, nil
+}
 
-	// Store file on all replica nodes
-	var storedNodes []string
-	for _, nodeID := range nodeIDs {
-		node, exists := fm.nodes[nodeID]
-		if !exists {
-			continue
-		}
+// UploadResult is the metadata of an uploaded file along with the outcome of
+// the write to each of its replicas
+type UploadResult struct {
+	*metadata.FileMetadata
+	ReplicaResults []ReplicaResult
+}
 
-		if err := node.StoreFile(fileID, versionID, data); err != nil {
-			// Log error but continue with other replicas
-			fmt.Printf("Failed to store on node %s: %v\n", nodeID, err)
-			continue
-		}
+// UploadStream handles a streaming file upload with sharding and replication.
+// The data is written to every replica as it is read from r, so memory use
+// stays bounded regardless of the file size.
+func (fm *FileManager) UploadStream(ctx context.Context, filename string, r io.Reader, contentType string, opts UploadOptions) (*UploadResult, error) {
+	fileID := uuid.New().String()
+	versionID := uuid.New().String()
 
-		storedNodes = append(storedNodes, nodeID)
+	replicaFactor, err := fm.replicaFactorFor(opts)
+	if err != nil {
+		return nil, err
 	}
-
-	if len(storedNodes) == 0 {
-		return nil, fmt.Errorf("failed to store file on any node")
+	codec, err := fm.compressionFor(opts, contentType)
+	if err != nil {
+		return nil, err
+	}
+	result, err := fm.storeVersion(ctx, fileID, versionID, r, opts, replicaFactor, codec)
+	if err != nil {
+		return nil, err
 	}
+	storedNodes := result.StoredNodes
 
 	// Create metadata
 	fileMetadata := &metadata.FileMetadata{
 		FileID:      fileID,
 		Filename:    filename,
-		Size:        int64(len(data)),
+		Size:        result.Size,
 		ContentType: contentType,
 		Replicas:    storedNodes,
 		Versions: []metadata.Version{
 			{
-				VersionID: versionID,
-				Size:      int64(len(data)),
-				Nodes:     storedNodes,
-				CreatedAt: time.Now(),
+				VersionID:   versionID,
+				Size:        result.Size,
+				Checksum:    result.Checksum,
+				Chunks:      result.Chunks,
+				Nodes:       storedNodes,
+				StorageMode: result.StorageMode,
+				Erasure:     result.Erasure,
+				Compression: result.Compression,
+				StoredSize:  result.StoredSize,
+				Encryption:  result.Encryption,
+				CreatedAt:   time.Now(),
 			},
 		},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		ReplicaFactor: opts.ReplicaFactor,
 	}
 
 	// Save metadata to MongoDB
@@ -106,59 +209,475 @@ func (fm *FileManager) UploadFile(ctx context.Context, filename string, data []b
 		return nil, err
 	}
 
-	return fileMetadata, nil
+	return &UploadResult{FileMetadata: fileMetadata, ReplicaResults: result.Replicas}, nil
 }
 
-// DownloadFile retrieves a file from storage
-func (fm *FileManager) DownloadFile(ctx context.Context, fileID, versionID string) ([]byte, *metadata.FileMetadata, error) {
-	// Get metadata
+// UploadNewVersion streams a new version of an existing file to the file's
+// ring nodes and records it as the latest version. The version is kept at the
+// file's replica factor, which SetReplicaFactor changes.
+func (fm *FileManager) UploadNewVersion(ctx context.Context, fileID string, r io.Reader, opts UploadOptions) (*UploadResult, error) {
+	// Make sure the file exists before storing any data
 	fileMeta, err := fm.metadataStore.GetMetadata(ctx, fileID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("file not found: %w", err)
+		return nil, fmt.Errorf("file not found: %w", err)
 	}
 
-	// Determine which version to download
-	var targetVersion *metadata.Version
-	if versionID == "" {
-		// Get latest version
-		if len(fileMeta.Versions) == 0 {
-			return nil, nil, fmt.Errorf("no versions available")
+	replicaFactor := fm.replicaFactorOf(fileMeta)
+	if opts.ReplicaFactor != 0 && opts.ReplicaFactor != replicaFactor {
+		return nil, fmt.Errorf("file is kept at %d replicas; change its replica factor to use %d", replicaFactor, opts.ReplicaFactor)
+	}
+
+	codec, err := fm.compressionFor(opts, fileMeta.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	versionID := uuid.New().String()
+	result, err := fm.storeVersion(ctx, fileID, versionID, r, opts, replicaFactor, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	version := metadata.Version{
+		VersionID:   versionID,
+		Size:        result.Size,
+		Checksum:    result.Checksum,
+		Chunks:      result.Chunks,
+		Nodes:       result.StoredNodes,
+		StorageMode: result.StorageMode,
+		Erasure:     result.Erasure,
+		Compression: result.Compression,
+		StoredSize:  result.StoredSize,
+		Encryption:  result.Encryption,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := fm.metadataStore.AddVersion(ctx, fileID, version); err != nil {
+		fm.cleanupFailedUpload(fileID, versionID, result.StoredNodes)
+		return nil, err
+	}
+
+	fileMeta.Versions = append(fileMeta.Versions, version)
+	fileMeta.Size = version.Size
+	fileMeta.Replicas = mergeNodes(fileMeta.Replicas, version.Nodes)
+	fileMeta.UpdatedAt = version.CreatedAt
+
+	return &UploadResult{FileMetadata: fileMeta, ReplicaResults: result.Replicas}, nil
+}
+
+// storeVersion streams a file version to replicaFactor of the file's ring
+// nodes, compressed with codec and encrypted with a new data key if
+// encryption is enabled, and enforces the write quorum. Ring nodes that are
+// marked unhealthy or fail the write are stood in for by the next healthy
+// nodes along the ring, with hints to hand the version back. If too few
+// replicas acknowledge the write, the copies that were stored are removed
+// and the upload fails with a QuorumError.
+func (fm *FileManager) storeVersion(ctx context.Context, fileID, versionID string, r io.Reader, opts UploadOptions, replicaFactor int, codec string) (*streamResult, error) {
+	layout, err := fm.storageFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, wrappedKey, err := fm.newDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if layout.mode == metadata.StorageErasure {
+		result, err := fm.storeErasure(ctx, fileID, versionID, r, layout, codec, dataKey)
+		if err != nil {
+			return nil, err
 		}
-		targetVersion = &fileMeta.Versions[len(fileMeta.Versions)-1]
-	} else {
-		// Find specific version
-		for i := range fileMeta.Versions {
-			if fileMeta.Versions[i].VersionID == versionID {
-				targetVersion = &fileMeta.Versions[i]
-				break
-			}
+		result.Encryption = wrappedKey
+		return result, nil
+	}
+
+	writeQuorum, err := fm.writeQuorumFor(opts, replicaFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get nodes for this file using consistent hashing
+	nodeIDs, standIns := fm.writeNodes(fileID, replicaFactor)
+	if len(nodeIDs) == 0 {
+		return nil, fmt.Errorf("no storage nodes available")
+	}
+	if len(nodeIDs) < writeQuorum {
+		return nil, fmt.Errorf("write quorum %d cannot be met with %d storage nodes", writeQuorum, len(nodeIDs))
+	}
+
+	// Stream file to all replica nodes
+	result, err := fm.streamToReplicas(ctx, fileID, versionID, nodeIDs, r, codec, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nodes further along the ring stand in for the ones that failed
+	fm.storeHinted(ctx, fileID, versionID, nodeIDs, standIns, result, dataKey)
+
+	if len(result.StoredNodes) < writeQuorum {
+		fm.cleanupFailedUpload(fileID, versionID, result.StoredNodes)
+		return nil, &QuorumError{Required: writeQuorum, Replicas: result.Replicas}
+	}
+
+	result.Encryption = wrappedKey
+	return result, nil
+}
+
+// DownloadFile retrieves a file from storage. opts.ReadQuorum overrides the
+// server's read quorum for this download.
+func (fm *FileManager) DownloadFile(ctx context.Context, fileID, versionID string, opts DownloadOptions) ([]byte, *metadata.FileMetadata, error) {
+	fileMeta, targetVersion, err := fm.resolveVersion(ctx, fileID, versionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	dataKey, err := fm.dataKey(ctx, *targetVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if targetVersion.IsErasureCoded() {
+		reader, err := fm.openErasure(fileID, *targetVersion, dataKey, dataOutput)
+		if err != nil {
+			return nil, nil, err
 		}
-		if targetVersion == nil {
-			return nil, nil, fmt.Errorf("version not found")
+		defer reader.Close()
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, nil, err
 		}
+		return data, fileMeta, nil
+	}
+
+	readQuorum, err := fm.readQuorumFor(opts, fm.replicaFactorOf(fileMeta))
+	if err != nil {
+		return nil, nil, err
+	}
+	nodeIDs, err := fm.readQuorumNodes(fileID, targetVersion, readQuorum)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Try to retrieve from any replica node
 	var data []byte
 	var lastErr error
-	
-	for _, nodeID := range targetVersion.Nodes {
-		node, exists := fm.nodes[nodeID]
+	var bad []badReplica
+
+	for _, nodeID := range nodeIDs {
+		node, exists := fm.getNode(nodeID)
 		if !exists {
 			continue
 		}
 
-		data, lastErr = node.RetrieveFile(fileID, targetVersion.VersionID)
+		data, lastErr = node.RetrieveFile(fileID, targetVersion.VersionID, dataKey)
 		if lastErr == nil {
+			// Rewrite the replicas that failed from a healthy one
+			fm.scheduleRepairs(fileID, targetVersion.VersionID, bad)
 			return data, fileMeta, nil
 		}
+		if reason, ok := repairReason(lastErr); ok {
+			bad = append(bad, badReplica{nodeID: nodeID, reason: reason})
+		}
 	}
 
 	return nil, nil, fmt.Errorf("failed to retrieve file from any replica: %w", lastErr)
 }
 
-// DeleteFile deletes a file and its metadata
+// FileReader streams a file version from one of its replicas. NodeID is the
+// replica being read, or the comma-separated shard nodes of an erasure-coded
+// version.
+type FileReader struct {
+	io.ReadCloser
+	Metadata *metadata.FileMetadata
+	Version  *metadata.Version
+	NodeID   string
+}
+
+// OpenFile opens a file version for streaming from the first replica that
+// can serve it. Data is read from disk as the caller consumes it, so large
+// downloads start immediately and use constant memory. Because bytes may
+// already have been handed to the caller, a checksum mismatch is reported as
+// an error from the final Read rather than by failing over to another replica.
+// Erasure-coded versions are read from their data shards, rebuilding blocks
+// from the parity shards where a shard can't be read; the read quorum does
+// not apply to them.
+func (fm *FileManager) OpenFile(ctx context.Context, fileID, versionID string, opts DownloadOptions) (*FileReader, error) {
+	fileMeta, targetVersion, err := fm.resolveVersion(ctx, fileID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := fm.dataKey(ctx, *targetVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if targetVersion.IsErasureCoded() {
+		reader, err := fm.openErasure(fileID, *targetVersion, dataKey, dataOutput)
+		if err != nil {
+			return nil, err
+		}
+		return &FileReader{
+			ReadCloser: reader,
+			Metadata:   fileMeta,
+			Version:    targetVersion,
+			NodeID:     strings.Join(targetVersion.Nodes, ","),
+		}, nil
+	}
+
+	readQuorum, err := fm.readQuorumFor(opts, fm.replicaFactorOf(fileMeta))
+	if err != nil {
+		return nil, err
+	}
+	nodeIDs, err := fm.readQuorumNodes(fileID, targetVersion, readQuorum)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	var bad []badReplica
+	for _, nodeID := range nodeIDs {
+		node, exists := fm.getNode(nodeID)
+		if !exists {
+			continue
+		}
+
+		reader, err := node.OpenFile(fileID, targetVersion.VersionID, dataKey)
+		if err != nil {
+			lastErr = err
+			if reason, ok := repairReason(err); ok {
+				bad = append(bad, badReplica{nodeID: nodeID, reason: reason})
+			}
+			continue
+		}
+
+		fm.scheduleRepairs(fileID, targetVersion.VersionID, bad)
+		return &FileReader{
+			ReadCloser: &repairingReader{
+				ReadCloser:  reader,
+				fileManager: fm,
+				fileID:      fileID,
+				versionID:   targetVersion.VersionID,
+				nodeID:      nodeID,
+			},
+			Metadata:   fileMeta,
+			Version:    targetVersion,
+			NodeID:     nodeID,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("failed to retrieve file from any replica: %w", lastErr)
+}
+
+// readQuorumNodes returns the replicas a version may be read from. With a read
+// quorum above one, the stored checksums of the replicas are compared first
+// and only the replicas that agree with the version checksum are returned; the
+// read fails if fewer than readQuorum of them agree.
+func (fm *FileManager) readQuorumNodes(fileID string, version *metadata.Version, readQuorum int) ([]string, error) {
+	if readQuorum <= 1 {
+		return version.Nodes, nil
+	}
+
+	checksums := make(map[string]string)
+	votes := make(map[string]int)
+	for _, nodeID := range version.Nodes {
+		node, exists := fm.getNode(nodeID)
+		if !exists {
+			continue
+		}
+
+		checksum, err := node.Checksum(fileID, version.VersionID)
+		if err != nil {
+			continue
+		}
+		checksums[nodeID] = checksum
+		votes[checksum]++
+	}
+
+	// Versions written before checksums were recorded fall back to the majority
+	expected := version.Checksum
+	if expected == "" {
+		for checksum, count := range votes {
+			if count > votes[expected] {
+				expected = checksum
+			}
+		}
+	}
+
+	var agreeing []string
+	var bad []badReplica
+	for _, nodeID := range version.Nodes {
+		checksum, ok := checksums[nodeID]
+		switch {
+		case ok && checksum == expected:
+			agreeing = append(agreeing, nodeID)
+		case ok:
+			bad = append(bad, badReplica{nodeID: nodeID, reason: RepairDiverged})
+		default:
+			if _, exists := fm.getNode(nodeID); exists {
+				bad = append(bad, badReplica{nodeID: nodeID, reason: RepairMissing})
+			}
+		}
+	}
+
+	// Replicas that disagree with the version are rewritten from one that agrees
+	if len(agreeing) > 0 && expected == version.Checksum {
+		fm.scheduleRepairs(fileID, version.VersionID, bad)
+	}
+
+	if len(agreeing) < readQuorum {
+		return nil, fmt.Errorf("read quorum not met: %d of %d replicas agree, need %d",
+			len(agreeing), len(version.Nodes), readQuorum)
+	}
+
+	return agreeing, nil
+}
+
+// resolveVersion looks up a file and the requested version, defaulting to the latest
+func (fm *FileManager) resolveVersion(ctx context.Context, fileID, versionID string) (*metadata.FileMetadata, *metadata.Version, error) {
+	// Get metadata
+	fileMeta, err := fm.metadataStore.GetMetadata(ctx, fileID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("file not found: %w", err)
+	}
+
+	// Determine which version to download
+	if versionID == "" {
+		// Get latest version
+		if len(fileMeta.Versions) == 0 {
+			return nil, nil, fmt.Errorf("no versions available")
+		}
+		return fileMeta, &fileMeta.Versions[len(fileMeta.Versions)-1], nil
+	}
+
+	// Find specific version
+	for i := range fileMeta.Versions {
+		if fileMeta.Versions[i].VersionID == versionID {
+			return fileMeta, &fileMeta.Versions[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("version not found")
+}
+
+// errVersionGone is returned by withVersion when the version no longer exists
+var errVersionGone = errors.New("version no longer exists")
+
+// errPlacementChanged is returned by withVersion when the placement of a
+// version changed while it was being copied; the copies are discarded and
+// the next pass starts from the new placement
+var errPlacementChanged = errors.New("version placement changed while copying")
+
+// placementChange is what a background worker did to a version without the
+// placement lock, to be recorded under it
+type placementChange struct {
+	// Nodes is the new placement of the version, or nil to keep it
+	Nodes []string
+
+	// Written are the nodes the version was copied to. Copies that the
+	// recorded placement doesn't reference are removed again.
+	Written []string
+}
+
+// withVersion calls fn with the current metadata of a version and its file,
+// then records the placement change fn returns. fn copies data without the
+// placement lock, so one large copy doesn't hold up every other placement
+// change. The change is recorded under the lock only if the version still
+// has the placement fn was given; otherwise the new copies are removed and
+// errVersionGone or errPlacementChanged is returned. Once a new placement is
+// recorded, the copies on nodes it dropped are deleted.
+func (fm *FileManager) withVersion(ctx context.Context, fileID, versionID string, fn func(file *metadata.FileMetadata, version metadata.Version) (placementChange, error)) error {
+	fileMeta, version, err := fm.lookupVersion(ctx, fileID, versionID)
+	if err != nil {
+		return err
+	}
+
+	change, err := fn(fileMeta, version)
+	if change.Nodes == nil && len(change.Written) == 0 {
+		return err
+	}
+	if commitErr := fm.commitPlacement(ctx, fileID, version, change); commitErr != nil {
+		return commitErr
+	}
+	return err
+}
+
+// lookupVersion returns the current metadata of a version and its file, or
+// errVersionGone if either was deleted
+func (fm *FileManager) lookupVersion(ctx context.Context, fileID, versionID string) (*metadata.FileMetadata, metadata.Version, error) {
+	fileMeta, err := fm.metadataStore.GetMetadata(ctx, fileID)
+	if errors.Is(err, metadata.ErrNotFound) {
+		return nil, metadata.Version{}, errVersionGone
+	}
+	if err != nil {
+		return nil, metadata.Version{}, err
+	}
+	for _, version := range fileMeta.Versions {
+		if version.VersionID == versionID {
+			return fileMeta, version, nil
+		}
+	}
+	return nil, metadata.Version{}, errVersionGone
+}
+
+// commitPlacement records a placement change made from the snapshot of a
+// version, holding the placement lock
+func (fm *FileManager) commitPlacement(ctx context.Context, fileID string, snapshot metadata.Version, change placementChange) error {
+	fm.placementMu.Lock()
+	defer fm.placementMu.Unlock()
+
+	_, current, err := fm.lookupVersion(ctx, fileID, snapshot.VersionID)
+	if errors.Is(err, errVersionGone) {
+		fm.deleteCopies(fileID, snapshot.VersionID, change.Written, nil)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if change.Nodes == nil {
+		// Copies replaced in place only need their nodes to still be placed
+		for _, nodeID := range change.Written {
+			if !containsNode(current.Nodes, nodeID) {
+				fm.deleteCopies(fileID, snapshot.VersionID, change.Written, current.Nodes)
+				return errPlacementChanged
+			}
+		}
+		return nil
+	}
+	if !slices.Equal(current.Nodes, snapshot.Nodes) {
+		fm.deleteCopies(fileID, snapshot.VersionID, change.Written, current.Nodes)
+		return errPlacementChanged
+	}
+
+	if err := fm.metadataStore.SetVersionNodes(ctx, fileID, snapshot.VersionID, change.Nodes); err != nil {
+		// The new copies are not referenced; remove them and keep the old placement
+		fm.deleteCopies(fileID, snapshot.VersionID, change.Written, current.Nodes)
+		return fmt.Errorf("failed to update version nodes: %w", err)
+	}
+	fm.deleteCopies(fileID, snapshot.VersionID, append(slices.Clone(current.Nodes), change.Written...), change.Nodes)
+	return nil
+}
+
+// deleteCopies deletes the copies of a version on the given nodes that keep
+// doesn't contain
+func (fm *FileManager) deleteCopies(fileID, versionID string, nodeIDs, keep []string) {
+	for _, nodeID := range nodeIDs {
+		if containsNode(keep, nodeID) {
+			continue
+		}
+		node, exists := fm.getNode(nodeID)
+		if !exists {
+			continue
+		}
+		if err := node.DeleteFile(fileID, versionID); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("Failed to delete copy from node %s: %v\n", nodeID, err)
+		}
+	}
+}
+
+// DeleteFile deletes a file and its metadata. It holds the placement lock,
+// so no background worker copies a version to a node the delete misses.
 func (fm *FileManager) DeleteFile(ctx context.Context, fileID string) error {
+	fm.placementMu.Lock()
+	defer fm.placementMu.Unlock()
+
 	// Get metadata to find all nodes
 	fileMeta, err := fm.metadataStore.GetMetadata(ctx, fileID)
 	if err != nil {
@@ -167,7 +686,7 @@ func (fm *FileManager) DeleteFile(ctx context.Context, fileID string) error {
 
 	// Delete from all replica nodes
 	for _, nodeID := range fileMeta.Replicas {
-		node, exists := fm.nodes[nodeID]
+		node, exists := fm.getNode(nodeID)
 		if !exists {
 			continue
 		}
@@ -181,6 +700,133 @@ func (fm *FileManager) DeleteFile(ctx context.Context, fileID string) error {
 	return fm.metadataStore.DeleteMetadata(ctx, fileID)
 }
 
+// DeleteVersion deletes a single version of a file from its nodes and metadata.
+// The only remaining version of a file cannot be deleted; delete the file instead.
+// Like DeleteFile, it holds the placement lock while it deletes.
+func (fm *FileManager) DeleteVersion(ctx context.Context, fileID, versionID string) error {
+	if versionID == "" {
+		return fmt.Errorf("version ID is required")
+	}
+
+	fm.placementMu.Lock()
+	defer fm.placementMu.Unlock()
+
+	fileMeta, version, err := fm.resolveVersion(ctx, fileID, versionID)
+	if err != nil {
+		return err
+	}
+	if len(fileMeta.Versions) == 1 {
+		return fmt.Errorf("cannot delete the only version of a file")
+	}
+
+	// Remove the metadata first so readers never see a version without data
+	if err := fm.metadataStore.RemoveVersion(ctx, fileID, versionID); err != nil {
+		return err
+	}
+
+	for _, nodeID := range version.Nodes {
+		node, exists := fm.getNode(nodeID)
+		if !exists {
+			continue
+		}
+
+		if err := node.DeleteFile(fileID, versionID); err != nil {
+			fmt.Printf("Failed to delete version from node %s: %v\n", nodeID, err)
+		}
+	}
+
+	return nil
+}
+
+// RestoreVersion promotes an old version back to latest by copying its data
+// into a new version, leaving the version history intact. The new version is
+// stored the same way as the old one.
+func (fm *FileManager) RestoreVersion(ctx context.Context, fileID, versionID string) (*UploadResult, error) {
+	if versionID == "" {
+		return nil, fmt.Errorf("version ID is required")
+	}
+
+	reader, err := fm.OpenFile(ctx, fileID, versionID, DownloadOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	opts := UploadOptions{StorageMode: metadata.StorageReplicated}
+	if reader.Version.IsErasureCoded() {
+		opts = UploadOptions{
+			StorageMode:  metadata.StorageErasure,
+			DataShards:   reader.Version.Erasure.DataShards,
+			ParityShards: reader.Version.Erasure.ParityShards,
+		}
+	}
+	opts.Compression = reader.Version.Compression
+	if opts.Compression == "" {
+		// Stored before compression was recorded
+		opts.Compression = compression.None
+	}
+	return fm.UploadNewVersion(ctx, fileID, reader, opts)
+}
+
+// SetRetention sets the retention policy of a file, overriding the global
+// policy. A nil or zero policy reverts the file to the global policy.
+func (fm *FileManager) SetRetention(ctx context.Context, fileID string, policy *metadata.RetentionPolicy) error {
+	if policy != nil && (policy.KeepLast < 0 || policy.MaxAge < 0) {
+		return fmt.Errorf("retention values must not be negative")
+	}
+	if err := fm.metadataStore.SetRetention(ctx, fileID, policy); err != nil {
+		return fmt.Errorf("file not found: %w", err)
+	}
+	return nil
+}
+
+// SetReplicaFactor sets the number of replicas the replicated versions of a
+// file are kept at, overriding the server default, and copies or trims every
+// version to match. Zero reverts the file to the server default. Erasure-coded
+// versions keep one shard per node and are left as they are.
+func (fm *FileManager) SetReplicaFactor(ctx context.Context, fileID string, replicaFactor int) (*metadata.FileMetadata, error) {
+	if replicaFactor < 0 {
+		return nil, fmt.Errorf("replica factor %d must not be negative", replicaFactor)
+	}
+	if err := fm.metadataStore.SetReplicaFactor(ctx, fileID, replicaFactor); err != nil {
+		return nil, fmt.Errorf("file not found: %w", err)
+	}
+
+	fileMeta, err := fm.metadataStore.GetMetadata(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %w", err)
+	}
+
+	failed := 0
+	var lastErr error
+	for _, version := range fileMeta.Versions {
+		err := fm.enforceReplicaFactor(ctx, fileID, version.VersionID)
+		if err != nil && !errors.Is(err, errVersionGone) {
+			failed++
+			lastErr = fmt.Errorf("version %s: %w", version.VersionID, err)
+		}
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("failed to re-replicate %d of %d versions: %w", failed, len(fileMeta.Versions), lastErr)
+	}
+
+	return fm.metadataStore.GetMetadata(ctx, fileID)
+}
+
+// replicaFactorOf returns the number of replicas a file is kept at: its own
+// replica factor, or the server default if it has none
+func (fm *FileManager) replicaFactorOf(file *metadata.FileMetadata) int {
+	if file != nil && file.ReplicaFactor > 0 {
+		return file.ReplicaFactor
+	}
+	return fm.replicaFactor
+}
+
+// replicaOwners returns the ring nodes a file's replicated versions belong on
+func (fm *FileManager) replicaOwners(file *metadata.FileMetadata) []string {
+	return fm.hashRing.GetNodesN(file.FileID, fm.replicaFactorOf(file))
+}
+
 // GetFileInfo retrieves file metadata
 func (fm *FileManager) GetFileInfo(ctx context.Context, fileID string) (*metadata.FileMetadata, error) {
 	return fm.metadataStore.GetMetadata(ctx, fileID)
@@ -191,16 +837,41 @@ func (fm *FileManager) ListFiles(ctx context.Context, page, pageSize int32) ([]*
 	return fm.metadataStore.ListMetadata(ctx, page, pageSize)
 }
 
+// forEachFile calls fn for the metadata of every file, one page at a time
+func (fm *FileManager) forEachFile(ctx context.Context, fn func(*metadata.FileMetadata) error) error {
+	const pageSize = 100
+
+	for page := int32(1); ; page++ {
+		files, total, err := fm.metadataStore.ListMetadata(ctx, page, pageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(file); err != nil {
+				return err
+			}
+		}
+
+		if len(files) < pageSize || int64(page)*pageSize >= total {
+			return nil
+		}
+	}
+}
+
 // GetVersion retrieves a specific version of a file
 func (fm *FileManager) GetVersion(ctx context.Context, fileID, versionID string) ([]byte, error) {
-	data, _, err := fm.DownloadFile(ctx, fileID, versionID)
+	data, _, err := fm.DownloadFile(ctx, fileID, versionID, DownloadOptions{})
 	return data, err
 }
 
 // cleanupFailedUpload removes file data from nodes on upload failure
 func (fm *FileManager) cleanupFailedUpload(fileID, versionID string, nodeIDs []string) {
 	for _, nodeID := range nodeIDs {
-		node, exists := fm.nodes[nodeID]
+		node, exists := fm.getNode(nodeID)
 		if !exists {
 			continue
 		}
@@ -208,11 +879,29 @@ func (fm *FileManager) cleanupFailedUpload(fileID, versionID string, nodeIDs []s
 	}
 }
 
+// mergeNodes returns the union of two node lists, preserving order
+func mergeNodes(nodes, extra []string) []string {
+	merged := append([]string(nil), nodes...)
+	for _, nodeID := range extra {
+		found := false
+		for _, existing := range merged {
+			if existing == nodeID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, nodeID)
+		}
+	}
+	return merged
+}
+
 // HealthCheck checks the health of all storage nodes
 func (fm *FileManager) HealthCheck() map[string]bool {
 	health := make(map[string]bool)
 	
-	for nodeID, node := range fm.nodes {
+	for nodeID, node := range fm.snapshotNodes() {
 		_, err := node.GetStorageSize()
 		health[nodeID] = (err == nil)
 	}
@@ -222,5 +911,27 @@ func (fm *FileManager) HealthCheck() map[string]bool {
 
 // GetNodeCount returns the number of active storage nodes
 func (fm *FileManager) GetNodeCount() int {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
 	return len(fm.nodes)
 }
+
+// getNode returns a registered storage node
+func (fm *FileManager) getNode(nodeID string) (NodeClient, bool) {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	node, exists := fm.nodes[nodeID]
+	return node, exists
+}
+
+// snapshotNodes returns a copy of the registered storage nodes that is safe
+// to iterate while nodes are registered or removed
+func (fm *FileManager) snapshotNodes() map[string]NodeClient {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	nodes := make(map[string]NodeClient, len(fm.nodes))
+	for nodeID, node := range fm.nodes {
+		nodes[nodeID] = node
+	}
+	return nodes
+}
@@ -1,17 +1,29 @@
 package manager
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/yashlad/distributed-file-store/internal/metadata"
+	"github.com/yashlad/distributed-file-store/internal/storage"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // MockMetadataStore implements a simple in-memory metadata store for testing
 type MockMetadataStore struct {
+	mu    sync.Mutex
 	files map[string]*metadata.FileMetadata
+	hints []metadata.Hint
 }
 
 func NewMockMetadataStore() *MockMetadataStore {
@@ -21,6 +33,9 @@ func NewMockMetadataStore() *MockMetadataStore {
 }
 
 func (m *MockMetadataStore) SaveMetadata(ctx context.Context, meta *metadata.FileMetadata) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	meta.UpdatedAt = time.Now()
 	if meta.CreatedAt.IsZero() {
 		meta.CreatedAt = time.Now()
@@ -28,55 +43,213 @@ func (m *MockMetadataStore) SaveMetadata(ctx context.Context, meta *metadata.Fil
 	if meta.ID.IsZero() {
 		meta.ID = primitive.NewObjectID()
 	}
-	m.files[meta.FileID] = meta
+	m.files[meta.FileID] = copyMetadata(meta)
 	return nil
 }
 
 func (m *MockMetadataStore) GetMetadata(ctx context.Context, fileID string) (*metadata.FileMetadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	meta, exists := m.files[fileID]
 	if !exists {
 		return nil, ErrFileNotFound
 	}
-	return meta, nil
+	return copyMetadata(meta), nil
 }
 
 func (m *MockMetadataStore) DeleteMetadata(ctx context.Context, fileID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	delete(m.files, fileID)
 	return nil
 }
 
 func (m *MockMetadataStore) ListMetadata(ctx context.Context, page, pageSize int32) ([]*metadata.FileMetadata, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	files := make([]*metadata.FileMetadata, 0, len(m.files))
 	for _, file := range m.files {
-		files = append(files, file)
+		files = append(files, copyMetadata(file))
 	}
-	return files, int64(len(files)), nil
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CreatedAt.After(files[j].CreatedAt)
+	})
+
+	total := int64(len(files))
+	start := int((page - 1) * pageSize)
+	if start > len(files) {
+		start = len(files)
+	}
+	end := start + int(pageSize)
+	if end > len(files) {
+		end = len(files)
+	}
+	return files[start:end], total, nil
 }
 
 func (m *MockMetadataStore) AddVersion(ctx context.Context, fileID string, version metadata.Version) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	meta, exists := m.files[fileID]
 	if !exists {
 		return ErrFileNotFound
 	}
 	meta.Versions = append(meta.Versions, version)
+	meta.Size = version.Size
+	meta.Replicas = mergeNodes(meta.Replicas, version.Nodes)
 	meta.UpdatedAt = time.Now()
 	return nil
 }
 
-func (m *MockMetadataStore) Close(ctx context.Context) error {
+func (m *MockMetadataStore) RemoveVersion(ctx context.Context, fileID, versionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meta, exists := m.files[fileID]
+	if !exists {
+		return ErrFileNotFound
+	}
+	versions := meta.Versions[:0]
+	for _, v := range meta.Versions {
+		if v.VersionID != versionID {
+			versions = append(versions, v)
+		}
+	}
+	meta.Versions = versions
+	meta.Size = 0
+	if len(versions) > 0 {
+		meta.Size = versions[len(versions)-1].Size
+	}
+	meta.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MockMetadataStore) SetVersionNodes(ctx context.Context, fileID, versionID string, nodes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meta, exists := m.files[fileID]
+	if !exists {
+		return ErrFileNotFound
+	}
+	var replicas []string
+	for i := range meta.Versions {
+		if meta.Versions[i].VersionID == versionID {
+			meta.Versions[i].Nodes = append([]string(nil), nodes...)
+		}
+		replicas = mergeNodes(replicas, meta.Versions[i].Nodes)
+	}
+	meta.Replicas = replicas
+	meta.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MockMetadataStore) SetVersionEncryption(ctx context.Context, fileID, versionID string, encryption *metadata.Encryption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meta, exists := m.files[fileID]
+	if !exists {
+		return ErrFileNotFound
+	}
+	for i := range meta.Versions {
+		if meta.Versions[i].VersionID == versionID {
+			meta.Versions[i].Encryption = encryption
+			return nil
+		}
+	}
+	return ErrFileNotFound
+}
+
+func (m *MockMetadataStore) SetRetention(ctx context.Context, fileID string, policy *metadata.RetentionPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meta, exists := m.files[fileID]
+	if !exists {
+		return ErrFileNotFound
+	}
+	meta.Retention = nil
+	if policy != nil && !policy.IsZero() {
+		p := *policy
+		meta.Retention = &p
+	}
+	return nil
+}
+
+func (m *MockMetadataStore) SetReplicaFactor(ctx context.Context, fileID string, replicaFactor int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meta, exists := m.files[fileID]
+	if !exists {
+		return ErrFileNotFound
+	}
+	meta.ReplicaFactor = replicaFactor
 	return nil
 }
 
-var ErrFileNotFound = &MockError{"file not found"}
+func (m *MockMetadataStore) SaveHint(ctx context.Context, hint metadata.Hint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-type MockError struct {
-	msg string
+	for i, h := range m.hints {
+		if h.FileID == hint.FileID && h.VersionID == hint.VersionID && h.Owner == hint.Owner {
+			m.hints[i] = hint
+			return nil
+		}
+	}
+	m.hints = append(m.hints, hint)
+	return nil
 }
 
-func (e *MockError) Error() string {
-	return e.msg
+func (m *MockMetadataStore) ListHints(ctx context.Context) ([]metadata.Hint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]metadata.Hint(nil), m.hints...), nil
 }
 
+func (m *MockMetadataStore) DeleteHint(ctx context.Context, hint metadata.Hint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hints := m.hints[:0]
+	for _, h := range m.hints {
+		if h.FileID != hint.FileID || h.VersionID != hint.VersionID || h.Owner != hint.Owner {
+			hints = append(hints, h)
+		}
+	}
+	m.hints = hints
+	return nil
+}
+
+// copyMetadata returns a deep copy so callers can't mutate stored state,
+// matching the behaviour of a real database
+func copyMetadata(meta *metadata.FileMetadata) *metadata.FileMetadata {
+	c := *meta
+	c.Replicas = append([]string(nil), meta.Replicas...)
+	c.Versions = make([]metadata.Version, len(meta.Versions))
+	for i, v := range meta.Versions {
+		v.Nodes = append([]string(nil), v.Nodes...)
+		c.Versions[i] = v
+	}
+	if meta.Retention != nil {
+		p := *meta.Retention
+		c.Retention = &p
+	}
+	return &c
+}
+
+func (m *MockMetadataStore) Close(ctx context.Context) error {
+	return nil
+}
+
+var ErrFileNotFound = metadata.ErrNotFound
+
 func setupTestFileManager(t *testing.T) *FileManager {
 	mockStore := NewMockMetadataStore()
 	fm := NewFileManager(mockStore, 2)
@@ -86,14 +259,23 @@ func setupTestFileManager(t *testing.T) *FileManager {
 	for i := 1; i <= 3; i++ {
 		nodeID := "test-node-" + string(rune('0'+i))
 		storagePath := tempDir + "/" + nodeID
-		if err := fm.RegisterNode(nodeID, storagePath); err != nil {
+		if err := fm.RegisterNode(nodeID, storage.BackendConfig{Path: storagePath}); err != nil {
 			t.Fatalf("Failed to register node: %v", err)
 		}
 	}
+	// Let background repairs finish before the storage is removed
+	t.Cleanup(fm.repairWG.Wait)
 
 	return fm
 }
 
+// localNode returns a node the test registered in process, for tests that
+// reach into its storage
+func localNode(fm *FileManager, nodeID string) *storage.Node {
+	node, _ := fm.nodes[nodeID].(*storage.Node)
+	return node
+}
+
 func TestNewFileManager(t *testing.T) {
 	mockStore := NewMockMetadataStore()
 
@@ -127,7 +309,7 @@ func TestRegisterNode(t *testing.T) {
 
 	t.Run("register new node", func(t *testing.T) {
 		tempDir := t.TempDir()
-		err := fm.RegisterNode("new-node", tempDir+"/new-node")
+		err := fm.RegisterNode("new-node", storage.BackendConfig{Path: tempDir + "/new-node"})
 		if err != nil {
 			t.Errorf("RegisterNode failed: %v", err)
 		}
@@ -135,6 +317,51 @@ func TestRegisterNode(t *testing.T) {
 			t.Errorf("node count = %d, want 4", fm.GetNodeCount())
 		}
 	})
+
+	t.Run("mixed backends", func(t *testing.T) {
+		mixed := NewFileManager(NewMockMetadataStore(), 3)
+		mixed.RegisterNode("disk-node", storage.BackendConfig{Path: t.TempDir()})
+		mixed.RegisterNode("memory-node-1", storage.BackendConfig{Type: storage.BackendMemory})
+		mixed.RegisterNode("memory-node-2", storage.BackendConfig{Type: storage.BackendMemory})
+
+		ctx := context.Background()
+		data := []byte("replicated across backends")
+		meta, err := mixed.UploadFile(ctx, "mixed.txt", data, "text/plain")
+		if err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+		for _, nodeID := range meta.Versions[0].Nodes {
+			stored, err := mixed.nodes[nodeID].RetrieveFile(meta.FileID, meta.Versions[0].VersionID, nil)
+			if err != nil || !bytes.Equal(stored, data) {
+				t.Errorf("replica on %s = %q, %v", nodeID, stored, err)
+			}
+		}
+	})
+
+	t.Run("re-register a node", func(t *testing.T) {
+		node := localNode(fm, "new-node")
+		if err := fm.AddNode("new-node", node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+		fm.UnregisterNode("new-node")
+		for i := 0; i < 100; i++ {
+			for _, nodeID := range fm.hashRing.GetNodes(fmt.Sprintf("key-%d", i)) {
+				if nodeID == "new-node" || nodeID == "" {
+					t.Fatalf("ring still places keys on %q after unregistering", nodeID)
+				}
+			}
+		}
+		fm.AddNode("new-node", node)
+	})
+
+	t.Run("invalid backend", func(t *testing.T) {
+		if err := fm.RegisterNode("bad-node", storage.BackendConfig{Type: "tape"}); err == nil {
+			t.Error("expected error for an unknown backend")
+		}
+		if fm.GetNodeCount() != 4 {
+			t.Errorf("node count = %d after a failed registration, want 4", fm.GetNodeCount())
+		}
+	})
 }
 
 func TestUnregisterNode(t *testing.T) {
@@ -212,6 +439,263 @@ func TestUploadFile(t *testing.T) {
 	})
 }
 
+func TestUploadStream(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+
+	t.Run("upload from reader", func(t *testing.T) {
+		data := bytes.Repeat([]byte("0123456789"), 300*1024) // 3MB
+		meta, err := fm.UploadStream(ctx, "stream.bin", bytes.NewReader(data), "application/octet-stream", UploadOptions{})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+
+		if meta.Size != int64(len(data)) {
+			t.Errorf("Size = %d, want %d", meta.Size, len(data))
+		}
+		sum := sha256.Sum256(data)
+		if meta.Versions[0].Checksum != hex.EncodeToString(sum[:]) {
+			t.Error("version checksum does not match data")
+		}
+		if len(meta.Replicas) != fm.replicaFactor {
+			t.Errorf("replicas = %d, want %d", len(meta.Replicas), fm.replicaFactor)
+		}
+
+		for _, nodeID := range meta.Replicas {
+			stored, err := fm.nodes[nodeID].RetrieveFile(meta.FileID, meta.Versions[0].VersionID, nil)
+			if err != nil {
+				t.Fatalf("RetrieveFile on %s failed: %v", nodeID, err)
+			}
+			if !bytes.Equal(stored, data) {
+				t.Errorf("replica %s does not match original", nodeID)
+			}
+		}
+	})
+
+	t.Run("reports each replica", func(t *testing.T) {
+		result, err := fm.UploadStream(ctx, "report.txt", bytes.NewReader([]byte("replicated")), "text/plain", UploadOptions{})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+
+		if len(result.ReplicaResults) != fm.replicaFactor {
+			t.Fatalf("replica results = %d, want %d", len(result.ReplicaResults), fm.replicaFactor)
+		}
+		for _, replica := range result.ReplicaResults {
+			if replica.Err != nil {
+				t.Errorf("replica %s failed: %v", replica.NodeID, replica.Err)
+			}
+		}
+		fm.DeleteFile(ctx, result.FileID)
+	})
+
+	t.Run("reader error cleans up replicas", func(t *testing.T) {
+		reader := io.MultiReader(bytes.NewReader(make([]byte, 1024*1024)), iotest.ErrReader(errors.New("client went away")))
+		if _, err := fm.UploadStream(ctx, "broken.bin", reader, "application/octet-stream", UploadOptions{}); err == nil {
+			t.Fatal("expected error from failing reader")
+		}
+
+		_, total, _ := fm.ListFiles(ctx, 1, 10)
+		if total != 1 {
+			t.Errorf("total = %d, want 1", total)
+		}
+		for nodeID, node := range fm.nodes {
+			files, _ := node.ListFiles()
+			if len(files) > 1 {
+				t.Errorf("node %s has %d files, want at most 1", nodeID, len(files))
+			}
+		}
+	})
+
+	t.Run("replicas time out independently of the request", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		fm.SetReplicaTimeout(50 * time.Millisecond)
+
+		// Hold back the end of the upload until every replica has timed out
+		pr, pw := io.Pipe()
+		go func() {
+			pw.Write([]byte("partial"))
+			time.Sleep(200 * time.Millisecond)
+			pw.Close()
+		}()
+
+		_, err := fm.UploadStream(ctx, "slow.txt", pr, "text/plain", UploadOptions{})
+		var quorumErr *QuorumError
+		if !errors.As(err, &quorumErr) {
+			t.Fatalf("expected QuorumError, got %v", err)
+		}
+		for _, replica := range quorumErr.Replicas {
+			if !errors.Is(replica.Err, context.DeadlineExceeded) {
+				t.Errorf("replica %s error = %v, want deadline exceeded", replica.NodeID, replica.Err)
+			}
+		}
+		for nodeID, node := range fm.nodes {
+			if files, _ := node.ListFiles(); len(files) != 0 {
+				t.Errorf("node %s kept %d files from timed out upload", nodeID, len(files))
+			}
+		}
+	})
+}
+
+func TestUploadNewVersion(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+
+	t.Run("add second version", func(t *testing.T) {
+		meta, _ := fm.UploadFile(ctx, "doc.txt", []byte("first draft"), "text/plain")
+		firstVersion := meta.Versions[0].VersionID
+
+		updated, err := fm.UploadNewVersion(ctx, meta.FileID, bytes.NewReader([]byte("second draft, longer")), UploadOptions{})
+		if err != nil {
+			t.Fatalf("UploadNewVersion failed: %v", err)
+		}
+
+		if len(updated.Versions) != 2 {
+			t.Fatalf("Versions count = %d, want 2", len(updated.Versions))
+		}
+		latest := updated.Versions[1]
+		if latest.VersionID == firstVersion {
+			t.Error("new version reused the old version ID")
+		}
+		if updated.Size != int64(len("second draft, longer")) {
+			t.Errorf("Size = %d, want %d", updated.Size, len("second draft, longer"))
+		}
+
+		// Stored metadata reflects the new version too
+		stored, _ := fm.GetFileInfo(ctx, meta.FileID)
+		if len(stored.Versions) != 2 || stored.Size != updated.Size {
+			t.Error("stored metadata was not updated")
+		}
+
+		// Latest download returns the new data, old version is still available
+		data, _, err := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{})
+		if err != nil || string(data) != "second draft, longer" {
+			t.Errorf("latest download = %q, %v", data, err)
+		}
+		data, _, err = fm.DownloadFile(ctx, meta.FileID, firstVersion, DownloadOptions{})
+		if err != nil || string(data) != "first draft" {
+			t.Errorf("first version download = %q, %v", data, err)
+		}
+	})
+
+	t.Run("unknown file", func(t *testing.T) {
+		_, err := fm.UploadNewVersion(ctx, "non-existing-id", bytes.NewReader([]byte("data")), UploadOptions{})
+		if err == nil {
+			t.Error("expected error for non-existing file")
+		}
+	})
+}
+
+func TestDeleteVersion(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+
+	t.Run("delete old version", func(t *testing.T) {
+		meta, _ := fm.UploadFile(ctx, "doc.txt", []byte("v1"), "text/plain")
+		oldVersion := meta.Versions[0]
+		fm.UploadNewVersion(ctx, meta.FileID, bytes.NewReader([]byte("v2")), UploadOptions{})
+
+		if err := fm.DeleteVersion(ctx, meta.FileID, oldVersion.VersionID); err != nil {
+			t.Fatalf("DeleteVersion failed: %v", err)
+		}
+
+		info, _ := fm.GetFileInfo(ctx, meta.FileID)
+		if len(info.Versions) != 1 {
+			t.Errorf("Versions count = %d, want 1", len(info.Versions))
+		}
+		for _, nodeID := range oldVersion.Nodes {
+			if fm.nodes[nodeID].FileExists(meta.FileID, oldVersion.VersionID) {
+				t.Errorf("version data still on node %s", nodeID)
+			}
+		}
+	})
+
+	t.Run("delete latest version falls back to previous", func(t *testing.T) {
+		meta, _ := fm.UploadFile(ctx, "doc.txt", []byte("v1"), "text/plain")
+		updated, _ := fm.UploadNewVersion(ctx, meta.FileID, bytes.NewReader([]byte("version two")), UploadOptions{})
+		latest := updated.Versions[1].VersionID
+
+		if err := fm.DeleteVersion(ctx, meta.FileID, latest); err != nil {
+			t.Fatalf("DeleteVersion failed: %v", err)
+		}
+
+		info, _ := fm.GetFileInfo(ctx, meta.FileID)
+		if info.Size != 2 {
+			t.Errorf("Size = %d, want 2", info.Size)
+		}
+		data, _, _ := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{})
+		if string(data) != "v1" {
+			t.Errorf("latest data = %q, want v1", data)
+		}
+	})
+
+	t.Run("refuse to delete only version", func(t *testing.T) {
+		meta, _ := fm.UploadFile(ctx, "only.txt", []byte("only"), "text/plain")
+		if err := fm.DeleteVersion(ctx, meta.FileID, meta.Versions[0].VersionID); err == nil {
+			t.Error("expected error when deleting the only version")
+		}
+	})
+
+	t.Run("delete racing a move leaves no copies", func(t *testing.T) {
+		meta, _ := fm.UploadFile(ctx, "moving.txt", []byte("v1"), "text/plain")
+		oldVersion := meta.Versions[0]
+		fm.UploadNewVersion(ctx, meta.FileID, bytes.NewReader([]byte("v2")), UploadOptions{})
+
+		var target string
+		for nodeID := range fm.nodes {
+			if !containsNode(oldVersion.Nodes, nodeID) {
+				target = nodeID
+			}
+		}
+
+		err := fm.withVersion(ctx, meta.FileID, oldVersion.VersionID, func(file *metadata.FileMetadata, version metadata.Version) (placementChange, error) {
+			if _, _, err := fm.copyReplica(ctx, file.FileID, version, version.Nodes, target, nil); err != nil {
+				return placementChange{}, err
+			}
+			// The delete lands after the copy but before the move is committed
+			if err := fm.DeleteVersion(ctx, meta.FileID, oldVersion.VersionID); err != nil {
+				t.Fatalf("DeleteVersion failed: %v", err)
+			}
+			return placementChange{Nodes: append(version.Nodes, target), Written: []string{target}}, nil
+		})
+		if !errors.Is(err, errVersionGone) {
+			t.Fatalf("move error = %v, want errVersionGone", err)
+		}
+
+		for nodeID, node := range fm.nodes {
+			if node.FileExists(meta.FileID, oldVersion.VersionID) {
+				t.Errorf("version data left on node %s", nodeID)
+			}
+		}
+	})
+}
+
+func TestRestoreVersion(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+
+	meta, _ := fm.UploadFile(ctx, "doc.txt", []byte("original"), "text/plain")
+	original := meta.Versions[0].VersionID
+	fm.UploadNewVersion(ctx, meta.FileID, bytes.NewReader([]byte("bad edit")), UploadOptions{})
+
+	restored, err := fm.RestoreVersion(ctx, meta.FileID, original)
+	if err != nil {
+		t.Fatalf("RestoreVersion failed: %v", err)
+	}
+
+	if len(restored.Versions) != 3 {
+		t.Errorf("Versions count = %d, want 3", len(restored.Versions))
+	}
+	if restored.Versions[2].Checksum != restored.Versions[0].Checksum {
+		t.Error("restored version checksum does not match original")
+	}
+
+	data, _, _ := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{})
+	if string(data) != "original" {
+		t.Errorf("latest data = %q, want original", data)
+	}
+}
+
 func TestDownloadFile(t *testing.T) {
 	fm := setupTestFileManager(t)
 	ctx := context.Background()
@@ -220,7 +704,7 @@ func TestDownloadFile(t *testing.T) {
 		originalData := []byte("Download test data")
 		meta, _ := fm.UploadFile(ctx, "download.txt", originalData, "text/plain")
 
-		downloadedData, _, err := fm.DownloadFile(ctx, meta.FileID, "")
+		downloadedData, _, err := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{})
 		if err != nil {
 			t.Fatalf("DownloadFile failed: %v", err)
 		}
@@ -231,7 +715,7 @@ func TestDownloadFile(t *testing.T) {
 	})
 
 	t.Run("download non-existing file", func(t *testing.T) {
-		_, _, err := fm.DownloadFile(ctx, "non-existing-id", "")
+		_, _, err := fm.DownloadFile(ctx, "non-existing-id", "", DownloadOptions{})
 		if err == nil {
 			t.Error("expected error for non-existing file")
 		}
@@ -242,7 +726,7 @@ func TestDownloadFile(t *testing.T) {
 		meta, _ := fm.UploadFile(ctx, "version.txt", data, "text/plain")
 		versionID := meta.Versions[0].VersionID
 
-		downloadedData, _, err := fm.DownloadFile(ctx, meta.FileID, versionID)
+		downloadedData, _, err := fm.DownloadFile(ctx, meta.FileID, versionID, DownloadOptions{})
 		if err != nil {
 			t.Fatalf("DownloadFile with version failed: %v", err)
 		}
@@ -252,6 +736,63 @@ func TestDownloadFile(t *testing.T) {
 	})
 }
 
+func TestOpenFile(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+
+	t.Run("stream latest version", func(t *testing.T) {
+		data := bytes.Repeat([]byte("stream me "), 200*1024)
+		meta, _ := fm.UploadFile(ctx, "open.bin", data, "application/octet-stream")
+
+		reader, err := fm.OpenFile(ctx, meta.FileID, "", DownloadOptions{})
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		defer reader.Close()
+
+		if reader.Version.VersionID != meta.Versions[0].VersionID {
+			t.Error("opened wrong version")
+		}
+		got, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("reading stream failed: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Error("streamed data does not match original")
+		}
+	})
+
+	t.Run("fails over to another replica", func(t *testing.T) {
+		data := []byte("failover data")
+		meta, _ := fm.UploadFile(ctx, "failover.txt", data, "text/plain")
+
+		// Remove the primary copy
+		primary := meta.Versions[0].Nodes[0]
+		fm.nodes[primary].DeleteAllVersions(meta.FileID)
+
+		reader, err := fm.OpenFile(ctx, meta.FileID, "", DownloadOptions{})
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		defer reader.Close()
+
+		if reader.NodeID == primary {
+			t.Error("served from the missing replica")
+		}
+		got, _ := io.ReadAll(reader)
+		if !bytes.Equal(got, data) {
+			t.Error("streamed data does not match original")
+		}
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		meta, _ := fm.UploadFile(ctx, "v.txt", []byte("v"), "text/plain")
+		if _, err := fm.OpenFile(ctx, meta.FileID, "no-such-version", DownloadOptions{}); err == nil {
+			t.Error("expected error for unknown version")
+		}
+	})
+}
+
 func TestDeleteFile(t *testing.T) {
 	fm := setupTestFileManager(t)
 	ctx := context.Background()
@@ -266,7 +807,7 @@ func TestDeleteFile(t *testing.T) {
 		}
 
 		// Try to download - should fail
-		_, _, err = fm.DownloadFile(ctx, meta.FileID, "")
+		_, _, err = fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{})
 		if err == nil {
 			t.Error("file still exists after deletion")
 		}
@@ -402,8 +943,8 @@ func BenchmarkUploadFile(b *testing.B) {
 	mockStore := NewMockMetadataStore()
 	fm := NewFileManager(mockStore, 2)
 	tempDir := b.TempDir()
-	fm.RegisterNode("bench-node-1", tempDir+"/node1")
-	fm.RegisterNode("bench-node-2", tempDir+"/node2")
+	fm.RegisterNode("bench-node-1", storage.BackendConfig{Path: tempDir + "/node1"})
+	fm.RegisterNode("bench-node-2", storage.BackendConfig{Path: tempDir + "/node2"})
 
 	data := make([]byte, 1024*1024) // 1MB
 	ctx := context.Background()
@@ -418,8 +959,8 @@ func BenchmarkDownloadFile(b *testing.B) {
 	mockStore := NewMockMetadataStore()
 	fm := NewFileManager(mockStore, 2)
 	tempDir := b.TempDir()
-	fm.RegisterNode("bench-node-1", tempDir+"/node1")
-	fm.RegisterNode("bench-node-2", tempDir+"/node2")
+	fm.RegisterNode("bench-node-1", storage.BackendConfig{Path: tempDir + "/node1"})
+	fm.RegisterNode("bench-node-2", storage.BackendConfig{Path: tempDir + "/node2"})
 
 	data := make([]byte, 1024*1024) // 1MB
 	ctx := context.Background()
@@ -427,6 +968,90 @@ func BenchmarkDownloadFile(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		fm.DownloadFile(ctx, meta.FileID, "")
+		fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{})
+	}
+}
+
+func TestSetReplicaFactor(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+
+	meta, err := fm.UploadFile(ctx, "f.txt", []byte("replicated data"), "text/plain")
+	if err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
 	}
+	if _, err := fm.UploadNewVersion(ctx, meta.FileID, bytes.NewReader([]byte("second version")), UploadOptions{}); err != nil {
+		t.Fatalf("UploadNewVersion failed: %v", err)
+	}
+
+	checkPlacement := func(t *testing.T, want int) {
+		t.Helper()
+		info, err := fm.GetFileInfo(ctx, meta.FileID)
+		if err != nil {
+			t.Fatalf("GetFileInfo failed: %v", err)
+		}
+		for _, version := range info.Versions {
+			if len(version.Nodes) != want {
+				t.Errorf("version %s on %v, want %d nodes", version.VersionID, version.Nodes, want)
+			}
+			for _, nodeID := range version.Nodes {
+				if _, err := fm.nodes[nodeID].Checksum(meta.FileID, version.VersionID); err != nil {
+					t.Errorf("version %s missing on %s: %v", version.VersionID, nodeID, err)
+				}
+			}
+		}
+
+		// The background workers must agree with the new placement
+		moves, err := NewRebalancer(fm, 0).Plan(ctx)
+		if err != nil || len(moves) != 0 {
+			t.Errorf("rebalance plan = %+v, err %v, want no moves", moves, err)
+		}
+		report, err := fm.Fsck(ctx, FsckOptions{})
+		if err != nil || len(report.Issues) != 0 {
+			t.Errorf("fsck issues = %+v, err %v, want none", report.Issues, err)
+		}
+	}
+
+	t.Run("raise", func(t *testing.T) {
+		result, err := fm.SetReplicaFactor(ctx, meta.FileID, 3)
+		if err != nil {
+			t.Fatalf("SetReplicaFactor failed: %v", err)
+		}
+		if result.ReplicaFactor != 3 || len(result.Replicas) != 3 {
+			t.Errorf("replica factor = %d on %v, want 3 replicas", result.ReplicaFactor, result.Replicas)
+		}
+		checkPlacement(t, 3)
+	})
+
+	t.Run("lower", func(t *testing.T) {
+		if _, err := fm.SetReplicaFactor(ctx, meta.FileID, 1); err != nil {
+			t.Fatalf("SetReplicaFactor failed: %v", err)
+		}
+		checkPlacement(t, 1)
+
+		downloaded, _, err := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{})
+		if err != nil || string(downloaded) != "second version" {
+			t.Errorf("DownloadFile = %q, %v", downloaded, err)
+		}
+	})
+
+	t.Run("revert to default", func(t *testing.T) {
+		result, err := fm.SetReplicaFactor(ctx, meta.FileID, 0)
+		if err != nil {
+			t.Fatalf("SetReplicaFactor failed: %v", err)
+		}
+		if result.ReplicaFactor != 0 {
+			t.Errorf("replica factor = %d, want 0", result.ReplicaFactor)
+		}
+		checkPlacement(t, 2)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := fm.SetReplicaFactor(ctx, meta.FileID, -1); err == nil {
+			t.Error("expected error for negative replica factor")
+		}
+		if _, err := fm.SetReplicaFactor(ctx, "missing", 2); err == nil {
+			t.Error("expected error for missing file")
+		}
+	})
 }
@@ -0,0 +1,288 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// Kinds of inconsistency found by fsck
+const (
+	// FsckDangling is a version whose metadata names a node that does not
+	// hold a copy of it or is no longer registered
+	FsckDangling = "dangling"
+
+	// FsckDiverged is a copy whose checksum differs from its version's
+	FsckDiverged = "diverged"
+
+	// FsckUnderReplicated is a version with fewer healthy copies than the
+	// replica factor
+	FsckUnderReplicated = "under-replicated"
+
+	// FsckOverReplicated is a version placed on more nodes than the replica
+	// factor
+	FsckOverReplicated = "over-replicated"
+
+	// FsckOrphan is a copy on a node that no version's metadata refers to
+	FsckOrphan = "orphan"
+)
+
+// FsckOptions controls a consistency check
+type FsckOptions struct {
+	// Repair fixes what can be fixed instead of only reporting it
+	Repair bool
+}
+
+// FsckIssue is one inconsistency between the metadata and the nodes
+type FsckIssue struct {
+	Kind      string
+	FileID    string
+	VersionID string
+	NodeID    string
+	Detail    string
+	Repaired  bool
+	Error     string
+}
+
+// FsckReport is the result of a consistency check
+type FsckReport struct {
+	Repair          bool
+	Started         time.Time
+	Finished        time.Time
+	NodesChecked    int
+	FilesChecked    int
+	VersionsChecked int
+	ObjectsChecked  int
+	Issues          []FsckIssue
+	Repaired        int
+	Failed          int
+}
+
+// Fsck cross-checks the metadata of every file against the versions stored
+// on every node and reports dangling references, orphaned copies and
+// versions with too few or too many replicas for their file's replica factor.
+// With Repair set, versions are re-replicated or trimmed to the replica factor
+// and orphans older than the grace period are deleted.
+func (fm *FileManager) Fsck(ctx context.Context, opts FsckOptions) (*FsckReport, error) {
+	report := &FsckReport{Repair: opts.Repair, Started: time.Now()}
+
+	// Take the inventories first: a copy written after this is not in them
+	// and can't be mistaken for an orphan
+	nodes := fm.snapshotNodes()
+	stored := make(map[string]map[string]storage.InventoryEntry, len(nodes))
+	for nodeID, node := range nodes {
+		inventory, err := node.Inventory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files on node %s: %w", nodeID, err)
+		}
+		copies := make(map[string]storage.InventoryEntry, len(inventory))
+		for _, entry := range inventory {
+			copies[entry.FileID+"/"+entry.VersionID] = entry
+		}
+		stored[nodeID] = copies
+		report.NodesChecked++
+		report.ObjectsChecked += len(inventory)
+	}
+
+	nodeCount := fm.GetNodeCount()
+
+	files := make(map[string]bool)
+	versions := make(map[string]bool)
+	referenced := make(map[string]bool)
+	err := fm.forEachFile(ctx, func(file *metadata.FileMetadata) error {
+		files[file.FileID] = true
+		report.FilesChecked++
+		want := min(fm.replicaFactorOf(file), nodeCount)
+
+		for _, version := range file.Versions {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			report.VersionsChecked++
+
+			// Every shard of an erasure-coded version needs a node of its own
+			wantVersion := want
+			if version.IsErasureCoded() {
+				wantVersion = len(version.Nodes)
+			}
+
+			key := file.FileID + "/" + version.VersionID
+			versions[key] = true
+			var issues []FsckIssue
+			healthy := 0
+			for _, nodeID := range version.Nodes {
+				referenced[key+"/"+nodeID] = true
+
+				issue := FsckIssue{FileID: file.FileID, VersionID: version.VersionID, NodeID: nodeID}
+				copies, registered := stored[nodeID]
+				entry, ok := copies[key]
+				switch {
+				case !registered:
+					issue.Kind = FsckDangling
+					issue.Detail = "node not registered"
+				case !ok:
+					issue.Kind = FsckDangling
+					issue.Detail = "no copy on node"
+				case version.ChecksumOn(nodeID) != "" && entry.Checksum != version.ChecksumOn(nodeID):
+					issue.Kind = FsckDiverged
+					issue.Detail = fmt.Sprintf("checksum %q, want %q", entry.Checksum, version.ChecksumOn(nodeID))
+				default:
+					healthy++
+					continue
+				}
+				issues = append(issues, issue)
+			}
+
+			if healthy < wantVersion {
+				issues = append(issues, FsckIssue{
+					Kind:      FsckUnderReplicated,
+					FileID:    file.FileID,
+					VersionID: version.VersionID,
+					Detail:    fmt.Sprintf("%d of %d healthy replicas", healthy, wantVersion),
+				})
+			}
+			if len(version.Nodes) > wantVersion {
+				issues = append(issues, FsckIssue{
+					Kind:      FsckOverReplicated,
+					FileID:    file.FileID,
+					VersionID: version.VersionID,
+					Detail:    fmt.Sprintf("placed on %d nodes, want %d", len(version.Nodes), wantVersion),
+				})
+			}
+
+			if opts.Repair && len(issues) > 0 {
+				repairErr := fm.enforceReplicaFactor(ctx, file.FileID, version.VersionID)
+				if errors.Is(repairErr, errVersionGone) {
+					// Deleted since the check listed it
+					continue
+				}
+				for i := range issues {
+					if repairErr != nil {
+						issues[i].Error = repairErr.Error()
+					} else {
+						issues[i].Repaired = true
+					}
+				}
+			}
+			report.Issues = append(report.Issues, issues...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodeIDs := make([]string, 0, len(stored))
+	for nodeID := range stored {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	for _, nodeID := range nodeIDs {
+		keys := make([]string, 0, len(stored[nodeID]))
+		for key := range stored[nodeID] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if referenced[key+"/"+nodeID] {
+				continue
+			}
+			entry := stored[nodeID][key]
+
+			issue := FsckIssue{
+				Kind:      FsckOrphan,
+				FileID:    entry.FileID,
+				VersionID: entry.VersionID,
+				NodeID:    nodeID,
+				Detail:    "version not in metadata",
+			}
+			if !files[entry.FileID] {
+				issue.Detail = "no metadata for file"
+			} else if versions[key] {
+				issue.Detail = "node not in version placement"
+			}
+
+			if opts.Repair {
+				if time.Since(entry.ModTime) < orphanGracePeriod {
+					issue.Detail += ", kept during grace period"
+				} else {
+					removed, err := fm.removeUnreferencedCopy(ctx, entry.FileID, entry.VersionID, nodeID)
+					if err == nil && !removed {
+						// Referenced by a placement changed since the check
+						continue
+					}
+					if err != nil {
+						issue.Error = err.Error()
+					}
+					issue.Repaired = removed
+				}
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	for _, issue := range report.Issues {
+		if issue.Repaired {
+			report.Repaired++
+		} else if issue.Error != "" {
+			report.Failed++
+		}
+	}
+	report.Finished = time.Now()
+	return report, nil
+}
+
+// enforceReplicaFactor re-replicates a version from its healthy copies and
+// then trims its placement to its file's replica factor
+func (fm *FileManager) enforceReplicaFactor(ctx context.Context, fileID, versionID string) error {
+	return fm.withVersion(ctx, fileID, versionID, func(file *metadata.FileMetadata, version metadata.Version) (placementChange, error) {
+		change, _, err := fm.replicateVersion(ctx, file, version)
+		if err != nil {
+			return change, err
+		}
+
+		nodes := change.Nodes
+		if nodes == nil {
+			nodes = version.Nodes
+		}
+		if trimmed, ok := fm.trimNodes(file, version, nodes); ok {
+			change.Nodes = trimmed
+		}
+		return change, nil
+	})
+}
+
+// trimNodes returns the placement of a version trimmed to its file's replica
+// factor, keeping the ring owners first, and whether any node was dropped.
+// The copies on dropped nodes are deleted once the placement is recorded.
+// Erasure-coded versions have one node per shard and are never trimmed.
+func (fm *FileManager) trimNodes(file *metadata.FileMetadata, version metadata.Version, nodes []string) ([]string, bool) {
+	if version.IsErasureCoded() {
+		return nil, false
+	}
+
+	want := min(fm.replicaFactorOf(file), fm.GetNodeCount())
+	if len(nodes) <= want {
+		return nil, false
+	}
+
+	var keep []string
+	for _, nodeID := range fm.replicaOwners(file) {
+		if len(keep) < want && containsNode(nodes, nodeID) {
+			keep = append(keep, nodeID)
+		}
+	}
+	for _, nodeID := range nodes {
+		if len(keep) < want && !containsNode(keep, nodeID) {
+			keep = append(keep, nodeID)
+		}
+	}
+	return keep, true
+}
@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ageCopy makes a stored copy look older than the orphan grace period
+func ageCopy(t *testing.T, fm *FileManager, nodeID, fileID, versionID string) {
+	old := time.Now().Add(-2 * orphanGracePeriod)
+	path := filepath.Join(localNode(fm, nodeID).StoragePath, fileID, versionID, "manifest")
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("failed to age copy: %v", err)
+	}
+}
+
+// issueKinds counts the issues of a report by kind
+func issueKinds(report *FsckReport) map[string]int {
+	kinds := make(map[string]int)
+	for _, issue := range report.Issues {
+		kinds[issue.Kind]++
+	}
+	return kinds
+}
+
+func TestFsck(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("check me")
+
+	t.Run("consistent store", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		fm.UploadFile(ctx, "a.txt", data, "text/plain")
+		fm.UploadFile(ctx, "b.txt", data, "text/plain")
+
+		report, err := fm.Fsck(ctx, FsckOptions{})
+		if err != nil {
+			t.Fatalf("Fsck failed: %v", err)
+		}
+		if len(report.Issues) != 0 {
+			t.Errorf("Issues = %+v, want none", report.Issues)
+		}
+		if report.NodesChecked != 3 || report.FilesChecked != 2 || report.VersionsChecked != 2 || report.ObjectsChecked != 4 {
+			t.Errorf("unexpected counts: %+v", report)
+		}
+	})
+
+	t.Run("reports without repairing", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "dangling.txt", data, "text/plain")
+		version := meta.Versions[0]
+		fm.nodes[version.Nodes[0]].DeleteFile(meta.FileID, version.VersionID)
+		localNode(fm, "test-node-1").StoreFile("orphan-file", "v1", data)
+
+		report, err := fm.Fsck(ctx, FsckOptions{})
+		if err != nil {
+			t.Fatalf("Fsck failed: %v", err)
+		}
+		kinds := issueKinds(report)
+		if kinds[FsckDangling] != 1 || kinds[FsckUnderReplicated] != 1 || kinds[FsckOrphan] != 1 {
+			t.Errorf("issue kinds = %v", kinds)
+		}
+		if report.Repaired != 0 || report.Failed != 0 {
+			t.Errorf("Repaired = %d, Failed = %d, want none", report.Repaired, report.Failed)
+		}
+		if !fm.nodes["test-node-1"].FileExists("orphan-file", "v1") {
+			t.Error("orphan removed without repair")
+		}
+	})
+
+	t.Run("repairs dangling and diverged replicas", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "diverged.txt", data, "text/plain")
+		version := meta.Versions[0]
+		checksumPath := filepath.Join(localNode(fm, version.Nodes[1]).StoragePath, meta.FileID, version.VersionID, "checksum")
+		os.WriteFile(checksumPath, []byte("0000"), 0644)
+
+		report, err := fm.Fsck(ctx, FsckOptions{Repair: true})
+		if err != nil {
+			t.Fatalf("Fsck failed: %v", err)
+		}
+		if kinds := issueKinds(report); kinds[FsckDiverged] != 1 || kinds[FsckUnderReplicated] != 1 {
+			t.Errorf("issue kinds = %v", kinds)
+		}
+		if report.Repaired != 2 || report.Failed != 0 {
+			t.Errorf("Repaired = %d, Failed = %d, want 2 and 0", report.Repaired, report.Failed)
+		}
+
+		if report, _ := fm.Fsck(ctx, FsckOptions{}); len(report.Issues) != 0 {
+			t.Errorf("issues after repair: %+v", report.Issues)
+		}
+	})
+
+	t.Run("trims over-replicated version", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "extra.txt", data, "text/plain")
+		version := meta.Versions[0]
+		var extra string
+		for nodeID := range fm.nodes {
+			if !containsNode(version.Nodes, nodeID) {
+				extra = nodeID
+			}
+		}
+		localNode(fm, extra).StoreFile(meta.FileID, version.VersionID, data)
+		fm.metadataStore.SetVersionNodes(ctx, meta.FileID, version.VersionID, append(version.Nodes, extra))
+
+		report, err := fm.Fsck(ctx, FsckOptions{Repair: true})
+		if err != nil {
+			t.Fatalf("Fsck failed: %v", err)
+		}
+		if kinds := issueKinds(report); kinds[FsckOverReplicated] != 1 || len(report.Issues) != 1 {
+			t.Errorf("Issues = %+v", report.Issues)
+		}
+
+		updated, _ := fm.metadataStore.GetMetadata(ctx, meta.FileID)
+		if nodes := updated.Versions[0].Nodes; len(nodes) != 2 {
+			t.Errorf("version nodes = %v, want 2", nodes)
+		}
+		if report, _ := fm.Fsck(ctx, FsckOptions{}); len(report.Issues) != 0 {
+			t.Errorf("issues after repair: %+v", report.Issues)
+		}
+	})
+
+	t.Run("removes orphans after grace period", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "kept.txt", data, "text/plain")
+		localNode(fm, "test-node-1").StoreFile("old-orphan", "v1", data)
+		ageCopy(t, fm, "test-node-1", "old-orphan", "v1")
+		localNode(fm, "test-node-2").StoreFile("new-orphan", "v1", data)
+		localNode(fm, "test-node-3").StoreFile(meta.FileID, "deleted-version", data)
+		ageCopy(t, fm, "test-node-3", meta.FileID, "deleted-version")
+
+		report, err := fm.Fsck(ctx, FsckOptions{Repair: true})
+		if err != nil {
+			t.Fatalf("Fsck failed: %v", err)
+		}
+		if kinds := issueKinds(report); kinds[FsckOrphan] != 3 {
+			t.Errorf("issue kinds = %v", kinds)
+		}
+		if report.Repaired != 2 {
+			t.Errorf("Repaired = %d, want 2", report.Repaired)
+		}
+		if fm.nodes["test-node-1"].FileExists("old-orphan", "v1") {
+			t.Error("old orphan not removed")
+		}
+		if fm.nodes["test-node-3"].FileExists(meta.FileID, "deleted-version") {
+			t.Error("copy of deleted version not removed")
+		}
+		if !fm.nodes["test-node-2"].FileExists("new-orphan", "v1") {
+			t.Error("orphan removed during grace period")
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package manager
+
+import (
+	"io"
+	"log"
+	"sync"
+
+	"github.com/yashlad/distributed-file-store/internal/gossip"
+)
+
+// GossipFollower keeps the nodes registered with a File Manager in step with
+// a gossip cluster. Storage nodes that gossip reports alive or suspect are
+// dialed at their advertised address and registered, with suspect nodes
+// marked unhealthy so writes skip them; nodes it reports dead or departed
+// are removed. Every coordinator following the same cluster ends
+// up with the same nodes, and so the same ring, without a central registry.
+//
+// Gossip itself is not authenticated, so the dialer must only accept nodes
+// that prove they belong to the cluster, such as with cluster TLS. A node
+// that is registered, by gossip or otherwise, is never moved to another
+// address on gossip's word; it has to be declared dead or leave first.
+type GossipFollower struct {
+	fileManager *FileManager
+	dial        func(address string) (NodeClient, error)
+
+	mu      sync.Mutex
+	nodes   map[string]gossipNode
+	stopped bool
+}
+
+// gossipNode is a storage node registered because of gossip
+type gossipNode struct {
+	address string
+	client  NodeClient
+}
+
+// FollowGossip registers the storage nodes known to node with a file
+// manager, and keeps registering and removing them as the cluster changes
+func FollowGossip(fileManager *FileManager, node *gossip.Node, dial func(address string) (NodeClient, error)) *GossipFollower {
+	f := &GossipFollower{
+		fileManager: fileManager,
+		dial:        dial,
+		nodes:       make(map[string]gossipNode),
+	}
+	node.OnChange(f.apply)
+	for _, member := range node.Members() {
+		f.apply(member)
+	}
+	return f
+}
+
+// Stop stops following the cluster. Registered nodes stay registered.
+func (f *GossipFollower) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = true
+}
+
+// apply registers or removes a storage node to match its gossip state
+func (f *GossipFollower) apply(member gossip.Member) {
+	if member.Tags[gossip.RoleTag] != gossip.RoleStorage {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopped {
+		return
+	}
+
+	current, registered := f.nodes[member.ID]
+	switch member.State {
+	case gossip.StateAlive, gossip.StateSuspect:
+		f.fileManager.SetNodeHealthy(member.ID, member.State == gossip.StateAlive)
+		address := member.Tags[gossip.AddressTag]
+		if address == "" || (registered && current.address == address) {
+			return
+		}
+		if registered {
+			log.Printf("Ignoring gossip moving node %s from %s to %s", member.ID, current.address, address)
+			return
+		}
+		if _, exists := f.fileManager.getNode(member.ID); exists {
+			log.Printf("Ignoring gossip about node %s, which is registered by other means", member.ID)
+			return
+		}
+		client, err := f.dial(address)
+		if err != nil {
+			log.Printf("Failed to dial node %s at %s: %v", member.ID, address, err)
+			return
+		}
+		if err := f.fileManager.AddNode(member.ID, client); err != nil {
+			log.Printf("Failed to register node %s: %v", member.ID, err)
+			closeClient(client)
+			return
+		}
+		f.nodes[member.ID] = gossipNode{address: address, client: client}
+		log.Printf("Node %s joined at %s", member.ID, address)
+
+	case gossip.StateDead, gossip.StateLeft:
+		if !registered {
+			return
+		}
+		f.fileManager.UnregisterNode(member.ID)
+		delete(f.nodes, member.ID)
+		log.Printf("Node %s is %s", member.ID, member.State)
+		closeClient(current.client)
+	}
+}
+
+// closeClient closes a node client that holds a connection
+func closeClient(client NodeClient) {
+	if closer, ok := client.(io.Closer); ok {
+		closer.Close()
+	}
+}
@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/gossip"
+)
+
+// gossipMember starts a gossip member on network with short intervals
+func gossipMember(t *testing.T, network *gossip.Network, id string, tags map[string]string) *gossip.Node {
+	t.Helper()
+	transport, err := network.Transport(id)
+	if err != nil {
+		t.Fatalf("Transport failed: %v", err)
+	}
+	node, err := gossip.New(gossip.Config{
+		ID:               id,
+		Tags:             tags,
+		ProbeInterval:    20 * time.Millisecond,
+		ProbeTimeout:     8 * time.Millisecond,
+		SuspicionTimeout: 100 * time.Millisecond,
+		SyncInterval:     100 * time.Millisecond,
+	}, transport)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { node.Close() })
+	return node
+}
+
+// eventually fails the test if cond doesn't hold within a few seconds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFollowGossip(t *testing.T) {
+	network := gossip.NewNetwork()
+
+	// Storage nodes announce the address they serve on, which the dialer
+	// maps to in-memory nodes
+	var mu sync.Mutex
+	nodes := make(map[string]NodeClient)
+	dial := func(address string) (NodeClient, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		node, exists := nodes[address]
+		if !exists {
+			return nil, fmt.Errorf("nothing listening on %s", address)
+		}
+		return node, nil
+	}
+
+	seed := gossipMember(t, network, "coordinator-1", map[string]string{gossip.RoleTag: gossip.RoleCoordinator})
+	coordinator := gossipMember(t, network, "coordinator-2", map[string]string{gossip.RoleTag: gossip.RoleCoordinator})
+	if err := coordinator.Join("coordinator-1"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	var storage []*gossip.Node
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprintf("node-%d", i)
+		address := fmt.Sprintf("10.0.0.%d:50061", i)
+		mu.Lock()
+		nodes[address] = memoryNode(t, id)
+		mu.Unlock()
+
+		member := gossipMember(t, network, id, map[string]string{gossip.RoleTag: gossip.RoleStorage, gossip.AddressTag: address})
+		if err := member.Join("coordinator-2"); err != nil {
+			t.Fatalf("Join failed: %v", err)
+		}
+		storage = append(storage, member)
+	}
+
+	// Coordinators start following after the nodes joined, picking up the
+	// nodes they already know of as well as later changes
+	first := NewFileManager(NewMockMetadataStore(), 2)
+	FollowGossip(first, seed, dial)
+	eventually(t, "the first coordinator registers every storage node", func() bool {
+		return first.GetNodeCount() == 3
+	})
+	second := NewFileManager(NewMockMetadataStore(), 2)
+	follower := FollowGossip(second, coordinator, dial)
+	eventually(t, "the second coordinator registers every storage node", func() bool {
+		return second.GetNodeCount() == 3
+	})
+
+	sameRing := func() bool {
+		for i := 0; i < 50; i++ {
+			key := fmt.Sprintf("file-%d", i)
+			if !reflect.DeepEqual(first.hashRing.GetNodes(key), second.hashRing.GetNodes(key)) {
+				return false
+			}
+		}
+		return true
+	}
+	if !sameRing() {
+		t.Error("coordinators place files on different nodes")
+	}
+
+	// Gossip doesn't move a registered node to another address, nor take
+	// over a node registered by other means
+	probed := memoryNode(t, "node-1")
+	third := NewFileManager(NewMockMetadataStore(), 2)
+	if err := third.AddNode("node-1", probed); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	FollowGossip(third, seed, dial)
+	eventually(t, "the third coordinator registers the other storage nodes", func() bool {
+		return third.GetNodeCount() == 3
+	})
+	registered, _ := first.getNode("node-1")
+	mu.Lock()
+	nodes["10.0.0.9:50061"] = memoryNode(t, "impostor")
+	mu.Unlock()
+	storage[0].SetTags(map[string]string{gossip.RoleTag: gossip.RoleStorage, gossip.AddressTag: "10.0.0.9:50061"})
+	eventually(t, "the coordinators hear of the new address", func() bool {
+		for _, member := range seed.Members() {
+			if member.ID == "node-1" {
+				return member.Tags[gossip.AddressTag] == "10.0.0.9:50061"
+			}
+		}
+		return false
+	})
+	if client, _ := first.getNode("node-1"); client != registered {
+		t.Error("gossip moved a registered node")
+	}
+	if client, _ := third.getNode("node-1"); client != probed {
+		t.Error("gossip took over a node registered by other means")
+	}
+
+	storage[2].Close()
+	eventually(t, "both coordinators remove the failed node", func() bool {
+		_, onFirst := first.getNode("node-3")
+		_, onSecond := second.getNode("node-3")
+		return !onFirst && !onSecond
+	})
+
+	storage[1].Leave()
+	eventually(t, "both coordinators remove the departed node", func() bool {
+		return first.GetNodeCount() == 1 && second.GetNodeCount() == 1
+	})
+	if !sameRing() {
+		t.Error("coordinators place files on different nodes after nodes left")
+	}
+
+	// Coordinators are never registered as storage, and a stopped follower
+	// no longer changes the nodes
+	follower.Stop()
+	restarted := gossipMember(t, network, "node-2", map[string]string{gossip.RoleTag: gossip.RoleStorage, gossip.AddressTag: "10.0.0.2:50061"})
+	if err := restarted.Join("coordinator-1"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	eventually(t, "the restarted node is registered again", func() bool {
+		return first.GetNodeCount() == 2
+	})
+	if _, exists := first.getNode("coordinator-2"); exists {
+		t.Error("coordinator registered as a storage node")
+	}
+	if second.GetNodeCount() != 1 {
+		t.Errorf("stopped follower registered nodes: %d nodes", second.GetNodeCount())
+	}
+}
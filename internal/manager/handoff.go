@@ -0,0 +1,414 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+)
+
+// HandoffStatus reports the hinted handoff pass that is running or, if none
+// is, the last one that ran
+type HandoffStatus struct {
+	Running      bool
+	LastStarted  time.Time
+	LastFinished time.Time
+	Hints        int
+	HandedOff    int
+	Dropped      int
+	Waiting      int
+	Failed       int
+	BytesCopied  int64
+	LastError    string
+}
+
+// HintedHandoff hands versions back to the ring nodes they belong on. When a
+// ring node fails an upload, the version is stored on the next node along
+// the ring instead, with a hint naming the owner. Once the owner answers a
+// probe again, the version is copied back to it, the placement is updated
+// and the temporary copy is removed. Hints live in the metadata store, so
+// they survive a restart of the server.
+type HintedHandoff struct {
+	fileManager *FileManager
+	interval    time.Duration
+	trigger     chan struct{}
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	runMu sync.Mutex
+
+	statusMu sync.Mutex
+	status   HandoffStatus
+}
+
+// handoffProbeTimeout is how long an owner may take to answer the probe
+// that decides whether versions are handed back to it
+const handoffProbeTimeout = 5 * time.Second
+
+// NewHintedHandoff creates a hinted handoff worker that checks the hints
+// every interval and whenever a storage node is registered or removed
+func NewHintedHandoff(fileManager *FileManager, interval time.Duration) *HintedHandoff {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	h := &HintedHandoff{
+		fileManager: fileManager,
+		interval:    interval,
+		trigger:     make(chan struct{}, 1),
+	}
+	fileManager.onTopologyChange(h.Trigger)
+	return h
+}
+
+// Start runs hinted handoff in the background until Stop is called
+func (h *HintedHandoff) Start() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.done = make(chan struct{})
+
+	go func() {
+		defer close(h.done)
+
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-h.trigger:
+			}
+
+			status, err := h.RunOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Hinted handoff failed: %v", err)
+			} else if status.HandedOff > 0 || status.Failed > 0 {
+				log.Printf("Handed off %d versions (%d failed, %d bytes copied)", status.HandedOff, status.Failed, status.BytesCopied)
+			}
+		}
+	}()
+}
+
+// Stop stops the background handoff and waits for it to exit
+func (h *HintedHandoff) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cancel == nil {
+		return
+	}
+	h.cancel()
+	<-h.done
+	h.cancel = nil
+}
+
+// Trigger requests a pass as soon as possible without waiting for the next
+// interval. It does nothing if a pass is already pending.
+func (h *HintedHandoff) Trigger() {
+	select {
+	case h.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the progress of the current or last pass
+func (h *HintedHandoff) Status() HandoffStatus {
+	h.statusMu.Lock()
+	defer h.statusMu.Unlock()
+	return h.status
+}
+
+// updateStatus applies fn to the status under its lock
+func (h *HintedHandoff) updateStatus(fn func(status *HandoffStatus)) {
+	h.statusMu.Lock()
+	defer h.statusMu.Unlock()
+	fn(&h.status)
+}
+
+// RunOnce goes through every hint, handing versions back to owners that are
+// healthy and dropping hints that no longer apply, and returns the final
+// status of the pass
+func (h *HintedHandoff) RunOnce(ctx context.Context) (HandoffStatus, error) {
+	h.runMu.Lock()
+	defer h.runMu.Unlock()
+
+	h.updateStatus(func(status *HandoffStatus) {
+		*status = HandoffStatus{Running: true, LastStarted: time.Now()}
+	})
+
+	err := h.run(ctx)
+
+	h.updateStatus(func(status *HandoffStatus) {
+		status.Running = false
+		status.LastFinished = time.Now()
+		if err != nil {
+			status.LastError = err.Error()
+		}
+	})
+	return h.Status(), err
+}
+
+// run handles every hint, probing each owner at most once
+func (h *HintedHandoff) run(ctx context.Context) error {
+	fm := h.fileManager
+	hints, err := fm.metadataStore.ListHints(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list hints: %w", err)
+	}
+	h.updateStatus(func(status *HandoffStatus) {
+		status.Hints = len(hints)
+	})
+
+	healthy := make(map[string]bool)
+	ownerHealthy := func(nodeID string) bool {
+		if ok, probed := healthy[nodeID]; probed {
+			return ok
+		}
+		node, exists := fm.getNode(nodeID)
+		healthy[nodeID] = exists && probeNode(ctx, node, handoffProbeTimeout)
+		return healthy[nodeID]
+	}
+
+	for _, hint := range hints {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		outcome, copied, err := fm.handOff(ctx, hint, ownerHealthy)
+		if err == nil && outcome != handoffWaiting {
+			err = fm.metadataStore.DeleteHint(ctx, hint)
+		}
+		h.updateStatus(func(status *HandoffStatus) {
+			status.BytesCopied += copied
+			switch {
+			case err != nil:
+				status.Failed++
+			case outcome == handoffDone:
+				status.HandedOff++
+			case outcome == handoffStale:
+				status.Dropped++
+			default:
+				status.Waiting++
+			}
+		})
+		if err != nil {
+			log.Printf("Failed to hand version %s of %s back to node %s: %v", hint.VersionID, hint.FileID, hint.Owner, err)
+		}
+	}
+	return nil
+}
+
+// Outcomes of handing a version back
+const (
+	// handoffDone means the owner holds the version again
+	handoffDone = iota
+
+	// handoffStale means the hint no longer applies, because the version
+	// was deleted or moved off the holder in the meantime
+	handoffStale
+
+	// handoffWaiting means the owner is not healthy yet
+	handoffWaiting
+)
+
+// handOff moves a hinted version from its holder back to its owner if the
+// owner is healthy. The holder keeps its copy only if the ring has made it
+// an owner of the file in the meantime. It reports the outcome and how many
+// bytes were copied.
+func (fm *FileManager) handOff(ctx context.Context, hint metadata.Hint, ownerHealthy func(nodeID string) bool) (int, int64, error) {
+	outcome := handoffStale
+	var copied int64
+	err := fm.withVersion(ctx, hint.FileID, hint.VersionID, func(file *metadata.FileMetadata, version metadata.Version) (placementChange, error) {
+		if version.IsErasureCoded() || !containsNode(version.Nodes, hint.Holder) {
+			return placementChange{}, nil
+		}
+		if !ownerHealthy(hint.Owner) {
+			outcome = handoffWaiting
+			return placementChange{}, nil
+		}
+
+		var change placementChange
+		nodes := append([]string(nil), version.Nodes...)
+		if !containsNode(nodes, hint.Owner) {
+			var err error
+			_, copied, err = fm.copyReplica(ctx, file.FileID, version, []string{hint.Holder}, hint.Owner, nil)
+			if err != nil {
+				return placementChange{}, err
+			}
+			nodes = append(nodes, hint.Owner)
+			change.Written = []string{hint.Owner}
+		}
+
+		// The holder's copy is deleted once it is no longer placed there
+		if !containsNode(fm.replicaOwners(file), hint.Holder) {
+			nodes = removeNode(nodes, hint.Holder)
+		}
+		change.Nodes = nodes
+		outcome = handoffDone
+		return change, nil
+	})
+	if errors.Is(err, errVersionGone) {
+		return handoffStale, copied, nil
+	}
+	return outcome, copied, err
+}
+
+// writeNodes returns the nodes a new replicated version of a file is
+// written to: the first n healthy nodes of the file's preference list. Each
+// of them that is not a ring owner stands in for an owner that was skipped as
+// unhealthy, and standIns maps it to that owner. If fewer than n nodes are
+// healthy, the skipped owners that remain are written to as well, since they
+// may only be slow.
+func (fm *FileManager) writeNodes(fileID string, n int) ([]string, map[string]string) {
+	owners := fm.hashRing.GetNodesN(fileID, n)
+	nodes := fm.hashRing.GetHealthyNodesN(fileID, n)
+
+	var skipped []string
+	for _, owner := range owners {
+		if !containsNode(nodes, owner) {
+			skipped = append(skipped, owner)
+		}
+	}
+
+	standIns := make(map[string]string)
+	for _, nodeID := range nodes {
+		if !containsNode(owners, nodeID) {
+			standIns[nodeID] = skipped[0]
+			skipped = skipped[1:]
+		}
+	}
+	return append(nodes, skipped...), standIns
+}
+
+// storeHinted records a hint for every stand-in the version was written to
+// in place of an unhealthy owner. Then it stands in for the nodes that failed
+// to store the version: for each of them, the version is copied from a
+// replica that stored it to the next healthy node along the ring that takes
+// it, and a hint is recorded so the version is handed back once the failed
+// node recovers. The stand-ins are added to the result, so they count toward
+// the write quorum. A stand-in is only chosen once an owner has failed; if no
+// replica stored the version there is nothing to copy, and the upload fails
+// its quorum.
+func (fm *FileManager) storeHinted(ctx context.Context, fileID, versionID string, nodeIDs []string, standIns map[string]string, result *streamResult, dataKey []byte) {
+	var failed []string
+	for i := range result.Replicas {
+		replica := &result.Replicas[i]
+		owner, standIn := standIns[replica.NodeID]
+		if !standIn {
+			if replica.Err != nil {
+				failed = append(failed, replica.NodeID)
+			}
+			continue
+		}
+
+		replica.HintedFor = owner
+		if replica.Err == nil {
+			if replica.Err = fm.saveHint(ctx, fileID, versionID, owner, replica.NodeID); replica.Err == nil {
+				continue
+			}
+			fm.cleanupFailedUpload(fileID, versionID, []string{replica.NodeID})
+			result.StoredNodes = removeNode(result.StoredNodes, replica.NodeID)
+		}
+		failed = append(failed, owner)
+	}
+	if len(failed) == 0 || len(result.StoredNodes) == 0 {
+		return
+	}
+
+	version := metadata.Version{VersionID: versionID, Checksum: result.Checksum, Compression: result.Compression}
+	sources := append([]string(nil), result.StoredNodes...)
+	candidates := fm.hashRing.PreferenceList(fileID)
+	next := 0
+	for _, owner := range failed {
+		for next < len(candidates) {
+			holder := candidates[next]
+			next++
+			if containsNode(nodeIDs, holder) || !fm.hashRing.IsHealthy(holder) {
+				continue
+			}
+
+			start := time.Now()
+			if err := fm.storeHint(ctx, fileID, version, sources, owner, holder, dataKey); err != nil {
+				fmt.Printf("Failed to store hinted replica for node %s on node %s: %v\n", owner, holder, err)
+				continue
+			}
+			result.StoredNodes = append(result.StoredNodes, holder)
+			result.Replicas = append(result.Replicas, ReplicaResult{
+				NodeID:    holder,
+				Duration:  time.Since(start),
+				HintedFor: owner,
+			})
+			break
+		}
+	}
+}
+
+// storeHint copies a version from the first source that can serve it to
+// holder and records a hint that it belongs on owner
+func (fm *FileManager) storeHint(ctx context.Context, fileID string, version metadata.Version, sources []string, owner, holder string, dataKey []byte) error {
+	target, exists := fm.getNode(holder)
+	if !exists {
+		return fmt.Errorf("node %s not registered", holder)
+	}
+
+	err := fmt.Errorf("no source replica available")
+	for _, sourceID := range sources {
+		source, exists := fm.getNode(sourceID)
+		if !exists {
+			continue
+		}
+		if _, err = copyVersion(ctx, sourceID, source, target, fileID, version, dataKey, nil); err == nil {
+			break
+		}
+		target.DeleteFile(fileID, version.VersionID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := fm.saveHint(ctx, fileID, version.VersionID, owner, holder); err != nil {
+		target.DeleteFile(fileID, version.VersionID)
+		return err
+	}
+	return nil
+}
+
+// saveHint records that holder keeps a version that belongs on owner
+func (fm *FileManager) saveHint(ctx context.Context, fileID, versionID, owner, holder string) error {
+	hint := metadata.Hint{
+		FileID:    fileID,
+		VersionID: versionID,
+		Owner:     owner,
+		Holder:    holder,
+		CreatedAt: time.Now(),
+	}
+	if err := fm.metadataStore.SaveHint(ctx, hint); err != nil {
+		return fmt.Errorf("failed to record hint: %w", err)
+	}
+	return nil
+}
+
+// removeNode returns nodes without nodeID
+func removeNode(nodes []string, nodeID string) []string {
+	var kept []string
+	for _, n := range nodes {
+		if n != nodeID {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
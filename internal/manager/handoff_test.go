@@ -0,0 +1,318 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// unavailableNode is a node that fails writes and probes while it is down
+type unavailableNode struct {
+	flakyNode
+}
+
+// StoreFileStream implements NodeClient, failing while the node is down
+func (n *unavailableNode) StoreFileStream(fileID, versionID string, r io.Reader, codec string, key []byte) (*storage.ObjectInfo, error) {
+	if n.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return n.NodeClient.StoreFileStream(fileID, versionID, r, codec, key)
+}
+
+// ReplicateFile implements NodeClient, failing while the node is down
+func (n *unavailableNode) ReplicateFile(fileID, versionID string, source io.Reader, codec string, key []byte) error {
+	if n.down.Load() {
+		return errors.New("connection refused")
+	}
+	return n.NodeClient.ReplicateFile(fileID, versionID, source, codec, key)
+}
+
+// sortedNodes returns a sorted copy of nodes
+func sortedNodes(nodes []string) []string {
+	sorted := append([]string(nil), nodes...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func TestHintedHandoff(t *testing.T) {
+	ctx := context.Background()
+	store := NewMockMetadataStore()
+	fm := NewFileManager(store, 2)
+	nodes := make(map[string]*unavailableNode)
+	for i := 1; i <= 4; i++ {
+		nodeID := fmt.Sprintf("node-%d", i)
+		nodes[nodeID] = &unavailableNode{flakyNode{NodeClient: memoryNode(t, nodeID)}}
+		fm.AddNode(nodeID, nodes[nodeID])
+	}
+
+	// Upload while node-2 is down until some files belong on it
+	nodes["node-2"].down.Store(true)
+	contents := make(map[string][]byte)
+	var hinted []string
+	for i := 0; len(hinted) < 3; i++ {
+		if i == 100 {
+			t.Fatal("no file placed on node-2")
+		}
+		data := []byte(fmt.Sprintf("file %d written while node-2 is down", i))
+		result, err := fm.UploadStream(ctx, fmt.Sprintf("file-%d.txt", i), bytes.NewReader(data), "text/plain", UploadOptions{WriteQuorum: 2})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		contents[result.FileID] = data
+
+		version := result.Versions[0]
+		if len(version.Nodes) != 2 || containsNode(version.Nodes, "node-2") {
+			t.Fatalf("version nodes = %v, want two nodes other than node-2", version.Nodes)
+		}
+		if !containsNode(fm.hashRing.GetNodes(result.FileID), "node-2") {
+			continue
+		}
+		hinted = append(hinted, result.FileID)
+
+		var standIn *ReplicaResult
+		for i, replica := range result.ReplicaResults {
+			if replica.HintedFor != "" {
+				standIn = &result.ReplicaResults[i]
+			}
+		}
+		if standIn == nil || standIn.HintedFor != "node-2" || standIn.Err != nil || !containsNode(version.Nodes, standIn.NodeID) {
+			t.Fatalf("replica results = %+v, want a stand-in for node-2", result.ReplicaResults)
+		}
+	}
+
+	hints, _ := store.ListHints(ctx)
+	if len(hints) != len(hinted) {
+		t.Fatalf("%d hints recorded for %d files placed on node-2", len(hints), len(hinted))
+	}
+	for _, hint := range hints {
+		if hint.Owner != "node-2" || hint.Holder == "node-2" || hint.Holder == "" {
+			t.Errorf("hint = %+v", hint)
+		}
+	}
+
+	t.Run("hints wait for the owner to recover", func(t *testing.T) {
+		status, err := NewHintedHandoff(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.Waiting != len(hinted) || status.HandedOff != 0 {
+			t.Errorf("status = %+v, want every hint waiting", status)
+		}
+		if remaining, _ := store.ListHints(ctx); len(remaining) != len(hinted) {
+			t.Errorf("%d hints left, want %d", len(remaining), len(hinted))
+		}
+	})
+
+	t.Run("versions are handed back after a restart", func(t *testing.T) {
+		// A new file manager on the same metadata finds the hints
+		nodes["node-2"].down.Store(false)
+		restarted := NewFileManager(store, 2)
+		for nodeID, node := range nodes {
+			restarted.AddNode(nodeID, node)
+		}
+
+		status, err := NewHintedHandoff(restarted, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.HandedOff != len(hinted) || status.Failed != 0 || status.BytesCopied == 0 {
+			t.Errorf("status = %+v, want %d versions handed off", status, len(hinted))
+		}
+		if remaining, _ := store.ListHints(ctx); len(remaining) != 0 {
+			t.Errorf("hints left after handing off: %+v", remaining)
+		}
+
+		for _, hint := range hints {
+			meta, _ := store.GetMetadata(ctx, hint.FileID)
+			owners := sortedNodes(restarted.hashRing.GetNodes(hint.FileID))
+			if got := sortedNodes(meta.Versions[0].Nodes); fmt.Sprint(got) != fmt.Sprint(owners) {
+				t.Errorf("version nodes = %v, want the ring owners %v", got, owners)
+			}
+			if nodes[hint.Holder].FileExists(hint.FileID, hint.VersionID) {
+				t.Errorf("temporary copy left on %s", hint.Holder)
+			}
+
+			data, _, err := restarted.DownloadFile(ctx, hint.FileID, "", DownloadOptions{})
+			if err != nil || !bytes.Equal(data, contents[hint.FileID]) {
+				t.Errorf("DownloadFile = %q, %v", data, err)
+			}
+		}
+	})
+
+	t.Run("stale hints are dropped", func(t *testing.T) {
+		nodes["node-3"].down.Store(true)
+		defer nodes["node-3"].down.Store(false)
+
+		var fileID string
+		for i := 0; fileID == ""; i++ {
+			if i == 100 {
+				t.Fatal("no file placed on node-3")
+			}
+			meta, err := fm.UploadFile(ctx, "stale.txt", []byte("deleted before node-3 recovers"), "text/plain")
+			if err != nil {
+				t.Fatalf("UploadFile failed: %v", err)
+			}
+			if containsNode(fm.hashRing.GetNodes(meta.FileID), "node-3") {
+				fileID = meta.FileID
+			}
+		}
+		if err := fm.DeleteFile(ctx, fileID); err != nil {
+			t.Fatalf("DeleteFile failed: %v", err)
+		}
+
+		status, err := NewHintedHandoff(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.Dropped != 1 || status.HandedOff != 0 {
+			t.Errorf("status = %+v, want one stale hint dropped", status)
+		}
+		if remaining, _ := store.ListHints(ctx); len(remaining) != 0 {
+			t.Errorf("hints left after dropping: %+v", remaining)
+		}
+	})
+
+	t.Run("writes skip unhealthy owners", func(t *testing.T) {
+		fm.SetNodeHealthy("node-4", false)
+
+		var result *UploadResult
+		for i := 0; result == nil; i++ {
+			if i == 100 {
+				t.Fatal("no file placed on node-4")
+			}
+			uploaded, err := fm.UploadStream(ctx, "skipped.txt", bytes.NewReader([]byte("written around node-4")), "text/plain", UploadOptions{})
+			if err != nil {
+				t.Fatalf("UploadStream failed: %v", err)
+			}
+			if containsNode(fm.hashRing.GetNodes(uploaded.FileID), "node-4") {
+				result = uploaded
+			}
+		}
+
+		version := result.Versions[0]
+		if len(version.Nodes) != 2 || containsNode(version.Nodes, "node-4") {
+			t.Fatalf("version nodes = %v, want two nodes other than node-4", version.Nodes)
+		}
+		for _, replica := range result.ReplicaResults {
+			if replica.NodeID == "node-4" || replica.Err != nil {
+				t.Errorf("replica result = %+v, want node-4 skipped and no failures", replica)
+			}
+		}
+		hints, _ := store.ListHints(ctx)
+		if len(hints) != 1 || hints[0].FileID != result.FileID || hints[0].Owner != "node-4" {
+			t.Fatalf("hints = %+v, want one for node-4", hints)
+		}
+
+		fm.SetNodeHealthy("node-4", true)
+		status, err := NewHintedHandoff(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.HandedOff != 1 {
+			t.Errorf("status = %+v, want the version handed off", status)
+		}
+		if !nodes["node-4"].FileExists(result.FileID, version.VersionID) {
+			t.Error("version not handed back to node-4")
+		}
+	})
+}
+
+func TestHintedHandoffEveryOwnerDown(t *testing.T) {
+	ctx := context.Background()
+	for _, replicaFactor := range []int{1, 2} {
+		t.Run(fmt.Sprintf("replica factor %d", replicaFactor), func(t *testing.T) {
+			store := NewMockMetadataStore()
+			fm := NewFileManager(store, replicaFactor)
+			nodes := make(map[string]*unavailableNode)
+			for i := 1; i <= 4; i++ {
+				nodeID := fmt.Sprintf("node-%d", i)
+				nodes[nodeID] = &unavailableNode{flakyNode{NodeClient: memoryNode(t, nodeID)}}
+				fm.AddNode(nodeID, nodes[nodeID])
+			}
+
+			// The owners are down and marked unhealthy, so no owner stores
+			// the version and the stand-ins are written to directly
+			var down []string
+			for i := 1; i <= replicaFactor; i++ {
+				nodeID := fmt.Sprintf("node-%d", i)
+				nodes[nodeID].down.Store(true)
+				fm.SetNodeHealthy(nodeID, false)
+				down = append(down, nodeID)
+			}
+
+			data := []byte("written while every owner is down")
+			var result *UploadResult
+			for i := 0; result == nil; i++ {
+				if i == 200 {
+					t.Fatalf("no file placed on %v", down)
+				}
+				uploaded, err := fm.UploadStream(ctx, "orphan.txt", bytes.NewReader(data), "text/plain", UploadOptions{})
+				if err != nil {
+					t.Fatalf("UploadStream failed: %v", err)
+				}
+				if fmt.Sprint(sortedNodes(fm.hashRing.GetNodes(uploaded.FileID))) == fmt.Sprint(down) {
+					result = uploaded
+				} else {
+					fm.DeleteFile(ctx, uploaded.FileID)
+				}
+			}
+
+			version := result.Versions[0]
+			if len(version.Nodes) != replicaFactor {
+				t.Fatalf("version nodes = %v, want %d stand-ins", version.Nodes, replicaFactor)
+			}
+			for _, nodeID := range version.Nodes {
+				if containsNode(down, nodeID) {
+					t.Errorf("version placed on failed owner %s", nodeID)
+				}
+			}
+			for nodeID, node := range nodes {
+				if !containsNode(version.Nodes, nodeID) && node.FileExists(result.FileID, version.VersionID) {
+					t.Errorf("copy left on %s, which is not placed", nodeID)
+				}
+			}
+
+			hints, _ := store.ListHints(ctx)
+			var owners []string
+			for _, hint := range hints {
+				if hint.FileID != result.FileID {
+					continue
+				}
+				if !containsNode(version.Nodes, hint.Holder) {
+					t.Errorf("hint = %+v, want a stand-in as holder", hint)
+				}
+				owners = append(owners, hint.Owner)
+			}
+			if fmt.Sprint(sortedNodes(owners)) != fmt.Sprint(down) {
+				t.Fatalf("hints = %+v, want one for each of %v", hints, down)
+			}
+
+			for _, nodeID := range down {
+				nodes[nodeID].down.Store(false)
+				fm.SetNodeHealthy(nodeID, true)
+			}
+			status, err := NewHintedHandoff(fm, time.Hour).RunOnce(ctx)
+			if err != nil {
+				t.Fatalf("RunOnce failed: %v", err)
+			}
+			if status.HandedOff != replicaFactor || status.Failed != 0 {
+				t.Errorf("status = %+v, want %d versions handed off", status, replicaFactor)
+			}
+			meta, _ := store.GetMetadata(ctx, result.FileID)
+			if got := sortedNodes(meta.Versions[0].Nodes); fmt.Sprint(got) != fmt.Sprint(down) {
+				t.Errorf("version nodes = %v, want the owners %v", got, down)
+			}
+			got, _, err := fm.DownloadFile(ctx, result.FileID, "", DownloadOptions{})
+			if err != nil || !bytes.Equal(got, data) {
+				t.Errorf("DownloadFile = %q, %v", got, err)
+			}
+		})
+	}
+}
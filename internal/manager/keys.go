@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/yashlad/distributed-file-store/internal/encryption"
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+)
+
+// SetKeyManager enables encryption at rest. Every new version is encrypted
+// by the nodes with a data key of its own, and the data key, wrapped by km,
+// is recorded in the version's metadata. Versions stored before stay as they
+// are.
+func (fm *FileManager) SetKeyManager(km encryption.KeyManager) {
+	fm.keys = km
+}
+
+// newDataKey returns a data key for a new version and its wrapped form to
+// record in the metadata, or nothing if encryption is not enabled
+func (fm *FileManager) newDataKey(ctx context.Context) ([]byte, *metadata.Encryption, error) {
+	if fm.keys == nil {
+		return nil, nil, nil
+	}
+	dataKey, err := encryption.GenerateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	keyID, wrapped, err := fm.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return dataKey, &metadata.Encryption{KeyID: keyID, WrappedKey: wrapped}, nil
+}
+
+// dataKey unwraps the data key a version is encrypted with. Unencrypted
+// versions have none.
+func (fm *FileManager) dataKey(ctx context.Context, version metadata.Version) ([]byte, error) {
+	if version.Encryption == nil {
+		return nil, nil
+	}
+	if fm.keys == nil {
+		return nil, fmt.Errorf("version %s is encrypted but no key manager is configured", version.VersionID)
+	}
+	dataKey, err := fm.keys.UnwrapKey(ctx, version.Encryption.KeyID, version.Encryption.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key of version %s: %w", version.VersionID, err)
+	}
+	return dataKey, nil
+}
+
+// storedDataKey unwraps the data key of a version looked up by ID. A version
+// the metadata doesn't know has none.
+func (fm *FileManager) storedDataKey(ctx context.Context, fileID, versionID string) ([]byte, error) {
+	file, err := fm.metadataStore.GetMetadata(ctx, fileID)
+	if errors.Is(err, metadata.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, version := range file.Versions {
+		if version.VersionID == versionID {
+			return fm.dataKey(ctx, version)
+		}
+	}
+	return nil, nil
+}
+
+// KeyRotation reports the outcome of rewrapping data keys
+type KeyRotation struct {
+	KeyID     string
+	Rewrapped int
+	Failed    int
+}
+
+// RotateKeys rewraps the data key of every encrypted version that isn't
+// wrapped by the key manager's current master key. Only the metadata
+// changes; the encrypted data is not rewritten. Once it reports no failures,
+// the old master keys are no longer needed.
+func (fm *FileManager) RotateKeys(ctx context.Context) (KeyRotation, error) {
+	if fm.keys == nil {
+		return KeyRotation{}, fmt.Errorf("encryption is not enabled")
+	}
+	rotation := KeyRotation{KeyID: fm.keys.CurrentKeyID()}
+
+	err := fm.forEachFile(ctx, func(file *metadata.FileMetadata) error {
+		for _, version := range file.Versions {
+			if version.Encryption == nil || version.Encryption.KeyID == rotation.KeyID {
+				continue
+			}
+			if err := fm.rewrapKey(ctx, file.FileID, version); err != nil {
+				log.Printf("Failed to rewrap data key of %s/%s: %v", file.FileID, version.VersionID, err)
+				rotation.Failed++
+				continue
+			}
+			rotation.Rewrapped++
+		}
+		return nil
+	})
+	return rotation, err
+}
+
+// rewrapKey wraps the data key of a version with the current master key
+func (fm *FileManager) rewrapKey(ctx context.Context, fileID string, version metadata.Version) error {
+	dataKey, err := fm.dataKey(ctx, version)
+	if err != nil {
+		return err
+	}
+	keyID, wrapped, err := fm.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return err
+	}
+
+	err = fm.metadataStore.SetVersionEncryption(ctx, fileID, version.VersionID, &metadata.Encryption{KeyID: keyID, WrappedKey: wrapped})
+	if errors.Is(err, metadata.ErrNotFound) {
+		// Deleted while the key was rewrapped
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,274 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/yashlad/distributed-file-store/internal/encryption"
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+)
+
+// stubKMS stands in for a remote KMS that can be made to fail
+type stubKMS struct {
+	*encryption.Keyring
+
+	mu  sync.Mutex
+	err error
+}
+
+// newStubKMS returns a KMS holding the given master keys, the last current
+func newStubKMS(t *testing.T, keyIDs ...string) *stubKMS {
+	t.Helper()
+	keys := make(map[string][]byte)
+	for _, id := range keyIDs {
+		keys[id] = testMasterKey(id)
+	}
+	ring, err := encryption.NewKeyring(keys, keyIDs[len(keyIDs)-1])
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+	return &stubKMS{Keyring: ring}
+}
+
+// testMasterKey derives a fixed master key from its ID
+func testMasterKey(id string) []byte {
+	return bytes.Repeat([]byte(id), encryption.KeySize)[:encryption.KeySize]
+}
+
+func (k *stubKMS) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.err != nil {
+		return "", nil, k.err
+	}
+	return k.Keyring.WrapKey(ctx, dataKey)
+}
+
+func (k *stubKMS) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.err != nil {
+		return nil, k.err
+	}
+	return k.Keyring.UnwrapKey(ctx, keyID, wrapped)
+}
+
+// snapshotStorage returns the content of every file the nodes store
+func snapshotStorage(t *testing.T, fm *FileManager) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	for nodeID := range fm.nodes {
+		filepath.WalkDir(localNode(fm, nodeID).StoragePath, func(path string, entry os.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				content, _ := os.ReadFile(path)
+				files[path] = string(content)
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+func TestEncryption(t *testing.T) {
+	ctx := context.Background()
+	secret := bytes.Repeat([]byte("patient 1138 diagnosis confidential\n"), 1000)
+
+	t.Run("versions are encrypted at rest", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		kms := newStubKMS(t, "k1")
+		fm.SetKeyManager(kms)
+
+		result, err := fm.UploadStream(ctx, "record.txt", bytes.NewReader(secret), "text/plain", UploadOptions{})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		version := result.Versions[0]
+		if version.Encryption == nil || version.Encryption.KeyID != "k1" || len(version.Encryption.WrappedKey) == 0 {
+			t.Fatalf("encryption = %+v, want a data key wrapped by k1", version.Encryption)
+		}
+		if version.Size != int64(len(secret)) {
+			t.Errorf("size = %d, want the plaintext size", version.Size)
+		}
+		for path, content := range snapshotStorage(t, fm) {
+			if bytes.Contains([]byte(content), []byte("diagnosis")) {
+				t.Errorf("plaintext found in %s", path)
+			}
+		}
+
+		data, _, err := fm.DownloadFile(ctx, result.FileID, "", DownloadOptions{})
+		if err != nil || !bytes.Equal(data, secret) {
+			t.Errorf("DownloadFile = %d bytes, %v", len(data), err)
+		}
+		reader, err := fm.OpenFile(ctx, result.FileID, "", DownloadOptions{})
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		streamed, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil || !bytes.Equal(streamed, secret) {
+			t.Errorf("OpenFile read %d bytes, %v", len(streamed), err)
+		}
+
+		// Each version has its own data key
+		next, _ := fm.UploadNewVersion(ctx, result.FileID, bytes.NewReader(secret), UploadOptions{})
+		if bytes.Equal(next.Versions[1].Encryption.WrappedKey, version.Encryption.WrappedKey) {
+			t.Error("two versions share a wrapped data key")
+		}
+	})
+
+	t.Run("erasure-coded", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		fm.SetKeyManager(newStubKMS(t, "k1"))
+		result, err := fm.UploadStream(ctx, "record.txt", bytes.NewReader(secret), "text/plain", UploadOptions{
+			StorageMode:  metadata.StorageErasure,
+			DataShards:   2,
+			ParityShards: 1,
+		})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		version := result.Versions[0]
+		if version.Encryption == nil {
+			t.Fatal("erasure-coded version not encrypted")
+		}
+
+		// Rebuilding a lost shard keeps it encrypted
+		lost := version.Nodes[0]
+		fm.nodes[lost].DeleteFile(result.FileID, version.VersionID)
+		if err := fm.repairReplica(ctx, result.FileID, version.VersionID, lost, RepairMissing); err != nil {
+			t.Fatalf("repairReplica failed: %v", err)
+		}
+		dataKey, _ := fm.dataKey(ctx, version)
+		if _, err := fm.nodes[lost].RetrieveFile(result.FileID, version.VersionID, nil); err == nil {
+			t.Error("rebuilt shard readable without its data key")
+		}
+		if _, err := fm.nodes[lost].RetrieveFile(result.FileID, version.VersionID, dataKey); err != nil {
+			t.Errorf("rebuilt shard unreadable: %v", err)
+		}
+
+		data, _, err := fm.DownloadFile(ctx, result.FileID, "", DownloadOptions{})
+		if err != nil || !bytes.Equal(data, secret) {
+			t.Errorf("DownloadFile = %d bytes, %v", len(data), err)
+		}
+	})
+
+	t.Run("repaired replicas stay encrypted", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		fm.SetKeyManager(newStubKMS(t, "k1"))
+		meta, _ := fm.UploadFile(ctx, "record.txt", secret, "text/plain")
+		version := meta.Versions[0]
+
+		lost := version.Nodes[1]
+		corruptReplica(t, fm, lost, meta.FileID, version.VersionID)
+		if err := fm.repairReplica(ctx, meta.FileID, version.VersionID, lost, RepairCorrupt); err != nil {
+			t.Fatalf("repairReplica failed: %v", err)
+		}
+		dataKey, _ := fm.dataKey(ctx, version)
+		stored, err := fm.nodes[lost].RetrieveFile(meta.FileID, version.VersionID, dataKey)
+		if err != nil || !bytes.Equal(stored, secret) {
+			t.Errorf("repaired replica = %d bytes, %v", len(stored), err)
+		}
+		if _, err := fm.nodes[lost].RetrieveFile(meta.FileID, version.VersionID, nil); err == nil {
+			t.Error("repaired replica readable without its data key")
+		}
+	})
+
+	t.Run("key manager failures", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		kms := newStubKMS(t, "k1")
+		fm.SetKeyManager(kms)
+		meta, _ := fm.UploadFile(ctx, "record.txt", secret, "text/plain")
+
+		kms.mu.Lock()
+		kms.err = errors.New("kms unavailable")
+		kms.mu.Unlock()
+		if _, err := fm.UploadFile(ctx, "other.txt", secret, "text/plain"); err == nil {
+			t.Error("expected upload to fail without the key manager")
+		}
+		if _, _, err := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{}); err == nil {
+			t.Error("expected download to fail without the key manager")
+		}
+
+		fm.SetKeyManager(nil)
+		if _, _, err := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{}); err == nil {
+			t.Error("expected download of an encrypted version to fail with encryption disabled")
+		}
+	})
+
+	t.Run("scrub verifies encrypted objects", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		fm.SetKeyManager(newStubKMS(t, "k1"))
+		meta, _ := fm.UploadFile(ctx, "record.txt", secret, "text/plain")
+		corruptReplica(t, fm, meta.Versions[0].Nodes[0], meta.FileID, meta.Versions[0].VersionID)
+
+		stats, err := NewScrubber(fm, 0, 0, false).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stats.ObjectsScanned != 2 || stats.Corrupt != 1 {
+			t.Errorf("scanned %d objects, %d corrupt, want 2 and 1", stats.ObjectsScanned, stats.Corrupt)
+		}
+	})
+}
+
+func TestRotateKeys(t *testing.T) {
+	ctx := context.Background()
+	fm := setupTestFileManager(t)
+	if _, err := fm.RotateKeys(ctx); err == nil {
+		t.Error("expected error rotating keys with encryption disabled")
+	}
+
+	plain, _ := fm.UploadFile(ctx, "plain.txt", []byte("stored before encryption"), "text/plain")
+	fm.SetKeyManager(newStubKMS(t, "2024-01"))
+	var fileIDs []string
+	for _, name := range []string{"a.txt", "b.txt"} {
+		meta, err := fm.UploadFile(ctx, name, []byte("secret "+name), "text/plain")
+		if err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+		fileIDs = append(fileIDs, meta.FileID)
+	}
+	before := snapshotStorage(t, fm)
+
+	// A new master key is added; the old one is kept until the rotation
+	fm.SetKeyManager(newStubKMS(t, "2024-01", "2024-07"))
+	rotation, err := fm.RotateKeys(ctx)
+	if err != nil {
+		t.Fatalf("RotateKeys failed: %v", err)
+	}
+	if rotation.KeyID != "2024-07" || rotation.Rewrapped != 2 || rotation.Failed != 0 {
+		t.Errorf("rotation = %+v, want 2 keys rewrapped with 2024-07", rotation)
+	}
+	after := snapshotStorage(t, fm)
+	if len(after) != len(before) {
+		t.Errorf("%d stored files after rotation, want %d", len(after), len(before))
+	}
+	for path, content := range before {
+		if after[path] != content {
+			t.Errorf("%s rewritten by the rotation", path)
+		}
+	}
+
+	// The old master key is no longer needed
+	fm.SetKeyManager(newStubKMS(t, "2024-07"))
+	for i, fileID := range fileIDs {
+		data, meta, err := fm.DownloadFile(ctx, fileID, "", DownloadOptions{})
+		if err != nil || string(data) != "secret "+meta.Filename {
+			t.Errorf("file %d after rotation = %q, %v", i, data, err)
+		}
+	}
+	if data, _, err := fm.DownloadFile(ctx, plain.FileID, "", DownloadOptions{}); err != nil || string(data) != "stored before encryption" {
+		t.Errorf("unencrypted file after rotation = %q, %v", data, err)
+	}
+
+	// Nothing left to rotate
+	if rotation, _ := fm.RotateKeys(ctx); rotation.Rewrapped != 0 || rotation.Failed != 0 {
+		t.Errorf("second rotation = %+v, want nothing rewrapped", rotation)
+	}
+}
@@ -0,0 +1,421 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Membership states of a storage node
+const (
+	// NodeAlive nodes have sent a heartbeat or answered a probe recently
+	NodeAlive = "alive"
+
+	// NodeSuspect nodes have missed heartbeats but still serve reads, in
+	// case they are only slow; new versions go to the next healthy node
+	// along the ring instead
+	NodeSuspect = "suspect"
+
+	// NodeDead nodes have missed heartbeats for so long that they are
+	// removed from the ring until they recover
+	NodeDead = "dead"
+)
+
+// MembershipConfig sets how often nodes are expected to report and how long
+// they may stay silent
+type MembershipConfig struct {
+	// HeartbeatInterval is how often node daemons send heartbeats and other
+	// nodes are probed
+	HeartbeatInterval time.Duration
+
+	// SuspectAfter is how long a node may go without a heartbeat before it
+	// is suspected
+	SuspectAfter time.Duration
+
+	// DeadAfter is how long a node may go without a heartbeat before it is
+	// declared dead
+	DeadAfter time.Duration
+}
+
+// DefaultMembershipConfig returns a config that suspects a node after three
+// missed heartbeats and declares it dead after twelve
+func DefaultMembershipConfig() MembershipConfig {
+	return MembershipConfig{
+		HeartbeatInterval: 5 * time.Second,
+		SuspectAfter:      15 * time.Second,
+		DeadAfter:         time.Minute,
+	}
+}
+
+// Member describes a storage node known to the membership
+type Member struct {
+	NodeID string
+
+	// Address is the address a node daemon registered with; empty for nodes
+	// that are probed rather than sending heartbeats
+	Address string
+
+	State         string
+	LastHeartbeat time.Time
+	StateChanged  time.Time
+}
+
+// member is a node tracked by the membership
+type member struct {
+	Member
+	client NodeClient
+
+	// probe is set for nodes that don't send heartbeats, such as nodes
+	// running in process, which are probed instead
+	probe bool
+
+	// registered is the client registered with the File Manager; nil while
+	// the node is dead
+	registered NodeClient
+}
+
+// Membership tracks which storage nodes are up. Node daemons register
+// themselves and then send heartbeats; nodes registered with the File Manager
+// directly are probed every heartbeat interval instead. A node that stays
+// silent for SuspectAfter is suspected, and one that stays silent for
+// DeadAfter is declared dead and removed from the File Manager and the ring,
+// so reads and writes skip it and its versions are re-replicated. A dead node
+// that reports again is registered again and rebalanced like a new node.
+type Membership struct {
+	fileManager *FileManager
+	config      MembershipConfig
+	dial        func(address string) (NodeClient, error)
+	now         func() time.Time
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	membersMu sync.Mutex
+	members   map[string]*member
+
+	// applyMu serializes registering and removing nodes with the File
+	// Manager, which may call the nodes
+	applyMu sync.Mutex
+}
+
+// NewMembership creates a membership for the nodes of a file manager.
+// Timeouts that are not positive take their defaults, and a node is never
+// declared dead before it is suspected.
+func NewMembership(fileManager *FileManager, config MembershipConfig) *Membership {
+	defaults := DefaultMembershipConfig()
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = defaults.HeartbeatInterval
+	}
+	if config.SuspectAfter <= 0 {
+		config.SuspectAfter = 3 * config.HeartbeatInterval
+	}
+	if config.DeadAfter < config.SuspectAfter {
+		config.DeadAfter = 4 * config.SuspectAfter
+	}
+	return &Membership{
+		fileManager: fileManager,
+		config:      config,
+		now:         time.Now,
+		members:     make(map[string]*member),
+	}
+}
+
+// SetDialer sets how the membership connects to node daemons that register
+// themselves. Without a dialer, heartbeats from unknown nodes are rejected.
+func (m *Membership) SetDialer(dial func(address string) (NodeClient, error)) {
+	m.membersMu.Lock()
+	defer m.membersMu.Unlock()
+	m.dial = dial
+}
+
+// HeartbeatInterval returns how often nodes should send heartbeats
+func (m *Membership) HeartbeatInterval() time.Duration {
+	return m.config.HeartbeatInterval
+}
+
+// Start checks the members in the background every heartbeat interval until
+// Stop is called
+func (m *Membership) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(m.config.HeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			m.RunOnce(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops checking the members and waits for the check to exit
+func (m *Membership) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	<-m.done
+	m.cancel = nil
+}
+
+// Heartbeat records a heartbeat from the node daemon nodeID listening on
+// address. A node that is not yet known, or that moved to a new address, is
+// dialed and registered; a dead node is registered again. Only authenticated
+// callers, cluster members that presented a certificate, may move a node that
+// is already known, including nodes registered by other means, so anyone
+// able to reach the server can't point a node's ID at an address of their
+// own.
+func (m *Membership) Heartbeat(nodeID, address string, authenticated bool) error {
+	if nodeID == "" || address == "" {
+		return fmt.Errorf("node ID and address are required")
+	}
+
+	// Nodes registered by other means are known before the next check
+	// adopts them
+	m.adoptNodes()
+
+	m.membersMu.Lock()
+	mb := m.members[nodeID]
+	dial := m.dial
+	m.membersMu.Unlock()
+
+	var replaced NodeClient
+	if mb == nil || mb.Address != address {
+		if mb != nil && !authenticated {
+			return fmt.Errorf("node %s is already registered; only cluster members can move it to %s", nodeID, address)
+		}
+		if dial == nil {
+			return fmt.Errorf("node registration is not enabled")
+		}
+		client, err := dial(address)
+		if err != nil {
+			return fmt.Errorf("dial node %s at %s: %w", nodeID, address, err)
+		}
+
+		m.membersMu.Lock()
+		if mb = m.members[nodeID]; mb == nil {
+			mb = &member{Member: Member{NodeID: nodeID, State: NodeAlive, StateChanged: m.now()}}
+			m.members[nodeID] = mb
+			log.Printf("Node %s joined at %s", nodeID, address)
+		} else {
+			replaced = mb.client
+			log.Printf("Node %s moved to %s", nodeID, address)
+		}
+		mb.Address = address
+		mb.client = client
+		mb.probe = false
+		m.membersMu.Unlock()
+	}
+
+	m.membersMu.Lock()
+	mb.LastHeartbeat = m.now()
+	m.setState(mb, NodeAlive)
+	m.membersMu.Unlock()
+
+	err := m.reconcile(nodeID)
+	if closer, ok := replaced.(io.Closer); ok {
+		closer.Close()
+	}
+	return err
+}
+
+// RunOnce probes the nodes that don't send heartbeats, updates the state of
+// every member and registers or removes nodes to match their state. Nodes
+// registered with the File Manager by other means are adopted as probed
+// members.
+func (m *Membership) RunOnce(ctx context.Context) []Member {
+	m.adoptNodes()
+
+	// Probe concurrently so one unresponsive node doesn't delay the others
+	m.membersMu.Lock()
+	probes := make(map[string]NodeClient)
+	for nodeID, mb := range m.members {
+		if mb.probe {
+			probes[nodeID] = mb.client
+		}
+	}
+	m.membersMu.Unlock()
+
+	var wg sync.WaitGroup
+	var probeMu sync.Mutex
+	answered := make(map[string]bool)
+	for nodeID, client := range probes {
+		wg.Add(1)
+		go func(nodeID string, client NodeClient) {
+			defer wg.Done()
+			if probeNode(ctx, client, m.config.HeartbeatInterval) {
+				probeMu.Lock()
+				answered[nodeID] = true
+				probeMu.Unlock()
+			}
+		}(nodeID, client)
+	}
+	wg.Wait()
+
+	m.membersMu.Lock()
+	now := m.now()
+	nodeIDs := make([]string, 0, len(m.members))
+	for nodeID, mb := range m.members {
+		nodeIDs = append(nodeIDs, nodeID)
+		if answered[nodeID] {
+			mb.LastHeartbeat = now
+		}
+		silent := now.Sub(mb.LastHeartbeat)
+		switch {
+		case answered[nodeID]:
+			m.setState(mb, NodeAlive)
+		case silent >= m.config.DeadAfter:
+			m.setState(mb, NodeDead)
+		case silent >= m.config.SuspectAfter && mb.State == NodeAlive:
+			m.setState(mb, NodeSuspect)
+		}
+	}
+	m.membersMu.Unlock()
+
+	// Nodes whose registration failed earlier are retried as well
+	sort.Strings(nodeIDs)
+	for _, nodeID := range nodeIDs {
+		if err := m.reconcile(nodeID); err != nil {
+			log.Printf("Failed to update membership of node %s: %v", nodeID, err)
+		}
+	}
+	return m.Members()
+}
+
+// probeID names the version probes look up. Stored IDs never start with a
+// dot, so the lookup is cheap and always misses.
+const probeID = ".probe"
+
+// probeNode reports whether a node answers within timeout. The probe reads
+// from the node's backend, so a node whose storage is unreachable fails it.
+func probeNode(ctx context.Context, client NodeClient, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	answered := make(chan bool, 1)
+	go func() {
+		_, err := client.Checksum(probeID, probeID)
+		answered <- err == nil || errors.Is(err, fs.ErrNotExist)
+	}()
+	select {
+	case ok := <-answered:
+		return ok
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// adoptNodes starts tracking the nodes registered with the File Manager that
+// the membership doesn't know yet
+func (m *Membership) adoptNodes() {
+	nodes := m.fileManager.snapshotNodes()
+
+	m.membersMu.Lock()
+	defer m.membersMu.Unlock()
+	now := m.now()
+	for nodeID, client := range nodes {
+		if _, known := m.members[nodeID]; known {
+			continue
+		}
+		m.members[nodeID] = &member{
+			Member:     Member{NodeID: nodeID, State: NodeAlive, LastHeartbeat: now, StateChanged: now},
+			client:     client,
+			probe:      true,
+			registered: client,
+		}
+	}
+}
+
+// setState moves a member to state, logging the transition. The caller must
+// hold membersMu.
+func (m *Membership) setState(mb *member, state string) {
+	if mb.State == state {
+		return
+	}
+	log.Printf("Node %s is %s (was %s, last heard from %s)", mb.NodeID, state, mb.State, formatLastHeard(mb.LastHeartbeat))
+	mb.State = state
+	mb.StateChanged = m.now()
+	m.fileManager.SetNodeHealthy(mb.NodeID, state == NodeAlive)
+}
+
+// formatLastHeard formats the time of a node's last heartbeat for logs
+func formatLastHeard(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// reconcile registers a member with the File Manager or removes it, to match
+// its state: dead nodes are removed and every other node is registered with
+// its current client
+func (m *Membership) reconcile(nodeID string) error {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	m.membersMu.Lock()
+	mb := m.members[nodeID]
+	dead := mb.State == NodeDead
+	client, registered := mb.client, mb.registered
+	m.membersMu.Unlock()
+
+	switch {
+	case dead && registered != nil:
+		m.fileManager.UnregisterNode(nodeID)
+		registered = nil
+	case !dead && registered != client:
+		if err := m.fileManager.AddNode(nodeID, client); err != nil {
+			return err
+		}
+		registered = client
+	default:
+		return nil
+	}
+
+	m.membersMu.Lock()
+	mb.registered = registered
+	m.membersMu.Unlock()
+	return nil
+}
+
+// Members returns every known node sorted by ID
+func (m *Membership) Members() []Member {
+	m.membersMu.Lock()
+	defer m.membersMu.Unlock()
+
+	members := make([]Member, 0, len(m.members))
+	for _, mb := range m.members {
+		members = append(members, mb.Member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].NodeID < members[j].NodeID
+	})
+	return members
+}
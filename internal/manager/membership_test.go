@@ -0,0 +1,219 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// flakyNode is a node that can be made to stop answering
+type flakyNode struct {
+	NodeClient
+	down atomic.Bool
+}
+
+// Checksum implements NodeClient, failing while the node is down
+func (n *flakyNode) Checksum(fileID, versionID string) (string, error) {
+	if n.down.Load() {
+		return "", errors.New("connection refused")
+	}
+	return n.NodeClient.Checksum(fileID, versionID)
+}
+
+// memoryNode creates a node that keeps its data in memory
+func memoryNode(t *testing.T, nodeID string) *storage.Node {
+	t.Helper()
+	node, err := storage.NewBackendNode(nodeID, storage.NewMemoryBackend())
+	if err != nil {
+		t.Fatalf("NewBackendNode failed: %v", err)
+	}
+	return node
+}
+
+// testMembership creates a membership whose clock only moves when the test
+// advances it
+func testMembership(fm *FileManager) (*Membership, func(time.Duration)) {
+	m := NewMembership(fm, MembershipConfig{
+		HeartbeatInterval: time.Second,
+		SuspectAfter:      3 * time.Second,
+		DeadAfter:         10 * time.Second,
+	})
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	return m, func(d time.Duration) { now = now.Add(d) }
+}
+
+// memberState returns the state of a member, or "" if it is unknown
+func memberState(m *Membership, nodeID string) string {
+	for _, member := range m.Members() {
+		if member.NodeID == nodeID {
+			return member.State
+		}
+	}
+	return ""
+}
+
+func TestMembership(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("silent nodes are suspected, removed and added back", func(t *testing.T) {
+		fm := NewFileManager(NewMockMetadataStore(), 2)
+		flaky := &flakyNode{NodeClient: memoryNode(t, "node-3")}
+		fm.AddNode("node-1", memoryNode(t, "node-1"))
+		fm.AddNode("node-2", memoryNode(t, "node-2"))
+		fm.AddNode("node-3", flaky)
+
+		m, advance := testMembership(fm)
+		if members := m.RunOnce(ctx); len(members) != 3 || members[2].State != NodeAlive || members[2].Address != "" {
+			t.Fatalf("members = %+v, want three alive probed nodes", members)
+		}
+
+		flaky.down.Store(true)
+		advance(4 * time.Second)
+		m.RunOnce(ctx)
+		if state := memberState(m, "node-3"); state != NodeSuspect {
+			t.Errorf("state after missing heartbeats = %s, want suspect", state)
+		}
+		if _, exists := fm.getNode("node-3"); !exists {
+			t.Error("suspect node was removed")
+		}
+
+		advance(7 * time.Second)
+		m.RunOnce(ctx)
+		if state := memberState(m, "node-3"); state != NodeDead {
+			t.Errorf("state after the dead timeout = %s, want dead", state)
+		}
+		if _, exists := fm.getNode("node-3"); exists {
+			t.Error("dead node is still registered")
+		}
+		for i := 0; i < 50; i++ {
+			meta, err := fm.UploadFile(ctx, fmt.Sprintf("file-%d.txt", i), []byte("written while node-3 is dead"), "text/plain")
+			if err != nil {
+				t.Fatalf("UploadFile failed: %v", err)
+			}
+			if containsNode(meta.Versions[0].Nodes, "node-3") {
+				t.Fatalf("version written to dead node: %v", meta.Versions[0].Nodes)
+			}
+		}
+
+		flaky.down.Store(false)
+		advance(time.Second)
+		m.RunOnce(ctx)
+		if state := memberState(m, "node-3"); state != NodeAlive {
+			t.Errorf("state after recovering = %s, want alive", state)
+		}
+		if _, exists := fm.getNode("node-3"); !exists || fm.GetNodeCount() != 3 {
+			t.Errorf("recovered node not registered again, %d nodes", fm.GetNodeCount())
+		}
+	})
+
+	t.Run("daemons register with heartbeats", func(t *testing.T) {
+		fm := NewFileManager(NewMockMetadataStore(), 2)
+		m, advance := testMembership(fm)
+		var dialed []string
+		m.SetDialer(func(address string) (NodeClient, error) {
+			dialed = append(dialed, address)
+			return memoryNode(t, address), nil
+		})
+
+		if err := m.Heartbeat("node-1", "10.0.0.1:50061", false); err != nil {
+			t.Fatalf("Heartbeat failed: %v", err)
+		}
+		if _, exists := fm.getNode("node-1"); !exists {
+			t.Fatal("node not registered by its first heartbeat")
+		}
+		members := m.Members()
+		if len(members) != 1 || members[0].Address != "10.0.0.1:50061" || members[0].State != NodeAlive {
+			t.Errorf("members = %+v", members)
+		}
+
+		advance(2 * time.Second)
+		m.Heartbeat("node-1", "10.0.0.1:50061", false)
+		advance(2 * time.Second)
+		m.RunOnce(ctx)
+		if state := memberState(m, "node-1"); state != NodeAlive {
+			t.Errorf("state of a node sending heartbeats = %s, want alive", state)
+		}
+		if len(dialed) != 1 {
+			t.Errorf("dialed %v, want one dial", dialed)
+		}
+
+		advance(11 * time.Second)
+		m.RunOnce(ctx)
+		if _, exists := fm.getNode("node-1"); exists || memberState(m, "node-1") != NodeDead {
+			t.Error("silent daemon not removed")
+		}
+
+		// The daemon restarts on another address, which only a cluster
+		// member may claim
+		if err := m.Heartbeat("node-1", "10.0.0.2:50061", false); err == nil {
+			t.Fatal("expected error moving a node without authentication")
+		}
+		if len(dialed) != 1 || m.Members()[0].Address != "10.0.0.1:50061" {
+			t.Errorf("unauthenticated move dialed %v, members %+v", dialed, m.Members())
+		}
+		if err := m.Heartbeat("node-1", "10.0.0.2:50061", true); err != nil {
+			t.Fatalf("Heartbeat after restarting failed: %v", err)
+		}
+		if _, exists := fm.getNode("node-1"); !exists || memberState(m, "node-1") != NodeAlive {
+			t.Error("restarted daemon not registered again")
+		}
+		if len(dialed) != 2 || m.Members()[0].Address != "10.0.0.2:50061" {
+			t.Errorf("dialed %v, members %+v", dialed, m.Members())
+		}
+	})
+
+	t.Run("probed nodes can't be taken over", func(t *testing.T) {
+		fm := NewFileManager(NewMockMetadataStore(), 2)
+		node := memoryNode(t, "node-1")
+		if err := fm.AddNode("node-1", node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+		m, _ := testMembership(fm)
+		m.SetDialer(func(address string) (NodeClient, error) {
+			return memoryNode(t, address), nil
+		})
+
+		// Before the first check has adopted the node
+		if err := m.Heartbeat("node-1", "10.0.0.9:50061", false); err == nil {
+			t.Fatal("expected error re-pointing a probed node without authentication")
+		}
+		if client, _ := fm.getNode("node-1"); client != node {
+			t.Error("probed node's client replaced")
+		}
+		if members := m.Members(); len(members) != 1 || members[0].Address != "" {
+			t.Errorf("members = %+v", members)
+		}
+
+		if err := m.Heartbeat("node-1", "10.0.0.9:50061", true); err != nil {
+			t.Fatalf("authenticated Heartbeat failed: %v", err)
+		}
+		if client, _ := fm.getNode("node-1"); client == node {
+			t.Error("node not moved by an authenticated heartbeat")
+		}
+	})
+
+	t.Run("invalid heartbeats", func(t *testing.T) {
+		m, _ := testMembership(NewFileManager(NewMockMetadataStore(), 2))
+		if err := m.Heartbeat("node-1", "10.0.0.1:50061", false); err == nil {
+			t.Error("expected error registering a node without a dialer")
+		}
+		m.SetDialer(func(address string) (NodeClient, error) {
+			return nil, errors.New("connection refused")
+		})
+		if err := m.Heartbeat("", "10.0.0.1:50061", false); err == nil {
+			t.Error("expected error for a heartbeat without a node ID")
+		}
+		if err := m.Heartbeat("node-1", "10.0.0.1:50061", false); err == nil {
+			t.Error("expected error when the node can't be dialed")
+		}
+		if len(m.Members()) != 0 {
+			t.Errorf("members = %+v, want none", m.Members())
+		}
+	})
+}
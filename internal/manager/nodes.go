@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"context"
+	"io"
+
+	"github.com/yashlad/distributed-file-store/internal/chunker"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// NodeClient is how the File Manager reaches a storage node. A
+// *storage.Node serves it in process; a storagenode.Client reaches a node
+// daemon over gRPC. Missing versions must be reported as fs.ErrNotExist and
+// corrupted data as storage.ErrChecksumMismatch, whichever way the node is
+// reached.
+type NodeClient interface {
+	SetChunking(config chunker.Config) error
+
+	StoreFileStream(fileID, versionID string, r io.Reader, codec string, key []byte) (*storage.ObjectInfo, error)
+	ReplicateFile(fileID, versionID string, source io.Reader, codec string, key []byte) error
+	OpenFile(fileID, versionID string, key []byte) (io.ReadCloser, error)
+	RetrieveFile(fileID, versionID string, key []byte) ([]byte, error)
+	DeleteFile(fileID, versionID string) error
+	DeleteAllVersions(fileID string) error
+
+	FileExists(fileID, versionID string) bool
+	Checksum(fileID, versionID string) (string, error)
+	GetStorageSize() (int64, error)
+	ListFiles() ([]string, error)
+	ListVersions(fileID string) ([]string, error)
+	Inventory() ([]storage.InventoryEntry, error)
+}
+
+var _ NodeClient = (*storage.Node)(nil)
+
+// peerReplicator is implemented by clients of node daemons, which can copy a
+// version from one another without the data passing through the File
+// Manager
+type peerReplicator interface {
+	Address() string
+	PeerCopies() bool
+	ReplicateFrom(ctx context.Context, source, fileID, versionID, codec string, key []byte) (int64, error)
+}
+
+// contextWriter is implemented by clients of node daemons, whose writes can
+// be bound to a context, so a node that stops reading or never answers
+// can't stall the writer past its deadline
+type contextWriter interface {
+	StoreFileStreamContext(ctx context.Context, fileID, versionID string, r io.Reader, codec string, key []byte) (*storage.ObjectInfo, error)
+}
+
+// storeStream writes a version to node, bound to ctx if the node supports it
+func storeStream(ctx context.Context, node NodeClient, fileID, versionID string, r io.Reader, codec string, key []byte) (*storage.ObjectInfo, error) {
+	if writer, ok := node.(contextWriter); ok {
+		return writer.StoreFileStreamContext(ctx, fileID, versionID, r, codec, key)
+	}
+	return node.StoreFileStream(fileID, versionID, r, codec, key)
+}
+
+// replicateTo stores a copy of a version read from source on target, bound
+// to ctx if the target supports it
+func replicateTo(ctx context.Context, target NodeClient, fileID, versionID string, source io.Reader, codec string, key []byte) error {
+	if writer, ok := target.(contextWriter); ok {
+		_, err := writer.StoreFileStreamContext(ctx, fileID, versionID, source, codec, key)
+		return err
+	}
+	return target.ReplicateFile(fileID, versionID, source, codec, key)
+}
@@ -0,0 +1,150 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+	"github.com/yashlad/distributed-file-store/internal/storagenode"
+)
+
+var (
+	_ NodeClient     = (*storagenode.Client)(nil)
+	_ peerReplicator = (*storagenode.Client)(nil)
+	_ contextWriter  = (*storagenode.Client)(nil)
+)
+
+// remoteNode is a node daemon a test runs on a localhost port
+type remoteNode struct {
+	node   *storage.Node
+	daemon *storagenode.Daemon
+}
+
+// setupRemoteFileManager registers three node daemons, each running on its
+// own localhost port with the storagenode tests' cluster TLS identity, with
+// a new file manager
+func setupRemoteFileManager(t *testing.T) (*FileManager, map[string]*remoteNode) {
+	t.Helper()
+	fm := NewFileManager(NewMockMetadataStore(), 2)
+	t.Cleanup(fm.repairWG.Wait)
+
+	dir := filepath.Join("..", "storagenode", "testdata")
+	clusterTLS, err := storagenode.LoadClusterTLS(filepath.Join(dir, "cluster-node.pem"), filepath.Join(dir, "cluster-node-key.pem"), filepath.Join(dir, "cluster-ca.pem"))
+	if err != nil {
+		t.Fatalf("LoadClusterTLS failed: %v", err)
+	}
+
+	nodes := make(map[string]*remoteNode)
+	for i := 1; i <= 3; i++ {
+		nodeID := fmt.Sprintf("node-%d", i)
+		node, err := storage.NewNode(nodeID, t.TempDir())
+		if err != nil {
+			t.Fatalf("NewNode failed: %v", err)
+		}
+		daemon, err := storagenode.Listen("127.0.0.1:0", node, clusterTLS)
+		if err != nil {
+			t.Fatalf("Listen failed: %v", err)
+		}
+		go daemon.Serve()
+		t.Cleanup(daemon.Stop)
+
+		client, err := storagenode.Dial(daemon.Address(), clusterTLS)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		if err := fm.AddNode(nodeID, client); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+		nodes[nodeID] = &remoteNode{node: node, daemon: daemon}
+	}
+	return fm, nodes
+}
+
+func TestRemoteNodes(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("stored on a node daemon "), 50000)
+
+	t.Run("upload and download", func(t *testing.T) {
+		fm, nodes := setupRemoteFileManager(t)
+		meta, err := fm.UploadFile(ctx, "remote.txt", data, "text/plain")
+		if err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+		version := meta.Versions[0]
+		for _, nodeID := range version.Nodes {
+			if !nodes[nodeID].node.FileExists(meta.FileID, version.VersionID) {
+				t.Errorf("version not stored on daemon %s", nodeID)
+			}
+			if version.ChecksumOn(nodeID) == "" {
+				t.Errorf("no checksum recorded for %s", nodeID)
+			}
+		}
+
+		downloaded, _, err := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{})
+		if err != nil || !bytes.Equal(downloaded, data) {
+			t.Fatalf("DownloadFile = %d bytes, %v", len(downloaded), err)
+		}
+
+		if err := fm.DeleteFile(ctx, meta.FileID); err != nil {
+			t.Fatalf("DeleteFile failed: %v", err)
+		}
+		for nodeID, remote := range nodes {
+			if files, _ := remote.node.ListFiles(); len(files) != 0 {
+				t.Errorf("daemon %s still stores %v", nodeID, files)
+			}
+		}
+	})
+
+	t.Run("erasure coded upload", func(t *testing.T) {
+		fm, _ := setupRemoteFileManager(t)
+		result, err := fm.UploadStream(ctx, "striped.bin", bytes.NewReader(data), "application/octet-stream", UploadOptions{
+			StorageMode:  metadata.StorageErasure,
+			DataShards:   2,
+			ParityShards: 1,
+		})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		downloaded, _, err := fm.DownloadFile(ctx, result.FileID, "", DownloadOptions{})
+		if err != nil || !bytes.Equal(downloaded, data) {
+			t.Errorf("DownloadFile = %d bytes, %v", len(downloaded), err)
+		}
+	})
+
+	t.Run("reads survive a stopped daemon", func(t *testing.T) {
+		fm, nodes := setupRemoteFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "remote.txt", data, "text/plain")
+		nodes[meta.Versions[0].Nodes[0]].daemon.Stop()
+
+		downloaded, _, err := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{})
+		if err != nil || !bytes.Equal(downloaded, data) {
+			t.Errorf("DownloadFile with a replica down = %d bytes, %v", len(downloaded), err)
+		}
+		if health := fm.HealthCheck(); health[meta.Versions[0].Nodes[0]] {
+			t.Error("stopped daemon reported healthy")
+		}
+	})
+
+	t.Run("re-replication copies between daemons", func(t *testing.T) {
+		fm, nodes := setupRemoteFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "remote.txt", data, "text/plain")
+		lost := meta.Versions[0].Nodes[0]
+		nodes[lost].daemon.Stop()
+		fm.UnregisterNode(lost)
+
+		status, err := NewReplicator(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.Repaired != 1 {
+			t.Errorf("repaired %d versions, want 1", status.Repaired)
+		}
+		checkReplicated(t, fm, meta.FileID, data)
+	})
+}
@@ -0,0 +1,228 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/chunker"
+	"github.com/yashlad/distributed-file-store/internal/compression"
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+)
+
+// UploadOptions controls how an upload is replicated
+type UploadOptions struct {
+	// WriteQuorum is the number of replicas that must acknowledge the write
+	// for the upload to succeed. Zero uses the server default.
+	WriteQuorum int
+
+	// ReplicaFactor is the number of replicas a new file is kept at. Zero
+	// uses the server default. New versions always use the file's factor.
+	ReplicaFactor int
+
+	// StorageMode is metadata.StorageReplicated or metadata.StorageErasure.
+	// Empty uses the server default.
+	StorageMode string
+
+	// DataShards and ParityShards set the Reed-Solomon layout of an
+	// erasure-coded upload. Zero uses the server default.
+	DataShards   int
+	ParityShards int
+
+	// Compression is the codec nodes store the upload with, one of the
+	// compression package's codecs. Empty picks the codec by the server's
+	// content-type rules, falling back to the server default.
+	Compression string
+}
+
+// DownloadOptions controls how a download is served
+type DownloadOptions struct {
+	// ReadQuorum is the number of replicas whose checksums must agree before
+	// data is served. Zero uses the server default.
+	ReadQuorum int
+}
+
+// SetQuorum sets the default write and read quorums used when a request does
+// not specify its own. Both must be between 1 and the replica factor. Files
+// kept at fewer replicas use at most their own replica factor.
+func (fm *FileManager) SetQuorum(writeQuorum, readQuorum int) error {
+	if err := checkQuorum("write", writeQuorum, fm.replicaFactor); err != nil {
+		return err
+	}
+	if err := checkQuorum("read", readQuorum, fm.replicaFactor); err != nil {
+		return err
+	}
+
+	fm.writeQuorum = writeQuorum
+	fm.readQuorum = readQuorum
+	return nil
+}
+
+// SetReplicaTimeout sets how long each replica may take to store an upload.
+// The deadline applies to every replica separately, so a slow replica is
+// dropped without failing the others. Zero means replicas are bounded only by
+// the request context.
+func (fm *FileManager) SetReplicaTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("replica timeout %v must not be negative", timeout)
+	}
+
+	fm.replicaTimeout = timeout
+	return nil
+}
+
+// SetChunking sets the content-defined chunk sizes every node splits new
+// versions into. Versions already stored keep their chunks, so changing the
+// sizes only loses deduplication against data stored before the change.
+func (fm *FileManager) SetChunking(config chunker.Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	for _, node := range fm.nodes {
+		if err := node.SetChunking(config); err != nil {
+			return err
+		}
+	}
+	fm.chunking = config
+	return nil
+}
+
+// SetStorageMode sets how uploads that don't choose a storage mode are
+// stored, and the default Reed-Solomon layout of erasure-coded uploads
+func (fm *FileManager) SetStorageMode(mode string, dataShards, parityShards int) error {
+	layout, err := newStorageLayout(mode, dataShards, parityShards)
+	if err != nil {
+		return err
+	}
+
+	fm.storage = layout
+	return nil
+}
+
+// SetCompression sets the codec uploads that don't choose one are stored
+// with: the codec of the first rule matching the upload's content type, or
+// defaultCodec if none matches. Versions already stored keep their codec.
+func (fm *FileManager) SetCompression(defaultCodec string, rules []compression.Rule) error {
+	if err := compression.Validate(defaultCodec); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := compression.Validate(rule.Codec); err != nil {
+			return err
+		}
+	}
+
+	fm.compression = compressionPolicy{defaultCodec: defaultCodec, rules: rules}
+	return nil
+}
+
+// compressionPolicy picks the codec of uploads that don't choose one
+type compressionPolicy struct {
+	defaultCodec string
+	rules        []compression.Rule
+}
+
+// compressionFor returns the codec to store an upload of the given content
+// type with
+func (fm *FileManager) compressionFor(opts UploadOptions, contentType string) (string, error) {
+	codec := opts.Compression
+	if codec == "" {
+		var matched bool
+		if codec, matched = compression.Match(fm.compression.rules, contentType); !matched {
+			codec = fm.compression.defaultCodec
+		}
+	}
+	if err := compression.Validate(codec); err != nil {
+		return "", err
+	}
+	if codec == "" {
+		codec = compression.None
+	}
+	return codec, nil
+}
+
+// QuorumError is returned when too few replicas acknowledge a write. It
+// carries the outcome of every replica so callers can report why.
+type QuorumError struct {
+	Required int
+	Replicas []ReplicaResult
+}
+
+// Error implements error
+func (e *QuorumError) Error() string {
+	acked := 0
+	for _, replica := range e.Replicas {
+		if replica.Err == nil {
+			acked++
+		}
+	}
+	return fmt.Sprintf("write quorum not met: %d of %d replicas acknowledged, need %d",
+		acked, len(e.Replicas), e.Required)
+}
+
+// writeQuorumFor returns the write quorum to use for an upload of a file
+// kept at replicaFactor replicas
+func (fm *FileManager) writeQuorumFor(opts UploadOptions, replicaFactor int) (int, error) {
+	if opts.WriteQuorum == 0 {
+		return min(fm.writeQuorum, replicaFactor), nil
+	}
+	return opts.WriteQuorum, checkQuorum("write", opts.WriteQuorum, replicaFactor)
+}
+
+// replicaFactorFor returns the replica factor to use for a new file
+func (fm *FileManager) replicaFactorFor(opts UploadOptions) (int, error) {
+	if opts.ReplicaFactor == 0 {
+		return fm.replicaFactor, nil
+	}
+	if opts.ReplicaFactor < 0 {
+		return 0, fmt.Errorf("replica factor %d must be positive", opts.ReplicaFactor)
+	}
+	return opts.ReplicaFactor, nil
+}
+
+// storageFor returns how an upload is stored, filling in the server defaults
+func (fm *FileManager) storageFor(opts UploadOptions) (storageLayout, error) {
+	mode := opts.StorageMode
+	if mode == "" {
+		mode = fm.storage.mode
+	}
+	dataShards := opts.DataShards
+	if dataShards == 0 {
+		dataShards = fm.storage.dataShards
+	}
+	parityShards := opts.ParityShards
+	if parityShards == 0 {
+		parityShards = fm.storage.parityShards
+	}
+
+	layout, err := newStorageLayout(mode, dataShards, parityShards)
+	if err != nil {
+		return storageLayout{}, err
+	}
+	if layout.mode == metadata.StorageErasure && opts.WriteQuorum != 0 {
+		return storageLayout{}, fmt.Errorf("write quorum does not apply to erasure-coded uploads, which need every shard")
+	}
+	if layout.mode == metadata.StorageErasure && opts.ReplicaFactor != 0 {
+		return storageLayout{}, fmt.Errorf("replica factor does not apply to erasure-coded uploads, which store one shard per node")
+	}
+	return layout, nil
+}
+
+// readQuorumFor returns the read quorum to use for a download of a file
+// kept at replicaFactor replicas
+func (fm *FileManager) readQuorumFor(opts DownloadOptions, replicaFactor int) (int, error) {
+	if opts.ReadQuorum == 0 {
+		return min(fm.readQuorum, replicaFactor), nil
+	}
+	return opts.ReadQuorum, checkQuorum("read", opts.ReadQuorum, replicaFactor)
+}
+
+// checkQuorum validates a quorum against a replica factor
+func checkQuorum(kind string, quorum, replicaFactor int) error {
+	if quorum < 1 || quorum > replicaFactor {
+		return fmt.Errorf("%s quorum %d must be between 1 and the replica factor %d", kind, quorum, replicaFactor)
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/chunker"
+	"github.com/yashlad/distributed-file-store/internal/compression"
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+)
+
+// breakNode makes a node's storage path unusable so every write to it fails
+func breakNode(t *testing.T, fm *FileManager, nodeID string) {
+	path := localNode(fm, nodeID).StoragePath
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("failed to remove storage path: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("not a directory"), 0644); err != nil {
+		t.Fatalf("failed to replace storage path: %v", err)
+	}
+}
+
+func TestSetQuorum(t *testing.T) {
+	fm := setupTestFileManager(t)
+
+	tests := []struct {
+		name    string
+		write   int
+		read    int
+		wantErr bool
+	}{
+		{"defaults", 1, 1, false},
+		{"full quorum", 2, 2, false},
+		{"zero write quorum", 0, 1, true},
+		{"read quorum above replica factor", 1, 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fm.SetQuorum(tt.write, tt.read)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetQuorum(%d, %d) error = %v, wantErr %v", tt.write, tt.read, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetReplicaTimeout(t *testing.T) {
+	fm := setupTestFileManager(t)
+
+	if err := fm.SetReplicaTimeout(time.Second); err != nil {
+		t.Errorf("SetReplicaTimeout(1s) failed: %v", err)
+	}
+	if err := fm.SetReplicaTimeout(0); err != nil {
+		t.Errorf("SetReplicaTimeout(0) failed: %v", err)
+	}
+	if err := fm.SetReplicaTimeout(-time.Second); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+}
+
+func TestWriteQuorum(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("succeeds when enough replicas ack", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, err := fm.UploadStream(ctx, "w.txt", bytes.NewReader([]byte("data")), "text/plain", UploadOptions{WriteQuorum: 2})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		if len(meta.Replicas) != 2 {
+			t.Errorf("replicas = %d, want 2", len(meta.Replicas))
+		}
+	})
+
+	t.Run("fails and cleans up when too few replicas ack", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		// With two of three nodes broken, every placement includes a broken node
+		breakNode(t, fm, "test-node-1")
+		breakNode(t, fm, "test-node-2")
+
+		_, err := fm.UploadStream(ctx, "w.txt", bytes.NewReader([]byte("data")), "text/plain", UploadOptions{WriteQuorum: 2})
+		var quorumErr *QuorumError
+		if !errors.As(err, &quorumErr) {
+			t.Fatalf("expected QuorumError, got %v", err)
+		}
+		if len(quorumErr.Replicas) != 2 {
+			t.Errorf("replica results = %d, want 2", len(quorumErr.Replicas))
+		}
+
+		_, total, _ := fm.ListFiles(ctx, 1, 10)
+		if total != 0 {
+			t.Errorf("metadata saved for failed upload")
+		}
+		files, _ := fm.nodes["test-node-3"].ListFiles()
+		if len(files) != 0 {
+			t.Errorf("healthy node kept %d files from failed upload", len(files))
+		}
+	})
+
+	t.Run("rejects quorum above replica factor", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		_, err := fm.UploadStream(ctx, "w.txt", bytes.NewReader([]byte("data")), "text/plain", UploadOptions{WriteQuorum: 3})
+		if err == nil {
+			t.Error("expected error for write quorum above replica factor")
+		}
+	})
+
+	t.Run("server default applies", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		fm.SetQuorum(2, 1)
+		breakNode(t, fm, "test-node-1")
+		breakNode(t, fm, "test-node-2")
+
+		if _, err := fm.UploadFile(ctx, "w.txt", []byte("data"), "text/plain"); err == nil {
+			t.Error("expected default write quorum to be enforced")
+		}
+	})
+}
+
+func TestReadQuorum(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+
+	meta, _ := fm.UploadFile(ctx, "r.txt", []byte("quorum read"), "text/plain")
+	version := meta.Versions[0]
+
+	t.Run("replicas agree", func(t *testing.T) {
+		reader, err := fm.OpenFile(ctx, meta.FileID, "", DownloadOptions{ReadQuorum: 2})
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		reader.Close()
+	})
+
+	// Make one replica disagree
+	diverged := localNode(fm, version.Nodes[1])
+	checksumPath := filepath.Join(diverged.StoragePath, meta.FileID, version.VersionID, "checksum")
+	os.WriteFile(checksumPath, []byte("0000"), 0644)
+
+	t.Run("replicas disagree", func(t *testing.T) {
+		if _, err := fm.OpenFile(ctx, meta.FileID, "", DownloadOptions{ReadQuorum: 2}); err == nil {
+			t.Error("expected read quorum error")
+		}
+
+		// Diverge the replica again once read repair has run
+		fm.repairWG.Wait()
+		os.WriteFile(checksumPath, []byte("0000"), 0644)
+		if _, _, err := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{ReadQuorum: 2}); err == nil {
+			t.Error("expected read quorum error from DownloadFile")
+		}
+	})
+
+	t.Run("single replica read ignores disagreement", func(t *testing.T) {
+		fm.SetQuorum(1, 1)
+		reader, err := fm.OpenFile(ctx, meta.FileID, "", DownloadOptions{})
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		reader.Close()
+	})
+}
+
+func TestSetChunking(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+
+	if err := fm.SetChunking(chunker.Config{MinSize: 4096, AvgSize: 1024, MaxSize: 8192}); err == nil {
+		t.Error("expected error for average below minimum")
+	}
+	if err := fm.SetChunking(chunker.Config{MinSize: 1024, AvgSize: 4096, MaxSize: 16384}); err != nil {
+		t.Fatalf("SetChunking failed: %v", err)
+	}
+
+	data := make([]byte, 256*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	meta, err := fm.UploadFile(ctx, "big.bin", data, "application/octet-stream")
+	if err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+
+	edited := append(append(append([]byte(nil), data[:len(data)/2]...), []byte("edit")...), data[len(data)/2:]...)
+	result, err := fm.UploadNewVersion(ctx, meta.FileID, bytes.NewReader(edited), UploadOptions{})
+	if err != nil {
+		t.Fatalf("UploadNewVersion failed: %v", err)
+	}
+
+	original := make(map[string]bool)
+	for _, chunk := range meta.Versions[0].Chunks {
+		original[chunk.Digest] = true
+	}
+	changed := 0
+	for _, chunk := range result.Versions[1].Chunks {
+		if !original[chunk.Digest] {
+			changed++
+		}
+	}
+	if len(meta.Versions[0].Chunks) < 2 || changed > 3 {
+		t.Errorf("new version has %d of %d chunks changed, want at most 3", changed, len(result.Versions[1].Chunks))
+	}
+}
+
+func TestReplicaFactor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("upload keeps the requested replicas", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		result, err := fm.UploadStream(ctx, "critical.txt", bytes.NewReader([]byte("data")), "text/plain", UploadOptions{ReplicaFactor: 3})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		if len(result.Versions[0].Nodes) != 3 {
+			t.Errorf("version nodes = %v, want 3", result.Versions[0].Nodes)
+		}
+		if result.ReplicaFactor != 3 {
+			t.Errorf("replica factor = %d, want 3", result.ReplicaFactor)
+		}
+
+		// New versions follow the file, not the server default
+		next, err := fm.UploadNewVersion(ctx, result.FileID, bytes.NewReader([]byte("more")), UploadOptions{})
+		if err != nil {
+			t.Fatalf("UploadNewVersion failed: %v", err)
+		}
+		if len(next.Versions[1].Nodes) != 3 {
+			t.Errorf("new version nodes = %v, want 3", next.Versions[1].Nodes)
+		}
+	})
+
+	t.Run("single replica caps the default quorums", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		fm.SetQuorum(2, 2)
+		result, err := fm.UploadStream(ctx, "scratch.txt", bytes.NewReader([]byte("data")), "text/plain", UploadOptions{ReplicaFactor: 1})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		if len(result.Versions[0].Nodes) != 1 {
+			t.Errorf("version nodes = %v, want 1", result.Versions[0].Nodes)
+		}
+
+		reader, err := fm.OpenFile(ctx, result.FileID, "", DownloadOptions{})
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		reader.Close()
+		if _, err := fm.OpenFile(ctx, result.FileID, "", DownloadOptions{ReadQuorum: 2}); err == nil {
+			t.Error("expected error for read quorum above the file's replica factor")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		tests := []struct {
+			name string
+			opts UploadOptions
+		}{
+			{"negative", UploadOptions{ReplicaFactor: -1}},
+			{"write quorum above replica factor", UploadOptions{ReplicaFactor: 1, WriteQuorum: 2}},
+			{"erasure-coded", UploadOptions{ReplicaFactor: 2, StorageMode: "erasure"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := fm.UploadStream(ctx, "f.txt", bytes.NewReader([]byte("data")), "", tt.opts); err == nil {
+					t.Error("expected error")
+				}
+			})
+		}
+	})
+
+	t.Run("new version can't change it", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "f.txt", []byte("data"), "text/plain")
+		if _, err := fm.UploadNewVersion(ctx, meta.FileID, bytes.NewReader([]byte("more")), UploadOptions{ReplicaFactor: 3}); err == nil {
+			t.Error("expected error for a replica factor differing from the file's")
+		}
+	})
+}
+
+func TestCompression(t *testing.T) {
+	ctx := context.Background()
+	text := bytes.Repeat([]byte("2024-01-01T00:00:00Z INFO request served status=200\n"), 2000)
+
+	t.Run("upload with a codec", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		result, err := fm.UploadStream(ctx, "app.log", bytes.NewReader(text), "text/plain", UploadOptions{Compression: compression.Zstd})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		version := result.Versions[0]
+		if version.Compression != compression.Zstd {
+			t.Errorf("compression = %q, want zstd", version.Compression)
+		}
+		if version.Size != int64(len(text)) || version.StoredSize == 0 || version.StoredSize >= version.Size/2 {
+			t.Errorf("size %d, stored size %d, want the uncompressed size and less stored", version.Size, version.StoredSize)
+		}
+
+		data, _, err := fm.DownloadFile(ctx, result.FileID, "", DownloadOptions{})
+		if err != nil || !bytes.Equal(data, text) {
+			t.Errorf("DownloadFile = %d bytes, %v", len(data), err)
+		}
+
+		// Restoring keeps the version's codec
+		restored, err := fm.RestoreVersion(ctx, result.FileID, version.VersionID)
+		if err != nil {
+			t.Fatalf("RestoreVersion failed: %v", err)
+		}
+		if codec := restored.Versions[1].Compression; codec != compression.Zstd {
+			t.Errorf("restored compression = %q, want zstd", codec)
+		}
+	})
+
+	t.Run("content-type rules and default", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		rules, _ := compression.ParseRules("text/*=gzip,image/*=none")
+		if err := fm.SetCompression(compression.Snappy, rules); err != nil {
+			t.Fatalf("SetCompression failed: %v", err)
+		}
+
+		tests := []struct {
+			contentType string
+			opts        UploadOptions
+			want        string
+		}{
+			{"text/csv", UploadOptions{}, compression.Gzip},
+			{"image/png", UploadOptions{}, compression.None},
+			{"application/json", UploadOptions{}, compression.Snappy},
+			{"text/csv", UploadOptions{Compression: compression.Zstd}, compression.Zstd},
+		}
+		for _, tt := range tests {
+			result, err := fm.UploadStream(ctx, "f", bytes.NewReader(text), tt.contentType, tt.opts)
+			if err != nil {
+				t.Fatalf("UploadStream(%s) failed: %v", tt.contentType, err)
+			}
+			if codec := result.Versions[0].Compression; codec != tt.want {
+				t.Errorf("%s uploaded with %q, want %q", tt.contentType, codec, tt.want)
+			}
+		}
+	})
+
+	t.Run("erasure-coded", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		result, err := fm.UploadStream(ctx, "app.log", bytes.NewReader(text), "text/plain", UploadOptions{
+			StorageMode:  metadata.StorageErasure,
+			DataShards:   2,
+			ParityShards: 1,
+			Compression:  compression.Gzip,
+		})
+		if err != nil {
+			t.Fatalf("UploadStream failed: %v", err)
+		}
+		if stored := result.Versions[0].StoredSize; stored == 0 || stored >= int64(len(text)) {
+			t.Errorf("stored %d bytes across shards, want less than %d", stored, len(text))
+		}
+		data, _, err := fm.DownloadFile(ctx, result.FileID, "", DownloadOptions{})
+		if err != nil || !bytes.Equal(data, text) {
+			t.Errorf("DownloadFile = %d bytes, %v", len(data), err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		if _, err := fm.UploadStream(ctx, "f", bytes.NewReader(text), "", UploadOptions{Compression: "lz4"}); err == nil {
+			t.Error("expected error for unknown codec")
+		}
+		if err := fm.SetCompression("lz4", nil); err == nil {
+			t.Error("expected error for unknown default codec")
+		}
+		if err := fm.SetCompression(compression.Zstd, []compression.Rule{{Pattern: "text/*", Codec: "lz4"}}); err == nil {
+			t.Error("expected error for unknown rule codec")
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+)
+
+// Pruner periodically enforces version retention policies, deleting expired
+// versions from both the storage nodes and the metadata store
+type Pruner struct {
+	fileManager *FileManager
+	policy      metadata.RetentionPolicy
+	interval    time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPruner creates a pruner that applies policy to every file without a
+// retention policy of its own
+func NewPruner(fileManager *FileManager, policy metadata.RetentionPolicy, interval time.Duration) *Pruner {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &Pruner{
+		fileManager: fileManager,
+		policy:      policy,
+		interval:    interval,
+	}
+}
+
+// Start runs the pruner in the background until Stop is called
+func (p *Pruner) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pruned, err := p.RunOnce(ctx)
+				if err != nil && ctx.Err() == nil {
+					log.Printf("Retention pruning failed: %v", err)
+				} else if pruned > 0 {
+					log.Printf("Pruned %d expired versions", pruned)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the background pruner and waits for it to exit
+func (p *Pruner) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+	p.cancel = nil
+}
+
+// RunOnce applies retention policies to every file and returns the number of
+// versions that were deleted
+func (p *Pruner) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	pruned := 0
+
+	err := p.fileManager.forEachFile(ctx, func(file *metadata.FileMetadata) error {
+		policy := p.policy
+		if file.Retention != nil {
+			policy = *file.Retention
+		}
+
+		for _, version := range policy.Expired(file.Versions, now) {
+			if err := p.fileManager.DeleteVersion(ctx, file.FileID, version.VersionID); err != nil {
+				log.Printf("Failed to prune version %s of %s: %v", version.VersionID, file.FileID, err)
+				continue
+			}
+			pruned++
+		}
+		return nil
+	})
+
+	return pruned, err
+}
@@ -0,0 +1,138 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+)
+
+// uploadVersions uploads a file with the given number of versions
+func uploadVersions(t *testing.T, fm *FileManager, count int) *metadata.FileMetadata {
+	ctx := context.Background()
+	meta, err := fm.UploadFile(ctx, "versions.txt", []byte("version 0"), "text/plain")
+	if err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	for i := 1; i < count; i++ {
+		data := []byte(fmt.Sprintf("version %d", i))
+		result, err := fm.UploadNewVersion(ctx, meta.FileID, bytes.NewReader(data), UploadOptions{})
+		if err != nil {
+			t.Fatalf("UploadNewVersion failed: %v", err)
+		}
+		meta = result.FileMetadata
+	}
+	return meta
+}
+
+func TestPrunerKeepLast(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+	meta := uploadVersions(t, fm, 5)
+
+	pruner := NewPruner(fm, metadata.RetentionPolicy{KeepLast: 2}, time.Hour)
+	pruned, err := pruner.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if pruned != 3 {
+		t.Errorf("pruned = %d, want 3", pruned)
+	}
+
+	info, _ := fm.GetFileInfo(ctx, meta.FileID)
+	if len(info.Versions) != 2 {
+		t.Fatalf("Versions count = %d, want 2", len(info.Versions))
+	}
+	if info.Versions[1].VersionID != meta.Versions[4].VersionID {
+		t.Error("latest version was not kept")
+	}
+
+	// Pruned version data is gone from the nodes
+	for _, version := range meta.Versions[:3] {
+		for _, nodeID := range version.Nodes {
+			if fm.nodes[nodeID].FileExists(meta.FileID, version.VersionID) {
+				t.Errorf("pruned version %s still on node %s", version.VersionID, nodeID)
+			}
+		}
+	}
+}
+
+func TestPrunerMaxAge(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+	meta := uploadVersions(t, fm, 3)
+
+	// Age every version; the latest must survive regardless
+	stored := fm.metadataStore.(*MockMetadataStore)
+	stored.mu.Lock()
+	for i := range stored.files[meta.FileID].Versions {
+		stored.files[meta.FileID].Versions[i].CreatedAt = time.Now().Add(-48 * time.Hour)
+	}
+	stored.mu.Unlock()
+
+	pruner := NewPruner(fm, metadata.RetentionPolicy{MaxAge: 24 * time.Hour}, time.Hour)
+	pruned, err := pruner.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("pruned = %d, want 2", pruned)
+	}
+
+	info, _ := fm.GetFileInfo(ctx, meta.FileID)
+	if len(info.Versions) != 1 || info.Versions[0].VersionID != meta.Versions[2].VersionID {
+		t.Error("only the latest version should remain")
+	}
+}
+
+func TestPrunerPerFilePolicy(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+	keepAll := uploadVersions(t, fm, 3)
+	keepOne := uploadVersions(t, fm, 3)
+
+	if err := fm.SetRetention(ctx, keepOne.FileID, &metadata.RetentionPolicy{KeepLast: 1}); err != nil {
+		t.Fatalf("SetRetention failed: %v", err)
+	}
+
+	// No global policy: only the file with its own policy is pruned
+	pruner := NewPruner(fm, metadata.RetentionPolicy{}, time.Hour)
+	if _, err := pruner.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	info, _ := fm.GetFileInfo(ctx, keepAll.FileID)
+	if len(info.Versions) != 3 {
+		t.Errorf("file without policy has %d versions, want 3", len(info.Versions))
+	}
+	info, _ = fm.GetFileInfo(ctx, keepOne.FileID)
+	if len(info.Versions) != 1 {
+		t.Errorf("file with policy has %d versions, want 1", len(info.Versions))
+	}
+}
+
+func TestPrunerStartStop(t *testing.T) {
+	fm := setupTestFileManager(t)
+	meta := uploadVersions(t, fm, 3)
+
+	pruner := NewPruner(fm, metadata.RetentionPolicy{KeepLast: 1}, 10*time.Millisecond)
+	pruner.Start()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		info, _ := fm.GetFileInfo(context.Background(), meta.FileID)
+		if len(info.Versions) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	pruner.Stop()
+
+	info, _ := fm.GetFileInfo(context.Background(), meta.FileID)
+	if len(info.Versions) != 1 {
+		t.Errorf("Versions count = %d, want 1", len(info.Versions))
+	}
+}
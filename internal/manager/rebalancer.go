@@ -0,0 +1,313 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+)
+
+// RebalanceOptions controls a rebalance run
+type RebalanceOptions struct {
+	// DryRun plans the moves without copying or deleting any data
+	DryRun bool
+
+	// BytesPerSecond limits how fast data is copied. Zero uses the
+	// rebalancer's default rate.
+	BytesPerSecond int64
+}
+
+// RebalanceMove is a change of placement for one file version: the version is
+// copied to the Add nodes and, once the copies are verified, removed from the
+// Remove nodes
+type RebalanceMove struct {
+	FileID    string
+	VersionID string
+	Size      int64
+	Add       []string
+	Remove    []string
+}
+
+// RebalanceReport describes the rebalance that is running or, if none is,
+// the last one that ran. In dry-run mode it only lists the planned moves.
+type RebalanceReport struct {
+	DryRun      bool
+	Running     bool
+	Started     time.Time
+	Finished    time.Time
+	Moves       []RebalanceMove
+	Moved       int
+	Failed      int
+	BytesCopied int64
+	LastError   string
+}
+
+// Rebalancer moves file versions to the nodes the ring currently assigns them,
+// so that a newly registered node takes its share of existing data and
+// placement keeps matching the ring. Old copies are only deleted once every
+// new owner holds a verified copy.
+type Rebalancer struct {
+	fileManager    *FileManager
+	bytesPerSecond int64
+	pending        chan struct{}
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	runMu sync.Mutex
+
+	reportMu sync.Mutex
+	report   RebalanceReport
+}
+
+// NewRebalancer creates a rebalancer that by default copies at most
+// bytesPerSecond. A non-positive rate means unlimited.
+func NewRebalancer(fileManager *FileManager, bytesPerSecond int64) *Rebalancer {
+	r := &Rebalancer{
+		fileManager:    fileManager,
+		bytesPerSecond: bytesPerSecond,
+		pending:        make(chan struct{}, 1),
+	}
+	fileManager.onTopologyChange(r.Trigger)
+	return r
+}
+
+// Start rebalances in the background whenever a storage node is registered
+// or removed, until Stop is called
+func (r *Rebalancer) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.pending:
+			}
+
+			report, err := r.Run(ctx, RebalanceOptions{})
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Rebalance failed: %v", err)
+			} else if len(report.Moves) > 0 {
+				log.Printf("Rebalanced %d versions (%d failed, %d bytes copied)", report.Moved, report.Failed, report.BytesCopied)
+			}
+		}
+	}()
+}
+
+// Stop stops the background rebalancer and waits for it to exit
+func (r *Rebalancer) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	r.cancel = nil
+}
+
+// Trigger requests a rebalance as soon as possible. It does nothing if one is
+// already pending.
+func (r *Rebalancer) Trigger() {
+	select {
+	case r.pending <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the report of the current or last rebalance
+func (r *Rebalancer) Status() RebalanceReport {
+	r.reportMu.Lock()
+	defer r.reportMu.Unlock()
+	return r.report
+}
+
+// updateReport applies fn to the report under its lock
+func (r *Rebalancer) updateReport(fn func(report *RebalanceReport)) {
+	r.reportMu.Lock()
+	defer r.reportMu.Unlock()
+	fn(&r.report)
+}
+
+// Plan returns the moves needed to make the placement of every file version
+// match the ring
+func (r *Rebalancer) Plan(ctx context.Context) ([]RebalanceMove, error) {
+	var moves []RebalanceMove
+	err := r.fileManager.forEachFile(ctx, func(file *metadata.FileMetadata) error {
+		for _, version := range file.Versions {
+			if move, ok := r.fileManager.planMove(file, version); ok {
+				moves = append(moves, move)
+			}
+		}
+		return nil
+	})
+	return moves, err
+}
+
+// Run plans and, unless it is a dry run, executes a rebalance, returning its
+// report
+func (r *Rebalancer) Run(ctx context.Context, opts RebalanceOptions) (RebalanceReport, error) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	r.updateReport(func(report *RebalanceReport) {
+		*report = RebalanceReport{DryRun: opts.DryRun, Running: true, Started: time.Now()}
+	})
+
+	err := r.run(ctx, opts)
+
+	r.updateReport(func(report *RebalanceReport) {
+		report.Running = false
+		report.Finished = time.Now()
+		if err != nil {
+			report.LastError = err.Error()
+		}
+	})
+	return r.Status(), err
+}
+
+// run executes a rebalance, recording its progress in the report
+func (r *Rebalancer) run(ctx context.Context, opts RebalanceOptions) error {
+	moves, err := r.Plan(ctx)
+	r.updateReport(func(report *RebalanceReport) {
+		report.Moves = moves
+	})
+	if err != nil || opts.DryRun {
+		return err
+	}
+
+	rate := opts.BytesPerSecond
+	if rate == 0 {
+		rate = r.bytesPerSecond
+	}
+	limiter := newRateLimiter(rate)
+
+	for _, move := range moves {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var copied int64
+		err := r.fileManager.withVersion(ctx, move.FileID, move.VersionID, func(file *metadata.FileMetadata, current metadata.Version) (placementChange, error) {
+			change, n, err := r.fileManager.moveVersion(ctx, file, current, limiter)
+			copied = n
+			return change, err
+		})
+		if errors.Is(err, errVersionGone) {
+			// Deleted since it was planned
+			continue
+		}
+
+		r.updateReport(func(report *RebalanceReport) {
+			report.BytesCopied += copied
+			if err != nil {
+				report.Failed++
+			} else {
+				report.Moved++
+			}
+		})
+		if err != nil {
+			log.Printf("Failed to move version %s of %s: %v", move.VersionID, move.FileID, err)
+		}
+	}
+	return nil
+}
+
+// ringOwners returns the nodes the ring assigns a version to: the file's
+// replica factor's worth for a replicated version and one per shard for an
+// erasure-coded one
+func (fm *FileManager) ringOwners(file *metadata.FileMetadata, version metadata.Version) []string {
+	if version.IsErasureCoded() {
+		return fm.hashRing.GetNodesN(file.FileID, len(version.Nodes))
+	}
+	return fm.replicaOwners(file)
+}
+
+// planMove compares the placement of a version with its ring owners
+func (fm *FileManager) planMove(file *metadata.FileMetadata, version metadata.Version) (RebalanceMove, bool) {
+	owners := fm.ringOwners(file, version)
+	if version.IsErasureCoded() && len(owners) < len(version.Nodes) {
+		// Each shard needs a node of its own; nowhere to move them to
+		return RebalanceMove{}, false
+	}
+	move := RebalanceMove{
+		FileID:    file.FileID,
+		VersionID: version.VersionID,
+		Size:      version.Size,
+	}
+	for _, nodeID := range owners {
+		if !containsNode(version.Nodes, nodeID) {
+			move.Add = append(move.Add, nodeID)
+		}
+	}
+	for _, nodeID := range version.Nodes {
+		if !containsNode(owners, nodeID) {
+			move.Remove = append(move.Remove, nodeID)
+		}
+	}
+	return move, len(move.Add) > 0 || len(move.Remove) > 0
+}
+
+// moveVersion copies a version to every ring owner that lacks a healthy copy
+// and returns the owners as its new placement; the copies left on other
+// nodes are deleted once it is recorded. If any copy fails, the old copies
+// are kept and only the successful copies are added to the placement. It
+// returns the placement change and the number of bytes copied.
+func (fm *FileManager) moveVersion(ctx context.Context, file *metadata.FileMetadata, version metadata.Version, limiter *rateLimiter) (placementChange, int64, error) {
+	if _, ok := fm.planMove(file, version); !ok {
+		return placementChange{}, 0, nil
+	}
+	if version.IsErasureCoded() {
+		return fm.moveShards(ctx, file, version, limiter)
+	}
+
+	fileID := file.FileID
+	replicas := fm.healthyReplicas(fileID, version)
+	if len(replicas.healthy) == 0 {
+		return placementChange{}, 0, fmt.Errorf("no healthy replica to copy from")
+	}
+
+	owners := fm.replicaOwners(file)
+	placed := replicas.placed()
+	var written []string
+	var copied int64
+	var copyErr error
+	for _, nodeID := range owners {
+		if containsNode(placed, nodeID) {
+			continue
+		}
+
+		_, n, err := fm.copyReplica(ctx, fileID, version, replicas.healthy, nodeID, limiter)
+		copied += n
+		if err != nil {
+			copyErr = fmt.Errorf("copy to %s: %w", nodeID, err)
+			continue
+		}
+		placed = append(placed, nodeID)
+		written = append(written, nodeID)
+	}
+
+	if copyErr != nil {
+		// Keep the old copies until every owner has a verified copy
+		return placementChange{Nodes: placed, Written: written}, copied, copyErr
+	}
+	return placementChange{Nodes: owners, Written: written}, copied, nil
+}
@@ -0,0 +1,205 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// setupRebalance uploads files to a three node manager and then registers a
+// fourth node, so some versions are no longer placed on their ring owners
+func setupRebalance(t *testing.T, files int) (*FileManager, map[string][]byte) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+
+	contents := make(map[string][]byte)
+	for i := 0; i < files; i++ {
+		data := bytes.Repeat([]byte(fmt.Sprintf("file %d ", i)), 1024)
+		meta, err := fm.UploadFile(ctx, fmt.Sprintf("file-%d.txt", i), data, "text/plain")
+		if err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+		contents[meta.FileID] = data
+	}
+
+	if err := fm.RegisterNode("test-node-4", storage.BackendConfig{Path: filepath.Join(t.TempDir(), "test-node-4")}); err != nil {
+		t.Fatalf("Failed to register node: %v", err)
+	}
+	return fm, contents
+}
+
+func TestRebalancerDryRun(t *testing.T) {
+	fm, _ := setupRebalance(t, 20)
+	ctx := context.Background()
+	rebalancer := NewRebalancer(fm, 0)
+
+	plan, err := rebalancer.Plan(ctx)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan) == 0 {
+		t.Fatal("expected moves after adding a node")
+	}
+	for _, move := range plan {
+		if !containsNode(move.Add, "test-node-4") {
+			t.Errorf("move of %s does not add the new node: %+v", move.FileID, move)
+		}
+	}
+
+	report, err := rebalancer.Run(ctx, RebalanceOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !report.DryRun || len(report.Moves) != len(plan) {
+		t.Errorf("dry run reported %d moves, want %d", len(report.Moves), len(plan))
+	}
+	if report.Moved != 0 || report.BytesCopied != 0 {
+		t.Errorf("dry run moved %d versions, copying %d bytes", report.Moved, report.BytesCopied)
+	}
+	if files, _ := fm.nodes["test-node-4"].ListFiles(); len(files) != 0 {
+		t.Errorf("dry run copied %d files to the new node", len(files))
+	}
+}
+
+func TestRebalancerRun(t *testing.T) {
+	fm, contents := setupRebalance(t, 20)
+	ctx := context.Background()
+	rebalancer := NewRebalancer(fm, 0)
+
+	plan, _ := rebalancer.Plan(ctx)
+	report, err := rebalancer.Run(ctx, RebalanceOptions{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Moved != len(plan) || report.Failed != 0 {
+		t.Errorf("moved %d of %d versions, %d failed", report.Moved, len(plan), report.Failed)
+	}
+
+	for _, move := range plan {
+		info, _ := fm.GetFileInfo(ctx, move.FileID)
+		version := info.Versions[0]
+		owners := fm.hashRing.GetNodes(move.FileID)
+		if len(version.Nodes) != len(owners) {
+			t.Fatalf("version nodes = %v, want ring owners %v", version.Nodes, owners)
+		}
+
+		for _, nodeID := range owners {
+			stored, err := fm.nodes[nodeID].RetrieveFile(move.FileID, version.VersionID, nil)
+			if err != nil {
+				t.Fatalf("owner %s missing moved version: %v", nodeID, err)
+			}
+			if !bytes.Equal(stored, contents[move.FileID]) {
+				t.Errorf("owner %s holds wrong data", nodeID)
+			}
+		}
+		for _, nodeID := range move.Remove {
+			if fm.nodes[nodeID].FileExists(move.FileID, version.VersionID) {
+				t.Errorf("old copy on %s was not garbage-collected", nodeID)
+			}
+		}
+	}
+
+	if remaining, _ := rebalancer.Plan(ctx); len(remaining) != 0 {
+		t.Errorf("%d moves remain after rebalance", len(remaining))
+	}
+}
+
+func TestRebalancerKeepsOldCopiesOnFailure(t *testing.T) {
+	fm, _ := setupRebalance(t, 20)
+	ctx := context.Background()
+	breakNode(t, fm, "test-node-4")
+
+	rebalancer := NewRebalancer(fm, 0)
+	plan, _ := rebalancer.Plan(ctx)
+	report, err := rebalancer.Run(ctx, RebalanceOptions{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Failed != len(plan) {
+		t.Errorf("failed = %d, want %d", report.Failed, len(plan))
+	}
+
+	for _, move := range plan {
+		info, _ := fm.GetFileInfo(ctx, move.FileID)
+		version := info.Versions[0]
+		if len(version.Nodes) != fm.replicaFactor {
+			t.Errorf("version nodes = %v after failed move", version.Nodes)
+		}
+		for _, nodeID := range version.Nodes {
+			if !fm.nodes[nodeID].FileExists(move.FileID, version.VersionID) {
+				t.Errorf("copy on %s was removed although the move failed", nodeID)
+			}
+		}
+	}
+}
+
+func TestRebalancerThrottle(t *testing.T) {
+	fm, _ := setupRebalance(t, 10)
+	ctx := context.Background()
+	rebalancer := NewRebalancer(fm, 0)
+
+	plan, _ := rebalancer.Plan(ctx)
+	var total int64
+	for _, move := range plan {
+		total += move.Size * int64(len(move.Add))
+	}
+	if total == 0 {
+		t.Skip("no data to move")
+	}
+
+	// Copying everything should take about 200ms at this rate
+	start := time.Now()
+	report, err := rebalancer.Run(ctx, RebalanceOptions{BytesPerSecond: total * 5})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.BytesCopied != total {
+		t.Errorf("BytesCopied = %d, want %d", report.BytesCopied, total)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("rebalance took %v, expected throttling to slow it down", elapsed)
+	}
+}
+
+func TestRebalancerTriggeredByNodeJoin(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		fm.UploadFile(ctx, fmt.Sprintf("join-%d.txt", i), []byte("join"), "text/plain")
+	}
+
+	rebalancer := NewRebalancer(fm, 0)
+	rebalancer.Start()
+	defer rebalancer.Stop()
+
+	fm.RegisterNode("test-node-4", storage.BackendConfig{Path: filepath.Join(t.TempDir(), "test-node-4")})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		report := rebalancer.Status()
+		if !report.Running && !report.Finished.IsZero() && report.Moved > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("rebalance did not run after node join, report = %+v", report)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var placed int
+	fm.forEachFile(ctx, func(file *metadata.FileMetadata) error {
+		if containsNode(file.Versions[0].Nodes, "test-node-4") {
+			placed++
+		}
+		return nil
+	})
+	if placed == 0 {
+		t.Error("no versions were moved to the new node")
+	}
+}
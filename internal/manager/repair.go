@@ -0,0 +1,255 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// Reasons a replica needs repair
+const (
+	RepairMissing  = "missing"
+	RepairCorrupt  = "corrupt"
+	RepairDiverged = "diverged"
+)
+
+// repairLogSize is the number of repair events kept in memory
+const repairLogSize = 1000
+
+// RepairEvent records a replica that was found bad and rewritten from a
+// healthy one
+type RepairEvent struct {
+	Time      time.Time
+	FileID    string
+	VersionID string
+	NodeID    string
+	Reason    string
+	Source    string
+	Error     string
+}
+
+// NodeRepairStats counts the bad replicas found on a node and the outcome of
+// repairing them. A node whose counts keep growing likely has a failing disk.
+type NodeRepairStats struct {
+	Missing  int
+	Corrupt  int
+	Diverged int
+	Repaired int
+	Failed   int
+}
+
+// repairLog keeps the most recent repair events and per-node totals
+type repairLog struct {
+	mu     sync.Mutex
+	events []RepairEvent
+	next   int
+	stats  map[string]*NodeRepairStats
+}
+
+// record adds an event to the log and the totals of its node
+func (l *repairLog) record(event RepairEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.events) < repairLogSize {
+		l.events = append(l.events, event)
+	} else {
+		l.events[l.next] = event
+	}
+	l.next = (l.next + 1) % repairLogSize
+
+	if l.stats == nil {
+		l.stats = make(map[string]*NodeRepairStats)
+	}
+	stats, ok := l.stats[event.NodeID]
+	if !ok {
+		stats = &NodeRepairStats{}
+		l.stats[event.NodeID] = stats
+	}
+	switch event.Reason {
+	case RepairMissing:
+		stats.Missing++
+	case RepairCorrupt:
+		stats.Corrupt++
+	case RepairDiverged:
+		stats.Diverged++
+	}
+	if event.Error == "" {
+		stats.Repaired++
+	} else {
+		stats.Failed++
+	}
+}
+
+// RepairLog returns the most recent repair events, oldest first
+func (fm *FileManager) RepairLog() []RepairEvent {
+	fm.repairs.mu.Lock()
+	defer fm.repairs.mu.Unlock()
+
+	events := make([]RepairEvent, 0, len(fm.repairs.events))
+	if len(fm.repairs.events) == repairLogSize {
+		events = append(events, fm.repairs.events[fm.repairs.next:]...)
+		events = append(events, fm.repairs.events[:fm.repairs.next]...)
+	} else {
+		events = append(events, fm.repairs.events...)
+	}
+	return events
+}
+
+// RepairStats returns the repair totals of every node that needed a repair
+func (fm *FileManager) RepairStats() map[string]NodeRepairStats {
+	fm.repairs.mu.Lock()
+	defer fm.repairs.mu.Unlock()
+
+	stats := make(map[string]NodeRepairStats, len(fm.repairs.stats))
+	for nodeID, s := range fm.repairs.stats {
+		stats[nodeID] = *s
+	}
+	return stats
+}
+
+// repairReason classifies a read error, reporting whether rewriting the
+// replica from a healthy one would fix it
+func repairReason(err error) (string, bool) {
+	switch {
+	case errors.Is(err, storage.ErrChecksumMismatch):
+		return RepairCorrupt, true
+	case errors.Is(err, fs.ErrNotExist):
+		return RepairMissing, true
+	default:
+		return "", false
+	}
+}
+
+// badReplica is a replica found bad while serving a read
+type badReplica struct {
+	nodeID string
+	reason string
+}
+
+// scheduleRepairs repairs bad replicas of a version in the background
+func (fm *FileManager) scheduleRepairs(fileID, versionID string, bad []badReplica) {
+	for _, replica := range bad {
+		fm.scheduleRepair(fileID, versionID, replica.nodeID, replica.reason)
+	}
+}
+
+// scheduleRepair rewrites a bad replica in the background from a healthy one.
+// A replica that is already being repaired is not scheduled again.
+func (fm *FileManager) scheduleRepair(fileID, versionID, nodeID, reason string) {
+	key := fileID + "/" + versionID + "/" + nodeID
+
+	fm.repairMu.Lock()
+	if fm.repairing == nil {
+		fm.repairing = make(map[string]bool)
+	}
+	if fm.repairing[key] {
+		fm.repairMu.Unlock()
+		return
+	}
+	fm.repairing[key] = true
+	fm.repairMu.Unlock()
+
+	fm.repairWG.Add(1)
+	go func() {
+		defer fm.repairWG.Done()
+		defer func() {
+			fm.repairMu.Lock()
+			delete(fm.repairing, key)
+			fm.repairMu.Unlock()
+		}()
+
+		fm.repairReplica(context.Background(), fileID, versionID, nodeID, reason)
+	}()
+}
+
+// repairReplica replaces the copy of a version on a node with one copied from
+// another replica, or for an erasure-coded version rebuilt from the other
+// shards, and records the outcome in the repair log. Replicas that are no
+// longer part of the version's placement are left alone and reported as
+// errVersionGone.
+func (fm *FileManager) repairReplica(ctx context.Context, fileID, versionID, nodeID, reason string) error {
+	event := RepairEvent{
+		FileID:    fileID,
+		VersionID: versionID,
+		NodeID:    nodeID,
+		Reason:    reason,
+	}
+
+	err := fm.withVersion(ctx, fileID, versionID, func(_ *metadata.FileMetadata, version metadata.Version) (placementChange, error) {
+		_, exists := fm.getNode(nodeID)
+		if !exists || !containsNode(version.Nodes, nodeID) {
+			return placementChange{}, errVersionGone
+		}
+		repaired := placementChange{Written: []string{nodeID}}
+
+		if version.IsErasureCoded() {
+			// The rebuilt shard replaces the bad one only once it is fully
+			// written, and nothing is written unless enough shards open
+			source, _, err := fm.rebuildShard(ctx, fileID, version, shardIndex(version, nodeID), nodeID, nil)
+			event.Source = source
+			if err != nil {
+				return placementChange{}, err
+			}
+			return repaired, nil
+		}
+
+		var sources []string
+		for _, sourceID := range fm.healthyReplicas(fileID, version).healthy {
+			if sourceID != nodeID {
+				sources = append(sources, sourceID)
+			}
+		}
+		if len(sources) == 0 {
+			return placementChange{}, fmt.Errorf("no healthy replica to copy from")
+		}
+
+		// The new copy replaces the bad one only once it is fully written
+		source, _, err := fm.copyReplica(ctx, fileID, version, sources, nodeID, nil)
+		event.Source = source
+		if err != nil {
+			return placementChange{}, err
+		}
+		return repaired, nil
+	})
+	if errors.Is(err, errVersionGone) {
+		return err
+	}
+
+	event.Time = time.Now()
+	if err != nil {
+		event.Error = err.Error()
+		log.Printf("Failed to repair %s replica of %s/%s on node %s: %v", reason, fileID, versionID, nodeID, err)
+	} else {
+		log.Printf("Repaired %s replica of %s/%s on node %s from %s", reason, fileID, versionID, nodeID, event.Source)
+	}
+	fm.repairs.record(event)
+	return err
+}
+
+// repairingReader schedules a repair of the replica it reads from if the
+// data turns out to be corrupt
+type repairingReader struct {
+	io.ReadCloser
+	fileManager *FileManager
+	fileID      string
+	versionID   string
+	nodeID      string
+}
+
+// Read implements io.Reader
+func (r *repairingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if errors.Is(err, storage.ErrChecksumMismatch) {
+		r.fileManager.scheduleRepair(r.fileID, r.versionID, r.nodeID, RepairCorrupt)
+	}
+	return n, err
+}
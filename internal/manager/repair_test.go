@@ -0,0 +1,177 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// corruptReplica overwrites the first chunk of a replica, leaving its
+// checksum intact
+func corruptReplica(t *testing.T, fm *FileManager, nodeID, fileID, versionID string) {
+	chunks, err := localNode(fm, nodeID).Chunks(fileID, versionID)
+	if err != nil || len(chunks) == 0 {
+		t.Fatalf("no chunks to corrupt: %v", err)
+	}
+	// Compressed or encrypted chunks have a suffix after the digest
+	digest := chunks[0].Digest
+	paths, _ := filepath.Glob(filepath.Join(localNode(fm, nodeID).StoragePath, ".chunks", digest[:2], digest+"*"))
+	if len(paths) != 1 {
+		t.Fatalf("found %d files for chunk %s, want 1", len(paths), digest)
+	}
+	if err := os.WriteFile(paths[0], []byte("bit rot"), 0644); err != nil {
+		t.Fatalf("failed to corrupt replica: %v", err)
+	}
+}
+
+// checkRepaired verifies a replica holds the original data again and that the
+// repair was logged
+func checkRepaired(t *testing.T, fm *FileManager, nodeID, fileID, versionID string, data []byte, reason string) {
+	t.Helper()
+	fm.repairWG.Wait()
+
+	stored, err := fm.nodes[nodeID].RetrieveFile(fileID, versionID, nil)
+	if err != nil {
+		t.Fatalf("replica on %s not repaired: %v", nodeID, err)
+	}
+	if !bytes.Equal(stored, data) {
+		t.Errorf("repaired replica on %s has wrong data", nodeID)
+	}
+
+	events := fm.RepairLog()
+	if len(events) != 1 {
+		t.Fatalf("repair log has %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.NodeID != nodeID || event.Reason != reason || event.Error != "" || event.Source == "" {
+		t.Errorf("repair event = %+v, want successful %s repair of %s", event, reason, nodeID)
+	}
+
+	stats := fm.RepairStats()[nodeID]
+	if stats.Repaired != 1 || stats.Failed != 0 {
+		t.Errorf("stats for %s = %+v, want one repair", nodeID, stats)
+	}
+}
+
+func TestReadRepair(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("heal me on read")
+
+	t.Run("corrupt replica on download", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "corrupt.txt", data, "text/plain")
+		version := meta.Versions[0]
+		corruptReplica(t, fm, version.Nodes[0], meta.FileID, version.VersionID)
+
+		downloaded, _, err := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{})
+		if err != nil {
+			t.Fatalf("DownloadFile failed: %v", err)
+		}
+		if !bytes.Equal(downloaded, data) {
+			t.Error("downloaded data does not match")
+		}
+
+		checkRepaired(t, fm, version.Nodes[0], meta.FileID, version.VersionID, data, RepairCorrupt)
+		if fm.RepairStats()[version.Nodes[0]].Corrupt != 1 {
+			t.Error("corrupt replica not counted")
+		}
+	})
+
+	t.Run("missing replica on open", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "missing.txt", data, "text/plain")
+		version := meta.Versions[0]
+		fm.nodes[version.Nodes[0]].DeleteFile(meta.FileID, version.VersionID)
+
+		reader, err := fm.OpenFile(ctx, meta.FileID, "", DownloadOptions{})
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		io.ReadAll(reader)
+		reader.Close()
+
+		checkRepaired(t, fm, version.Nodes[0], meta.FileID, version.VersionID, data, RepairMissing)
+	})
+
+	t.Run("corruption found while streaming", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "stream.txt", data, "text/plain")
+		version := meta.Versions[0]
+		corruptReplica(t, fm, version.Nodes[0], meta.FileID, version.VersionID)
+
+		reader, err := fm.OpenFile(ctx, meta.FileID, "", DownloadOptions{})
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		_, err = io.ReadAll(reader)
+		reader.Close()
+		if !errors.Is(err, storage.ErrChecksumMismatch) {
+			t.Fatalf("expected checksum mismatch, got %v", err)
+		}
+
+		checkRepaired(t, fm, version.Nodes[0], meta.FileID, version.VersionID, data, RepairCorrupt)
+	})
+
+	t.Run("diverged replica on quorum read", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "diverged.txt", data, "text/plain")
+		version := meta.Versions[0]
+		checksumPath := filepath.Join(localNode(fm, version.Nodes[1]).StoragePath, meta.FileID, version.VersionID, "checksum")
+		os.WriteFile(checksumPath, []byte("0000"), 0644)
+
+		if _, err := fm.OpenFile(ctx, meta.FileID, "", DownloadOptions{ReadQuorum: 2}); err == nil {
+			t.Error("expected read quorum error")
+		}
+
+		checkRepaired(t, fm, version.Nodes[1], meta.FileID, version.VersionID, data, RepairDiverged)
+		reader, err := fm.OpenFile(ctx, meta.FileID, "", DownloadOptions{ReadQuorum: 2})
+		if err != nil {
+			t.Fatalf("quorum read after repair failed: %v", err)
+		}
+		reader.Close()
+	})
+
+	t.Run("no repair without a healthy replica", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "lost.txt", data, "text/plain")
+		version := meta.Versions[0]
+		for _, nodeID := range version.Nodes {
+			corruptReplica(t, fm, nodeID, meta.FileID, version.VersionID)
+		}
+
+		if _, _, err := fm.DownloadFile(ctx, meta.FileID, "", DownloadOptions{}); err == nil {
+			t.Fatal("expected download to fail")
+		}
+		fm.repairWG.Wait()
+		if events := fm.RepairLog(); len(events) != 0 {
+			t.Errorf("repair log has %d events, want none", len(events))
+		}
+	})
+}
+
+func TestRepairLogWraps(t *testing.T) {
+	var log repairLog
+	for i := 0; i < repairLogSize+5; i++ {
+		log.record(RepairEvent{NodeID: "node", Reason: RepairMissing, VersionID: string(rune('a' + i%26))})
+	}
+
+	fm := &FileManager{}
+	fm.repairs.events = log.events
+	fm.repairs.next = log.next
+	events := fm.RepairLog()
+	if len(events) != repairLogSize {
+		t.Fatalf("log has %d events, want %d", len(events), repairLogSize)
+	}
+	if events[len(events)-1].VersionID != string(rune('a'+(repairLogSize+4)%26)) {
+		t.Error("latest event is not last")
+	}
+	if log.stats["node"].Missing != repairLogSize+5 {
+		t.Errorf("Missing = %d, want %d", log.stats["node"].Missing, repairLogSize+5)
+	}
+}
@@ -0,0 +1,402 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// ReplicationStatus reports the progress of the re-replication scan that is
+// running or, if none is, of the last one that ran
+type ReplicationStatus struct {
+	Running         bool
+	LastStarted     time.Time
+	LastFinished    time.Time
+	FilesScanned    int
+	VersionsScanned int
+	UnderReplicated int
+	Repaired        int
+	Failed          int
+	BytesCopied     int64
+	LastError       string
+}
+
+// Replicator restores the replica factor of file versions that lost replicas
+// because a storage node was removed or its copy went missing or corrupt. It
+// copies data from surviving replicas to the nodes the ring now assigns and
+// records the new placement in the metadata store.
+type Replicator struct {
+	fileManager *FileManager
+	interval    time.Duration
+	trigger     chan struct{}
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	runMu sync.Mutex
+
+	statusMu sync.Mutex
+	status   ReplicationStatus
+}
+
+// NewReplicator creates a replicator that scans all files every interval and
+// whenever a storage node is registered or removed
+func NewReplicator(fileManager *FileManager, interval time.Duration) *Replicator {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	r := &Replicator{
+		fileManager: fileManager,
+		interval:    interval,
+		trigger:     make(chan struct{}, 1),
+	}
+	fileManager.onTopologyChange(r.Trigger)
+	return r
+}
+
+// Start runs the replicator in the background until Stop is called
+func (r *Replicator) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-r.trigger:
+			}
+
+			status, err := r.RunOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Re-replication failed: %v", err)
+			} else if status.Repaired > 0 || status.Failed > 0 {
+				log.Printf("Re-replicated %d versions (%d failed, %d bytes copied)", status.Repaired, status.Failed, status.BytesCopied)
+			}
+		}
+	}()
+}
+
+// Stop stops the background replicator and waits for it to exit
+func (r *Replicator) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	r.cancel = nil
+}
+
+// Trigger requests a scan as soon as possible without waiting for the next
+// interval. It does nothing if a scan is already pending.
+func (r *Replicator) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the progress of the current or last scan
+func (r *Replicator) Status() ReplicationStatus {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	return r.status
+}
+
+// updateStatus applies fn to the status under its lock
+func (r *Replicator) updateStatus(fn func(status *ReplicationStatus)) {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	fn(&r.status)
+}
+
+// RunOnce scans every file for under-replicated versions and repairs them,
+// returning the final status of the scan
+func (r *Replicator) RunOnce(ctx context.Context) (ReplicationStatus, error) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	r.updateStatus(func(status *ReplicationStatus) {
+		*status = ReplicationStatus{Running: true, LastStarted: time.Now()}
+	})
+
+	err := r.fileManager.forEachFile(ctx, func(file *metadata.FileMetadata) error {
+		for _, version := range file.Versions {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			var repaired bool
+			var copied int64
+			err := r.fileManager.withVersion(ctx, file.FileID, version.VersionID, func(file *metadata.FileMetadata, current metadata.Version) (placementChange, error) {
+				change, n, err := r.fileManager.replicateVersion(ctx, file, current)
+				repaired, copied = change.Nodes != nil, n
+				return change, err
+			})
+			if errors.Is(err, errVersionGone) {
+				// Deleted since the scan listed it
+				err = nil
+			}
+			r.updateStatus(func(status *ReplicationStatus) {
+				status.VersionsScanned++
+				status.BytesCopied += copied
+				if repaired || err != nil {
+					status.UnderReplicated++
+				}
+				if repaired {
+					status.Repaired++
+				}
+				if err != nil {
+					status.Failed++
+				}
+			})
+			if err != nil {
+				log.Printf("Failed to re-replicate version %s of %s: %v", version.VersionID, file.FileID, err)
+			}
+		}
+
+		r.updateStatus(func(status *ReplicationStatus) {
+			status.FilesScanned++
+		})
+		return nil
+	})
+
+	r.updateStatus(func(status *ReplicationStatus) {
+		status.Running = false
+		status.LastFinished = time.Now()
+		if err != nil {
+			status.LastError = err.Error()
+		}
+	})
+	return r.Status(), err
+}
+
+// replicateVersion brings a version back to its file's replica factor.
+// Replicas on removed nodes or with a missing or mismatched checksum are
+// dropped, and the version is copied from a healthy replica to the ring nodes
+// that lack it. Mismatched copies are only deleted once the new placement is
+// recorded; replicas that could not be checked keep their place. It returns
+// the placement change, with no new placement if none is needed, and how
+// many bytes were copied. Erasure-coded versions have their missing shards
+// rebuilt instead.
+func (fm *FileManager) replicateVersion(ctx context.Context, file *metadata.FileMetadata, version metadata.Version) (placementChange, int64, error) {
+	fileID := file.FileID
+	if version.IsErasureCoded() {
+		return fm.replicateShards(ctx, fileID, version)
+	}
+
+	replicas := fm.healthyReplicas(fileID, version)
+	placed := replicas.placed()
+
+	want := min(fm.replicaFactorOf(file), fm.GetNodeCount())
+	if len(placed) >= want && len(placed) == len(version.Nodes) {
+		return placementChange{}, 0, nil
+	}
+	if len(replicas.healthy) == 0 {
+		return placementChange{}, 0, fmt.Errorf("no healthy replica to copy from")
+	}
+
+	var written []string
+	var copied int64
+	for _, nodeID := range fm.replicaOwners(file) {
+		if len(placed) >= want {
+			break
+		}
+		if containsNode(placed, nodeID) {
+			continue
+		}
+
+		// A mismatched copy on an owner is replaced by the new one
+		_, n, err := fm.copyReplica(ctx, fileID, version, replicas.healthy, nodeID, nil)
+		copied += n
+		if err != nil {
+			fmt.Printf("Failed to replicate to node %s: %v\n", nodeID, err)
+			continue
+		}
+		placed = append(placed, nodeID)
+		written = append(written, nodeID)
+	}
+
+	change := placementChange{Nodes: placed, Written: written}
+	if len(placed) < want {
+		return change, copied, fmt.Errorf("only %d of %d replicas available", len(placed), want)
+	}
+	return change, copied, nil
+}
+
+// replicaHealth sorts the registered nodes of a version by the state of
+// their copy, or their shard of an erasure-coded version
+type replicaHealth struct {
+	// healthy nodes hold a copy whose checksum matches the version
+	healthy []string
+
+	// bad nodes have no copy or one whose checksum doesn't match
+	bad []string
+
+	// unknown nodes could not be checked, and keep their place in case the
+	// error was transient
+	unknown []string
+}
+
+// placed returns the nodes that keep their place in the version's placement
+func (r replicaHealth) placed() []string {
+	placed := append([]string(nil), r.healthy...)
+	return append(placed, r.unknown...)
+}
+
+// healthyReplicas checks the copy of a version on each of its nodes that is
+// still registered. It only reads checksums; bad copies are replaced or
+// deleted when a placement change made from healthy copies is recorded.
+func (fm *FileManager) healthyReplicas(fileID string, version metadata.Version) replicaHealth {
+	var replicas replicaHealth
+	for _, nodeID := range version.Nodes {
+		node, exists := fm.getNode(nodeID)
+		if !exists {
+			continue
+		}
+
+		checksum, err := node.Checksum(fileID, version.VersionID)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			replicas.bad = append(replicas.bad, nodeID)
+		case err != nil:
+			replicas.unknown = append(replicas.unknown, nodeID)
+		case version.ChecksumOn(nodeID) != "" && checksum != version.ChecksumOn(nodeID):
+			replicas.bad = append(replicas.bad, nodeID)
+		default:
+			replicas.healthy = append(replicas.healthy, nodeID)
+		}
+	}
+	return replicas
+}
+
+// copyReplica copies a version from the first source node that can serve it
+// to the target node, verifying the copy against the version checksum. Reads
+// are paced by limiter if one is given. It returns the source that was used
+// and the number of bytes read from the sources.
+func (fm *FileManager) copyReplica(ctx context.Context, fileID string, version metadata.Version, sources []string, targetID string, limiter *rateLimiter) (string, int64, error) {
+	target, exists := fm.getNode(targetID)
+	if !exists {
+		return "", 0, fmt.Errorf("node %s not registered", targetID)
+	}
+	dataKey, err := fm.dataKey(ctx, version)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var copied int64
+	lastErr := fmt.Errorf("no source replica available")
+	for _, sourceID := range sources {
+		if err := ctx.Err(); err != nil {
+			return "", copied, err
+		}
+
+		source, exists := fm.getNode(sourceID)
+		if !exists {
+			continue
+		}
+
+		n, err := copyVersion(ctx, sourceID, source, target, fileID, version, dataKey, limiter)
+		copied += n
+		if err != nil {
+			lastErr = fmt.Errorf("copy from %s: %w", sourceID, err)
+			if !containsNode(version.Nodes, targetID) {
+				// Writes replace a version atomically, so a node of the
+				// version still has its old copy for the caller to handle
+				target.DeleteFile(fileID, version.VersionID)
+			}
+			continue
+		}
+		return sourceID, copied, nil
+	}
+	return "", copied, lastErr
+}
+
+// copyVersion streams a version from one node to another, compressed with
+// the version's codec and encrypted with its data key, and checks that the
+// copy has the checksum expected of the source's copy. Unless the copy is
+// throttled, node daemons with cluster TLS copy the version between
+// themselves.
+func copyVersion(ctx context.Context, sourceID string, source, target NodeClient, fileID string, version metadata.Version, dataKey []byte, limiter *rateLimiter) (int64, error) {
+	var copied int64
+	sourcePeer, sourceIsPeer := source.(peerReplicator)
+	targetPeer, targetIsPeer := target.(peerReplicator)
+	if sourceIsPeer && targetIsPeer && targetPeer.PeerCopies() && limiter == nil {
+		n, err := targetPeer.ReplicateFrom(ctx, sourcePeer.Address(), fileID, version.VersionID, version.Compression, dataKey)
+		if err != nil {
+			return n, err
+		}
+		copied = n
+	} else {
+		reader, err := source.OpenFile(fileID, version.VersionID, dataKey)
+		if err != nil {
+			return 0, err
+		}
+		defer reader.Close()
+
+		counter := &countingReader{Reader: &throttledReader{ctx: ctx, reader: reader, limiter: limiter}}
+		err = replicateTo(ctx, target, fileID, version.VersionID, counter, version.Compression, dataKey)
+		copied = counter.n
+		if err != nil {
+			return copied, err
+		}
+	}
+
+	checksum, err := target.Checksum(fileID, version.VersionID)
+	if err != nil {
+		return copied, err
+	}
+	if expected := version.ChecksumOn(sourceID); expected != "" && checksum != expected {
+		return copied, storage.ErrChecksumMismatch
+	}
+	return copied, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+// Read implements io.Reader
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// containsNode reports whether nodeID is in nodes
+func containsNode(nodes []string, nodeID string) bool {
+	for _, n := range nodes {
+		if n == nodeID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// stalledNode is a node whose incoming copies of one version wait until
+// released
+type stalledNode struct {
+	NodeClient
+	versionID string
+	started   chan struct{}
+	release   chan struct{}
+}
+
+// ReplicateFile implements NodeClient, holding the copy until released
+func (n *stalledNode) ReplicateFile(fileID, versionID string, source io.Reader, codec string, key []byte) error {
+	if versionID == n.versionID {
+		close(n.started)
+		<-n.release
+	}
+	return n.NodeClient.ReplicateFile(fileID, versionID, source, codec, key)
+}
+
+// checkReplicated verifies that a file's latest version is on the replica
+// factor of registered nodes that all hold the original data
+func checkReplicated(t *testing.T, fm *FileManager, fileID string, data []byte) {
+	t.Helper()
+
+	info, err := fm.GetFileInfo(context.Background(), fileID)
+	if err != nil {
+		t.Fatalf("GetFileInfo failed: %v", err)
+	}
+	version := info.Versions[len(info.Versions)-1]
+	if len(version.Nodes) != fm.replicaFactor {
+		t.Fatalf("version nodes = %v, want %d nodes", version.Nodes, fm.replicaFactor)
+	}
+
+	for _, nodeID := range version.Nodes {
+		node, exists := fm.getNode(nodeID)
+		if !exists {
+			t.Fatalf("version still placed on removed node %s", nodeID)
+		}
+		stored, err := node.RetrieveFile(fileID, version.VersionID, nil)
+		if err != nil {
+			t.Fatalf("RetrieveFile on %s failed: %v", nodeID, err)
+		}
+		if !bytes.Equal(stored, data) {
+			t.Errorf("replica on %s does not match original", nodeID)
+		}
+	}
+	for _, nodeID := range info.Replicas {
+		if _, exists := fm.getNode(nodeID); !exists {
+			t.Errorf("replicas still include removed node %s", nodeID)
+		}
+	}
+}
+
+func TestReplicatorRunOnce(t *testing.T) {
+	ctx := context.Background()
+	data := []byte("replicate me")
+
+	t.Run("healthy files are left alone", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		fm.UploadFile(ctx, "healthy.txt", data, "text/plain")
+
+		status, err := NewReplicator(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.FilesScanned != 1 || status.VersionsScanned != 1 {
+			t.Errorf("scanned %d files, %d versions, want 1 and 1", status.FilesScanned, status.VersionsScanned)
+		}
+		if status.Repaired != 0 || status.BytesCopied != 0 {
+			t.Errorf("repaired %d versions copying %d bytes, want none", status.Repaired, status.BytesCopied)
+		}
+	})
+
+	t.Run("replaces replica on removed node", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "removed.txt", data, "text/plain")
+		fm.UnregisterNode(meta.Replicas[0])
+
+		status, err := NewReplicator(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.UnderReplicated != 1 || status.Repaired != 1 || status.Failed != 0 {
+			t.Errorf("status = %+v, want one repaired version", status)
+		}
+		if status.BytesCopied != int64(len(data)) {
+			t.Errorf("BytesCopied = %d, want %d", status.BytesCopied, len(data))
+		}
+		if status.Running || status.LastFinished.IsZero() {
+			t.Error("status does not show a finished scan")
+		}
+		checkReplicated(t, fm, meta.FileID, data)
+	})
+
+	t.Run("replaces corrupt replica", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "corrupt.txt", data, "text/plain")
+		version := meta.Versions[0]
+		corrupted := localNode(fm, version.Nodes[0])
+		os.WriteFile(filepath.Join(corrupted.StoragePath, meta.FileID, version.VersionID, "checksum"), []byte("0000"), 0644)
+
+		status, err := NewReplicator(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.Repaired != 1 {
+			t.Errorf("Repaired = %d, want 1", status.Repaired)
+		}
+		checkReplicated(t, fm, meta.FileID, data)
+	})
+
+	t.Run("reports versions with no healthy replica", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "lost.txt", data, "text/plain")
+		for _, nodeID := range meta.Replicas {
+			fm.nodes[nodeID].DeleteFile(meta.FileID, meta.Versions[0].VersionID)
+		}
+
+		status, err := NewReplicator(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.Failed != 1 || status.Repaired != 0 {
+			t.Errorf("status = %+v, want one failed version", status)
+		}
+	})
+
+	t.Run("keeps mismatched copies when none is healthy", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "mismatched.txt", data, "text/plain")
+		version := meta.Versions[0]
+		for _, nodeID := range version.Nodes {
+			node := localNode(fm, nodeID)
+			os.WriteFile(filepath.Join(node.StoragePath, meta.FileID, version.VersionID, "checksum"), []byte("0000"), 0644)
+		}
+
+		status, err := NewReplicator(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.Failed != 1 || status.Repaired != 0 {
+			t.Errorf("status = %+v, want one failed version", status)
+		}
+		info, _ := fm.GetFileInfo(ctx, meta.FileID)
+		if fmt.Sprint(info.Versions[0].Nodes) != fmt.Sprint(version.Nodes) {
+			t.Errorf("version nodes = %v, want %v", info.Versions[0].Nodes, version.Nodes)
+		}
+		for _, nodeID := range version.Nodes {
+			if !fm.nodes[nodeID].FileExists(meta.FileID, version.VersionID) {
+				t.Errorf("copy on %s was deleted", nodeID)
+			}
+		}
+	})
+
+	t.Run("keeps replicas that can't be checked", func(t *testing.T) {
+		fm := NewFileManager(NewMockMetadataStore(), 2)
+		nodes := make(map[string]*flakyNode)
+		for i := 1; i <= 3; i++ {
+			nodeID := fmt.Sprintf("node-%d", i)
+			nodes[nodeID] = &flakyNode{NodeClient: memoryNode(t, nodeID)}
+			fm.AddNode(nodeID, nodes[nodeID])
+		}
+		meta, _ := fm.UploadFile(ctx, "unreachable.txt", data, "text/plain")
+		version := meta.Versions[0]
+		nodes[version.Nodes[0]].down.Store(true)
+
+		status, err := NewReplicator(fm, time.Hour).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if status.Repaired != 0 || status.Failed != 0 {
+			t.Errorf("status = %+v, want the version left alone", status)
+		}
+		info, _ := fm.GetFileInfo(ctx, meta.FileID)
+		if fmt.Sprint(info.Versions[0].Nodes) != fmt.Sprint(version.Nodes) {
+			t.Errorf("version nodes = %v, want %v", info.Versions[0].Nodes, version.Nodes)
+		}
+	})
+
+	t.Run("deletes are not blocked by copies", func(t *testing.T) {
+		fm := NewFileManager(NewMockMetadataStore(), 2)
+		for i := 1; i <= 2; i++ {
+			nodeID := fmt.Sprintf("node-%d", i)
+			fm.AddNode(nodeID, memoryNode(t, nodeID))
+		}
+		meta, _ := fm.UploadFile(ctx, "stalled.txt", data, "text/plain")
+		fm.UploadNewVersion(ctx, meta.FileID, bytes.NewReader([]byte("v2")), UploadOptions{})
+		version := meta.Versions[0]
+		stalled := &stalledNode{NodeClient: memoryNode(t, "node-3"), versionID: version.VersionID, started: make(chan struct{}), release: make(chan struct{})}
+		fm.AddNode("node-3", stalled)
+		fm.UnregisterNode(version.Nodes[0])
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := NewReplicator(fm, time.Hour).RunOnce(ctx)
+			done <- err
+		}()
+		<-stalled.started
+
+		deleted := make(chan error, 1)
+		go func() { deleted <- fm.DeleteVersion(ctx, meta.FileID, version.VersionID) }()
+		select {
+		case err := <-deleted:
+			if err != nil {
+				t.Fatalf("DeleteVersion failed: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("DeleteVersion waited for the copy")
+		}
+
+		close(stalled.release)
+		if err := <-done; err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stalled.FileExists(meta.FileID, version.VersionID) {
+			t.Error("copy of the deleted version left on node-3")
+		}
+		info, _ := fm.GetFileInfo(ctx, meta.FileID)
+		if len(info.Versions) != 1 {
+			t.Errorf("file has %d versions, want 1", len(info.Versions))
+		}
+	})
+}
+
+func TestReplicatorTriggeredByNodeRemoval(t *testing.T) {
+	fm := setupTestFileManager(t)
+	ctx := context.Background()
+	data := []byte("replicate on removal")
+	meta, _ := fm.UploadFile(ctx, "trigger.txt", data, "text/plain")
+
+	replicator := NewReplicator(fm, time.Hour)
+	replicator.Start()
+	defer replicator.Stop()
+
+	fm.UnregisterNode(meta.Replicas[0])
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := replicator.Status()
+		if status.Repaired == 1 && !status.Running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replication did not run after node removal, status = %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	checkReplicated(t, fm, meta.FileID, data)
+}
@@ -0,0 +1,285 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+// maxReportedCorrupt is the number of corrupt objects listed in scrub stats
+const maxReportedCorrupt = 100
+
+// CorruptObject is a stored file version that failed verification
+type CorruptObject struct {
+	NodeID    string
+	FileID    string
+	VersionID string
+	Error     string
+}
+
+// ScrubStats reports the scrub that is running or, if none is, the last one
+// that ran
+type ScrubStats struct {
+	Running        bool
+	Started        time.Time
+	Finished       time.Time
+	NodesScanned   int
+	ObjectsScanned int
+	BytesScanned   int64
+	Corrupt        int
+	Repaired       int
+	RepairFailed   int
+	CorruptObjects []CorruptObject
+	LastError      string
+}
+
+// Scrubber periodically reads every object stored on every node and verifies
+// it against its SHA-256 checksum, so corruption in data nobody downloads is
+// found while healthy replicas remain. Corrupt replicas can optionally be
+// rewritten from another replica.
+type Scrubber struct {
+	fileManager    *FileManager
+	interval       time.Duration
+	bytesPerSecond int64
+	repair         bool
+	pending        chan struct{}
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	runMu sync.Mutex
+
+	statsMu sync.Mutex
+	stats   ScrubStats
+}
+
+// NewScrubber creates a scrubber that verifies all stored data every interval,
+// reading at most bytesPerSecond. A non-positive rate means unlimited. If
+// repair is set, corrupt replicas are rewritten from a healthy one.
+func NewScrubber(fileManager *FileManager, interval time.Duration, bytesPerSecond int64, repair bool) *Scrubber {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return &Scrubber{
+		fileManager:    fileManager,
+		interval:       interval,
+		bytesPerSecond: bytesPerSecond,
+		repair:         repair,
+		pending:        make(chan struct{}, 1),
+	}
+}
+
+// Start runs the scrubber in the background until Stop is called
+func (s *Scrubber) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.pending:
+			}
+
+			stats, err := s.RunOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Scrub failed: %v", err)
+			} else if stats.Corrupt > 0 {
+				log.Printf("Scrub found %d corrupt objects (%d repaired)", stats.Corrupt, stats.Repaired)
+			}
+		}
+	}()
+}
+
+// Stop stops the background scrubber and waits for it to exit
+func (s *Scrubber) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+}
+
+// Trigger requests a scrub as soon as possible without waiting for the next
+// interval. It does nothing if a scrub is already pending.
+func (s *Scrubber) Trigger() {
+	select {
+	case s.pending <- struct{}{}:
+	default:
+	}
+}
+
+// Stats returns the stats of the current or last scrub
+func (s *Scrubber) Stats() ScrubStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	stats := s.stats
+	stats.CorruptObjects = append([]CorruptObject(nil), s.stats.CorruptObjects...)
+	return stats
+}
+
+// updateStats applies fn to the stats under their lock
+func (s *Scrubber) updateStats(fn func(stats *ScrubStats)) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	fn(&s.stats)
+}
+
+// RunOnce verifies every object on every node and returns the final stats
+func (s *Scrubber) RunOnce(ctx context.Context) (ScrubStats, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	s.updateStats(func(stats *ScrubStats) {
+		*stats = ScrubStats{Running: true, Started: time.Now()}
+	})
+
+	err := s.scrub(ctx)
+
+	s.updateStats(func(stats *ScrubStats) {
+		stats.Running = false
+		stats.Finished = time.Now()
+		if err != nil {
+			stats.LastError = err.Error()
+		}
+	})
+	return s.Stats(), err
+}
+
+// scrub walks the nodes in order, verifying each object they store
+func (s *Scrubber) scrub(ctx context.Context) error {
+	limiter := newRateLimiter(s.bytesPerSecond)
+
+	nodes := s.fileManager.snapshotNodes()
+	nodeIDs := make([]string, 0, len(nodes))
+	for nodeID := range nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	for _, nodeID := range nodeIDs {
+		node := nodes[nodeID]
+		fileIDs, err := node.ListFiles()
+		if err != nil {
+			log.Printf("Failed to list files on node %s: %v", nodeID, err)
+			continue
+		}
+
+		for _, fileID := range fileIDs {
+			versionIDs, err := node.ListVersions(fileID)
+			if err != nil {
+				continue
+			}
+
+			for _, versionID := range versionIDs {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				s.verify(ctx, nodeID, fileID, versionID, limiter)
+			}
+		}
+
+		s.updateStats(func(stats *ScrubStats) {
+			stats.NodesScanned++
+		})
+	}
+	return nil
+}
+
+// verify reads one object in full, checking it against its checksum, and
+// repairs it if it is corrupt and repair is enabled
+func (s *Scrubber) verify(ctx context.Context, nodeID, fileID, versionID string, limiter *rateLimiter) {
+	node, exists := s.fileManager.getNode(nodeID)
+	if !exists {
+		return
+	}
+
+	// Encrypted objects can only be read with their data key, which objects
+	// the metadata doesn't know have none; leave those to fsck
+	dataKey, err := s.fileManager.storedDataKey(ctx, fileID, versionID)
+	if err != nil {
+		log.Printf("Scrub skipped %s/%s on node %s: %v", fileID, versionID, nodeID, err)
+		return
+	}
+
+	var size int64
+	reader, err := node.OpenFile(fileID, versionID, dataKey)
+	if dataKey == nil && errors.Is(err, storage.ErrKeyMismatch) {
+		return
+	}
+	if err == nil {
+		size, err = io.Copy(io.Discard, &throttledReader{ctx: ctx, reader: reader, limiter: limiter})
+		reader.Close()
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.updateStats(func(stats *ScrubStats) {
+		stats.ObjectsScanned++
+		stats.BytesScanned += size
+		if err != nil {
+			stats.Corrupt++
+			if len(stats.CorruptObjects) < maxReportedCorrupt {
+				stats.CorruptObjects = append(stats.CorruptObjects, CorruptObject{
+					NodeID:    nodeID,
+					FileID:    fileID,
+					VersionID: versionID,
+					Error:     err.Error(),
+				})
+			}
+		}
+	})
+	if err == nil {
+		return
+	}
+
+	log.Printf("Scrub found corrupt object %s/%s on node %s: %v", fileID, versionID, nodeID, err)
+	if !s.repair {
+		return
+	}
+
+	reason, ok := repairReason(err)
+	if !ok {
+		reason = RepairCorrupt
+	}
+	repairErr := s.fileManager.repairReplica(ctx, fileID, versionID, nodeID, reason)
+	if errors.Is(repairErr, errVersionGone) {
+		// Not referenced by any version in the metadata, nothing to repair from
+		return
+	}
+	s.updateStats(func(stats *ScrubStats) {
+		if repairErr != nil {
+			stats.RepairFailed++
+		} else {
+			stats.Repaired++
+		}
+	})
+}
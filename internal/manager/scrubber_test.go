@@ -0,0 +1,114 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestScrubberRunOnce(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("scrub "), 1024)
+
+	t.Run("clean data", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		for i := 0; i < 3; i++ {
+			fm.UploadFile(ctx, fmt.Sprintf("clean-%d.txt", i), data, "text/plain")
+		}
+
+		stats, err := NewScrubber(fm, time.Hour, 0, true).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stats.NodesScanned != 3 {
+			t.Errorf("NodesScanned = %d, want 3", stats.NodesScanned)
+		}
+		if stats.ObjectsScanned != 6 {
+			t.Errorf("ObjectsScanned = %d, want 6", stats.ObjectsScanned)
+		}
+		if stats.BytesScanned != int64(6*len(data)) {
+			t.Errorf("BytesScanned = %d, want %d", stats.BytesScanned, 6*len(data))
+		}
+		if stats.Corrupt != 0 || stats.Running || stats.Finished.IsZero() {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("reports corruption without repair", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "rot.txt", data, "text/plain")
+		version := meta.Versions[0]
+		corruptReplica(t, fm, version.Nodes[0], meta.FileID, version.VersionID)
+
+		stats, err := NewScrubber(fm, time.Hour, 0, false).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stats.Corrupt != 1 || stats.Repaired != 0 {
+			t.Errorf("stats = %+v, want one unrepaired corrupt object", stats)
+		}
+		if len(stats.CorruptObjects) != 1 || stats.CorruptObjects[0].NodeID != version.Nodes[0] {
+			t.Errorf("CorruptObjects = %+v", stats.CorruptObjects)
+		}
+		if _, err := fm.nodes[version.Nodes[0]].RetrieveFile(meta.FileID, version.VersionID, nil); err == nil {
+			t.Error("corrupt replica was modified")
+		}
+	})
+
+	t.Run("repairs corruption", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		meta, _ := fm.UploadFile(ctx, "rot.txt", data, "text/plain")
+		version := meta.Versions[0]
+		corruptReplica(t, fm, version.Nodes[0], meta.FileID, version.VersionID)
+
+		stats, err := NewScrubber(fm, time.Hour, 0, true).RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if stats.Corrupt != 1 || stats.Repaired != 1 || stats.RepairFailed != 0 {
+			t.Errorf("stats = %+v, want one repaired corrupt object", stats)
+		}
+		checkRepaired(t, fm, version.Nodes[0], meta.FileID, version.VersionID, data, RepairCorrupt)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		fm := setupTestFileManager(t)
+		fm.UploadFile(ctx, "slow.txt", data, "text/plain")
+
+		// Two copies at this rate take about 200ms to read
+		start := time.Now()
+		if _, err := NewScrubber(fm, time.Hour, int64(len(data))*10, false).RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("scrub took %v, expected rate limiting to slow it down", elapsed)
+		}
+	})
+}
+
+func TestScrubberTrigger(t *testing.T) {
+	fm := setupTestFileManager(t)
+	fm.UploadFile(context.Background(), "trigger.txt", []byte("scrub me"), "text/plain")
+
+	scrubber := NewScrubber(fm, time.Hour, 0, true)
+	scrubber.Start()
+	defer scrubber.Stop()
+	scrubber.Trigger()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := scrubber.Stats()
+		if !stats.Running && !stats.Finished.IsZero() {
+			if stats.ObjectsScanned != 2 {
+				t.Errorf("ObjectsScanned = %d, want 2", stats.ObjectsScanned)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("scrub did not run after trigger, stats = %+v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,273 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/yashlad/distributed-file-store/internal/metadata"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+)
+
+const (
+	// streamBufferSize is the size of the buffer used to fan data out to replicas
+	streamBufferSize = 256 * 1024
+
+	// replicaQueueDepth is the number of buffers queued per replica, letting a
+	// replica fall briefly behind without stalling the others
+	replicaQueueDepth = 4
+)
+
+// errReplicaFinished is the cancel cause of a replica that stopped reading
+var errReplicaFinished = errors.New("replica finished")
+
+// ReplicaResult reports the outcome of writing a file version to one replica
+type ReplicaResult struct {
+	NodeID   string
+	Err      error
+	Duration time.Duration
+
+	// HintedFor is the ring node a replica stands in for because that node
+	// failed the write; empty for replicas on the node the ring chose
+	HintedFor string
+
+	// chunks are the chunks the replica stored the version as, and
+	// storedSize the bytes they take on disk
+	chunks     []storage.Chunk
+	storedSize int64
+}
+
+// replicaStream is an in-flight write of one file version to one replica node
+type replicaStream struct {
+	nodeID string
+	chunks chan []byte
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	result chan ReplicaResult
+	failed bool
+}
+
+// streamResult describes a file version streamed to a set of replicas
+type streamResult struct {
+	Size        int64
+	Checksum    string
+	Chunks      []metadata.Chunk
+	StorageMode string
+	Erasure     *metadata.ErasureCoding
+	Compression string
+	StoredSize  int64
+	Encryption  *metadata.Encryption
+	StoredNodes []string
+	Replicas    []ReplicaResult
+}
+
+// streamToReplicas writes the data read from r to every given node in
+// parallel. Each replica consumes the data from its own bounded queue under
+// its own deadline derived from ctx, and the SHA-256 checksum is computed
+// along the way, so memory stays bounded no matter how large the file is. A
+// replica that fails or times out is dropped from the fan-out and the
+// remaining replicas carry on; the outcome of every replica is reported.
+// Nodes compress what they store with codec and encrypt it with dataKey.
+func (fm *FileManager) streamToReplicas(ctx context.Context, fileID, versionID string, nodeIDs []string, r io.Reader, codec string, dataKey []byte) (*streamResult, error) {
+	result := &streamResult{StorageMode: metadata.StorageReplicated, Compression: codec}
+
+	var streams []*replicaStream
+	for _, nodeID := range nodeIDs {
+		node, exists := fm.getNode(nodeID)
+		if !exists {
+			result.Replicas = append(result.Replicas, ReplicaResult{
+				NodeID: nodeID,
+				Err:    fmt.Errorf("node not registered"),
+			})
+			continue
+		}
+
+		rs := fm.startReplica(ctx, nodeID, node, fileID, versionID, codec, dataKey)
+		streams = append(streams, rs)
+	}
+
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("no storage nodes available")
+	}
+
+	hasher := sha256.New()
+	buf := make([]byte, streamBufferSize)
+	var size int64
+
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			hasher.Write(buf[:n])
+			size += int64(n)
+
+			// Replicas read the chunk concurrently, so each one gets the same copy
+			chunk := make([]byte, n)
+			copy(chunk, buf[:n])
+
+			active := 0
+			for _, rs := range streams {
+				if rs.failed {
+					continue
+				}
+				select {
+				case rs.chunks <- chunk:
+					active++
+				case <-rs.ctx.Done():
+					rs.failed = true
+				}
+			}
+			if active == 0 {
+				// Every replica failed; stop reading and report why
+				break
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			fm.abortStreams(fileID, versionID, streams, readErr)
+			return nil, readErr
+		}
+	}
+
+	result.Size = size
+	result.Checksum = hex.EncodeToString(hasher.Sum(nil))
+
+	fm.finishStreams(fileID, versionID, streams, result)
+	for _, replica := range result.Replicas {
+		if replica.Err == nil {
+			// Every replica splits and compresses the data the same way
+			result.Chunks = versionChunks(replica.chunks)
+			result.StoredSize = replica.storedSize
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// abortStreams cancels every stream with cause, waits for the replicas to
+// stop and removes whatever was partially written
+func (fm *FileManager) abortStreams(fileID, versionID string, streams []*replicaStream, cause error) {
+	nodeIDs := make([]string, len(streams))
+	for i, rs := range streams {
+		rs.cancel(cause)
+		<-rs.result
+		nodeIDs[i] = rs.nodeID
+	}
+	fm.cleanupFailedUpload(fileID, versionID, nodeIDs)
+}
+
+// finishStreams closes the queues of the streams once all data has been
+// sent, waits for every replica to finish and records their outcomes in
+// result
+func (fm *FileManager) finishStreams(fileID, versionID string, streams []*replicaStream, result *streamResult) {
+	var failedNodes []string
+	for _, rs := range streams {
+		close(rs.chunks)
+		replica := <-rs.result
+		if replica.Err == nil && rs.failed {
+			replica.Err = fmt.Errorf("replica stopped reading")
+		}
+		result.Replicas = append(result.Replicas, replica)
+
+		if replica.Err != nil {
+			// Log error but continue with other replicas
+			fmt.Printf("Failed to store on node %s: %v\n", rs.nodeID, replica.Err)
+			failedNodes = append(failedNodes, rs.nodeID)
+			continue
+		}
+		result.StoredNodes = append(result.StoredNodes, rs.nodeID)
+	}
+
+	// Don't leave partial copies behind on replicas that failed mid-stream
+	fm.cleanupFailedUpload(fileID, versionID, failedNodes)
+}
+
+// startReplica starts writing a file version to a node in the background,
+// compressed with codec and encrypted with dataKey. The write gets its own
+// deadline derived from ctx and reads its data from a bounded queue that is
+// closed once the upload has been fully read.
+func (fm *FileManager) startReplica(ctx context.Context, nodeID string, node NodeClient, fileID, versionID, codec string, dataKey []byte) *replicaStream {
+	replicaCtx, cancel := context.WithCancelCause(ctx)
+	if fm.replicaTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		replicaCtx, cancelTimeout = context.WithTimeout(replicaCtx, fm.replicaTimeout)
+		stop := cancel
+		cancel = func(cause error) {
+			stop(cause)
+			cancelTimeout()
+		}
+	}
+
+	rs := &replicaStream{
+		nodeID: nodeID,
+		chunks: make(chan []byte, replicaQueueDepth),
+		ctx:    replicaCtx,
+		cancel: cancel,
+		result: make(chan ReplicaResult, 1),
+	}
+
+	go func() {
+		start := time.Now()
+		info, err := storeStream(replicaCtx, node, fileID, versionID, &chunkReader{ctx: replicaCtx, chunks: rs.chunks}, codec, dataKey)
+		if err == nil {
+			// Surface a deadline that expired while the data was being flushed
+			err = replicaCtx.Err()
+		}
+		// Unblock the sender if the node stopped reading early
+		cancel(errReplicaFinished)
+
+		result := ReplicaResult{NodeID: nodeID, Err: err, Duration: time.Since(start)}
+		if info != nil {
+			result.chunks = info.Chunks
+			result.storedSize = info.StoredSize
+		}
+		rs.result <- result
+	}()
+
+	return rs
+}
+
+// chunkReader reads the chunks queued for one replica
+type chunkReader struct {
+	ctx     context.Context
+	chunks  <-chan []byte
+	current []byte
+}
+
+// Read implements io.Reader, returning the cause if the replica was cancelled
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.current) == 0 {
+		select {
+		case chunk, ok := <-r.chunks:
+			if !ok {
+				return 0, io.EOF
+			}
+			r.current = chunk
+		case <-r.ctx.Done():
+			return 0, context.Cause(r.ctx)
+		}
+	}
+
+	n := copy(p, r.current)
+	r.current = r.current[n:]
+	return n, nil
+}
+
+// versionChunks converts the chunks stored by a node to their metadata
+func versionChunks(chunks []storage.Chunk) []metadata.Chunk {
+	if len(chunks) == 0 {
+		return nil
+	}
+	converted := make([]metadata.Chunk, len(chunks))
+	for i, chunk := range chunks {
+		converted[i] = metadata.Chunk{Digest: chunk.Digest, Size: chunk.Size}
+	}
+	return converted
+}
@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// rateLimiter paces reads to an average number of bytes per second. It is
+// safe for concurrent use, so several readers can share one budget.
+type rateLimiter struct {
+	mu             sync.Mutex
+	bytesPerSecond int64
+	next           time.Time
+}
+
+// newRateLimiter creates a limiter; a non-positive rate means unlimited
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{bytesPerSecond: bytesPerSecond}
+}
+
+// wait blocks until n more bytes fit within the rate or ctx is done
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.bytesPerSecond))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttledReader limits the rate at which an underlying reader is consumed
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *rateLimiter
+}
+
+// Read implements io.Reader
+func (r *throttledReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
@@ -9,5 +9,13 @@ type Store interface {
 	DeleteMetadata(ctx context.Context, fileID string) error
 	ListMetadata(ctx context.Context, page, pageSize int32) ([]*FileMetadata, int64, error)
 	AddVersion(ctx context.Context, fileID string, version Version) error
+	RemoveVersion(ctx context.Context, fileID, versionID string) error
+	SetVersionNodes(ctx context.Context, fileID, versionID string, nodes []string) error
+	SetVersionEncryption(ctx context.Context, fileID, versionID string, encryption *Encryption) error
+	SetRetention(ctx context.Context, fileID string, policy *RetentionPolicy) error
+	SetReplicaFactor(ctx context.Context, fileID string, replicaFactor int) error
+	SaveHint(ctx context.Context, hint Hint) error
+	ListHints(ctx context.Context) ([]Hint, error)
+	DeleteHint(ctx context.Context, hint Hint) error
 	Close(ctx context.Context) error
 }
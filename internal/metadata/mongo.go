@@ -2,6 +2,7 @@ package metadata
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -10,6 +11,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrNotFound is returned when no metadata exists for a file
+var ErrNotFound = errors.New("file not found")
+
 // FileMetadata represents file metadata stored in MongoDB
 type FileMetadata struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"`
@@ -19,22 +23,138 @@ type FileMetadata struct {
 	ContentType string             `bson:"content_type"`
 	Versions    []Version          `bson:"versions"`
 	Replicas    []string           `bson:"replicas"`
+	Retention   *RetentionPolicy   `bson:"retention,omitempty"`
 	CreatedAt   time.Time          `bson:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at"`
+
+	// ReplicaFactor is the number of replicas the file's replicated versions
+	// are kept at. Zero follows the server default.
+	ReplicaFactor int `bson:"replica_factor,omitempty"`
 }
 
-// Version represents a file version
+// Storage modes of a version
+const (
+	// StorageReplicated stores a full copy of the version on every node
+	StorageReplicated = "replicated"
+
+	// StorageErasure stores one Reed-Solomon shard of the version on every
+	// node; any DataShards of the shards are enough to rebuild it
+	StorageErasure = "erasure"
+)
+
+// Version represents a file version. Size and Checksum describe the
+// uncompressed content even when nodes store it compressed; StoredSize is
+// what one copy takes on disk, or all shards together for an erasure-coded
+// version.
 type Version struct {
-	VersionID   string    `bson:"version_id"`
-	Size        int64     `bson:"size"`
-	Nodes       []string  `bson:"nodes"`
-	CreatedAt   time.Time `bson:"created_at"`
+	VersionID   string         `bson:"version_id"`
+	Size        int64          `bson:"size"`
+	Checksum    string         `bson:"checksum"`
+	Chunks      []Chunk        `bson:"chunks,omitempty"`
+	Nodes       []string       `bson:"nodes"`
+	StorageMode string         `bson:"storage_mode,omitempty"`
+	Erasure     *ErasureCoding `bson:"erasure,omitempty"`
+	Compression string         `bson:"compression,omitempty"`
+	StoredSize  int64          `bson:"stored_size,omitempty"`
+	Encryption  *Encryption    `bson:"encryption,omitempty"`
+	CreatedAt   time.Time      `bson:"created_at"`
+}
+
+// IsErasureCoded reports whether the version is stored as erasure-coded
+// shards. Versions without a storage mode are replicated.
+func (v Version) IsErasureCoded() bool {
+	return v.StorageMode == StorageErasure && v.Erasure != nil
+}
+
+// ChecksumOn returns the checksum the copy of the version on a node must
+// have: the checksum of the node's shard for an erasure-coded version, and
+// the checksum of the whole version otherwise
+func (v Version) ChecksumOn(nodeID string) string {
+	if !v.IsErasureCoded() {
+		return v.Checksum
+	}
+	for i, id := range v.Nodes {
+		if id == nodeID && i < len(v.Erasure.ShardChecksums) {
+			return v.Erasure.ShardChecksums[i]
+		}
+	}
+	return ""
+}
+
+// ErasureCoding describes how an erasure-coded version is split. The data is
+// cut into stripes of DataShards blocks of BlockSize bytes, the last one
+// zero-padded, and ParityShards parity blocks are computed for each stripe.
+// Shard i is the concatenation of block i of every stripe and is stored on
+// Nodes[i] of the version.
+type ErasureCoding struct {
+	DataShards     int      `bson:"data_shards"`
+	ParityShards   int      `bson:"parity_shards"`
+	BlockSize      int      `bson:"block_size"`
+	ShardChecksums []string `bson:"shard_checksums"`
+}
+
+// Encryption holds the data key an encrypted version is stored with, wrapped
+// by the master key KeyID. The data key itself is never stored.
+type Encryption struct {
+	KeyID      string `bson:"key_id"`
+	WrappedKey []byte `bson:"wrapped_key"`
+}
+
+// Chunk is a piece of a version's content, identified by its SHA-256 digest.
+// Nodes store each distinct chunk once, however many versions contain it.
+type Chunk struct {
+	Digest string `bson:"digest"`
+	Size   int64  `bson:"size"`
+}
+
+// RetentionPolicy limits how many old versions of a file are kept. A zero
+// field is not enforced; the latest version is never removed.
+type RetentionPolicy struct {
+	KeepLast int           `bson:"keep_last,omitempty"`
+	MaxAge   time.Duration `bson:"max_age,omitempty"`
+}
+
+// IsZero reports whether the policy enforces nothing
+func (p RetentionPolicy) IsZero() bool {
+	return p.KeepLast <= 0 && p.MaxAge <= 0
+}
+
+// Expired returns the versions that fall outside the policy, oldest first.
+// A version is expired if it is not among the last KeepLast versions or is
+// older than MaxAge.
+func (p RetentionPolicy) Expired(versions []Version, now time.Time) []Version {
+	var expired []Version
+	for i, v := range versions {
+		// Always keep the latest version
+		if i == len(versions)-1 {
+			break
+		}
+
+		tooMany := p.KeepLast > 0 && i < len(versions)-p.KeepLast
+		tooOld := p.MaxAge > 0 && now.Sub(v.CreatedAt) > p.MaxAge
+		if tooMany || tooOld {
+			expired = append(expired, v)
+		}
+	}
+	return expired
+}
+
+// Hint records a version stored on a node other than the ring node it
+// belongs on, because that node failed the write. The version is handed back
+// to the Owner, and removed from the Holder, once the owner is healthy.
+type Hint struct {
+	FileID    string    `bson:"file_id"`
+	VersionID string    `bson:"version_id"`
+	Owner     string    `bson:"owner"`
+	Holder    string    `bson:"holder"`
+	CreatedAt time.Time `bson:"created_at"`
 }
 
 // MetadataStore handles MongoDB operations for file metadata
 type MetadataStore struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	hints      *mongo.Collection
 }
 
 // NewMetadataStore creates a new metadata store
@@ -64,9 +184,20 @@ func NewMetadataStore(mongoURI, database string) (*MetadataStore, error) {
 		return nil, err
 	}
 
+	// A version has at most one hint per owner
+	hints := client.Database(database).Collection("hints")
+	hintIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "file_id", Value: 1}, {Key: "version_id", Value: 1}, {Key: "owner", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := hints.Indexes().CreateOne(ctx, hintIndex); err != nil {
+		return nil, err
+	}
+
 	return &MetadataStore{
 		client:     client,
 		collection: collection,
+		hints:      hints,
 	}, nil
 }
 
@@ -91,6 +222,9 @@ func (ms *MetadataStore) GetMetadata(ctx context.Context, fileID string) (*FileM
 	filter := bson.M{"file_id": fileID}
 	
 	err := ms.collection.FindOne(ctx, filter).Decode(&metadata)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -131,15 +265,185 @@ func (ms *MetadataStore) ListMetadata(ctx context.Context, page, pageSize int32)
 	return files, total, nil
 }
 
-// AddVersion adds a new version to file metadata
+// AddVersion adds a new version to file metadata and makes it the latest,
+// updating the file size and replica set to match
 func (ms *MetadataStore) AddVersion(ctx context.Context, fileID string, version Version) error {
 	filter := bson.M{"file_id": fileID}
 	update := bson.M{
 		"$push": bson.M{"versions": version},
-		"$set":  bson.M{"updated_at": time.Now()},
+		"$set": bson.M{
+			"size":       version.Size,
+			"updated_at": time.Now(),
+		},
+		"$addToSet": bson.M{"replicas": bson.M{"$each": version.Nodes}},
+	}
+
+	result, err := ms.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
 	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RemoveVersion removes a version from file metadata, updating the file size
+// to that of the remaining latest version
+func (ms *MetadataStore) RemoveVersion(ctx context.Context, fileID, versionID string) error {
+	filter := bson.M{"file_id": fileID}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"versions": bson.M{"$filter": bson.M{
+				"input": "$versions",
+				"cond":  bson.M{"$ne": bson.A{"$$this.version_id", versionID}},
+			}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"size":       bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$versions.size", -1}}, 0}},
+			"updated_at": time.Now(),
+		}}},
+	}
+
+	result, err := ms.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetVersionNodes records the nodes holding a version and recomputes the
+// file's replica set as the union of the nodes of all its versions
+func (ms *MetadataStore) SetVersionNodes(ctx context.Context, fileID, versionID string, nodes []string) error {
+	filter := bson.M{"file_id": fileID, "versions.version_id": versionID}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"versions": bson.M{"$map": bson.M{
+				"input": "$versions",
+				"in": bson.M{"$cond": bson.A{
+					bson.M{"$eq": bson.A{"$$this.version_id", versionID}},
+					bson.M{"$mergeObjects": bson.A{"$$this", bson.M{"nodes": bson.M{"$literal": nodes}}}},
+					"$$this",
+				}},
+			}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"replicas": bson.M{"$reduce": bson.M{
+				"input":        "$versions.nodes",
+				"initialValue": bson.A{},
+				"in":           bson.M{"$setUnion": bson.A{"$$value", "$$this"}},
+			}},
+			"updated_at": time.Now(),
+		}}},
+	}
+
+	result, err := ms.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetVersionEncryption replaces the wrapped data key of a version, such as
+// after it was rewrapped with a new master key
+func (ms *MetadataStore) SetVersionEncryption(ctx context.Context, fileID, versionID string, encryption *Encryption) error {
+	filter := bson.M{"file_id": fileID, "versions.version_id": versionID}
+	update := bson.M{"$set": bson.M{"versions.$.encryption": encryption}}
+
+	result, err := ms.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetRetention sets or clears the retention policy of a file
+func (ms *MetadataStore) SetRetention(ctx context.Context, fileID string, policy *RetentionPolicy) error {
+	filter := bson.M{"file_id": fileID}
+	update := bson.M{
+		"$set": bson.M{"retention": policy, "updated_at": time.Now()},
+	}
+	if policy == nil || policy.IsZero() {
+		update = bson.M{
+			"$unset": bson.M{"retention": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		}
+	}
+
+	result, err := ms.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetReplicaFactor sets the replica factor of a file. Zero clears it so the
+// server default applies.
+func (ms *MetadataStore) SetReplicaFactor(ctx context.Context, fileID string, replicaFactor int) error {
+	filter := bson.M{"file_id": fileID}
+	update := bson.M{
+		"$set": bson.M{"replica_factor": replicaFactor, "updated_at": time.Now()},
+	}
+	if replicaFactor == 0 {
+		update = bson.M{
+			"$unset": bson.M{"replica_factor": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		}
+	}
+
+	result, err := ms.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SaveHint records a hint, replacing any earlier hint for the same version
+// and owner
+func (ms *MetadataStore) SaveHint(ctx context.Context, hint Hint) error {
+	filter := bson.M{"file_id": hint.FileID, "version_id": hint.VersionID, "owner": hint.Owner}
+	update := bson.M{"$set": hint}
+	opts := options.Update().SetUpsert(true)
+
+	_, err := ms.hints.UpdateOne(ctx, filter, update, opts)
+	return err
+}
+
+// ListHints returns every recorded hint, oldest first
+func (ms *MetadataStore) ListHints(ctx context.Context) ([]Hint, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := ms.hints.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hints []Hint
+	if err := cursor.All(ctx, &hints); err != nil {
+		return nil, err
+	}
+	return hints, nil
+}
 
-	_, err := ms.collection.UpdateOne(ctx, filter, update)
+// DeleteHint removes the hint for a version and owner
+func (ms *MetadataStore) DeleteHint(ctx context.Context, hint Hint) error {
+	filter := bson.M{"file_id": hint.FileID, "version_id": hint.VersionID, "owner": hint.Owner}
+	_, err := ms.hints.DeleteOne(ctx, filter)
 	return err
 }
 
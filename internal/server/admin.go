@@ -0,0 +1,328 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	pb "github.com/yashlad/distributed-file-store/api/proto"
+	"github.com/yashlad/distributed-file-store/internal/manager"
+	"github.com/yashlad/distributed-file-store/internal/storagenode"
+)
+
+// SetReplicator exposes the status of a re-replication worker through the
+// admin RPCs
+func (s *FileStoreServer) SetReplicator(replicator *manager.Replicator) {
+	s.replicator = replicator
+}
+
+// SetRebalancer exposes a rebalancer through the admin RPCs
+func (s *FileStoreServer) SetRebalancer(rebalancer *manager.Rebalancer) {
+	s.rebalancer = rebalancer
+}
+
+// SetScrubber exposes the stats of a scrubber through the admin RPCs
+func (s *FileStoreServer) SetScrubber(scrubber *manager.Scrubber) {
+	s.scrubber = scrubber
+}
+
+// SetAntiEntropy exposes the stats of an anti-entropy process through the
+// admin RPCs
+func (s *FileStoreServer) SetAntiEntropy(antiEntropy *manager.AntiEntropy) {
+	s.antiEntropy = antiEntropy
+}
+
+// SetHintedHandoff exposes the status of a hinted handoff worker through the
+// admin RPCs
+func (s *FileStoreServer) SetHintedHandoff(handoff *manager.HintedHandoff) {
+	s.handoff = handoff
+}
+
+// SetMembership exposes node membership through the admin RPCs and accepts
+// heartbeats from node daemons
+func (s *FileStoreServer) SetMembership(membership *manager.Membership) {
+	s.membership = membership
+}
+
+// GetReplicationStatus reports the progress of re-replication, optionally
+// starting a scan first
+func (s *FileStoreServer) GetReplicationStatus(ctx context.Context, req *pb.ReplicationStatusRequest) (*pb.ReplicationStatusResponse, error) {
+	if s.replicator == nil {
+		return nil, fmt.Errorf("re-replication is not enabled")
+	}
+	if req.RunNow {
+		s.replicator.Trigger()
+	}
+
+	status := s.replicator.Status()
+	return &pb.ReplicationStatusResponse{
+		Running:         status.Running,
+		LastStarted:     formatTime(status.LastStarted),
+		LastFinished:    formatTime(status.LastFinished),
+		FilesScanned:    int32(status.FilesScanned),
+		VersionsScanned: int32(status.VersionsScanned),
+		UnderReplicated: int32(status.UnderReplicated),
+		Repaired:        int32(status.Repaired),
+		Failed:          int32(status.Failed),
+		BytesCopied:     status.BytesCopied,
+		LastError:       status.LastError,
+	}, nil
+}
+
+// Rebalance moves file versions to their current ring owners, or only plans
+// the moves in dry-run mode. It returns once the rebalance has finished.
+func (s *FileStoreServer) Rebalance(ctx context.Context, req *pb.RebalanceRequest) (*pb.RebalanceResponse, error) {
+	if s.rebalancer == nil {
+		return nil, fmt.Errorf("rebalancing is not enabled")
+	}
+
+	report, err := s.rebalancer.Run(ctx, manager.RebalanceOptions{
+		DryRun:         req.DryRun,
+		BytesPerSecond: req.BytesPerSecond,
+	})
+
+	moves := make([]*pb.RebalanceMove, len(report.Moves))
+	for i, move := range report.Moves {
+		moves[i] = &pb.RebalanceMove{
+			FileId:    move.FileID,
+			VersionId: move.VersionID,
+			Size:      move.Size,
+			Add:       move.Add,
+			Remove:    move.Remove,
+		}
+	}
+
+	response := &pb.RebalanceResponse{
+		DryRun:      report.DryRun,
+		Moves:       moves,
+		Moved:       int32(report.Moved),
+		Failed:      int32(report.Failed),
+		BytesCopied: report.BytesCopied,
+		Started:     formatTime(report.Started),
+		Finished:    formatTime(report.Finished),
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	return response, nil
+}
+
+// GetRepairLog returns the most recent replica repairs and the repair totals
+// of each node
+func (s *FileStoreServer) GetRepairLog(ctx context.Context, req *pb.RepairLogRequest) (*pb.RepairLogResponse, error) {
+	events := s.fileManager.RepairLog()
+	if req.Limit > 0 && int(req.Limit) < len(events) {
+		events = events[len(events)-int(req.Limit):]
+	}
+
+	response := &pb.RepairLogResponse{}
+	for _, event := range events {
+		response.Events = append(response.Events, &pb.RepairEvent{
+			Time:      formatTime(event.Time),
+			FileId:    event.FileID,
+			VersionId: event.VersionID,
+			NodeId:    event.NodeID,
+			Reason:    event.Reason,
+			Source:    event.Source,
+			Error:     event.Error,
+		})
+	}
+
+	stats := s.fileManager.RepairStats()
+	nodeIDs := make([]string, 0, len(stats))
+	for nodeID := range stats {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+	for _, nodeID := range nodeIDs {
+		node := stats[nodeID]
+		response.Nodes = append(response.Nodes, &pb.NodeRepairStats{
+			NodeId:   nodeID,
+			Missing:  int32(node.Missing),
+			Corrupt:  int32(node.Corrupt),
+			Diverged: int32(node.Diverged),
+			Repaired: int32(node.Repaired),
+			Failed:   int32(node.Failed),
+		})
+	}
+	return response, nil
+}
+
+// GetScrubStatus reports the stats of the last scrub, optionally starting a
+// new one first
+func (s *FileStoreServer) GetScrubStatus(ctx context.Context, req *pb.ScrubStatusRequest) (*pb.ScrubStatusResponse, error) {
+	if s.scrubber == nil {
+		return nil, fmt.Errorf("scrubbing is not enabled")
+	}
+	if req.RunNow {
+		s.scrubber.Trigger()
+	}
+
+	stats := s.scrubber.Stats()
+	response := &pb.ScrubStatusResponse{
+		Running:        stats.Running,
+		Started:        formatTime(stats.Started),
+		Finished:       formatTime(stats.Finished),
+		NodesScanned:   int32(stats.NodesScanned),
+		ObjectsScanned: int32(stats.ObjectsScanned),
+		BytesScanned:   stats.BytesScanned,
+		Corrupt:        int32(stats.Corrupt),
+		Repaired:       int32(stats.Repaired),
+		RepairFailed:   int32(stats.RepairFailed),
+		LastError:      stats.LastError,
+	}
+	for _, object := range stats.CorruptObjects {
+		response.CorruptObjects = append(response.CorruptObjects, &pb.CorruptObject{
+			NodeId:    object.NodeID,
+			FileId:    object.FileID,
+			VersionId: object.VersionID,
+			Error:     object.Error,
+		})
+	}
+	return response, nil
+}
+
+// GetAntiEntropyStatus reports the stats of the last anti-entropy run,
+// optionally starting a new one first
+func (s *FileStoreServer) GetAntiEntropyStatus(ctx context.Context, req *pb.AntiEntropyStatusRequest) (*pb.AntiEntropyStatusResponse, error) {
+	if s.antiEntropy == nil {
+		return nil, fmt.Errorf("anti-entropy is not enabled")
+	}
+	if req.RunNow {
+		s.antiEntropy.Trigger()
+	}
+
+	stats := s.antiEntropy.Stats()
+	return &pb.AntiEntropyStatusResponse{
+		Running:         stats.Running,
+		Started:         formatTime(stats.Started),
+		Finished:        formatTime(stats.Finished),
+		PairsCompared:   int32(stats.PairsCompared),
+		BucketsDiffered: int32(stats.BucketsDiffered),
+		ObjectsDiffered: int32(stats.ObjectsDiffered),
+		Repaired:        int32(stats.Repaired),
+		Removed:         int32(stats.Removed),
+		Failed:          int32(stats.Failed),
+		LastError:       stats.LastError,
+	}, nil
+}
+
+// GetHandoffStatus reports the progress of hinted handoff, optionally
+// starting a pass first
+func (s *FileStoreServer) GetHandoffStatus(ctx context.Context, req *pb.HandoffStatusRequest) (*pb.HandoffStatusResponse, error) {
+	if s.handoff == nil {
+		return nil, fmt.Errorf("hinted handoff is not enabled")
+	}
+	if req.RunNow {
+		s.handoff.Trigger()
+	}
+
+	status := s.handoff.Status()
+	return &pb.HandoffStatusResponse{
+		Running:      status.Running,
+		LastStarted:  formatTime(status.LastStarted),
+		LastFinished: formatTime(status.LastFinished),
+		Hints:        int32(status.Hints),
+		HandedOff:    int32(status.HandedOff),
+		Dropped:      int32(status.Dropped),
+		Waiting:      int32(status.Waiting),
+		Failed:       int32(status.Failed),
+		BytesCopied:  status.BytesCopied,
+		LastError:    status.LastError,
+	}, nil
+}
+
+// Fsck cross-checks the metadata against the files stored on every node,
+// optionally repairing what it can. It returns once the check has finished.
+func (s *FileStoreServer) Fsck(ctx context.Context, req *pb.FsckRequest) (*pb.FsckResponse, error) {
+	report, err := s.fileManager.Fsck(ctx, manager.FsckOptions{Repair: req.Repair})
+	if err != nil {
+		return nil, fmt.Errorf("fsck failed: %w", err)
+	}
+
+	response := &pb.FsckResponse{
+		Repair:          report.Repair,
+		Started:         formatTime(report.Started),
+		Finished:        formatTime(report.Finished),
+		NodesChecked:    int32(report.NodesChecked),
+		FilesChecked:    int32(report.FilesChecked),
+		VersionsChecked: int32(report.VersionsChecked),
+		ObjectsChecked:  int32(report.ObjectsChecked),
+		Repaired:        int32(report.Repaired),
+		Failed:          int32(report.Failed),
+	}
+	for _, issue := range report.Issues {
+		response.Issues = append(response.Issues, &pb.FsckIssue{
+			Kind:      issue.Kind,
+			FileId:    issue.FileID,
+			VersionId: issue.VersionID,
+			NodeId:    issue.NodeID,
+			Detail:    issue.Detail,
+			Repaired:  issue.Repaired,
+			Error:     issue.Error,
+		})
+	}
+	return response, nil
+}
+
+// RotateKeys rewraps every data key with the current master key so the old
+// master keys can be retired. The stored data is not rewritten.
+func (s *FileStoreServer) RotateKeys(ctx context.Context, req *pb.RotateKeysRequest) (*pb.RotateKeysResponse, error) {
+	rotation, err := s.fileManager.RotateKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("key rotation failed: %w", err)
+	}
+
+	return &pb.RotateKeysResponse{
+		KeyId:     rotation.KeyID,
+		Rewrapped: int32(rotation.Rewrapped),
+		Failed:    int32(rotation.Failed),
+	}, nil
+}
+
+// GetMembership reports the state of every known storage node, optionally
+// probing the nodes first
+func (s *FileStoreServer) GetMembership(ctx context.Context, req *pb.MembershipRequest) (*pb.MembershipResponse, error) {
+	if s.membership == nil {
+		return nil, fmt.Errorf("membership is not enabled")
+	}
+
+	members := s.membership.Members()
+	if req.ProbeNow {
+		members = s.membership.RunOnce(ctx)
+	}
+
+	response := &pb.MembershipResponse{}
+	for _, member := range members {
+		response.Members = append(response.Members, &pb.Member{
+			NodeId:        member.NodeID,
+			Address:       member.Address,
+			State:         member.State,
+			LastHeartbeat: formatTime(member.LastHeartbeat),
+			StateChanged:  formatTime(member.StateChanged),
+		})
+	}
+	return response, nil
+}
+
+// Heartbeat registers a storage node daemon, or keeps it registered, and
+// tells it how often to report. Only daemons that reached the server over
+// cluster TLS may move a registered node to a new address.
+func (s *FileStoreServer) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	if s.membership == nil {
+		return nil, fmt.Errorf("membership is not enabled")
+	}
+	if err := s.membership.Heartbeat(req.NodeId, req.Address, storagenode.ClusterPeer(ctx)); err != nil {
+		return nil, err
+	}
+	return &pb.HeartbeatResponse{IntervalMs: s.membership.HeartbeatInterval().Milliseconds()}, nil
+}
+
+// formatTime formats a timestamp for a response, leaving unset times empty
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
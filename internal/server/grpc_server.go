@@ -1,14 +1,16 @@
 package server
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
 
 	pb "github.com/yashlad/distributed-file-store/api/proto"
+	"github.com/yashlad/distributed-file-store/internal/compression"
 	"github.com/yashlad/distributed-file-store/internal/manager"
+	"github.com/yashlad/distributed-file-store/internal/metadata"
 )
 
 const (
@@ -19,6 +21,12 @@ const (
 type FileStoreServer struct {
 	pb.UnimplementedFileStoreServer
 	fileManager *manager.FileManager
+	replicator  *manager.Replicator
+	rebalancer  *manager.Rebalancer
+	scrubber    *manager.Scrubber
+	antiEntropy *manager.AntiEntropy
+	handoff     *manager.HintedHandoff
+	membership  *manager.Membership
 }
 
 // NewFileStoreServer creates a new gRPC server
@@ -30,81 +38,162 @@ func NewFileStoreServer(fileManager *manager.FileManager) *FileStoreServer {
 
 // Upload handles file upload with streaming
 func (s *FileStoreServer) Upload(stream pb.FileStore_UploadServer) error {
-	var filename string
-	var contentType string
-	var buffer bytes.Buffer
-
-	// Receive chunks
-	for {
-		req, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("error receiving chunk: %w", err)
-		}
+	return s.receiveUpload(stream, func(ctx context.Context, first *pb.UploadRequest, reader io.Reader) (*manager.UploadResult, error) {
+		return s.fileManager.UploadStream(ctx, first.Filename, reader, first.ContentType, uploadOptions(first))
+	})
+}
 
-		if filename == "" {
-			filename = req.Filename
-			contentType = req.ContentType
+// UploadVersion handles uploading a new version of an existing file with streaming
+func (s *FileStoreServer) UploadVersion(stream pb.FileStore_UploadVersionServer) error {
+	return s.receiveUpload(stream, func(ctx context.Context, first *pb.UploadRequest, reader io.Reader) (*manager.UploadResult, error) {
+		if first.FileId == "" {
+			return nil, fmt.Errorf("file_id is required")
 		}
+		return s.fileManager.UploadNewVersion(ctx, first.FileId, reader, uploadOptions(first))
+	})
+}
 
-		buffer.Write(req.Chunk)
+// uploadOptions extracts the upload options carried by the first chunk
+func uploadOptions(first *pb.UploadRequest) manager.UploadOptions {
+	return manager.UploadOptions{
+		WriteQuorum:   int(first.WriteQuorum),
+		ReplicaFactor: int(first.ReplicaFactor),
+		StorageMode:   first.StorageMode,
+		DataShards:    int(first.DataShards),
+		ParityShards:  int(first.ParityShards),
+		Compression:   first.Compression,
 	}
+}
 
-	// Upload file
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// receiveUpload reads an upload stream and hands its data to store as it
+// arrives. The first chunk carries the file attributes.
+func (s *FileStoreServer) receiveUpload(stream pb.FileStore_UploadServer, store func(context.Context, *pb.UploadRequest, io.Reader) (*manager.UploadResult, error)) error {
+	first, err := stream.Recv()
+	if err == io.EOF {
+		first = &pb.UploadRequest{}
+	} else if err != nil {
+		return fmt.Errorf("error receiving chunk: %w", err)
+	}
 
-	metadata, err := s.fileManager.UploadFile(ctx, filename, buffer.Bytes(), contentType)
+	// Stream chunks to the storage nodes as they arrive
+	reader := &uploadReader{stream: stream, buf: first.Chunk}
+	result, err := store(stream.Context(), first, reader)
 	if err != nil {
-		return stream.SendAndClose(&pb.UploadResponse{
-			Success: false,
-			Message: fmt.Sprintf("Upload failed: %v", err),
-		})
+		return stream.SendAndClose(uploadFailed("Upload", err))
 	}
 
 	// Send response
-	return stream.SendAndClose(&pb.UploadResponse{
-		FileId:        metadata.FileID,
-		VersionId:     metadata.Versions[len(metadata.Versions)-1].VersionID,
-		Size:          metadata.Size,
-		NodeLocations: metadata.Replicas,
+	return stream.SendAndClose(uploadResponse(result, "File uploaded successfully"))
+}
+
+// uploadResponse converts an upload result to its response format
+func uploadResponse(result *manager.UploadResult, message string) *pb.UploadResponse {
+	return &pb.UploadResponse{
+		FileId:        result.FileID,
+		VersionId:     result.Versions[len(result.Versions)-1].VersionID,
+		Size:          result.Size,
+		NodeLocations: result.Replicas,
 		Success:       true,
-		Message:       "File uploaded successfully",
-	})
+		Message:       message,
+		Replicas:      replicaStatuses(result.ReplicaResults),
+	}
+}
+
+// uploadFailed builds the response of a failed upload, reporting the outcome
+// of each replica when the write quorum was not met
+func uploadFailed(operation string, err error) *pb.UploadResponse {
+	response := &pb.UploadResponse{
+		Success: false,
+		Message: fmt.Sprintf("%s failed: %v", operation, err),
+	}
+
+	var quorumErr *manager.QuorumError
+	if errors.As(err, &quorumErr) {
+		response.Replicas = replicaStatuses(quorumErr.Replicas)
+	}
+	return response
+}
+
+// replicaStatuses converts replica write results to their response format
+func replicaStatuses(results []manager.ReplicaResult) []*pb.ReplicaStatus {
+	statuses := make([]*pb.ReplicaStatus, len(results))
+	for i, result := range results {
+		statuses[i] = &pb.ReplicaStatus{
+			NodeId:     result.NodeID,
+			Success:    result.Err == nil,
+			DurationMs: result.Duration.Milliseconds(),
+			HintedFor:  result.HintedFor,
+		}
+		if result.Err != nil {
+			statuses[i].Error = result.Err.Error()
+		}
+	}
+	return statuses
+}
+
+// uploadReader exposes the chunks of an upload stream as an io.Reader
+type uploadReader struct {
+	stream pb.FileStore_UploadServer
+	buf    []byte
+}
+
+// Read implements io.Reader, receiving the next chunk once the current one is consumed
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, fmt.Errorf("error receiving chunk: %w", err)
+		}
+		r.buf = req.Chunk
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
 }
 
 // Download handles file download with streaming
 func (s *FileStoreServer) Download(req *pb.DownloadRequest, stream pb.FileStore_DownloadServer) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Download file
-	data, metadata, err := s.fileManager.DownloadFile(ctx, req.FileId, req.VersionId)
+	// Open file for streaming
+	reader, err := s.fileManager.OpenFile(stream.Context(), req.FileId, req.VersionId, manager.DownloadOptions{
+		ReadQuorum: int(req.ReadQuorum),
+	})
 	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
 	}
+	defer reader.Close()
+
+	return sendChunks(stream, reader, reader.Metadata.ContentType)
+}
+
+// sendChunks streams a file to the client in chunks as it is read from disk.
+// A checksum mismatch surfaces as a trailing error after the data is sent.
+func sendChunks(stream pb.FileStore_DownloadServer, reader *manager.FileReader, contentType string) error {
+	totalSize := reader.Version.Size
+	chunk := make([]byte, maxChunkSize)
 
-	// Stream chunks
-	totalSize := int64(len(data))
-	for offset := 0; offset < len(data); offset += maxChunkSize {
-		end := offset + maxChunkSize
-		if end > len(data) {
-			end = len(data)
+	for {
+		n, err := io.ReadFull(reader, chunk)
+		if n > 0 && (err == nil || err == io.ErrUnexpectedEOF) {
+			if err := stream.Send(&pb.DownloadResponse{
+				Chunk:       chunk[:n],
+				TotalSize:   totalSize,
+				ContentType: contentType,
+			}); err != nil {
+				return fmt.Errorf("error sending chunk: %w", err)
+			}
 		}
 
-		chunk := data[offset:end]
-		if err := stream.Send(&pb.DownloadResponse{
-			Chunk:       chunk,
-			TotalSize:   totalSize,
-			ContentType: metadata.ContentType,
-		}); err != nil {
-			return fmt.Errorf("error sending chunk: %w", err)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading file from %s: %w", reader.NodeID, err)
 		}
 	}
-
-	return nil
 }
 
 // Delete handles file deletion
@@ -130,22 +219,49 @@ func (s *FileStoreServer) GetFileInfo(ctx context.Context, req *pb.FileInfoReque
 		return nil, fmt.Errorf("file not found: %w", err)
 	}
 
+	return fileInfo(metadata), nil
+}
+
+// fileInfo converts file metadata to its response format
+func fileInfo(file *metadata.FileMetadata) *pb.FileInfoResponse {
 	// Extract version IDs
-	versions := make([]string, len(metadata.Versions))
-	for i, v := range metadata.Versions {
+	versions := make([]string, len(file.Versions))
+	for i, v := range file.Versions {
 		versions[i] = v.VersionID
 	}
 
-	return &pb.FileInfoResponse{
-		FileId:      metadata.FileID,
-		Filename:    metadata.Filename,
-		Size:        metadata.Size,
-		ContentType: metadata.ContentType,
-		CreatedAt:   metadata.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   metadata.UpdatedAt.Format(time.RFC3339),
+	info := &pb.FileInfoResponse{
+		FileId:      file.FileID,
+		Filename:    file.Filename,
+		Size:        file.Size,
+		ContentType: file.ContentType,
+		CreatedAt:   file.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   file.UpdatedAt.Format(time.RFC3339),
 		Versions:    versions,
-		Replicas:    metadata.Replicas,
-	}, nil
+		Replicas:    file.Replicas,
+
+		ReplicaFactor: int32(file.ReplicaFactor),
+	}
+	if len(file.Versions) > 0 {
+		latest := file.Versions[len(file.Versions)-1]
+		info.StorageMode = latest.StorageMode
+		if info.StorageMode == "" {
+			info.StorageMode = metadata.StorageReplicated
+		}
+		info.Compression = latest.Compression
+		if info.Compression == "" {
+			info.Compression = compression.None
+		}
+		info.StoredSize = latest.StoredSize
+		if latest.Encryption != nil {
+			info.EncryptionKeyId = latest.Encryption.KeyID
+		}
+	}
+	if file.Retention != nil {
+		info.RetentionKeepLast = int32(file.Retention.KeepLast)
+		info.RetentionMaxAgeDays = int32(file.Retention.MaxAge / (24 * time.Hour))
+	}
+	return info
 }
 
 // ListFiles lists all files with pagination
@@ -168,21 +284,7 @@ func (s *FileStoreServer) ListFiles(ctx context.Context, req *pb.ListFilesReques
 	// Convert to response format
 	fileInfos := make([]*pb.FileInfoResponse, len(files))
 	for i, file := range files {
-		versions := make([]string, len(file.Versions))
-		for j, v := range file.Versions {
-			versions[j] = v.VersionID
-		}
-
-		fileInfos[i] = &pb.FileInfoResponse{
-			FileId:      file.FileID,
-			Filename:    file.Filename,
-			Size:        file.Size,
-			ContentType: file.ContentType,
-			CreatedAt:   file.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   file.UpdatedAt.Format(time.RFC3339),
-			Versions:    versions,
-			Replicas:    file.Replicas,
-		}
+		fileInfos[i] = fileInfo(file)
 	}
 
 	return &pb.ListFilesResponse{
@@ -193,31 +295,85 @@ func (s *FileStoreServer) ListFiles(ctx context.Context, req *pb.ListFilesReques
 
 // GetVersion retrieves a specific version of a file
 func (s *FileStoreServer) GetVersion(req *pb.VersionRequest, stream pb.FileStore_GetVersionServer) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Download specific version
-	data, err := s.fileManager.GetVersion(ctx, req.FileId, req.VersionId)
+	// Open specific version for streaming
+	reader, err := s.fileManager.OpenFile(stream.Context(), req.FileId, req.VersionId, manager.DownloadOptions{
+		ReadQuorum: int(req.ReadQuorum),
+	})
 	if err != nil {
 		return fmt.Errorf("version not found: %w", err)
 	}
+	defer reader.Close()
 
-	// Stream chunks
-	totalSize := int64(len(data))
-	for offset := 0; offset < len(data); offset += maxChunkSize {
-		end := offset + maxChunkSize
-		if end > len(data) {
-			end = len(data)
-		}
+	return sendChunks(stream, reader, "")
+}
 
-		chunk := data[offset:end]
-		if err := stream.Send(&pb.DownloadResponse{
-			Chunk:     chunk,
-			TotalSize: totalSize,
-		}); err != nil {
-			return fmt.Errorf("error sending chunk: %w", err)
-		}
+// DeleteVersion deletes a single version of a file
+func (s *FileStoreServer) DeleteVersion(ctx context.Context, req *pb.VersionRequest) (*pb.DeleteResponse, error) {
+	if err := s.fileManager.DeleteVersion(ctx, req.FileId, req.VersionId); err != nil {
+		return &pb.DeleteResponse{
+			Success: false,
+			Message: fmt.Sprintf("Delete failed: %v", err),
+		}, nil
+	}
+
+	return &pb.DeleteResponse{
+		Success: true,
+		Message: "Version deleted successfully",
+	}, nil
+}
+
+// RestoreVersion promotes an old version of a file back to latest
+func (s *FileStoreServer) RestoreVersion(ctx context.Context, req *pb.VersionRequest) (*pb.UploadResponse, error) {
+	result, err := s.fileManager.RestoreVersion(ctx, req.FileId, req.VersionId)
+	if err != nil {
+		return uploadFailed("Restore", err), nil
+	}
+
+	return uploadResponse(result, fmt.Sprintf("Version %s restored as latest", req.VersionId)), nil
+}
+
+// SetRetention sets the version retention policy of a file
+func (s *FileStoreServer) SetRetention(ctx context.Context, req *pb.SetRetentionRequest) (*pb.SetRetentionResponse, error) {
+	policy := &metadata.RetentionPolicy{
+		KeepLast: int(req.KeepLast),
+		MaxAge:   time.Duration(req.MaxAgeDays) * 24 * time.Hour,
+	}
+
+	if err := s.fileManager.SetRetention(ctx, req.FileId, policy); err != nil {
+		return &pb.SetRetentionResponse{
+			Success: false,
+			Message: fmt.Sprintf("Set retention failed: %v", err),
+		}, nil
 	}
 
-	return nil
+	message := "Retention policy updated"
+	if policy.IsZero() {
+		message = "Retention policy cleared, global policy applies"
+	}
+	return &pb.SetRetentionResponse{
+		Success: true,
+		Message: message,
+	}, nil
+}
+
+// SetReplicaFactor changes how many replicas of a file are kept and adds or
+// removes replicas to match
+func (s *FileStoreServer) SetReplicaFactor(ctx context.Context, req *pb.SetReplicaFactorRequest) (*pb.SetReplicaFactorResponse, error) {
+	file, err := s.fileManager.SetReplicaFactor(ctx, req.FileId, int(req.ReplicaFactor))
+	if err != nil {
+		return &pb.SetReplicaFactorResponse{
+			Success: false,
+			Message: fmt.Sprintf("Set replica factor failed: %v", err),
+		}, nil
+	}
+
+	message := fmt.Sprintf("Replica factor set to %d", req.ReplicaFactor)
+	if req.ReplicaFactor == 0 {
+		message = "Replica factor cleared, server default applies"
+	}
+	return &pb.SetReplicaFactorResponse{
+		Success:  true,
+		Message:  message,
+		Replicas: file.Replicas,
+	}, nil
 }
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Backend stores the objects a node is made of: its chunks and the manifest
+// and checksum of each version. Keys are slash-separated paths such as
+// "file-1/v1/manifest"; a missing object is reported with fs.ErrNotExist.
+type Backend interface {
+	// Put stores an object, replacing any object with the same key. The
+	// object becomes visible only once it is completely and durably written.
+	Put(key string, r io.Reader) error
+
+	// Get opens an object for streaming
+	Get(key string) (io.ReadCloser, error)
+
+	// Delete removes an object. Removing a missing object is not an error.
+	Delete(key string) error
+
+	// List returns every object whose key starts with prefix, sorted by key
+	List(prefix string) ([]BlobInfo, error)
+
+	// Stat describes an object without reading it
+	Stat(key string) (BlobInfo, error)
+}
+
+// BlobInfo describes an object stored in a backend
+type BlobInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Backend types
+const (
+	BackendFS     = "fs"
+	BackendMemory = "memory"
+	BackendS3     = "s3"
+)
+
+// BackendConfig selects and configures the backend a node stores its data in
+type BackendConfig struct {
+	// Type is BackendFS, BackendMemory or BackendS3; empty means BackendFS
+	Type string
+
+	// Path is the directory of a filesystem backend
+	Path string
+
+	// S3 configures an S3-compatible backend
+	S3 S3Config
+}
+
+// OpenBackend creates the backend a config describes
+func OpenBackend(config BackendConfig) (Backend, error) {
+	switch config.Type {
+	case "", BackendFS:
+		if config.Path == "" {
+			return nil, fmt.Errorf("filesystem backend needs a path")
+		}
+		return NewFSBackend(config.Path)
+	case BackendMemory:
+		return NewMemoryBackend(), nil
+	case BackendS3:
+		return NewS3Backend(config.S3)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", config.Type)
+	}
+}
+
+// readObject reads a whole object
+func readObject(backend Backend, key string) ([]byte, error) {
+	reader, err := backend.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FSBackend stores objects as files under a directory on the local
+// filesystem. Every write goes to a temporary file that is fsynced and then
+// renamed into place, and the directory is fsynced after every rename, so a
+// crash leaves either the old object or the new one, never a partial file.
+type FSBackend struct {
+	root string
+}
+
+// NewFSBackend creates a filesystem backend rooted at root, creating the
+// directory if needed and removing temporary files left behind by writes a
+// crash interrupted
+func NewFSBackend(root string) (*FSBackend, error) {
+	if err := makeDir(root); err != nil {
+		return nil, err
+	}
+	backend := &FSBackend{root: root}
+	if err := backend.removeTemporary(); err != nil {
+		return nil, fmt.Errorf("failed to remove interrupted writes: %w", err)
+	}
+	return backend, nil
+}
+
+// Root returns the directory the backend stores its objects in
+func (b *FSBackend) Root() string {
+	return b.root
+}
+
+// path returns the file an object is stored in
+func (b *FSBackend) path(key string) (string, error) {
+	if key == "" || path.IsAbs(key) || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(b.root, filepath.FromSlash(key)), nil
+}
+
+// Put implements Backend
+func (b *FSBackend) Put(key string, r io.Reader) error {
+	target, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(target)
+
+	// A concurrent Delete may remove the directory once it is empty, between
+	// creating it and creating the temporary file in it
+	var file *os.File
+	for attempt := 0; ; attempt++ {
+		err = makeDir(dir)
+		if err == nil {
+			file, err = os.CreateTemp(dir, tempPrefix+"*")
+		}
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) || attempt == 2 {
+			return err
+		}
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), target); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Get implements Backend
+func (b *FSBackend) Get(key string) (io.ReadCloser, error) {
+	target, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(target)
+}
+
+// Delete implements Backend. Directories the removal leaves empty are
+// removed as well.
+func (b *FSBackend) Delete(key string) error {
+	target, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	dir := filepath.Dir(target)
+	for dir != b.root {
+		// Fails once a directory is not empty
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	if err := syncDir(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List implements Backend. Files still being written are left out.
+func (b *FSBackend) List(prefix string) ([]BlobInfo, error) {
+	// Only the directory the prefix points into needs to be walked
+	start := b.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir, err := b.path(prefix[:i])
+		if err != nil {
+			return nil, err
+		}
+		start = dir
+	}
+
+	var blobs []BlobInfo
+	err := filepath.WalkDir(start, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), tempPrefix) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(b.root, file)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// Deleted while listing
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, BlobInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].Key < blobs[j].Key })
+	return blobs, nil
+}
+
+// Stat implements Backend
+func (b *FSBackend) Stat(key string) (BlobInfo, error) {
+	target, err := b.path(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	if info.IsDir() {
+		return BlobInfo{}, &fs.PathError{Op: "stat", Path: target, Err: fs.ErrNotExist}
+	}
+	return BlobInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// removeTemporary removes the files and directories writes that never
+// completed left behind
+func (b *FSBackend) removeTemporary() error {
+	var leftovers []string
+	err := filepath.WalkDir(b.root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), tempPrefix) {
+			leftovers = append(leftovers, file)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, file := range leftovers {
+		if err := os.RemoveAll(file); err != nil {
+			return err
+		}
+	}
+	if len(leftovers) > 0 {
+		return syncDir(b.root)
+	}
+	return nil
+}
+
+// syncDir flushes a directory, making the entries created, renamed or
+// removed in it durable
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// makeDir creates a directory and any missing parents, syncing the parent of
+// each directory it creates so that the new entries survive a crash
+func makeDir(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	parent := filepath.Dir(path)
+	if err := makeDir(parent); err != nil {
+		return err
+	}
+	if err := os.Mkdir(path, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return syncDir(parent)
+}
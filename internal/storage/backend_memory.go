@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryBackend keeps objects in memory. Nothing survives a restart, so it
+// is meant for tests and for nodes holding scratch data.
+type MemoryBackend struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// memoryObject is an object stored in a MemoryBackend
+type memoryObject struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{objects: make(map[string]memoryObject)}
+}
+
+// Put implements Backend
+func (b *MemoryBackend) Put(key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[key] = memoryObject{data: data, modTime: time.Now()}
+	return nil
+}
+
+// Get implements Backend. Readers see the object as it was when it was
+// opened.
+func (b *MemoryBackend) Get(key string) (io.ReadCloser, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	object, ok := b.objects[key]
+	if !ok {
+		return nil, &fs.PathError{Op: "get", Path: key, Err: fs.ErrNotExist}
+	}
+	return io.NopCloser(bytes.NewReader(object.data)), nil
+}
+
+// Delete implements Backend
+func (b *MemoryBackend) Delete(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.objects, key)
+	return nil
+}
+
+// List implements Backend
+func (b *MemoryBackend) List(prefix string) ([]BlobInfo, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var blobs []BlobInfo
+	for key, object := range b.objects {
+		if strings.HasPrefix(key, prefix) {
+			blobs = append(blobs, BlobInfo{Key: key, Size: int64(len(object.data)), ModTime: object.modTime})
+		}
+	}
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].Key < blobs[j].Key })
+	return blobs, nil
+}
+
+// Stat implements Backend
+func (b *MemoryBackend) Stat(key string) (BlobInfo, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	object, ok := b.objects[key]
+	if !ok {
+		return BlobInfo{}, &fs.PathError{Op: "stat", Path: key, Err: fs.ErrNotExist}
+	}
+	return BlobInfo{Key: key, Size: int64(len(object.data)), ModTime: object.modTime}, nil
+}
@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config configures a backend that stores objects in a bucket of an
+// S3-compatible object store, such as AWS S3 or MinIO
+type S3Config struct {
+	// Endpoint is the base URL of the store, e.g. http://localhost:9000.
+	// Buckets are addressed by path, which every S3-compatible store supports.
+	Endpoint string
+	Region   string
+	Bucket   string
+
+	// Prefix is prepended to every key, so several nodes can share a bucket.
+	// It is treated as a directory: a missing trailing slash is added, so the
+	// prefix node1 doesn't also match the keys of node10.
+	Prefix string
+
+	AccessKey string
+	SecretKey string
+}
+
+// defaultS3Region is used when no region is configured; S3-compatible stores
+// running on premises usually accept any region
+const defaultS3Region = "us-east-1"
+
+// s3RequestTimeout bounds each request to the store, including reading the
+// response body; objects are at most one chunk, so this is generous
+const s3RequestTimeout = 2 * time.Minute
+
+// S3Backend stores objects in an S3-compatible bucket, signing its requests
+// with AWS Signature Version 4. S3 writes objects atomically, so a crash
+// leaves either the old object or the new one.
+type S3Backend struct {
+	config S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Backend creates a backend for an S3-compatible bucket, checking that
+// the bucket exists and the credentials are accepted
+func NewS3Backend(config S3Config) (*S3Backend, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("S3 backend needs an endpoint and a bucket")
+	}
+	if _, err := url.Parse(config.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.Region == "" {
+		config.Region = defaultS3Region
+	}
+	if config.Prefix != "" && !strings.HasSuffix(config.Prefix, "/") {
+		config.Prefix += "/"
+	}
+
+	backend := &S3Backend{config: config, client: &http.Client{Timeout: s3RequestTimeout}, now: time.Now}
+	resp, err := backend.do(http.MethodHead, "", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach bucket %s: %w", config.Bucket, err)
+	}
+	resp.Body.Close()
+	return backend, nil
+}
+
+// Put implements Backend. S3 needs the length of an object up front, so it
+// is buffered; objects are chunks, manifests and checksums, all bounded by
+// the maximum chunk size.
+func (b *S3Backend) Put(key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(http.MethodPut, b.config.Prefix+key, nil, data)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Get implements Backend
+func (b *S3Backend) Get(key string) (io.ReadCloser, error) {
+	resp, err := b.do(http.MethodGet, b.config.Prefix+key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete implements Backend
+func (b *S3Backend) Delete(key string) error {
+	resp, err := b.do(http.MethodDelete, b.config.Prefix+key, nil, nil)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// s3ListResult is the response to a ListObjectsV2 request
+type s3ListResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+	}
+}
+
+// List implements Backend, following continuation tokens until every
+// matching object is listed
+func (b *S3Backend) List(prefix string) ([]BlobInfo, error) {
+	var blobs []BlobInfo
+	query := url.Values{"list-type": {"2"}, "prefix": {b.config.Prefix + prefix}}
+	for {
+		resp, err := b.do(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("malformed list response: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			blobs = append(blobs, BlobInfo{
+				Key:     strings.TrimPrefix(object.Key, b.config.Prefix),
+				Size:    object.Size,
+				ModTime: object.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].Key < blobs[j].Key })
+	return blobs, nil
+}
+
+// Stat implements Backend
+func (b *S3Backend) Stat(key string) (BlobInfo, error) {
+	resp, err := b.do(http.MethodHead, b.config.Prefix+key, nil, nil)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp.Body.Close()
+
+	info := BlobInfo{Key: key, Size: resp.ContentLength}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info, nil
+}
+
+// s3Error is the body of an S3 error response
+type s3Error struct {
+	Code    string
+	Message string
+}
+
+// do sends a signed request for an object, or for the bucket itself if key is
+// empty. A 404 is returned as fs.ErrNotExist; any other failure status as an
+// error carrying the store's error code.
+func (b *S3Backend) do(method, key string, query url.Values, body []byte) (*http.Response, error) {
+	target := b.config.Endpoint + "/" + uriEncode(b.config.Bucket, false)
+	if key != "" {
+		target += "/" + uriEncode(key, false)
+	}
+	if len(query) > 0 {
+		target += "?" + canonicalQuery(query)
+	}
+
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	sum := sha256.Sum256(body)
+	signV4(req, hex.EncodeToString(sum[:]), b.config, b.now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &fs.PathError{Op: strings.ToLower(method), Path: key, Err: fs.ErrNotExist}
+	}
+	var s3Err s3Error
+	xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&s3Err)
+	if s3Err.Code == "" {
+		s3Err.Code = resp.Status
+	}
+	return nil, fmt.Errorf("S3 %s %s: %s %s", method, key, s3Err.Code, s3Err.Message)
+}
+
+// signV4 signs a request with AWS Signature Version 4, covering the host and
+// every header already set on the request
+func signV4(req *http.Request, payloadHash string, config S3Config, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretKey), date)
+	key = hmacSHA256(key, config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+config.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as signing requires
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var params []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and
+// slashes unless encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
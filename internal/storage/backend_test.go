@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// testBackends returns one backend of each type, backed by a temporary
+// directory and a fake S3 store
+func testBackends(t *testing.T) map[string]Backend {
+	t.Helper()
+	fsBackend, err := NewFSBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSBackend failed: %v", err)
+	}
+	_, config := newFakeS3(t)
+	s3Backend, err := NewS3Backend(config)
+	if err != nil {
+		t.Fatalf("NewS3Backend failed: %v", err)
+	}
+	return map[string]Backend{
+		BackendFS:     fsBackend,
+		BackendMemory: NewMemoryBackend(),
+		BackendS3:     s3Backend,
+	}
+}
+
+func TestBackends(t *testing.T) {
+	for name, backend := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			keys := []string{"file-1/v1/manifest", "file-1/v1/checksum", "file-10/v1/checksum", ".chunks/ab/abcd"}
+			for _, key := range keys {
+				if err := backend.Put(key, strings.NewReader("content of "+key)); err != nil {
+					t.Fatalf("Put %s failed: %v", key, err)
+				}
+			}
+
+			reader, err := backend.Get("file-1/v1/manifest")
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			data, _ := io.ReadAll(reader)
+			reader.Close()
+			if string(data) != "content of file-1/v1/manifest" {
+				t.Errorf("Get = %q", data)
+			}
+
+			backend.Put("file-1/v1/manifest", strings.NewReader("replaced"))
+			if data, _ := readObject(backend, "file-1/v1/manifest"); string(data) != "replaced" {
+				t.Errorf("Get after replacing = %q", data)
+			}
+
+			info, err := backend.Stat("file-1/v1/manifest")
+			if err != nil || info.Size != int64(len("replaced")) || info.ModTime.IsZero() {
+				t.Errorf("Stat = %+v, %v", info, err)
+			}
+
+			blobs, err := backend.List("file-1/")
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			var listed []string
+			for _, blob := range blobs {
+				listed = append(listed, blob.Key)
+			}
+			if strings.Join(listed, ",") != "file-1/v1/checksum,file-1/v1/manifest" {
+				t.Errorf("List(file-1/) = %v", listed)
+			}
+			if all, _ := backend.List(""); len(all) != len(keys) {
+				t.Errorf("List() returned %d objects, want %d", len(all), len(keys))
+			}
+
+			if err := backend.Delete("file-1/v1/manifest"); err != nil {
+				t.Errorf("Delete failed: %v", err)
+			}
+			if err := backend.Delete("file-1/v1/manifest"); err != nil {
+				t.Errorf("Delete of a missing object failed: %v", err)
+			}
+			if _, err := backend.Get("file-1/v1/manifest"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Get of a deleted object = %v, want fs.ErrNotExist", err)
+			}
+			if _, err := backend.Stat("file-1/v1/manifest"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat of a deleted object = %v, want fs.ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestNodeOnBackends(t *testing.T) {
+	data := bytes.Repeat([]byte("stored anywhere "), 4000)
+	for name, backend := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			node, err := NewBackendNode("test-node", backend)
+			if err != nil {
+				t.Fatalf("NewBackendNode failed: %v", err)
+			}
+			node.SetChunking(testChunking)
+
+			if err := node.StoreFile("file-1", "v1", data); err != nil {
+				t.Fatalf("StoreFile failed: %v", err)
+			}
+			node.StoreFile("file-1", "v2", append(data, "edited"...))
+			retrieved, err := node.RetrieveFile("file-1", "v2", nil)
+			if err != nil || !bytes.Equal(retrieved, append(data, "edited"...)) {
+				t.Errorf("RetrieveFile = %d bytes, %v", len(retrieved), err)
+			}
+			if versions, _ := node.ListVersions("file-1"); len(versions) != 2 {
+				t.Errorf("versions = %v, want 2", versions)
+			}
+
+			// Chunks survive a restart and are shared between versions
+			reopened, err := NewBackendNode("test-node", backend)
+			if err != nil {
+				t.Fatalf("reopening failed: %v", err)
+			}
+			reopened.DeleteFile("file-1", "v1")
+			if _, err := reopened.RetrieveFile("file-1", "v2", nil); err != nil {
+				t.Errorf("chunk shared with deleted version lost: %v", err)
+			}
+			reopened.DeleteAllVersions("file-1")
+			if blobs, _ := backend.List(""); len(blobs) != 0 {
+				t.Errorf("%d objects left after deleting every version", len(blobs))
+			}
+		})
+	}
+}
+
+func TestOpenBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  BackendConfig
+		wantErr bool
+	}{
+		{"filesystem by default", BackendConfig{Path: t.TempDir()}, false},
+		{"filesystem without a path", BackendConfig{Type: BackendFS}, true},
+		{"memory", BackendConfig{Type: BackendMemory}, false},
+		{"s3 without a bucket", BackendConfig{Type: BackendS3}, true},
+		{"unknown", BackendConfig{Type: "tape"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenBackend(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("OpenBackend error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yashlad/distributed-file-store/internal/compression"
+	"github.com/yashlad/distributed-file-store/internal/encryption"
+)
+
+const (
+	// chunkDir is the directory holding a node's chunks, named by their
+	// SHA-256 digest. File IDs never start with a dot, so it can't clash with
+	// a file directory.
+	chunkDir = ".chunks"
+
+	// manifestName is the object listing the chunks of a stored version
+	manifestName = "manifest"
+
+	// checksumName is the object holding the checksum of a stored version.
+	// It is written last, so a version without one is incomplete.
+	checksumName = "checksum"
+
+	// dataName is the object versions stored before chunking kept their
+	// whole content in
+	dataName = "data"
+
+	// tempPrefix marks files that are still being written
+	tempPrefix = ".tmp-"
+)
+
+// Chunk is a piece of a file version, stored once per node by the SHA-256
+// digest of its content no matter how many versions contain it
+type Chunk struct {
+	Digest string
+	Size   int64
+
+	// Codec is the compression the chunk is stored with; empty if it is
+	// stored as is. Digest and Size always describe the uncompressed content.
+	Codec string
+
+	// KeyID identifies the data key the chunk is encrypted with; empty if it
+	// is stored in the clear
+	KeyID string
+}
+
+// ErrKeyMismatch is returned when a version is read with a data key other
+// than the one it was encrypted with, or without one
+var ErrKeyMismatch = errors.New("data key does not match the stored version")
+
+// keyID returns the ID chunks encrypted with a data key are stored under: a
+// truncated hash of the key, which reveals nothing about it. Empty keys
+// leave chunks unencrypted.
+func keyID(key []byte) string {
+	if len(key) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// codecExtensions are the file name suffixes of compressed chunks
+var codecExtensions = map[string]string{
+	compression.Zstd:   ".zst",
+	compression.Gzip:   ".gz",
+	compression.Snappy: ".sz",
+}
+
+// fileName returns the name a chunk is stored under. A chunk stored with
+// different codecs or data keys is a different file, so each copy is counted
+// separately.
+func (c Chunk) fileName() string {
+	name := c.Digest
+	if c.KeyID != "" {
+		name += "." + c.KeyID
+	}
+	return name + codecExtensions[c.Codec]
+}
+
+// chunkKey returns the key a chunk is stored under, fanned out by the first
+// two characters of its digest to keep directories small
+func chunkKey(chunk Chunk) string {
+	return path.Join(chunkDir, chunk.Digest[:2], chunk.fileName())
+}
+
+// versionKey returns the key of an object belonging to a stored version
+func versionKey(fileID, versionID, name string) string {
+	return fileID + "/" + versionID + "/" + name
+}
+
+// splitVersionKey splits the key of an object belonging to a stored version
+// into its parts. Chunk keys and keys of another shape are not split.
+func splitVersionKey(key string) (fileID, versionID, name string, ok bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 3 || parts[0] == chunkDir {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// loadChunkRefs counts the references the stored manifests hold to each
+// chunk and removes chunks nothing references, such as those left behind by
+// a write that was interrupted
+func (n *Node) loadChunkRefs() error {
+	n.refs = make(map[string]int)
+
+	blobs, err := n.backend.List("")
+	if err != nil {
+		return err
+	}
+	var chunkKeys []string
+	for _, blob := range blobs {
+		if strings.HasPrefix(blob.Key, chunkDir+"/") {
+			chunkKeys = append(chunkKeys, blob.Key)
+			continue
+		}
+		fileID, versionID, name, ok := splitVersionKey(blob.Key)
+		if !ok || name != manifestName || reserved(fileID) || reserved(versionID) {
+			continue
+		}
+		chunks, err := n.readManifest(fileID, versionID)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			n.refs[chunk.fileName()]++
+		}
+	}
+
+	for _, key := range chunkKeys {
+		if n.refs[path.Base(key)] == 0 {
+			if err := n.backend.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// acquireChunk takes a reference to a chunk, compressing it with codec,
+// encrypting it with key if one is given, and writing it if the node does not
+// hold it yet. Data that doesn't shrink is stored uncompressed. A stored copy
+// that no longer matches its digest is replaced. It returns the chunk as
+// stored and the bytes it takes on disk.
+func (n *Node) acquireChunk(digest string, data []byte, codec string, key []byte) (Chunk, int64, error) {
+	chunk := Chunk{Digest: digest, Size: int64(len(data)), KeyID: keyID(key)}
+	stored := data
+	if !compression.IsNone(codec) {
+		compressed, err := compression.Compress(codec, data)
+		if err != nil {
+			return Chunk{}, 0, err
+		}
+		if len(compressed) < len(data) {
+			chunk.Codec = codec
+			stored = compressed
+		}
+	}
+	if chunk.KeyID != "" {
+		// The digest is authenticated so a chunk can't be swapped for another
+		sealed, err := encryption.Seal(key, stored, []byte(digest))
+		if err != nil {
+			return Chunk{}, 0, err
+		}
+		stored = sealed
+	}
+
+	n.chunkMu.Lock()
+	n.refs[chunk.fileName()]++
+	n.chunkMu.Unlock()
+
+	// The reference keeps the chunk from being removed while it is checked
+	objectKey := chunkKey(chunk)
+	if existing, err := readObject(n.backend, objectKey); err == nil {
+		if content, err := decodeChunk(chunk, existing, key); err == nil {
+			sum := sha256.Sum256(content)
+			if hex.EncodeToString(sum[:]) == digest {
+				return chunk, int64(len(existing)), nil
+			}
+		}
+	}
+
+	if err := n.backend.Put(objectKey, bytes.NewReader(stored)); err != nil {
+		n.releaseChunks([]Chunk{chunk})
+		return Chunk{}, 0, err
+	}
+	return chunk, int64(len(stored)), nil
+}
+
+// holdChunks takes a reference to chunks that are already stored, keeping
+// them from being removed while they are read
+func (n *Node) holdChunks(chunks []Chunk) {
+	n.chunkMu.Lock()
+	defer n.chunkMu.Unlock()
+
+	for _, chunk := range chunks {
+		n.refs[chunk.fileName()]++
+	}
+}
+
+// releaseChunks drops a reference to each chunk, removing the chunks that are
+// no longer referenced
+func (n *Node) releaseChunks(chunks []Chunk) {
+	n.chunkMu.Lock()
+	defer n.chunkMu.Unlock()
+
+	for _, chunk := range chunks {
+		name := chunk.fileName()
+		n.refs[name]--
+		if n.refs[name] > 0 {
+			continue
+		}
+		delete(n.refs, name)
+		if err := n.backend.Delete(chunkKey(chunk)); err != nil {
+			fmt.Printf("Failed to remove chunk %s from node %s: %v\n", chunk.Digest, n.ID, err)
+		}
+	}
+}
+
+// Chunks returns the chunks a stored version is made of
+func (n *Node) Chunks(fileID, versionID string) ([]Chunk, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.readManifest(fileID, versionID)
+}
+
+// encodeManifest records the chunks of a version, one "digest size" line per
+// chunk followed by the codec of compressed chunks and the key ID of
+// encrypted ones
+func encodeManifest(chunks []Chunk) []byte {
+	var manifest strings.Builder
+	for _, chunk := range chunks {
+		switch {
+		case chunk.KeyID != "":
+			codec := chunk.Codec
+			if codec == "" {
+				codec = compression.None
+			}
+			fmt.Fprintf(&manifest, "%s %d %s %s\n", chunk.Digest, chunk.Size, codec, chunk.KeyID)
+		case chunk.Codec != "":
+			fmt.Fprintf(&manifest, "%s %d %s\n", chunk.Digest, chunk.Size, chunk.Codec)
+		default:
+			fmt.Fprintf(&manifest, "%s %d\n", chunk.Digest, chunk.Size)
+		}
+	}
+	return []byte(manifest.String())
+}
+
+// readManifest reads the chunks of a version
+func (n *Node) readManifest(fileID, versionID string) ([]Chunk, error) {
+	file, err := n.backend.Get(versionKey(fileID, versionID, manifestName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var chunks []Chunk
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || len(fields) > 4 || len(fields[0]) != sha256.Size*2 {
+			return nil, fmt.Errorf("malformed manifest line %q", scanner.Text())
+		}
+		chunkSize, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed manifest line %q", scanner.Text())
+		}
+		chunk := Chunk{Digest: fields[0], Size: chunkSize}
+		if len(fields) >= 3 && fields[2] != compression.None {
+			if _, ok := codecExtensions[fields[2]]; !ok {
+				return nil, fmt.Errorf("unknown codec in manifest line %q", scanner.Text())
+			}
+			chunk.Codec = fields[2]
+		}
+		if len(fields) == 4 {
+			chunk.KeyID = fields[3]
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, scanner.Err()
+}
+
+// chunkedReader reads the chunks of a version in order, decrypting and
+// decompressing them as needed and verifying each one against its digest. It
+// holds a reference to the chunks until it is closed.
+type chunkedReader struct {
+	node    *Node
+	chunks  []Chunk
+	key     []byte
+	next    int
+	current io.ReadCloser
+	hasher  hash.Hash
+	once    sync.Once
+}
+
+// Read implements io.Reader
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.next == len(r.chunks) {
+				return 0, io.EOF
+			}
+			current, err := r.node.openChunk(r.chunks[r.next], r.key)
+			if err != nil {
+				return 0, err
+			}
+			r.current = current
+			r.hasher = sha256.New()
+		}
+
+		n, err := r.current.Read(p)
+		r.hasher.Write(p[:n])
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if hex.EncodeToString(r.hasher.Sum(nil)) != r.chunks[r.next].Digest {
+				return n, ErrChecksumMismatch
+			}
+			r.next++
+			if n == 0 {
+				continue
+			}
+			return n, nil
+		}
+		return n, err
+	}
+}
+
+// Close implements io.Closer, releasing the chunks
+func (r *chunkedReader) Close() error {
+	var err error
+	r.once.Do(func() {
+		if r.current != nil {
+			err = r.current.Close()
+		}
+		r.node.releaseChunks(r.chunks)
+	})
+	return err
+}
+
+// openChunk opens a stored chunk for reading. Plain chunks are streamed from
+// the backend; compressed or encrypted ones are decoded into memory, which
+// is bounded by the maximum chunk size. A chunk that can't be decoded is
+// reported as corrupt.
+func (n *Node) openChunk(chunk Chunk, key []byte) (io.ReadCloser, error) {
+	if chunk.Codec == "" && chunk.KeyID == "" {
+		return n.backend.Get(chunkKey(chunk))
+	}
+
+	stored, err := readObject(n.backend, chunkKey(chunk))
+	if err != nil {
+		return nil, err
+	}
+	content, err := decodeChunk(chunk, stored, key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: chunk %s: %v", ErrChecksumMismatch, chunk.Digest, err)
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// decodeChunk returns the content of a chunk from the bytes stored,
+// decrypting and decompressing them
+func decodeChunk(chunk Chunk, stored, key []byte) ([]byte, error) {
+	if chunk.KeyID != "" {
+		decrypted, err := encryption.Open(key, stored, []byte(chunk.Digest))
+		if err != nil {
+			return nil, err
+		}
+		stored = decrypted
+	}
+	return compression.Decompress(chunk.Codec, stored)
+}
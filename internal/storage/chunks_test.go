@@ -0,0 +1,380 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yashlad/distributed-file-store/internal/chunker"
+	"github.com/yashlad/distributed-file-store/internal/compression"
+	"github.com/yashlad/distributed-file-store/internal/encryption"
+)
+
+// testChunking uses small chunks so tests stay fast
+var testChunking = chunker.Config{MinSize: 1024, AvgSize: 4096, MaxSize: 16384}
+
+// newChunkedNode creates a node that splits versions into small chunks
+func newChunkedNode(t *testing.T, dir string) *Node {
+	t.Helper()
+	node, err := NewNode("test-node", dir)
+	if err != nil {
+		t.Fatalf("NewNode failed: %v", err)
+	}
+	if err := node.SetChunking(testChunking); err != nil {
+		t.Fatalf("SetChunking failed: %v", err)
+	}
+	return node
+}
+
+// countChunks returns the number of chunk files stored on a node
+func countChunks(t *testing.T, node *Node) int {
+	t.Helper()
+	count := 0
+	filepath.WalkDir(filepath.Join(node.StoragePath, chunkDir), func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func TestChunkedStorage(t *testing.T) {
+	data := make([]byte, 256*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	t.Run("splits versions into chunks", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		info, err := node.StoreFileStream("file-1", "v1", bytes.NewReader(data), compression.None, nil)
+		if err != nil {
+			t.Fatalf("StoreFileStream failed: %v", err)
+		}
+		if len(info.Chunks) < 2 {
+			t.Errorf("stored %d chunks, want several", len(info.Chunks))
+		}
+		var total int64
+		for _, chunk := range info.Chunks {
+			if chunk.Size > int64(testChunking.MaxSize) {
+				t.Errorf("chunk of %d bytes exceeds the maximum", chunk.Size)
+			}
+			total += chunk.Size
+		}
+		if total != int64(len(data)) {
+			t.Errorf("chunks cover %d bytes, want %d", total, len(data))
+		}
+
+		chunks, err := node.Chunks("file-1", "v1")
+		if err != nil || len(chunks) != len(info.Chunks) || chunks[0] != info.Chunks[0] {
+			t.Errorf("Chunks() = %+v, %v, want %+v", chunks, err, info.Chunks)
+		}
+
+		retrieved, err := node.RetrieveFile("file-1", "v1", nil)
+		if err != nil || !bytes.Equal(retrieved, data) {
+			t.Errorf("RetrieveFile = %d bytes, %v", len(retrieved), err)
+		}
+	})
+
+	t.Run("identical content is stored once", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		info, _ := node.StoreFileStream("file-1", "v1", bytes.NewReader(data), compression.None, nil)
+		node.StoreFile("file-1", "v2", data)
+		node.StoreFile("file-2", "v1", data)
+
+		if count := countChunks(t, node); count != len(info.Chunks) {
+			t.Errorf("stored %d chunks, want %d", count, len(info.Chunks))
+		}
+	})
+
+	t.Run("an edit only stores the changed chunks", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		info, _ := node.StoreFileStream("file-1", "v1", bytes.NewReader(data), compression.None, nil)
+
+		edited := append(append(append([]byte(nil), data[:len(data)/2]...), []byte("inserted")...), data[len(data)/2:]...)
+		if err := node.StoreFile("file-1", "v2", edited); err != nil {
+			t.Fatalf("StoreFile failed: %v", err)
+		}
+
+		if added := countChunks(t, node) - len(info.Chunks); added > 3 {
+			t.Errorf("edit stored %d new chunks, want at most 3", added)
+		}
+		retrieved, err := node.RetrieveFile("file-1", "v2", nil)
+		if err != nil || !bytes.Equal(retrieved, edited) {
+			t.Errorf("RetrieveFile = %d bytes, %v", len(retrieved), err)
+		}
+	})
+
+	t.Run("chunks are freed when no longer referenced", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		node.StoreFile("file-1", "v1", data)
+		node.StoreFile("file-2", "v1", data[:len(data)/2])
+		shared := countChunks(t, node)
+
+		if err := node.DeleteFile("file-1", "v1"); err != nil {
+			t.Fatalf("DeleteFile failed: %v", err)
+		}
+		if count := countChunks(t, node); count == 0 || count >= shared {
+			t.Errorf("stored %d chunks after deleting one version, want fewer than %d but some", count, shared)
+		}
+		if _, err := node.RetrieveFile("file-2", "v1", nil); err != nil {
+			t.Errorf("shared chunk lost: %v", err)
+		}
+
+		if err := node.DeleteAllVersions("file-2"); err != nil {
+			t.Fatalf("DeleteAllVersions failed: %v", err)
+		}
+		if count := countChunks(t, node); count != 0 {
+			t.Errorf("stored %d chunks after deleting every version, want 0", count)
+		}
+	})
+
+	t.Run("replacing a version frees its old chunks", func(t *testing.T) {
+		node, _ := NewNode("test-node", t.TempDir())
+		node.StoreFile("file-1", "v1", []byte("old content"))
+		node.StoreFile("file-1", "v1", []byte("new content"))
+
+		if count := countChunks(t, node); count != 1 {
+			t.Errorf("stored %d chunks, want 1", count)
+		}
+	})
+
+	t.Run("open readers keep chunks", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		node.StoreFile("file-1", "v1", data)
+
+		reader, err := node.OpenFile("file-1", "v1", nil)
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		node.DeleteFile("file-1", "v1")
+
+		read, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil || !bytes.Equal(read, data) {
+			t.Errorf("read %d bytes, %v after delete", len(read), err)
+		}
+		if count := countChunks(t, node); count != 0 {
+			t.Errorf("stored %d chunks after reader closed, want 0", count)
+		}
+	})
+
+	t.Run("storing heals a corrupt shared chunk", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		node.StoreFile("file-1", "v1", data)
+		os.WriteFile(firstChunkPath(t, node, "file-1", "v1"), []byte("bit rot"), 0644)
+
+		node.StoreFile("file-2", "v1", data)
+		for _, fileID := range []string{"file-1", "file-2"} {
+			if _, err := node.RetrieveFile(fileID, "v1", nil); err != nil {
+				t.Errorf("RetrieveFile(%s) failed: %v", fileID, err)
+			}
+		}
+	})
+
+	t.Run("reloads references and removes unreferenced chunks", func(t *testing.T) {
+		dir := t.TempDir()
+		node := newChunkedNode(t, dir)
+		node.StoreFile("file-1", "v1", data)
+		node.StoreFile("file-2", "v1", data)
+		stray := filepath.Join(dir, chunkDir, "ab", "ab"+string(bytes.Repeat([]byte("0"), 62)))
+		os.MkdirAll(filepath.Dir(stray), 0755)
+		os.WriteFile(stray, []byte("left by a crash"), 0644)
+
+		reopened := newChunkedNode(t, dir)
+		if _, err := os.Stat(stray); !os.IsNotExist(err) {
+			t.Error("unreferenced chunk not removed")
+		}
+
+		reopened.DeleteFile("file-1", "v1")
+		if _, err := reopened.RetrieveFile("file-2", "v1", nil); err != nil {
+			t.Errorf("chunk shared with deleted version lost: %v", err)
+		}
+	})
+
+	t.Run("reads versions stored before chunking", func(t *testing.T) {
+		dir := t.TempDir()
+		versionPath := filepath.Join(dir, "file-1", "v1")
+		os.MkdirAll(versionPath, 0755)
+		os.WriteFile(filepath.Join(versionPath, "data"), []byte("legacy"), 0644)
+		os.WriteFile(filepath.Join(versionPath, "checksum"), []byte((&Node{}).calculateChecksum([]byte("legacy"))), 0644)
+		node, _ := NewNode("test-node", dir)
+
+		retrieved, err := node.RetrieveFile("file-1", "v1", nil)
+		if err != nil || string(retrieved) != "legacy" {
+			t.Errorf("RetrieveFile = %q, %v", retrieved, err)
+		}
+		if !node.FileExists("file-1", "v1") {
+			t.Error("legacy version not found")
+		}
+	})
+}
+
+func TestCompressedChunks(t *testing.T) {
+	// Repetitive text compresses well; random data does not
+	text := bytes.Repeat([]byte(`{"level":"info","msg":"request served","status":200}`+"\n"), 2000)
+	random := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	for _, codec := range []string{compression.Zstd, compression.Gzip, compression.Snappy} {
+		t.Run(codec, func(t *testing.T) {
+			node := newChunkedNode(t, t.TempDir())
+			info, err := node.StoreFileStream("file-1", "v1", bytes.NewReader(text), codec, nil)
+			if err != nil {
+				t.Fatalf("StoreFileStream failed: %v", err)
+			}
+			if info.Size != int64(len(text)) || info.Checksum != node.calculateChecksum(text) {
+				t.Errorf("size %d, checksum %s, want the uncompressed size and checksum", info.Size, info.Checksum)
+			}
+			if info.StoredSize == 0 || info.StoredSize >= info.Size/2 {
+				t.Errorf("stored %d of %d bytes, want compression", info.StoredSize, info.Size)
+			}
+			for _, chunk := range info.Chunks {
+				if chunk.Codec != codec {
+					t.Errorf("chunk codec = %q, want %q", chunk.Codec, codec)
+				}
+			}
+
+			retrieved, err := node.RetrieveFile("file-1", "v1", nil)
+			if err != nil || !bytes.Equal(retrieved, text) {
+				t.Errorf("RetrieveFile = %d bytes, %v", len(retrieved), err)
+			}
+
+			// References survive a restart and are freed on delete
+			reopened := newChunkedNode(t, node.StoragePath)
+			if _, err := reopened.RetrieveFile("file-1", "v1", nil); err != nil {
+				t.Errorf("RetrieveFile after reopen failed: %v", err)
+			}
+			reopened.DeleteFile("file-1", "v1")
+			if count := countChunks(t, reopened); count != 0 {
+				t.Errorf("stored %d chunks after delete, want 0", count)
+			}
+		})
+	}
+
+	t.Run("incompressible chunks are stored as is", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		info, err := node.StoreFileStream("file-1", "v1", bytes.NewReader(random), compression.Zstd, nil)
+		if err != nil {
+			t.Fatalf("StoreFileStream failed: %v", err)
+		}
+		for _, chunk := range info.Chunks {
+			if chunk.Codec != "" {
+				t.Errorf("random chunk stored with %q", chunk.Codec)
+			}
+		}
+		if info.StoredSize != info.Size {
+			t.Errorf("stored %d bytes, want %d", info.StoredSize, info.Size)
+		}
+	})
+
+	t.Run("corrupt compressed chunk", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		node.StoreFileStream("file-1", "v1", bytes.NewReader(text), compression.Zstd, nil)
+		os.WriteFile(firstChunkPath(t, node, "file-1", "v1"), []byte("bit rot"), 0644)
+
+		if _, err := node.RetrieveFile("file-1", "v1", nil); !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("RetrieveFile error = %v, want ErrChecksumMismatch", err)
+		}
+	})
+
+	t.Run("unknown codec", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		if _, err := node.StoreFileStream("file-1", "v1", bytes.NewReader(text), "lz4", nil); err == nil {
+			t.Error("expected error for unknown codec")
+		}
+	})
+}
+
+func TestEncryptedChunks(t *testing.T) {
+	secret := bytes.Repeat([]byte("account 4111-1111-1111-1111 balance 1000000\n"), 2000)
+	key, _ := encryption.GenerateKey()
+	otherKey, _ := encryption.GenerateKey()
+
+	// chunkFilesContain reports whether any chunk file holds the needle
+	chunkFilesContain := func(t *testing.T, node *Node, needle []byte) bool {
+		t.Helper()
+		found := false
+		filepath.WalkDir(filepath.Join(node.StoragePath, chunkDir), func(path string, entry os.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				content, _ := os.ReadFile(path)
+				found = found || bytes.Contains(content, needle)
+			}
+			return nil
+		})
+		return found
+	}
+
+	for _, codec := range []string{compression.None, compression.Zstd} {
+		t.Run(codec, func(t *testing.T) {
+			node := newChunkedNode(t, t.TempDir())
+			info, err := node.StoreFileStream("file-1", "v1", bytes.NewReader(secret), codec, key)
+			if err != nil {
+				t.Fatalf("StoreFileStream failed: %v", err)
+			}
+			if info.Size != int64(len(secret)) || info.Checksum != node.calculateChecksum(secret) {
+				t.Errorf("size %d, checksum %s, want the plaintext size and checksum", info.Size, info.Checksum)
+			}
+			if chunkFilesContain(t, node, []byte("4111-1111")) {
+				t.Error("plaintext found on disk")
+			}
+
+			retrieved, err := node.RetrieveFile("file-1", "v1", key)
+			if err != nil || !bytes.Equal(retrieved, secret) {
+				t.Errorf("RetrieveFile = %d bytes, %v", len(retrieved), err)
+			}
+			for _, wrong := range [][]byte{nil, otherKey} {
+				if _, err := node.RetrieveFile("file-1", "v1", wrong); !errors.Is(err, ErrKeyMismatch) {
+					t.Errorf("RetrieveFile with the wrong key error = %v, want ErrKeyMismatch", err)
+				}
+			}
+
+			// References survive a restart and are freed on delete
+			reopened := newChunkedNode(t, node.StoragePath)
+			if _, err := reopened.RetrieveFile("file-1", "v1", key); err != nil {
+				t.Errorf("RetrieveFile after reopen failed: %v", err)
+			}
+			reopened.DeleteFile("file-1", "v1")
+			if count := countChunks(t, reopened); count != 0 {
+				t.Errorf("stored %d chunks after delete, want 0", count)
+			}
+		})
+	}
+
+	t.Run("chunks are shared per key", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		info, _ := node.StoreFileStream("file-1", "v1", bytes.NewReader(secret), compression.None, key)
+		node.StoreFileStream("file-1", "v2", bytes.NewReader(secret), compression.None, key)
+		if count := countChunks(t, node); count != len(info.Chunks) {
+			t.Errorf("stored %d chunks for two versions with the same key, want %d", count, len(info.Chunks))
+		}
+
+		node.StoreFileStream("file-1", "v3", bytes.NewReader(secret), compression.None, otherKey)
+		node.StoreFile("file-1", "v4", secret)
+		if count := countChunks(t, node); count != 3*len(info.Chunks) {
+			t.Errorf("stored %d chunks, want %d", count, 3*len(info.Chunks))
+		}
+	})
+
+	t.Run("tampered chunk", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		node.StoreFileStream("file-1", "v1", bytes.NewReader(secret), compression.None, key)
+		path := firstChunkPath(t, node, "file-1", "v1")
+		content, _ := os.ReadFile(path)
+		content[len(content)/2] ^= 1
+		os.WriteFile(path, content, 0644)
+
+		if _, err := node.RetrieveFile("file-1", "v1", key); !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("RetrieveFile error = %v, want ErrChecksumMismatch", err)
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		node := newChunkedNode(t, t.TempDir())
+		if _, err := node.StoreFileStream("file-1", "v1", bytes.NewReader(secret), compression.None, []byte("short")); err == nil {
+			t.Error("expected error for a short data key")
+		}
+	})
+}
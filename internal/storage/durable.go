@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reserved reports whether a key segment belongs to the node's own
+// bookkeeping rather than being a stored file or version. File and version
+// IDs never start with a dot.
+func reserved(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// recoverWrites cleans up after writes a crash interrupted: it removes
+// versions left without a checksum or without content, and objects that
+// belong to no version, such as those of the staging directories older nodes
+// wrote versions to. It runs before the chunk references are counted, so
+// chunks only the removed versions used are freed as well.
+func (n *Node) recoverWrites() error {
+	blobs, err := n.backend.List("")
+	if err != nil {
+		return err
+	}
+
+	// Objects of each version, keyed by "fileID/versionID"
+	versions := make(map[string][]string)
+	var order []string
+	var stray []string
+	for _, blob := range blobs {
+		if strings.HasPrefix(blob.Key, chunkDir+"/") {
+			continue
+		}
+		fileID, versionID, _, ok := splitVersionKey(blob.Key)
+		if !ok || reserved(fileID) || reserved(versionID) {
+			stray = append(stray, blob.Key)
+			continue
+		}
+		version := fileID + "/" + versionID
+		if _, seen := versions[version]; !seen {
+			order = append(order, version)
+		}
+		versions[version] = append(versions[version], blob.Key)
+	}
+
+	removed := 0
+	for _, version := range order {
+		if versionComplete(version, versions[version]) {
+			continue
+		}
+		stray = append(stray, versions[version]...)
+		removed++
+	}
+	for _, key := range stray {
+		if err := n.backend.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	if removed > 0 {
+		fmt.Printf("Node %s recovered from interrupted writes: removed %d partial versions\n", n.ID, removed)
+	}
+	return nil
+}
+
+// versionComplete reports whether the objects of a version include a
+// checksum and either a manifest or, for versions stored before chunking, a
+// data file
+func versionComplete(version string, keys []string) bool {
+	has := make(map[string]bool)
+	for _, key := range keys {
+		has[strings.TrimPrefix(key, version+"/")] = true
+	}
+	return has[checksumName] && (has[manifestName] || has[dataName])
+}
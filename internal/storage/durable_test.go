@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecoverWrites(t *testing.T) {
+	data := bytes.Repeat([]byte("survive the crash "), 2000)
+
+	t.Run("removes versions without a checksum", func(t *testing.T) {
+		dir := t.TempDir()
+		node := newChunkedNode(t, dir)
+		node.StoreFile("file-1", "v1", data)
+		node.StoreFile("file-1", "v2", []byte("only in v2"))
+		node.StoreFile("file-2", "v1", []byte("only in file-2"))
+		before := countChunks(t, node)
+
+		// Crashed after the manifest was written in place
+		os.Remove(filepath.Join(dir, "file-1", "v2", "checksum"))
+		os.Remove(filepath.Join(dir, "file-2", "v1", "checksum"))
+
+		reopened := newChunkedNode(t, dir)
+		if reopened.FileExists("file-1", "v2") {
+			t.Error("partial version not removed")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "file-2")); !os.IsNotExist(err) {
+			t.Error("file left empty by recovery not removed")
+		}
+		if got := countChunks(t, reopened); got != before-2 {
+			t.Errorf("%d chunks after recovery, want %d", got, before-2)
+		}
+		if retrieved, err := reopened.RetrieveFile("file-1", "v1", nil); err != nil || !bytes.Equal(retrieved, data) {
+			t.Errorf("complete version damaged by recovery: %v", err)
+		}
+	})
+
+	t.Run("removes temporary files", func(t *testing.T) {
+		dir := t.TempDir()
+		node := newChunkedNode(t, dir)
+		node.StoreFile("file-1", "v1", data)
+
+		// Crashed while staging a version and while removing a file
+		staging := filepath.Join(dir, "file-1", tempPrefix+"123")
+		os.MkdirAll(staging, 0755)
+		os.WriteFile(filepath.Join(staging, manifestName), []byte("partial"), 0644)
+		trash := filepath.Join(dir, tempPrefix+"removed-file-2", "v1")
+		os.MkdirAll(trash, 0755)
+		os.WriteFile(filepath.Join(trash, "checksum"), []byte("0000"), 0644)
+
+		reopened := newChunkedNode(t, dir)
+		for _, path := range []string{staging, filepath.Dir(trash)} {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("%s not removed", path)
+			}
+		}
+		files, _ := reopened.ListFiles()
+		versions, _ := reopened.ListVersions("file-1")
+		if len(files) != 1 || len(versions) != 1 {
+			t.Errorf("files = %v, versions = %v, want only file-1/v1", files, versions)
+		}
+	})
+
+	t.Run("removes a version whose replacement was interrupted", func(t *testing.T) {
+		dir := t.TempDir()
+		node := newChunkedNode(t, dir)
+		node.StoreFile("file-1", "v1", data)
+
+		// Crashed after the old checksum was removed, before the new one
+		// was written
+		os.Remove(filepath.Join(dir, "file-1", "v1", "checksum"))
+
+		reopened := newChunkedNode(t, dir)
+		if reopened.FileExists("file-1", "v1") {
+			t.Error("half-replaced version not removed")
+		}
+		if got := countChunks(t, reopened); got != 0 {
+			t.Errorf("%d chunks after recovery, want 0", got)
+		}
+	})
+
+	t.Run("removes versions moved aside by older nodes", func(t *testing.T) {
+		dir := t.TempDir()
+		node := newChunkedNode(t, dir)
+		node.StoreFile("file-1", "v1", data)
+		old := filepath.Join(dir, "file-1", ".old-v1")
+		os.MkdirAll(old, 0755)
+		os.WriteFile(filepath.Join(old, "checksum"), []byte("0000"), 0644)
+
+		reopened := newChunkedNode(t, dir)
+		if _, err := os.Stat(old); !os.IsNotExist(err) {
+			t.Error("moved-aside version not removed")
+		}
+		if retrieved, err := reopened.RetrieveFile("file-1", "v1", nil); err != nil || !bytes.Equal(retrieved, data) {
+			t.Errorf("version damaged by recovery: %v", err)
+		}
+	})
+}
+
+func TestReplaceVersion(t *testing.T) {
+	dir := t.TempDir()
+	node := newChunkedNode(t, dir)
+	data := bytes.Repeat([]byte("replacement "), 3000)
+	node.StoreFile("file-1", "v1", []byte("first"))
+	if err := node.StoreFile("file-1", "v1", data); err != nil {
+		t.Fatalf("StoreFile failed: %v", err)
+	}
+
+	retrieved, err := node.RetrieveFile("file-1", "v1", nil)
+	if err != nil || !bytes.Equal(retrieved, data) {
+		t.Errorf("replaced version = %d bytes, %v", len(retrieved), err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "file-1"))
+	if len(entries) != 1 {
+		t.Errorf("file directory holds %d entries after the replacement, want 1", len(entries))
+	}
+}
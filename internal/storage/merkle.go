@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+// MerkleLeaves is the number of buckets the inventory of a node is split into.
+// Trees built by different nodes always have the same shape, so they can be
+// compared bucket by bucket.
+const MerkleLeaves = 256
+
+// InventoryEntry describes a file version stored on a node
+type InventoryEntry struct {
+	FileID    string
+	VersionID string
+	Checksum  string
+	ModTime   time.Time
+}
+
+// Inventory returns every file version stored on this node with its checksum.
+// Versions without a checksum file have an empty checksum.
+func (n *Node) Inventory() ([]InventoryEntry, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	blobs, err := n.backend.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	var inventory []InventoryEntry
+	for _, blob := range blobs {
+		fileID, versionID, name, ok := splitVersionKey(blob.Key)
+		if !ok || reserved(fileID) || reserved(versionID) {
+			continue
+		}
+		last := len(inventory) - 1
+		if last < 0 || inventory[last].FileID != fileID || inventory[last].VersionID != versionID {
+			inventory = append(inventory, InventoryEntry{FileID: fileID, VersionID: versionID, ModTime: blob.ModTime})
+			last++
+		}
+
+		entry := &inventory[last]
+		switch name {
+		case checksumName:
+			if checksum, err := readObject(n.backend, blob.Key); err == nil {
+				entry.Checksum = string(checksum)
+			}
+		case manifestName, dataName:
+			// Versions stored before chunking have a data file instead
+			entry.ModTime = blob.ModTime
+		}
+	}
+
+	return inventory, nil
+}
+
+// MerkleTree summarizes an inventory so that two nodes can find the versions
+// they disagree on by exchanging hashes instead of full listings. Each leaf
+// hashes the versions of the files in one bucket; each inner node hashes its
+// two children.
+type MerkleTree struct {
+	// hashes holds the tree in heap order: the root is at index 1 and the
+	// leaves start at MerkleLeaves
+	hashes  [2 * MerkleLeaves][]byte
+	buckets [MerkleLeaves][]InventoryEntry
+}
+
+// MerkleTree builds a Merkle tree over the versions stored on this node.
+// Only files for which include returns true are added; a nil include adds
+// every file.
+func (n *Node) MerkleTree(include func(fileID string) bool) (*MerkleTree, error) {
+	inventory, err := n.Inventory()
+	if err != nil {
+		return nil, err
+	}
+	return BuildMerkleTree(inventory, include), nil
+}
+
+// BuildMerkleTree builds a Merkle tree over an inventory. Only files for which
+// include returns true are added; a nil include adds every file.
+func BuildMerkleTree(inventory []InventoryEntry, include func(fileID string) bool) *MerkleTree {
+	tree := &MerkleTree{}
+	for _, entry := range inventory {
+		if include != nil && !include(entry.FileID) {
+			continue
+		}
+		bucket := MerkleBucket(entry.FileID)
+		tree.buckets[bucket] = append(tree.buckets[bucket], entry)
+	}
+
+	for i := range tree.buckets {
+		entries := tree.buckets[i]
+		sort.Slice(entries, func(a, b int) bool {
+			if entries[a].FileID != entries[b].FileID {
+				return entries[a].FileID < entries[b].FileID
+			}
+			return entries[a].VersionID < entries[b].VersionID
+		})
+
+		hasher := sha256.New()
+		for _, entry := range entries {
+			hasher.Write([]byte(entry.FileID + "/" + entry.VersionID + ":" + entry.Checksum + "\n"))
+		}
+		tree.hashes[MerkleLeaves+i] = hasher.Sum(nil)
+	}
+
+	for i := MerkleLeaves - 1; i >= 1; i-- {
+		hasher := sha256.New()
+		hasher.Write(tree.hashes[2*i])
+		hasher.Write(tree.hashes[2*i+1])
+		tree.hashes[i] = hasher.Sum(nil)
+	}
+
+	return tree
+}
+
+// MerkleBucket returns the bucket a file's versions are placed in
+func MerkleBucket(fileID string) int {
+	hasher := fnv.New32a()
+	hasher.Write([]byte(fileID))
+	return int(hasher.Sum32() % MerkleLeaves)
+}
+
+// Root returns the hash of the whole tree
+func (t *MerkleTree) Root() []byte {
+	return t.hashes[1]
+}
+
+// Bucket returns the versions in a bucket, sorted by file and version ID
+func (t *MerkleTree) Bucket(i int) []InventoryEntry {
+	return t.buckets[i]
+}
+
+// Diff returns the buckets whose contents differ between two trees, only
+// descending into subtrees whose hashes differ
+func (t *MerkleTree) Diff(other *MerkleTree) []int {
+	var buckets []int
+	var walk func(i int)
+	walk = func(i int) {
+		if bytes.Equal(t.hashes[i], other.hashes[i]) {
+			return
+		}
+		if i >= MerkleLeaves {
+			buckets = append(buckets, i-MerkleLeaves)
+			return
+		}
+		walk(2 * i)
+		walk(2*i + 1)
+	}
+	walk(1)
+	return buckets
+}
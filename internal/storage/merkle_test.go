@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInventory(t *testing.T) {
+	node, err := NewNode("test-node", t.TempDir())
+	if err != nil {
+		t.Fatalf("NewNode failed: %v", err)
+	}
+	node.StoreFile("file-1", "v1", []byte("one"))
+	node.StoreFile("file-1", "v2", []byte("two"))
+	node.StoreFile("file-2", "v1", []byte("three"))
+	os.Remove(filepath.Join(node.StoragePath, "file-2", "v1", "checksum"))
+
+	inventory, err := node.Inventory()
+	if err != nil {
+		t.Fatalf("Inventory failed: %v", err)
+	}
+	if len(inventory) != 3 {
+		t.Fatalf("inventory has %d entries, want 3", len(inventory))
+	}
+
+	for _, entry := range inventory {
+		want, _ := node.Checksum(entry.FileID, entry.VersionID)
+		if entry.Checksum != want {
+			t.Errorf("%s/%s checksum = %q, want %q", entry.FileID, entry.VersionID, entry.Checksum, want)
+		}
+		if entry.ModTime.IsZero() {
+			t.Errorf("%s/%s has no modification time", entry.FileID, entry.VersionID)
+		}
+	}
+}
+
+func TestMerkleTree(t *testing.T) {
+	newNode := func(t *testing.T) *Node {
+		node, err := NewNode("test-node", t.TempDir())
+		if err != nil {
+			t.Fatalf("NewNode failed: %v", err)
+		}
+		for i := 0; i < 20; i++ {
+			node.StoreFile(fmt.Sprintf("file-%d", i), "v1", []byte(fmt.Sprintf("data %d", i)))
+		}
+		return node
+	}
+	buildTree := func(t *testing.T, node *Node, include func(string) bool) *MerkleTree {
+		tree, err := node.MerkleTree(include)
+		if err != nil {
+			t.Fatalf("MerkleTree failed: %v", err)
+		}
+		return tree
+	}
+
+	t.Run("identical inventories", func(t *testing.T) {
+		a, b := buildTree(t, newNode(t), nil), buildTree(t, newNode(t), nil)
+		if !bytes.Equal(a.Root(), b.Root()) {
+			t.Error("roots differ for identical inventories")
+		}
+		if diff := a.Diff(b); len(diff) != 0 {
+			t.Errorf("Diff = %v, want none", diff)
+		}
+	})
+
+	t.Run("missing version", func(t *testing.T) {
+		other := newNode(t)
+		other.DeleteFile("file-3", "v1")
+
+		diff := buildTree(t, newNode(t), nil).Diff(buildTree(t, other, nil))
+		if len(diff) != 1 || diff[0] != MerkleBucket("file-3") {
+			t.Errorf("Diff = %v, want [%d]", diff, MerkleBucket("file-3"))
+		}
+	})
+
+	t.Run("different checksum", func(t *testing.T) {
+		node, other := newNode(t), newNode(t)
+		other.StoreFile("file-7", "v1", []byte("changed"))
+
+		tree := buildTree(t, node, nil)
+		diff := tree.Diff(buildTree(t, other, nil))
+		if len(diff) != 1 || diff[0] != MerkleBucket("file-7") {
+			t.Fatalf("Diff = %v, want [%d]", diff, MerkleBucket("file-7"))
+		}
+
+		found := false
+		for _, entry := range tree.Bucket(diff[0]) {
+			found = found || entry.FileID == "file-7"
+		}
+		if !found {
+			t.Error("differing bucket does not list file-7")
+		}
+	})
+
+	t.Run("excluded files are ignored", func(t *testing.T) {
+		other := newNode(t)
+		other.StoreFile("file-5", "v1", []byte("changed"))
+		other.StoreFile("extra", "v1", []byte("extra"))
+
+		include := func(fileID string) bool {
+			return fileID != "file-5" && fileID != "extra"
+		}
+		if diff := buildTree(t, newNode(t), include).Diff(buildTree(t, other, include)); len(diff) != 0 {
+			t.Errorf("Diff = %v, want none", diff)
+		}
+	})
+}
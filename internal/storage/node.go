@@ -1,88 +1,276 @@
 package storage
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"strings"
 	"sync"
+
+	"github.com/yashlad/distributed-file-store/internal/chunker"
+	"github.com/yashlad/distributed-file-store/internal/compression"
+	"github.com/yashlad/distributed-file-store/internal/encryption"
 )
 
 // Node represents a storage node that stores file chunks
 type Node struct {
-	ID          string
+	ID string
+
+	// StoragePath is the directory a node on the local filesystem keeps its
+	// data in; it is empty for nodes on other backends
 	StoragePath string
-	mu          sync.RWMutex
+
+	backend  Backend
+	mu       sync.RWMutex
+	chunking chunker.Config
+
+	// refs counts the references to each stored chunk, from the manifests
+	// on disk and from writes and reads in progress
+	chunkMu sync.Mutex
+	refs    map[string]int
 }
 
-// NewNode creates a new storage node
+// NewNode creates a new storage node keeping its data in a directory on the
+// local filesystem
 func NewNode(id, storagePath string) (*Node, error) {
 	// Create storage directory if it doesn't exist
-	if err := os.MkdirAll(storagePath, 0755); err != nil {
+	backend, err := NewFSBackend(storagePath)
+	if err != nil {
 		return nil, err
 	}
+	return NewBackendNode(id, backend)
+}
 
-	return &Node{
-		ID:          id,
-		StoragePath: storagePath,
-	}, nil
+// NewBackendNode creates a storage node keeping its data in a backend
+func NewBackendNode(id string, backend Backend) (*Node, error) {
+	node := &Node{
+		ID:       id,
+		backend:  backend,
+		chunking: chunker.DefaultConfig(),
+	}
+	if fsBackend, ok := backend.(*FSBackend); ok {
+		node.StoragePath = fsBackend.Root()
+	}
+	if err := node.recoverWrites(); err != nil {
+		return nil, fmt.Errorf("failed to recover interrupted writes: %w", err)
+	}
+	if err := node.loadChunkRefs(); err != nil {
+		return nil, fmt.Errorf("failed to load chunks: %w", err)
+	}
+	return node, nil
+}
+
+// ObjectInfo describes a file version stored on a node. Size and Checksum
+// cover the uncompressed content; StoredSize is what its chunks take on disk.
+type ObjectInfo struct {
+	Size       int64
+	StoredSize int64
+	Checksum   string
+	Chunks     []Chunk
+}
+
+// SetChunking sets the chunk sizes new versions are split into. Versions
+// already stored keep their chunks.
+func (n *Node) SetChunking(config chunker.Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.chunking = config
+	return nil
 }
 
 // StoreFile stores a file on this node
 func (n *Node) StoreFile(fileID, versionID string, data []byte) error {
+	_, err := n.StoreFileStream(fileID, versionID, bytes.NewReader(data), compression.None, nil)
+	return err
+}
+
+// StoreFileStream stores a file on this node by streaming it from r. The
+// data is split into content-defined chunks that are stored by their SHA-256
+// digest, so content the node already holds is not written again, even when
+// an edit shifted it to a different offset, and the checksum of the whole
+// file is computed as the data is written. Nothing is visible until every
+// chunk is stored and the version is committed. Each chunk is compressed with
+// codec unless that doesn't make it smaller, then encrypted with AES-GCM if a
+// data key is given; the same key must be given to read the version back.
+// Storing a version that already exists replaces it.
+func (n *Node) StoreFileStream(fileID, versionID string, r io.Reader, codec string, key []byte) (*ObjectInfo, error) {
+	if err := compression.Validate(codec); err != nil {
+		return nil, err
+	}
+	if len(key) != 0 && len(key) != encryption.KeySize {
+		return nil, fmt.Errorf("data key is %d bytes, want %d", len(key), encryption.KeySize)
+	}
+	n.mu.RLock()
+	config := n.chunking
+	n.mu.RUnlock()
+
+	hasher := sha256.New()
+	splitter, err := chunker.New(io.TeeReader(r, hasher), config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Chunks are written without holding the lock; holding it while
+	// streaming would serialize every upload touching this node.
+	var chunks []Chunk
+	var size, storedSize int64
+	for {
+		data, err := splitter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			n.releaseChunks(chunks)
+			return nil, err
+		}
+
+		chunk, stored, err := n.acquireChunk(n.calculateChecksum(data), data, codec, key)
+		if err != nil {
+			n.releaseChunks(chunks)
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+		size += chunk.Size
+		storedSize += stored
+	}
+
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	replaced, err := n.commitVersion(fileID, versionID, chunks, checksum)
+	if err != nil {
+		n.releaseChunks(chunks)
+		return nil, err
+	}
+	n.releaseChunks(replaced)
+
+	return &ObjectInfo{Size: size, StoredSize: storedSize, Checksum: checksum, Chunks: chunks}, nil
+}
+
+// commitVersion makes a version visible by writing its manifest and then its
+// checksum, which marks the version complete: recovery removes versions a
+// crash left without one. When a version is replaced, its checksum is removed
+// first, so a crash partway through leaves an incomplete version rather than
+// a manifest that doesn't match its checksum. It returns the chunks of the
+// version it replaced, if any.
+func (n *Node) commitVersion(fileID, versionID string, chunks []Chunk, checksum string) ([]Chunk, error) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	// Create version directory
-	versionPath := filepath.Join(n.StoragePath, fileID, versionID)
-	if err := os.MkdirAll(versionPath, 0755); err != nil {
-		return err
+	replaced, err := n.readManifest(fileID, versionID)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
 	}
 
-	// Write file data
-	filePath := filepath.Join(versionPath, "data")
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return err
+	checksumKey := versionKey(fileID, versionID, checksumName)
+	if _, err := n.backend.Stat(checksumKey); err == nil {
+		if err := n.backend.Delete(checksumKey); err != nil {
+			return nil, err
+		}
+		// Versions stored before chunking kept their data in a single file
+		if err := n.backend.Delete(versionKey(fileID, versionID, dataName)); err != nil {
+			return nil, err
+		}
 	}
 
-	// Store checksum
-	checksum := n.calculateChecksum(data)
-	checksumPath := filepath.Join(versionPath, "checksum")
-	if err := os.WriteFile(checksumPath, []byte(checksum), 0644); err != nil {
-		return err
+	if err := n.backend.Put(versionKey(fileID, versionID, manifestName), bytes.NewReader(encodeManifest(chunks))); err != nil {
+		return nil, err
+	}
+	if err := n.backend.Put(checksumKey, strings.NewReader(checksum)); err != nil {
+		return nil, err
 	}
+	return replaced, nil
+}
 
-	return nil
+// ErrChecksumMismatch is returned when stored data does not match its checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch: data corrupted")
+
+// RetrieveFile retrieves a file from this node, decrypting it with key if it
+// is encrypted
+func (n *Node) RetrieveFile(fileID, versionID string, key []byte) ([]byte, error) {
+	reader, err := n.OpenFile(fileID, versionID, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
 }
 
-// RetrieveFile retrieves a file from this node
-func (n *Node) RetrieveFile(fileID, versionID string) ([]byte, error) {
+// OpenFile opens a file on this node for streaming. The checksum is verified
+// incrementally as the data is read; if it does not match, the final Read
+// returns ErrChecksumMismatch instead of io.EOF. An encrypted version must be
+// opened with the data key it was stored with, or ErrKeyMismatch is returned.
+func (n *Node) OpenFile(fileID, versionID string, key []byte) (io.ReadCloser, error) {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
-	versionPath := filepath.Join(n.StoragePath, fileID, versionID)
-	filePath := filepath.Join(versionPath, "data")
-
-	data, err := os.ReadFile(filePath)
+	storedChecksum, err := readObject(n.backend, versionKey(fileID, versionID, checksumName))
 	if err != nil {
 		return nil, err
 	}
 
-	// Verify checksum
-	checksumPath := filepath.Join(versionPath, "checksum")
-	storedChecksum, err := os.ReadFile(checksumPath)
+	chunks, err := n.readManifest(fileID, versionID)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Stored before chunking
+		file, err := n.backend.Get(versionKey(fileID, versionID, dataName))
+		if err != nil {
+			return nil, err
+		}
+		return &verifyingReader{file: file, hasher: sha256.New(), expected: string(storedChecksum)}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	calculatedChecksum := n.calculateChecksum(data)
-	if calculatedChecksum != string(storedChecksum) {
-		return nil, fmt.Errorf("checksum mismatch: data corrupted")
+	// Fail now rather than partway through the stream if a chunk is missing
+	// or encrypted with another key
+	id := keyID(key)
+	for _, chunk := range chunks {
+		if chunk.KeyID != "" && chunk.KeyID != id {
+			return nil, ErrKeyMismatch
+		}
+		if _, err := n.backend.Stat(chunkKey(chunk)); err != nil {
+			return nil, err
+		}
+	}
+	n.holdChunks(chunks)
+
+	return &verifyingReader{
+		file:     &chunkedReader{node: n, chunks: chunks, key: key},
+		hasher:   sha256.New(),
+		expected: string(storedChecksum),
+	}, nil
+}
+
+// verifyingReader hashes data as it is read and checks it against the stored checksum at EOF
+type verifyingReader struct {
+	file     io.ReadCloser
+	hasher   hash.Hash
+	expected string
+}
+
+// Read implements io.Reader
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.file.Read(p)
+	r.hasher.Write(p[:n])
+
+	if err == io.EOF && hex.EncodeToString(r.hasher.Sum(nil)) != r.expected {
+		return n, ErrChecksumMismatch
 	}
+	return n, err
+}
 
-	return data, nil
+// Close implements io.Closer
+func (r *verifyingReader) Close() error {
+	return r.file.Close()
 }
 
 // DeleteFile deletes a file from this node
@@ -90,23 +278,19 @@ func (n *Node) DeleteFile(fileID, versionID string) error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	versionPath := filepath.Join(n.StoragePath, fileID, versionID)
-	
-	// Remove version directory
-	if err := os.RemoveAll(versionPath); err != nil {
-		return err
-	}
-
-	// Check if file directory is empty and remove it
-	filePath := filepath.Join(n.StoragePath, fileID)
-	entries, err := os.ReadDir(filePath)
-	if err != nil {
+	chunks, err := n.readManifest(fileID, versionID)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
 
-	if len(entries) == 0 {
-		return os.Remove(filePath)
+	// The checksum goes first, so a crash partway through leaves an
+	// incomplete version that recovery removes
+	for _, name := range []string{checksumName, manifestName, dataName} {
+		if err := n.backend.Delete(versionKey(fileID, versionID, name)); err != nil {
+			return err
+		}
 	}
+	n.releaseChunks(chunks)
 
 	return nil
 }
@@ -116,8 +300,35 @@ func (n *Node) DeleteAllVersions(fileID string) error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	filePath := filepath.Join(n.StoragePath, fileID)
-	return os.RemoveAll(filePath)
+	blobs, err := n.backend.List(fileID + "/")
+	if err != nil {
+		return err
+	}
+	var chunks []Chunk
+	var checksums, others []string
+	for _, blob := range blobs {
+		_, versionID, name, ok := splitVersionKey(blob.Key)
+		switch {
+		case ok && name == checksumName:
+			checksums = append(checksums, blob.Key)
+			continue
+		case ok && name == manifestName && !reserved(versionID):
+			versionChunks, err := n.readManifest(fileID, versionID)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			chunks = append(chunks, versionChunks...)
+		}
+		others = append(others, blob.Key)
+	}
+
+	for _, key := range append(checksums, others...) {
+		if err := n.backend.Delete(key); err != nil {
+			return err
+		}
+	}
+	n.releaseChunks(chunks)
+	return nil
 }
 
 // FileExists checks if a file exists on this node
@@ -125,61 +336,47 @@ func (n *Node) FileExists(fileID, versionID string) bool {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
-	versionPath := filepath.Join(n.StoragePath, fileID, versionID)
-	filePath := filepath.Join(versionPath, "data")
-	
-	_, err := os.Stat(filePath)
+	if _, err := n.backend.Stat(versionKey(fileID, versionID, manifestName)); err == nil {
+		return true
+	}
+	_, err := n.backend.Stat(versionKey(fileID, versionID, dataName))
 	return err == nil
 }
 
-// GetStorageSize returns the total storage used by this node
-func (n *Node) GetStorageSize() (int64, error) {
+// Checksum returns the stored checksum of a file without reading its data
+func (n *Node) Checksum(fileID, versionID string) (string, error) {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
-	var size int64
-	err := filepath.Walk(n.StoragePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			size += info.Size()
-		}
-		return nil
-	})
-
-	return size, err
+	checksum, err := readObject(n.backend, versionKey(fileID, versionID, checksumName))
+	if err != nil {
+		return "", err
+	}
+	return string(checksum), nil
 }
 
-// ReplicateFile replicates a file from source data
-func (n *Node) ReplicateFile(fileID, versionID string, source io.Reader) error {
-	n.mu.Lock()
-	defer n.mu.Unlock()
-
-	versionPath := filepath.Join(n.StoragePath, fileID, versionID)
-	if err := os.MkdirAll(versionPath, 0755); err != nil {
-		return err
-	}
+// GetStorageSize returns the total storage used by this node
+func (n *Node) GetStorageSize() (int64, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 
-	filePath := filepath.Join(versionPath, "data")
-	file, err := os.Create(filePath)
+	blobs, err := n.backend.List("")
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer file.Close()
-
-	// Copy data and calculate checksum simultaneously
-	hasher := sha256.New()
-	multiWriter := io.MultiWriter(file, hasher)
-	
-	if _, err := io.Copy(multiWriter, source); err != nil {
-		return err
+	var size int64
+	for _, blob := range blobs {
+		size += blob.Size
 	}
 
-	// Store checksum
-	checksum := hex.EncodeToString(hasher.Sum(nil))
-	checksumPath := filepath.Join(versionPath, "checksum")
-	return os.WriteFile(checksumPath, []byte(checksum), 0644)
+	return size, nil
+}
+
+// ReplicateFile replicates a file from source data, compressing it with codec
+// and encrypting it with key
+func (n *Node) ReplicateFile(fileID, versionID string, source io.Reader, codec string, key []byte) error {
+	_, err := n.StoreFileStream(fileID, versionID, source, codec, key)
+	return err
 }
 
 // calculateChecksum calculates SHA-256 checksum of data
@@ -193,17 +390,48 @@ func (n *Node) ListFiles() ([]string, error) {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
-	entries, err := os.ReadDir(n.StoragePath)
+	blobs, err := n.backend.List("")
 	if err != nil {
 		return nil, err
 	}
 
 	var fileIDs []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			fileIDs = append(fileIDs, entry.Name())
+	for _, blob := range blobs {
+		fileID, versionID, _, ok := splitVersionKey(blob.Key)
+		if !ok || reserved(fileID) || reserved(versionID) {
+			continue
+		}
+		if len(fileIDs) == 0 || fileIDs[len(fileIDs)-1] != fileID {
+			fileIDs = append(fileIDs, fileID)
 		}
 	}
 
 	return fileIDs, nil
 }
+
+// ListVersions returns all version IDs stored on this node for a file
+func (n *Node) ListVersions(fileID string) ([]string, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	blobs, err := n.backend.List(fileID + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	var versionIDs []string
+	for _, blob := range blobs {
+		_, versionID, _, ok := splitVersionKey(blob.Key)
+		if !ok || reserved(versionID) {
+			continue
+		}
+		if len(versionIDs) == 0 || versionIDs[len(versionIDs)-1] != versionID {
+			versionIDs = append(versionIDs, versionID)
+		}
+	}
+	if len(versionIDs) == 0 {
+		return nil, &fs.PathError{Op: "list", Path: fileID, Err: fs.ErrNotExist}
+	}
+
+	return versionIDs, nil
+}
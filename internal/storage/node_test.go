@@ -2,12 +2,27 @@ package storage
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/iotest"
+
+	"github.com/yashlad/distributed-file-store/internal/compression"
 )
 
+// firstChunkPath returns the path of the first chunk of a stored version
+func firstChunkPath(t *testing.T, node *Node, fileID, versionID string) string {
+	t.Helper()
+	chunks, err := node.Chunks(fileID, versionID)
+	if err != nil || len(chunks) == 0 {
+		t.Fatalf("no chunks stored for %s/%s: %v", fileID, versionID, err)
+	}
+	return filepath.Join(node.StoragePath, filepath.FromSlash(chunkKey(chunks[0])))
+}
+
 func TestNewNode(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -54,8 +69,7 @@ func TestStoreFile(t *testing.T) {
 		}
 
 		// Verify file exists
-		filePath := filepath.Join(tempDir, "file-1", "version-1", "data")
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		if _, err := os.Stat(firstChunkPath(t, node, "file-1", "version-1")); os.IsNotExist(err) {
 			t.Error("file was not stored")
 		}
 
@@ -102,6 +116,41 @@ func TestStoreFile(t *testing.T) {
 	})
 }
 
+func TestStoreFileStream(t *testing.T) {
+	tempDir := t.TempDir()
+	node, _ := NewNode("test-node", tempDir)
+
+	t.Run("stream from reader", func(t *testing.T) {
+		data := bytes.Repeat([]byte("streamed data "), 100000)
+		info, err := node.StoreFileStream("file-1", "version-1", bytes.NewReader(data), compression.None, nil)
+		if err != nil {
+			t.Fatalf("StoreFileStream failed: %v", err)
+		}
+
+		if info.Size != int64(len(data)) {
+			t.Errorf("Size = %d, want %d", info.Size, len(data))
+		}
+		if info.Checksum != node.calculateChecksum(data) {
+			t.Error("checksum does not match data")
+		}
+
+		retrievedData, err := node.RetrieveFile("file-1", "version-1", nil)
+		if err != nil {
+			t.Fatalf("RetrieveFile failed: %v", err)
+		}
+		if !bytes.Equal(retrievedData, data) {
+			t.Error("retrieved data does not match original")
+		}
+	})
+
+	t.Run("reader error", func(t *testing.T) {
+		reader := io.MultiReader(bytes.NewReader([]byte("partial")), iotest.ErrReader(errors.New("boom")))
+		if _, err := node.StoreFileStream("file-2", "version-1", reader, compression.None, nil); err == nil {
+			t.Error("expected error from failing reader")
+		}
+	})
+}
+
 func TestRetrieveFile(t *testing.T) {
 	tempDir := t.TempDir()
 	node, _ := NewNode("test-node", tempDir)
@@ -110,7 +159,7 @@ func TestRetrieveFile(t *testing.T) {
 		originalData := []byte("Test data for retrieval")
 		node.StoreFile("file-1", "version-1", originalData)
 
-		retrievedData, err := node.RetrieveFile("file-1", "version-1")
+		retrievedData, err := node.RetrieveFile("file-1", "version-1", nil)
 		if err != nil {
 			t.Errorf("RetrieveFile failed: %v", err)
 		}
@@ -120,7 +169,7 @@ func TestRetrieveFile(t *testing.T) {
 	})
 
 	t.Run("retrieve non-existing file", func(t *testing.T) {
-		_, err := node.RetrieveFile("non-existing", "version-1")
+		_, err := node.RetrieveFile("non-existing", "version-1", nil)
 		if err == nil {
 			t.Error("expected error for non-existing file")
 		}
@@ -131,18 +180,69 @@ func TestRetrieveFile(t *testing.T) {
 		node.StoreFile("file-2", "version-1", data)
 
 		// Corrupt the data file
-		filePath := filepath.Join(tempDir, "file-2", "version-1", "data")
+		filePath := firstChunkPath(t, node, "file-2", "version-1")
 		corruptData := []byte("Corrupted data")
 		os.WriteFile(filePath, corruptData, 0644)
 
 		// Should fail checksum verification
-		_, err := node.RetrieveFile("file-2", "version-1")
+		_, err := node.RetrieveFile("file-2", "version-1", nil)
 		if err == nil {
 			t.Error("expected checksum mismatch error")
 		}
 	})
 }
 
+func TestOpenFile(t *testing.T) {
+	tempDir := t.TempDir()
+	node, _ := NewNode("test-node", tempDir)
+
+	t.Run("stream existing file", func(t *testing.T) {
+		data := bytes.Repeat([]byte("chunked read "), 200000)
+		node.StoreFile("file-1", "version-1", data)
+
+		reader, err := node.OpenFile("file-1", "version-1", nil)
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		defer reader.Close()
+
+		var out bytes.Buffer
+		if _, err := io.CopyBuffer(&out, reader, make([]byte, 4096)); err != nil {
+			t.Fatalf("reading stream failed: %v", err)
+		}
+		if !bytes.Equal(out.Bytes(), data) {
+			t.Error("streamed data does not match original")
+		}
+	})
+
+	t.Run("open non-existing file", func(t *testing.T) {
+		if _, err := node.OpenFile("non-existing", "version-1", nil); err == nil {
+			t.Error("expected error for non-existing file")
+		}
+	})
+
+	t.Run("trailing checksum error", func(t *testing.T) {
+		node.StoreFile("file-2", "version-1", []byte("Data with checksum"))
+
+		filePath := firstChunkPath(t, node, "file-2", "version-1")
+		os.WriteFile(filePath, []byte("Data with checksuM"), 0644)
+
+		reader, err := node.OpenFile("file-2", "version-1", nil)
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(reader)
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("err = %v, want ErrChecksumMismatch", err)
+		}
+		if len(data) != len("Data with checksuM") {
+			t.Errorf("read %d bytes before integrity error, want %d", len(data), len("Data with checksuM"))
+		}
+	})
+}
+
 func TestDeleteFile(t *testing.T) {
 	tempDir := t.TempDir()
 	node, _ := NewNode("test-node", tempDir)
@@ -266,13 +366,13 @@ func TestReplicateFile(t *testing.T) {
 		data := []byte("Data to replicate")
 		reader := bytes.NewReader(data)
 
-		err := node.ReplicateFile("file-1", "version-1", reader)
+		err := node.ReplicateFile("file-1", "version-1", reader, compression.None, nil)
 		if err != nil {
 			t.Errorf("ReplicateFile failed: %v", err)
 		}
 
 		// Verify file was stored correctly
-		retrievedData, err := node.RetrieveFile("file-1", "version-1")
+		retrievedData, err := node.RetrieveFile("file-1", "version-1", nil)
 		if err != nil {
 			t.Errorf("RetrieveFile after replication failed: %v", err)
 		}
@@ -288,7 +388,7 @@ func TestReplicateFile(t *testing.T) {
 		}
 		reader := bytes.NewReader(largeData)
 
-		err := node.ReplicateFile("file-2", "version-1", reader)
+		err := node.ReplicateFile("file-2", "version-1", reader, compression.None, nil)
 		if err != nil {
 			t.Errorf("ReplicateFile failed for large file: %v", err)
 		}
@@ -324,6 +424,27 @@ func TestListFiles(t *testing.T) {
 	})
 }
 
+func TestListVersions(t *testing.T) {
+	tempDir := t.TempDir()
+	node, _ := NewNode("test-node", tempDir)
+
+	node.StoreFile("file-1", "v1", []byte("data1"))
+	node.StoreFile("file-1", "v2", []byte("data2"))
+	node.StoreFile("file-2", "v1", []byte("data3"))
+
+	versions, err := node.ListVersions("file-1")
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Errorf("Expected 2 versions, got %d", len(versions))
+	}
+
+	if _, err := node.ListVersions("missing"); err == nil {
+		t.Error("Expected error for unknown file")
+	}
+}
+
 func TestConcurrentOperations(t *testing.T) {
 	tempDir := t.TempDir()
 	node, _ := NewNode("test-node", tempDir)
@@ -358,7 +479,7 @@ func TestConcurrentOperations(t *testing.T) {
 
 		for i := 0; i < numReads; i++ {
 			go func() {
-				_, err := node.RetrieveFile("file-read", "v1")
+				_, err := node.RetrieveFile("file-read", "v1", nil)
 				if err != nil {
 					t.Errorf("concurrent RetrieveFile failed: %v", err)
 				}
@@ -391,6 +512,6 @@ func BenchmarkRetrieveFile(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		node.RetrieveFile("file-bench", "v1")
+		node.RetrieveFile("file-bench", "v1", nil)
 	}
 }
@@ -0,0 +1,421 @@
+package storagenode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	nodepb "github.com/yashlad/distributed-file-store/api/proto/storagenode"
+	"github.com/yashlad/distributed-file-store/internal/chunker"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// callTimeout bounds the requests that don't stream data
+	callTimeout = 30 * time.Second
+
+	// replicateTimeout bounds a copy of one version between two daemons
+	replicateTimeout = 10 * time.Minute
+
+	// listTimeout bounds reading a node's whole inventory
+	listTimeout = 5 * time.Minute
+)
+
+// ErrInsecureKey is returned for a request that would send a data key over
+// a connection without TLS
+var ErrInsecureKey = errors.New("refusing to send a data key without cluster TLS")
+
+// Client talks to a storage node daemon. It offers the same operations as a
+// local storage.Node, and reports the same errors for missing versions,
+// corrupted data and wrong keys, so the File Manager can use either.
+type Client struct {
+	address string
+	secure  bool
+	conn    *grpc.ClientConn
+	client  nodepb.StorageNodeClient
+}
+
+// Dial creates a client for the daemon at address, using mutual TLS with
+// clusterTLS. Without it the connection is plaintext, and requests that
+// carry a data key fail with ErrInsecureKey. The connection is made lazily,
+// so an unreachable daemon only fails the requests sent to it.
+func Dial(address string, clusterTLS *ClusterTLS) (*Client, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(clusterTLS.clientCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		address: address,
+		secure:  clusterTLS != nil,
+		conn:    conn,
+		client:  nodepb.NewStorageNodeClient(conn),
+	}, nil
+}
+
+// PeerCopies reports whether the daemon can copy versions from other
+// daemons, which needs cluster TLS
+func (c *Client) PeerCopies() bool {
+	return c.secure
+}
+
+// checkKey fails if key would be sent in cleartext
+func (c *Client) checkKey(key []byte) error {
+	if len(key) > 0 && !c.secure {
+		return ErrInsecureKey
+	}
+	return nil
+}
+
+// Address returns the address of the daemon
+func (c *Client) Address() string {
+	return c.address
+}
+
+// Close closes the connection to the daemon
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// SetChunking sets the chunk sizes the node splits new versions into
+func (c *Client) SetChunking(config chunker.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	_, err := c.client.Configure(ctx, &nodepb.ConfigureRequest{
+		ChunkMinSize: int32(config.MinSize),
+		ChunkAvgSize: int32(config.AvgSize),
+		ChunkMaxSize: int32(config.MaxSize),
+	})
+	return fromStatus(err)
+}
+
+// StoreFileStream streams a version to the node. If reading r fails, the
+// stream is cancelled so the node drops what it has stored so far.
+func (c *Client) StoreFileStream(fileID, versionID string, r io.Reader, codec string, key []byte) (*storage.ObjectInfo, error) {
+	return c.StoreFileStreamContext(context.Background(), fileID, versionID, r, codec, key)
+}
+
+// StoreFileStreamContext is StoreFileStream bound to ctx: once ctx is done
+// the stream is cancelled, even if the node stopped reading or never
+// answers after the last message.
+func (c *Client) StoreFileStreamContext(ctx context.Context, fileID, versionID string, r io.Reader, codec string, key []byte) (*storage.ObjectInfo, error) {
+	if err := c.checkKey(key); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.client.Put(ctx)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+
+	req := &nodepb.PutRequest{FileId: fileID, VersionId: versionID, Codec: codec, Key: key}
+	for first := true; ; first = false {
+		buf := make([]byte, streamChunkSize)
+		n, readErr := io.ReadFull(r, buf)
+		if readErr == io.ErrUnexpectedEOF {
+			readErr = io.EOF
+		}
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
+		}
+
+		// The first message is sent even if r is empty, to name the version
+		if n > 0 || first {
+			req.Data = buf[:n]
+			if err := stream.Send(req); err != nil {
+				// The node ended the stream; its error comes with the response
+				if err == io.EOF {
+					_, err = stream.CloseAndRecv()
+				}
+				return nil, fromStatus(err)
+			}
+			req = &nodepb.PutRequest{}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	info := &storage.ObjectInfo{Size: resp.Size, StoredSize: resp.StoredSize, Checksum: resp.Checksum}
+	for _, chunk := range resp.Chunks {
+		info.Chunks = append(info.Chunks, storage.Chunk{
+			Digest: chunk.Digest,
+			Size:   chunk.Size,
+			Codec:  chunk.Codec,
+			KeyID:  chunk.KeyId,
+		})
+	}
+	return info, nil
+}
+
+// ReplicateFile stores a copy of a version read from source
+func (c *Client) ReplicateFile(fileID, versionID string, source io.Reader, codec string, key []byte) error {
+	_, err := c.StoreFileStream(fileID, versionID, source, codec, key)
+	return err
+}
+
+// ReplicateFrom asks the node to copy a version from the daemon at source,
+// and returns the number of bytes copied. The copy ends when ctx is done or
+// after replicateTimeout, whichever comes first.
+func (c *Client) ReplicateFrom(ctx context.Context, source, fileID, versionID, codec string, key []byte) (int64, error) {
+	if !c.secure {
+		return 0, fmt.Errorf("copies between daemons need cluster TLS")
+	}
+	ctx, cancel := context.WithTimeout(ctx, replicateTimeout)
+	defer cancel()
+	resp, err := c.client.Replicate(ctx, &nodepb.ReplicateRequest{
+		FileId:    fileID,
+		VersionId: versionID,
+		Source:    source,
+		Codec:     codec,
+		Key:       key,
+	})
+	if err != nil {
+		return 0, fromStatus(err)
+	}
+	return resp.Size, nil
+}
+
+// OpenFile opens a version for streaming. Errors opening the version are
+// returned here rather than on the first read.
+func (c *Client) OpenFile(fileID, versionID string, key []byte) (io.ReadCloser, error) {
+	return c.openFile(context.Background(), fileID, versionID, key)
+}
+
+// openFile opens a version for streaming; the stream ends when ctx is done
+func (c *Client) openFile(ctx context.Context, fileID, versionID string, key []byte) (io.ReadCloser, error) {
+	if err := c.checkKey(key); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.client.Get(ctx, &nodepb.GetRequest{FileId: fileID, VersionId: versionID, Key: key})
+	if err != nil {
+		cancel()
+		return nil, fromStatus(err)
+	}
+	// Wait for the node to confirm the version was opened
+	if _, err := stream.Recv(); err != nil {
+		cancel()
+		return nil, fromStatus(err)
+	}
+	return &getReader{stream: stream, cancel: cancel}, nil
+}
+
+// getReader adapts a Get stream to an io.ReadCloser
+type getReader struct {
+	stream nodepb.StorageNode_GetClient
+	cancel context.CancelFunc
+	buf    []byte
+}
+
+// Read implements io.Reader
+func (r *getReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		resp, err := r.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, fromStatus(err)
+		}
+		r.buf = resp.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close implements io.Closer, ending the stream if it wasn't read to the end
+func (r *getReader) Close() error {
+	r.cancel()
+	return nil
+}
+
+// RetrieveFile reads a whole version
+func (c *Client) RetrieveFile(fileID, versionID string, key []byte) ([]byte, error) {
+	reader, err := c.OpenFile(fileID, versionID, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// DeleteFile deletes a version from the node
+func (c *Client) DeleteFile(fileID, versionID string) error {
+	if versionID == "" {
+		return fmt.Errorf("version ID is required")
+	}
+	return c.delete(fileID, versionID)
+}
+
+// DeleteAllVersions deletes every version of a file from the node
+func (c *Client) DeleteAllVersions(fileID string) error {
+	return c.delete(fileID, "")
+}
+
+// delete sends a Delete request
+func (c *Client) delete(fileID, versionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	_, err := c.client.Delete(ctx, &nodepb.DeleteRequest{FileId: fileID, VersionId: versionID})
+	return fromStatus(err)
+}
+
+// FileExists reports whether the node stores a version. A node that can't
+// be reached is treated as not storing it.
+func (c *Client) FileExists(fileID, versionID string) bool {
+	resp, err := c.stat(fileID, versionID)
+	return err == nil && resp.Exists
+}
+
+// Checksum returns the checksum the node stored for a version
+func (c *Client) Checksum(fileID, versionID string) (string, error) {
+	resp, err := c.stat(fileID, versionID)
+	if err != nil {
+		return "", err
+	}
+	if !resp.Exists || resp.Checksum == "" {
+		return "", &fs.PathError{Op: "checksum", Path: fileID + "/" + versionID, Err: fs.ErrNotExist}
+	}
+	return resp.Checksum, nil
+}
+
+// GetStorageSize returns the number of bytes the node stores
+func (c *Client) GetStorageSize() (int64, error) {
+	resp, err := c.stat("", "")
+	if err != nil {
+		return 0, err
+	}
+	return resp.StorageSize, nil
+}
+
+// stat sends a Stat request
+func (c *Client) stat(fileID, versionID string) (*nodepb.StatResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp, err := c.client.Stat(ctx, &nodepb.StatRequest{FileId: fileID, VersionId: versionID})
+	return resp, fromStatus(err)
+}
+
+// Inventory returns every version stored on the node with its checksum
+func (c *Client) Inventory() ([]storage.InventoryEntry, error) {
+	return c.list("")
+}
+
+// ListFiles returns the IDs of the files stored on the node
+func (c *Client) ListFiles() ([]string, error) {
+	inventory, err := c.list("")
+	if err != nil {
+		return nil, err
+	}
+	var fileIDs []string
+	for _, entry := range inventory {
+		if len(fileIDs) == 0 || fileIDs[len(fileIDs)-1] != entry.FileID {
+			fileIDs = append(fileIDs, entry.FileID)
+		}
+	}
+	return fileIDs, nil
+}
+
+// ListVersions returns the IDs of the versions of a file stored on the node
+func (c *Client) ListVersions(fileID string) ([]string, error) {
+	inventory, err := c.list(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if len(inventory) == 0 {
+		return nil, &fs.PathError{Op: "list", Path: fileID, Err: fs.ErrNotExist}
+	}
+	versionIDs := make([]string, len(inventory))
+	for i, entry := range inventory {
+		versionIDs[i] = entry.VersionID
+	}
+	return versionIDs, nil
+}
+
+// list reads a List stream to the end, within listTimeout
+func (c *Client) list(fileID string) ([]storage.InventoryEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	stream, err := c.client.List(ctx, &nodepb.ListRequest{FileId: fileID})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+
+	var inventory []storage.InventoryEntry
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return inventory, nil
+		}
+		if err != nil {
+			return nil, fromStatus(err)
+		}
+		for _, entry := range resp.Entries {
+			item := storage.InventoryEntry{FileID: entry.FileId, VersionID: entry.VersionId, Checksum: entry.Checksum}
+			if entry.ModTime != 0 {
+				item.ModTime = time.Unix(0, entry.ModTime)
+			}
+			inventory = append(inventory, item)
+		}
+	}
+}
+
+// remoteError is an error reported by a node daemon. It keeps the node's
+// message and matches the sentinel error its status code stands for.
+type remoteError struct {
+	message string
+	target  error
+}
+
+// Error implements error
+func (e *remoteError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the sentinel error the node's status code stands for
+func (e *remoteError) Unwrap() error {
+	return e.target
+}
+
+// fromStatus converts a gRPC status back into an error matching the sentinel
+// toStatus encoded
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	var target error
+	switch st.Code() {
+	case codes.NotFound:
+		target = fs.ErrNotExist
+	case codes.DataLoss:
+		target = storage.ErrChecksumMismatch
+	case codes.PermissionDenied:
+		target = storage.ErrKeyMismatch
+	case codes.Canceled:
+		target = context.Canceled
+	case codes.DeadlineExceeded:
+		target = context.DeadlineExceeded
+	default:
+		return err
+	}
+	return &remoteError{message: st.Message(), target: target}
+}
@@ -0,0 +1,118 @@
+package storagenode
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	pb "github.com/yashlad/distributed-file-store/api/proto"
+	"google.golang.org/grpc"
+)
+
+// defaultHeartbeatInterval is how often heartbeats are sent until the
+// coordinator says otherwise
+const defaultHeartbeatInterval = 5 * time.Second
+
+// Heartbeater registers a node daemon with the coordinator and keeps it
+// registered by sending heartbeats at the interval the coordinator asks for
+type Heartbeater struct {
+	nodeID  string
+	address string
+	conn    *grpc.ClientConn
+	client  pb.FileStoreClient
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewHeartbeater creates a heartbeater that announces the node nodeID,
+// reachable at address, to the coordinator at coordinator. With clusterTLS
+// the coordinator is reached with mutual TLS, so it trusts the node to move
+// to a new address; without it the node can only register under an ID the
+// coordinator doesn't know yet.
+func NewHeartbeater(coordinator, nodeID, address string, clusterTLS *ClusterTLS) (*Heartbeater, error) {
+	conn, err := grpc.NewClient(coordinator, grpc.WithTransportCredentials(clusterTLS.clientCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &Heartbeater{
+		nodeID:  nodeID,
+		address: address,
+		conn:    conn,
+		client:  pb.NewFileStoreClient(conn),
+	}, nil
+}
+
+// Start sends heartbeats in the background until Stop is called
+func (h *Heartbeater) Start() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.done = make(chan struct{})
+
+	go func() {
+		defer close(h.done)
+
+		// Only changes are logged, so a coordinator that is down doesn't
+		// flood the log
+		registered := false
+		interval := defaultHeartbeatInterval
+		for first := true; ; first = false {
+			var err error
+			interval, err = h.beat(ctx, interval)
+			switch {
+			case ctx.Err() != nil:
+			case err != nil && (registered || first):
+				log.Printf("Heartbeat to the coordinator failed: %v", err)
+			case err == nil && !registered:
+				log.Printf("✓ Registered with the coordinator as %s (%s)", h.nodeID, h.address)
+			}
+			registered = err == nil
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// Stop stops sending heartbeats and closes the connection to the
+// coordinator
+func (h *Heartbeater) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cancel == nil {
+		return
+	}
+	h.cancel()
+	<-h.done
+	h.cancel = nil
+	h.conn.Close()
+}
+
+// beat sends one heartbeat, giving up once the next one is due, and returns
+// how long to wait for the next one
+func (h *Heartbeater) beat(ctx context.Context, interval time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	resp, err := h.client.Heartbeat(ctx, &pb.HeartbeatRequest{NodeId: h.nodeID, Address: h.address})
+	if err != nil {
+		return interval, err
+	}
+	if resp.IntervalMs <= 0 {
+		return defaultHeartbeatInterval, nil
+	}
+	return time.Duration(resp.IntervalMs) * time.Millisecond, nil
+}
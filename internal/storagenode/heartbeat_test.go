@@ -0,0 +1,99 @@
+package storagenode
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/yashlad/distributed-file-store/api/proto"
+	"google.golang.org/grpc"
+)
+
+// coordinator records the heartbeats it receives and asks for one every
+// 10ms
+type coordinator struct {
+	pb.UnimplementedFileStoreServer
+
+	mu         sync.Mutex
+	heartbeats []*pb.HeartbeatRequest
+
+	// fromPeers counts the heartbeats sent by verified cluster members
+	fromPeers int
+}
+
+// Heartbeat implements pb.FileStoreServer
+func (c *coordinator) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heartbeats = append(c.heartbeats, req)
+	if ClusterPeer(ctx) {
+		c.fromPeers++
+	}
+	return &pb.HeartbeatResponse{IntervalMs: 10}, nil
+}
+
+// count returns the number of heartbeats received
+func (c *coordinator) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.heartbeats)
+}
+
+func TestHeartbeater(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		clusterTLS *ClusterTLS
+	}{
+		{"plaintext", nil},
+		{"cluster TLS", testClusterTLS(t, "cluster")},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Listen failed: %v", err)
+			}
+			coord := &coordinator{}
+			server := grpc.NewServer(grpc.Creds(test.clusterTLS.ServerCredentials()))
+			pb.RegisterFileStoreServer(server, coord)
+			go server.Serve(listener)
+			defer server.Stop()
+
+			heartbeater, err := NewHeartbeater(listener.Addr().String(), "node-1", "10.0.0.1:50061", test.clusterTLS)
+			if err != nil {
+				t.Fatalf("NewHeartbeater failed: %v", err)
+			}
+			heartbeater.Start()
+
+			// Heartbeats follow the interval the coordinator asks for
+			deadline := time.Now().Add(5 * time.Second)
+			for coord.count() < 3 {
+				if time.Now().After(deadline) {
+					t.Fatalf("received %d heartbeats, want at least 3", coord.count())
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			heartbeater.Stop()
+
+			stopped := coord.count()
+			time.Sleep(50 * time.Millisecond)
+			if coord.count() != stopped {
+				t.Error("heartbeats sent after Stop")
+			}
+			first := coord.heartbeats[0]
+			if first.NodeId != "node-1" || first.Address != "10.0.0.1:50061" {
+				t.Errorf("heartbeat = %v, want node-1 at 10.0.0.1:50061", first)
+			}
+
+			// Only heartbeats over cluster TLS come from a verified member
+			want := 0
+			if test.clusterTLS != nil {
+				want = stopped
+			}
+			if coord.fromPeers != want {
+				t.Errorf("%d of %d heartbeats from cluster members, want %d", coord.fromPeers, stopped, want)
+			}
+		})
+	}
+}
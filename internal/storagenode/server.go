@@ -0,0 +1,327 @@
+package storagenode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+
+	nodepb "github.com/yashlad/distributed-file-store/api/proto/storagenode"
+	"github.com/yashlad/distributed-file-store/internal/chunker"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// streamChunkSize is the amount of data sent per stream message
+	streamChunkSize = 256 * 1024
+
+	// listBatchSize is the number of inventory entries sent per message
+	listBatchSize = 1000
+)
+
+// Server implements the StorageNode gRPC service over a local storage node
+type Server struct {
+	nodepb.UnimplementedStorageNodeServer
+	node       *storage.Node
+	clusterTLS *ClusterTLS
+}
+
+// NewServer creates a server for a storage node. The server copies versions
+// from other daemons of the cluster with clusterTLS; without it, it refuses
+// to copy from other daemons at all.
+func NewServer(node *storage.Node, clusterTLS *ClusterTLS) *Server {
+	return &Server{node: node, clusterTLS: clusterTLS}
+}
+
+// Put stores a version streamed by the client. The first message names the
+// version; if the client cancels the stream, the partial version is dropped.
+func (s *Server) Put(stream nodepb.StorageNode_PutServer) error {
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "no version given")
+	} else if err != nil {
+		return err
+	}
+	if first.FileId == "" || first.VersionId == "" {
+		return status.Error(codes.InvalidArgument, "file_id and version_id are required")
+	}
+
+	reader := &putReader{stream: stream, buf: first.Data}
+	info, err := s.node.StoreFileStream(first.FileId, first.VersionId, reader, first.Codec, first.Key)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	resp := &nodepb.PutResponse{Size: info.Size, StoredSize: info.StoredSize, Checksum: info.Checksum}
+	for _, chunk := range info.Chunks {
+		resp.Chunks = append(resp.Chunks, &nodepb.Chunk{
+			Digest: chunk.Digest,
+			Size:   chunk.Size,
+			Codec:  chunk.Codec,
+			KeyId:  chunk.KeyID,
+		})
+	}
+	return stream.SendAndClose(resp)
+}
+
+// putReader adapts a Put stream to an io.Reader
+type putReader struct {
+	stream nodepb.StorageNode_PutServer
+	buf    []byte
+}
+
+// Read implements io.Reader
+func (r *putReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = req.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Get streams a stored version. An empty first message confirms the version
+// was opened, so that clients learn of a missing version or a wrong key
+// before they start reading.
+func (s *Server) Get(req *nodepb.GetRequest, stream nodepb.StorageNode_GetServer) error {
+	reader, err := s.node.OpenFile(req.FileId, req.VersionId, req.Key)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer reader.Close()
+
+	if err := stream.Send(&nodepb.GetResponse{}); err != nil {
+		return err
+	}
+	return sendData(reader, func(data []byte) error {
+		return stream.Send(&nodepb.GetResponse{Data: data})
+	})
+}
+
+// sendData reads r to the end and passes its data to send a message at a time
+func sendData(r io.Reader, send func([]byte) error) error {
+	for {
+		// A sent message must not be modified, so each gets its own buffer
+		buf := make([]byte, streamChunkSize)
+		n, err := io.ReadFull(r, buf)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		if n > 0 {
+			if sendErr := send(buf[:n]); sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return toStatus(err)
+		}
+	}
+}
+
+// Delete deletes a version, or every version of a file
+func (s *Server) Delete(ctx context.Context, req *nodepb.DeleteRequest) (*nodepb.DeleteResponse, error) {
+	var err error
+	if req.VersionId == "" {
+		err = s.node.DeleteAllVersions(req.FileId)
+	} else {
+		err = s.node.DeleteFile(req.FileId, req.VersionId)
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &nodepb.DeleteResponse{}, nil
+}
+
+// List streams the versions of a file, or the node's whole inventory
+func (s *Server) List(req *nodepb.ListRequest, stream nodepb.StorageNode_ListServer) error {
+	var inventory []storage.InventoryEntry
+	if req.FileId == "" {
+		var err error
+		if inventory, err = s.node.Inventory(); err != nil {
+			return toStatus(err)
+		}
+	} else {
+		versionIDs, err := s.node.ListVersions(req.FileId)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return toStatus(err)
+		}
+		for _, versionID := range versionIDs {
+			checksum, _ := s.node.Checksum(req.FileId, versionID)
+			inventory = append(inventory, storage.InventoryEntry{FileID: req.FileId, VersionID: versionID, Checksum: checksum})
+		}
+	}
+
+	for start := 0; start < len(inventory); start += listBatchSize {
+		end := min(start+listBatchSize, len(inventory))
+		resp := &nodepb.ListResponse{}
+		for _, entry := range inventory[start:end] {
+			pbEntry := &nodepb.InventoryEntry{
+				FileId:    entry.FileID,
+				VersionId: entry.VersionID,
+				Checksum:  entry.Checksum,
+			}
+			if !entry.ModTime.IsZero() {
+				pbEntry.ModTime = entry.ModTime.UnixNano()
+			}
+			resp.Entries = append(resp.Entries, pbEntry)
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stat describes a version, or reports the node's storage size
+func (s *Server) Stat(ctx context.Context, req *nodepb.StatRequest) (*nodepb.StatResponse, error) {
+	if req.FileId == "" {
+		size, err := s.node.GetStorageSize()
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		return &nodepb.StatResponse{Exists: true, StorageSize: size}, nil
+	}
+
+	checksum, err := s.node.Checksum(req.FileId, req.VersionId)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, toStatus(err)
+	}
+	return &nodepb.StatResponse{
+		Exists:   s.node.FileExists(req.FileId, req.VersionId),
+		Checksum: checksum,
+	}, nil
+}
+
+// Replicate copies a version from another node daemon straight to this one,
+// so the data doesn't pass through the File Manager. The source is dialed
+// with the daemon's cluster TLS identity, so only a daemon with a
+// certificate from the cluster CA is copied from, and the copy is bounded by
+// replicateTimeout.
+func (s *Server) Replicate(ctx context.Context, req *nodepb.ReplicateRequest) (*nodepb.ReplicateResponse, error) {
+	if req.Source == "" {
+		return nil, status.Error(codes.InvalidArgument, "source is required")
+	}
+	if s.clusterTLS == nil {
+		return nil, status.Error(codes.FailedPrecondition, "copies between daemons need cluster TLS")
+	}
+	ctx, cancel := context.WithTimeout(ctx, replicateTimeout)
+	defer cancel()
+
+	source, err := Dial(req.Source, s.clusterTLS)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "dial %s: %v", req.Source, err)
+	}
+	defer source.Close()
+
+	reader, err := source.openFile(ctx, req.FileId, req.VersionId, req.Key)
+	if err != nil {
+		return nil, toStatus(fmt.Errorf("open on %s: %w", req.Source, err))
+	}
+	defer reader.Close()
+
+	counter := &countingReader{reader: reader}
+	if err := s.node.ReplicateFile(req.FileId, req.VersionId, counter, req.Codec, req.Key); err != nil {
+		return nil, toStatus(err)
+	}
+	return &nodepb.ReplicateResponse{Size: counter.n}, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+// Read implements io.Reader
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// Configure sets the chunk sizes the node splits new versions into
+func (s *Server) Configure(ctx context.Context, req *nodepb.ConfigureRequest) (*nodepb.ConfigureResponse, error) {
+	config := chunker.Config{
+		MinSize: int(req.ChunkMinSize),
+		AvgSize: int(req.ChunkAvgSize),
+		MaxSize: int(req.ChunkMaxSize),
+	}
+	if err := s.node.SetChunking(config); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &nodepb.ConfigureResponse{}, nil
+}
+
+// Daemon serves a storage node over gRPC on a TCP address
+type Daemon struct {
+	listener net.Listener
+	server   *grpc.Server
+}
+
+// Listen starts listening for StorageNode requests for node on address,
+// serving mutual TLS with clusterTLS, or plaintext without it. An address
+// with port 0 picks a free port; Address reports the one chosen.
+func Listen(address string, node *storage.Node, clusterTLS *ClusterTLS) (*Daemon, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	server := grpc.NewServer(grpc.Creds(clusterTLS.ServerCredentials()))
+	nodepb.RegisterStorageNodeServer(server, NewServer(node, clusterTLS))
+	return &Daemon{listener: listener, server: server}, nil
+}
+
+// Address returns the address the daemon listens on
+func (d *Daemon) Address() string {
+	return d.listener.Addr().String()
+}
+
+// Serve serves requests until the daemon is stopped
+func (d *Daemon) Serve() error {
+	return d.server.Serve(d.listener)
+}
+
+// GracefulStop stops accepting requests and waits for those in flight
+func (d *Daemon) GracefulStop() {
+	d.server.GracefulStop()
+}
+
+// Stop closes every connection immediately
+func (d *Daemon) Stop() {
+	d.server.Stop()
+}
+
+// toStatus converts a storage error into a gRPC status that fromStatus turns
+// back into an error matching the same sentinel
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := codes.Internal
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		code = codes.NotFound
+	case errors.Is(err, storage.ErrChecksumMismatch):
+		code = codes.DataLoss
+	case errors.Is(err, storage.ErrKeyMismatch):
+		code = codes.PermissionDenied
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, err.Error())
+}
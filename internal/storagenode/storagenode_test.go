@@ -0,0 +1,373 @@
+package storagenode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	nodepb "github.com/yashlad/distributed-file-store/api/proto/storagenode"
+	"github.com/yashlad/distributed-file-store/internal/chunker"
+	"github.com/yashlad/distributed-file-store/internal/compression"
+	"github.com/yashlad/distributed-file-store/internal/storage"
+	"google.golang.org/grpc"
+)
+
+// testChunking uses small chunks so tests stay fast
+var testChunking = chunker.Config{MinSize: 1024, AvgSize: 4096, MaxSize: 16384}
+
+// testClusterTLS loads the identity of a test node from testdata. The
+// "cluster" and "other" identities belong to different clusters, each with
+// its own CA; their certificates name localhost and 127.0.0.1.
+func testClusterTLS(t *testing.T, cluster string) *ClusterTLS {
+	t.Helper()
+	dir := filepath.Join("testdata", cluster)
+	clusterTLS, err := LoadClusterTLS(dir+"-node.pem", dir+"-node-key.pem", dir+"-ca.pem")
+	if err != nil {
+		t.Fatalf("LoadClusterTLS failed: %v", err)
+	}
+	return clusterTLS
+}
+
+// startNode runs a node daemon with the test cluster's TLS identity on a
+// free localhost port and returns the node it serves and a client connected
+// to it
+func startNode(t *testing.T, nodeID string) (*storage.Node, *Client) {
+	t.Helper()
+	return startNodeWith(t, nodeID, testClusterTLS(t, "cluster"))
+}
+
+// startNodeWith runs a node daemon with clusterTLS, or in plaintext if it
+// is nil, and connects to it the same way
+func startNodeWith(t *testing.T, nodeID string, clusterTLS *ClusterTLS) (*storage.Node, *Client) {
+	t.Helper()
+	node, err := storage.NewNode(nodeID, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewNode failed: %v", err)
+	}
+	daemon, err := Listen("127.0.0.1:0", node, clusterTLS)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go daemon.Serve()
+	t.Cleanup(daemon.Stop)
+
+	client, err := Dial(daemon.Address(), clusterTLS)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if err := client.SetChunking(testChunking); err != nil {
+		t.Fatalf("SetChunking failed: %v", err)
+	}
+	return node, client
+}
+
+// stalledServer is a daemon whose Put never reads or answers
+type stalledServer struct {
+	nodepb.UnimplementedStorageNodeServer
+}
+
+// Put implements StorageNodeServer, reading nothing until the client leaves
+func (stalledServer) Put(stream nodepb.StorageNode_PutServer) error {
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+// startStalled runs a stalled daemon in plaintext on a free localhost port
+func startStalled(t *testing.T) *Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	server := grpc.NewServer()
+	nodepb.RegisterStorageNodeServer(server, stalledServer{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	client, err := Dial(listener.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// failingReader returns its data and then fails
+type failingReader struct {
+	data []byte
+}
+
+// Read implements io.Reader
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("upload interrupted")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestClient(t *testing.T) {
+	data := bytes.Repeat([]byte("served over grpc "), 40000)
+	key := bytes.Repeat([]byte{7}, 32)
+
+	t.Run("stores and reads versions", func(t *testing.T) {
+		node, client := startNode(t, "node-1")
+		info, err := client.StoreFileStream("file-1", "v1", bytes.NewReader(data), compression.Zstd, key)
+		if err != nil {
+			t.Fatalf("StoreFileStream failed: %v", err)
+		}
+		if info.Size != int64(len(data)) || info.Checksum == "" || len(info.Chunks) == 0 {
+			t.Errorf("info = size %d, checksum %q, %d chunks", info.Size, info.Checksum, len(info.Chunks))
+		}
+		if chunks, _ := node.Chunks("file-1", "v1"); len(chunks) != len(info.Chunks) || chunks[0] != info.Chunks[0] {
+			t.Errorf("reported chunks differ from the stored ones")
+		}
+
+		retrieved, err := client.RetrieveFile("file-1", "v1", key)
+		if err != nil || !bytes.Equal(retrieved, data) {
+			t.Fatalf("RetrieveFile = %d bytes, %v", len(retrieved), err)
+		}
+		if checksum, err := client.Checksum("file-1", "v1"); err != nil || checksum != info.Checksum {
+			t.Errorf("Checksum = %q, %v, want %q", checksum, err, info.Checksum)
+		}
+		if !client.FileExists("file-1", "v1") || client.FileExists("file-1", "v2") {
+			t.Error("FileExists disagrees with what was stored")
+		}
+		if size, err := client.GetStorageSize(); err != nil || size == 0 {
+			t.Errorf("GetStorageSize = %d, %v", size, err)
+		}
+	})
+
+	t.Run("stores empty versions", func(t *testing.T) {
+		_, client := startNode(t, "node-1")
+		if _, err := client.StoreFileStream("file-1", "v1", bytes.NewReader(nil), "", nil); err != nil {
+			t.Fatalf("StoreFileStream failed: %v", err)
+		}
+		if retrieved, err := client.RetrieveFile("file-1", "v1", nil); err != nil || len(retrieved) != 0 {
+			t.Errorf("RetrieveFile = %q, %v", retrieved, err)
+		}
+	})
+
+	t.Run("lists and deletes versions", func(t *testing.T) {
+		_, client := startNode(t, "node-1")
+		client.StoreFileStream("file-1", "v1", bytes.NewReader(data), "", nil)
+		client.StoreFileStream("file-1", "v2", bytes.NewReader(data[:1000]), "", nil)
+		client.StoreFileStream("file-2", "v1", bytes.NewReader(data[:2000]), "", nil)
+
+		if files, err := client.ListFiles(); err != nil || len(files) != 2 {
+			t.Errorf("ListFiles = %v, %v", files, err)
+		}
+		if versions, err := client.ListVersions("file-1"); err != nil || len(versions) != 2 {
+			t.Errorf("ListVersions = %v, %v", versions, err)
+		}
+		inventory, err := client.Inventory()
+		if err != nil || len(inventory) != 3 || inventory[0].Checksum == "" || inventory[0].ModTime.IsZero() {
+			t.Errorf("Inventory = %+v, %v", inventory, err)
+		}
+
+		if err := client.DeleteFile("file-1", "v1"); err != nil {
+			t.Fatalf("DeleteFile failed: %v", err)
+		}
+		if versions, _ := client.ListVersions("file-1"); len(versions) != 1 || versions[0] != "v2" {
+			t.Errorf("versions after deleting v1 = %v", versions)
+		}
+		if err := client.DeleteAllVersions("file-1"); err != nil {
+			t.Fatalf("DeleteAllVersions failed: %v", err)
+		}
+		if _, err := client.ListVersions("file-1"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("ListVersions of a deleted file error = %v, want fs.ErrNotExist", err)
+		}
+		if err := client.DeleteFile("file-2", ""); err == nil {
+			t.Error("expected error deleting without a version")
+		}
+	})
+
+	t.Run("reports storage errors", func(t *testing.T) {
+		node, client := startNode(t, "node-1")
+		client.StoreFileStream("file-1", "v1", bytes.NewReader(data), "", key)
+
+		if _, err := client.OpenFile("file-1", "missing", key); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("OpenFile of a missing version error = %v, want fs.ErrNotExist", err)
+		}
+		if _, err := client.Checksum("file-1", "missing"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Checksum of a missing version error = %v, want fs.ErrNotExist", err)
+		}
+		wrong := bytes.Repeat([]byte{8}, 32)
+		if _, err := client.RetrieveFile("file-1", "v1", wrong); !errors.Is(err, storage.ErrKeyMismatch) {
+			t.Errorf("RetrieveFile with the wrong key error = %v, want ErrKeyMismatch", err)
+		}
+
+		chunks, _ := node.Chunks("file-1", "v1")
+		paths, _ := filepath.Glob(filepath.Join(node.StoragePath, ".chunks", chunks[0].Digest[:2], chunks[0].Digest+"*"))
+		os.WriteFile(paths[0], []byte("corrupted"), 0644)
+		if _, err := client.RetrieveFile("file-1", "v1", key); !errors.Is(err, storage.ErrChecksumMismatch) {
+			t.Errorf("RetrieveFile of a corrupted version error = %v, want ErrChecksumMismatch", err)
+		}
+	})
+
+	t.Run("drops interrupted uploads", func(t *testing.T) {
+		node, client := startNode(t, "node-1")
+		_, err := client.StoreFileStream("file-1", "v1", &failingReader{data: data}, "", nil)
+		if err == nil || err.Error() != "upload interrupted" {
+			t.Fatalf("StoreFileStream error = %v, want the read error", err)
+		}
+		// Versions are only committed once the stream ends cleanly
+		if node.FileExists("file-1", "v1") || client.FileExists("file-1", "v1") {
+			t.Error("interrupted version was stored")
+		}
+	})
+
+	t.Run("closes a version before reading it to the end", func(t *testing.T) {
+		_, client := startNode(t, "node-1")
+		client.StoreFileStream("file-1", "v1", bytes.NewReader(data), "", nil)
+		reader, err := client.OpenFile("file-1", "v1", nil)
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		head := make([]byte, 100)
+		if _, err := io.ReadFull(reader, head); err != nil || !bytes.Equal(head, data[:100]) {
+			t.Errorf("first bytes = %q, %v", head, err)
+		}
+		reader.Close()
+		if retrieved, err := client.RetrieveFile("file-1", "v1", nil); err != nil || !bytes.Equal(retrieved, data) {
+			t.Errorf("RetrieveFile after closing early = %d bytes, %v", len(retrieved), err)
+		}
+	})
+
+	t.Run("writes end with their context", func(t *testing.T) {
+		client := startStalled(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			// Far more than the stream's flow control window, so sends block
+			_, err := client.StoreFileStreamContext(ctx, "file-1", "v1", bytes.NewReader(data), "", nil)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("StoreFileStreamContext error = %v, want context.DeadlineExceeded", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("StoreFileStreamContext outlived its context")
+		}
+	})
+
+	t.Run("unreachable node", func(t *testing.T) {
+		_, client := startNode(t, "node-1")
+		unreachable, err := Dial("127.0.0.1:1", testClusterTLS(t, "cluster"))
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer unreachable.Close()
+		if _, err := unreachable.StoreFileStream("file-1", "v1", bytes.NewReader(data), "", nil); err == nil {
+			t.Error("expected error storing on an unreachable node")
+		}
+		if unreachable.FileExists("file-1", "v1") {
+			t.Error("unreachable node reported a version")
+		}
+		if _, err := client.ReplicateFrom(context.Background(), unreachable.Address(), "file-1", "v1", "", nil); err == nil {
+			t.Error("expected error replicating from an unreachable node")
+		}
+	})
+}
+
+func TestReplicateFrom(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("copied between daemons "), 20000)
+	key := bytes.Repeat([]byte{3}, 32)
+
+	_, source := startNode(t, "node-1")
+	_, target := startNode(t, "node-2")
+	info, err := source.StoreFileStream("file-1", "v1", bytes.NewReader(data), compression.Gzip, key)
+	if err != nil {
+		t.Fatalf("StoreFileStream failed: %v", err)
+	}
+
+	copied, err := target.ReplicateFrom(ctx, source.Address(), "file-1", "v1", compression.Gzip, key)
+	if err != nil {
+		t.Fatalf("ReplicateFrom failed: %v", err)
+	}
+	if copied != int64(len(data)) {
+		t.Errorf("copied %d bytes, want %d", copied, len(data))
+	}
+	if checksum, _ := target.Checksum("file-1", "v1"); checksum != info.Checksum {
+		t.Errorf("copy checksum = %q, want %q", checksum, info.Checksum)
+	}
+	if retrieved, err := target.RetrieveFile("file-1", "v1", key); err != nil || !bytes.Equal(retrieved, data) {
+		t.Errorf("RetrieveFile of the copy = %d bytes, %v", len(retrieved), err)
+	}
+
+	if _, err := target.ReplicateFrom(ctx, source.Address(), "file-1", "missing", "", key); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReplicateFrom of a missing version error = %v, want fs.ErrNotExist", err)
+	}
+
+	t.Run("sources outside the cluster are refused", func(t *testing.T) {
+		_, outsider := startNodeWith(t, "node-3", testClusterTLS(t, "other"))
+		if _, err := outsider.StoreFileStream("file-1", "v1", bytes.NewReader(data), "", key); err != nil {
+			t.Fatalf("StoreFileStream failed: %v", err)
+		}
+		if _, err := target.ReplicateFrom(ctx, outsider.Address(), "file-1", "v1", "", key); err == nil {
+			t.Error("expected error copying from a daemon of another cluster")
+		}
+	})
+
+	t.Run("copies need cluster TLS", func(t *testing.T) {
+		_, plain := startNodeWith(t, "node-3", nil)
+		if plain.PeerCopies() {
+			t.Error("plaintext daemon reports peer copies")
+		}
+		if _, err := plain.ReplicateFrom(ctx, source.Address(), "file-1", "v1", "", nil); err == nil {
+			t.Error("expected error copying to a plaintext daemon")
+		}
+	})
+}
+
+func TestClusterTLS(t *testing.T) {
+	data := []byte("only for cluster members")
+	key := bytes.Repeat([]byte{5}, 32)
+
+	t.Run("clients outside the cluster are refused", func(t *testing.T) {
+		_, member := startNode(t, "node-1")
+		outsider, err := Dial(member.Address(), testClusterTLS(t, "other"))
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer outsider.Close()
+		if _, err := outsider.StoreFileStream("file-1", "v1", bytes.NewReader(data), "", key); err == nil {
+			t.Error("expected error storing from another cluster")
+		}
+		plain, _ := Dial(member.Address(), nil)
+		defer plain.Close()
+		if _, err := plain.GetStorageSize(); err == nil {
+			t.Error("plaintext client was served")
+		}
+	})
+
+	t.Run("keys are not sent in cleartext", func(t *testing.T) {
+		node, plain := startNodeWith(t, "node-1", nil)
+		if _, err := plain.StoreFileStream("file-1", "v1", bytes.NewReader(data), "", key); !errors.Is(err, ErrInsecureKey) {
+			t.Errorf("StoreFileStream with a key error = %v, want ErrInsecureKey", err)
+		}
+		if node.FileExists("file-1", "v1") {
+			t.Error("version stored without TLS")
+		}
+		if _, err := plain.StoreFileStream("file-1", "v1", bytes.NewReader(data), "", nil); err != nil {
+			t.Fatalf("StoreFileStream without a key failed: %v", err)
+		}
+		if _, err := plain.OpenFile("file-1", "v1", key); !errors.Is(err, ErrInsecureKey) {
+			t.Errorf("OpenFile with a key error = %v, want ErrInsecureKey", err)
+		}
+	})
+}
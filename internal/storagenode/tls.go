@@ -0,0 +1,97 @@
+package storagenode
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+)
+
+// ClusterTLS is the mutual TLS identity of a cluster member. Node daemons,
+// the servers that reach them and daemons copying versions from each other
+// all present a certificate signed by the cluster CA and accept only peers
+// whose certificate is, so data keys never cross the network in cleartext
+// and a daemon only copies versions from members of its own cluster. Each
+// certificate must name the addresses its holder is reached at.
+type ClusterTLS struct {
+	certificate tls.Certificate
+	roots       *x509.CertPool
+}
+
+// LoadClusterTLS reads a member's certificate and key, and the cluster CA,
+// from PEM files
+func LoadClusterTLS(certFile, keyFile, caFile string) (*ClusterTLS, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+	}
+	return NewClusterTLS(certPEM, keyPEM, caPEM)
+}
+
+// NewClusterTLS creates a member's TLS identity from a PEM certificate and
+// key, and the PEM certificates of the cluster CA
+func NewClusterTLS(certPEM, keyPEM, caPEM []byte) (*ClusterTLS, error) {
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates in cluster CA")
+	}
+	return &ClusterTLS{certificate: certificate, roots: roots}, nil
+}
+
+// ServerCredentials returns the credentials a daemon, or a server taking
+// heartbeats from daemons, serves with, requiring clients to present a
+// certificate signed by the cluster CA. A nil identity serves in plaintext.
+func (c *ClusterTLS) ServerCredentials() credentials.TransportCredentials {
+	if c == nil {
+		return insecure.NewCredentials()
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{c.certificate},
+		ClientCAs:    c.roots,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})
+}
+
+// clientCredentials returns the credentials to dial a daemon with,
+// accepting only daemons whose certificate is signed by the cluster CA. A
+// nil identity dials in plaintext.
+func (c *ClusterTLS) clientCredentials() credentials.TransportCredentials {
+	if c == nil {
+		return insecure.NewCredentials()
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{c.certificate},
+		RootCAs:      c.roots,
+		MinVersion:   tls.VersionTLS12,
+	})
+}
+
+// ClusterPeer reports whether the caller of a request presented a
+// certificate the server verified, as callers of a listener serving with
+// ServerCredentials do
+func ClusterPeer(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(info.State.VerifiedChains) > 0
+}